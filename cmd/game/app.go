@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"game-engine/internal/agent"
 	"game-engine/internal/commands"
@@ -9,10 +10,15 @@ import (
 	"game-engine/internal/engineconfig"
 	"game-engine/internal/llm"
 	"game-engine/internal/logger"
+	"game-engine/internal/mainthread"
+	"game-engine/internal/render"
 	"game-engine/internal/scene"
 	"game-engine/internal/terminal"
 	"game-engine/internal/ui"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -21,6 +27,7 @@ import (
 type App struct {
 	Log       *logger.Logger
 	Scene     *scene.Scene
+	View      *render.View // raylib renderer and mouse editor for Scene
 	Debug     *debug.Debug
 	Registry  *commands.Registry
 	Terminal  *terminal.Terminal
@@ -28,28 +35,48 @@ type App struct {
 	Inspector *ui.Inspector
 	Agent     *agent.Agent
 	Client    llm.Client
+	Queue     *agent.Queue // natural-language requests, one agent run at a time
 
 	// Config state
-	CurrentProvider string // "ollama", "openai", "groq", or "" (auto)
+	CurrentProvider string // name registered in llm (e.g. "ollama", "anthropic"), or "" (auto)
 	CurrentAIModel  string
 	CurrentFont     string
+	AgentRetries    int                       // rounds the agent may use to correct failed actions (cmd retries)
+	AITimeouts      map[string]int            // request timeout in seconds per provider (cmd timeout); missing = default
+	PreviewMode     string                    // when LLM actions wait for cmd apply (cmd preview); "" = destructive
+	AIEndpoints     []engineconfig.AIEndpoint // custom providers from config/engine.json; saved back unchanged
+	UndoDepth       int                       // undo steps kept (cmd history --depth); 0 = scene default
+	AutosaveSecs    int                       // seconds between autosave snapshots; 0 = defaultAutosaveSecs, < 0 = off
+	AutosaveKeep    int                       // autosave snapshots kept; 0 = scene.DefaultAutosaveKeep
 
 	// Async result channels
 	DownloadDone     chan *downloadResult
 	SkyboxDone       chan *skyboxResult
 	FontDownloadDone chan *fontDownloadResult
-	PendingRunCmd    chan []string
+	MainThread       *mainthread.Queue // agent handlers submit scene mutations and commands here
+
+	// Agent progress line (setAgentStatus); written from the queue goroutine
+	statusMu    sync.Mutex
+	agentStatus string
+
+	// Undo step of the running agent request (see agentThread); set from the queue goroutine
+	requestMu     sync.Mutex
+	requestChange *scene.ChangeGroup
+
+	// Autosave state: scene revision of the last snapshot (or load) and when it was taken
+	autosaveRev uint64
+	autosaveAt  time.Time
 
 	// Internal draw state
-	baseNodes      []*ui.Node
-	uiFontTried    bool
+	baseNodes       []*ui.Node
+	uiFontTried     bool
 	engineFontPaths []string
 }
 
 type downloadResult struct {
-	Index int
-	Path  string
-	Err   error
+	IDs  []scene.ObjectID // objects selected when the download started
+	Path string
+	Err  error
 }
 
 type skyboxResult struct {
@@ -64,96 +91,278 @@ type fontDownloadResult struct {
 }
 
 func (app *App) SaveEnginePrefs() {
+	shadows, shadowQuality := app.View.Shadows()
+	shadowsPref := "on"
+	if !shadows {
+		shadowsPref = "off"
+	}
 	_ = engineconfig.Save(engineconfig.EnginePrefs{
-		ShowFPS:      app.Debug.ShowFPS,
-		ShowMemAlloc: app.Debug.ShowMemAlloc,
-		GridVisible:  app.Scene.GridVisible,
-		AIProvider:   app.CurrentProvider,
-		AIModel:      app.CurrentAIModel,
-		Font:         app.CurrentFont,
+		ShowFPS:       app.Debug.ShowFPS,
+		ShowMemAlloc:  app.Debug.ShowMemAlloc,
+		GridVisible:   app.View.GridVisible,
+		AIProvider:    app.CurrentProvider,
+		AIModel:       app.CurrentAIModel,
+		Font:          app.CurrentFont,
+		AgentRetries:  app.AgentRetries,
+		AITimeouts:    app.AITimeouts,
+		AIPreview:     app.PreviewMode,
+		AIEndpoints:   app.AIEndpoints,
+		UndoDepth:     app.UndoDepth,
+		Scene:         app.Scene.Name(),
+		AutosaveSecs:  app.AutosaveSecs,
+		AutosaveKeep:  app.AutosaveKeep,
+		Shadows:       shadowsPref,
+		ShadowQuality: shadowQuality,
 	})
 }
 
-// DefaultModelForProvider returns a sensible default model for a given provider.
+// defaultAutosaveSecs is the autosave interval when engine config does not set autosave_seconds.
+const defaultAutosaveSecs = 120
+
+// autosave writes a snapshot of the scene to the backup directory when it has unsaved changes that are not
+// in a snapshot yet and the autosave interval has passed since the last one. Called every frame.
+func (app *App) autosave() {
+	secs := app.AutosaveSecs
+	if secs == 0 {
+		secs = defaultAutosaveSecs
+	}
+	if secs < 0 || time.Since(app.autosaveAt) < time.Duration(secs)*time.Second {
+		return
+	}
+	if !app.Scene.Modified() || app.Scene.Revision() == app.autosaveRev {
+		return
+	}
+	app.autosaveAt = time.Now()
+	app.autosaveRev = app.Scene.Revision()
+	if _, err := app.Scene.Autosave(app.AutosaveKeep); err != nil {
+		app.Log.Log("Autosave: " + err.Error())
+	}
+}
+
+// RegisterEndpoints adds the custom OpenAI-compatible endpoints from engine config to the llm provider
+// registry, so they can be selected with cmd provider <name>. Header values may reference env vars ($NAME).
+// Invalid entries are skipped with a log line.
+func RegisterEndpoints(endpoints []engineconfig.AIEndpoint, log func(string)) {
+	for i, e := range endpoints {
+		if e.Name == "" || e.BaseURL == "" {
+			log(fmt.Sprintf("LLM: ai_endpoints[%d]: name and base_url are required", i))
+			continue
+		}
+		auth, err := llm.ParseAuthType(e.Auth)
+		if err != nil {
+			log(fmt.Sprintf("LLM: endpoint %s: %v", e.Name, err))
+			continue
+		}
+		headers := make(map[string]string, len(e.Headers))
+		for k, v := range e.Headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		llm.RegisterProvider(llm.OpenAICompatProvider(strings.ToLower(e.Name), e.BaseURL, e.KeyEnv, auth, headers, e.DefaultModel))
+	}
+}
+
+// DefaultModelForProvider returns the registered default model for a provider (gpt-4o-mini if unknown).
 func DefaultModelForProvider(provider string) string {
-	switch provider {
-	case "groq":
-		return "llama-3.3-70b-versatile"
-	case "openai":
-		return "gpt-4o-mini"
-	case "ollama":
-		return "qwen3-coder:30b"
-	default:
-		return "gpt-4o-mini"
+	if p, ok := llm.LookupProvider(provider); ok && p.DefaultModel != "" {
+		return p.DefaultModel
+	}
+	return "gpt-4o-mini"
+}
+
+// DefaultTimeoutForProvider returns the registered request timeout in seconds for a provider.
+func DefaultTimeoutForProvider(provider string) int {
+	if p, ok := llm.LookupProvider(provider); ok && p.Timeout > 0 {
+		return int(p.Timeout / time.Second)
 	}
+	return int(llm.DefaultTimeout / time.Second)
+}
+
+// RequestTimeout returns the agent request timeout for the current provider.
+func (app *App) RequestTimeout() time.Duration {
+	secs, ok := app.AITimeouts[app.CurrentProvider]
+	if !ok {
+		secs = DefaultTimeoutForProvider(app.CurrentProvider)
+	}
+	return time.Duration(secs) * time.Second
 }
 
 // RebuildAgent recreates the LLM agent with the current client and wires it to the terminal.
+// The conversation history of the previous agent (if any) is kept so a provider switch does not lose context.
 func (app *App) RebuildAgent() {
 	if app.Client == nil {
 		return
 	}
-	app.Agent = agent.New(app.Client, func() string { return app.CurrentAIModel })
-	agent.RegisterSceneHandlers(app.Agent, app.Scene, app.Registry, app.PendingRunCmd)
+	var conv *agent.Conversation
+	if app.Agent != nil {
+		conv = app.Agent.Conversation()
+	}
+	// A pending preview belongs to the old agent; drop its overlay (Reject would wait for the main thread).
+	app.View.ClearPreview()
+	model := app.CurrentAIModel // captured: Run reads it on the queue goroutine; cmd model rebuilds the agent
+	app.Agent = agent.New(app.Client, func() string { return model })
+	app.Agent.SetConversation(conv)
+	app.Agent.SetRetries(app.AgentRetries)
+	app.Agent.SetLogger(app.Log.Log)
+	app.Agent.SetStatus(app.setAgentStatus)
+	app.Agent.SetPreviewMode(app.PreviewMode)
+	app.Agent.SetPreviewDisplay(app.showPreview)
+	agent.RegisterSceneHandlers(app.Agent, app.Scene, app.Registry, agentThread{app})
+	if app.Queue == nil {
+		app.Queue = agent.NewQueue(app.runRequest)
+	}
 	if app.Terminal != nil {
 		app.Terminal.GetViewContext = func() string { return app.Scene.GetViewContextSummary() }
 		app.Terminal.OnNaturalLanguage = func(line string, viewContext string) {
-			app.Log.Log("Thinking…")
-			summary, err := app.Agent.Run(context.Background(), line, viewContext)
-			if err != nil {
+			r := agent.Request{Line: line, ViewContext: viewContext, Timeout: app.RequestTimeout()}
+			if ahead := app.Queue.Submit(r); ahead > 0 {
+				app.Log.Log(fmt.Sprintf("Queued (%d ahead). cmd cancel stops the running request.", ahead))
+			}
+			app.refreshAgentStatus()
+		}
+		app.Terminal.OnCancel = func() {
+			if err := app.Registry.Execute([]string{"cancel"}); err != nil {
 				app.Log.Log(err.Error())
-			} else {
-				app.Log.Log(summary)
 			}
 		}
 	}
 }
 
-// BuildLLMClient creates an LLM client for the given provider using env vars for API keys.
-func BuildLLMClient(provider string) (llm.Client, error) {
-	switch provider {
-	case "openai":
-		key := os.Getenv("OPENAI_API_KEY")
-		if key == "" {
-			return nil, fmt.Errorf("OPENAI_API_KEY not set in .env")
-		}
-		return llm.NewOpenAICompat("openai", llm.OpenAIBaseURL, key, llm.AuthBearer), nil
-	case "groq":
-		key := os.Getenv("GROQ_API_KEY")
-		if key == "" {
-			return nil, fmt.Errorf("GROQ_API_KEY not set in .env")
-		}
-		return llm.NewOpenAICompat("groq", llm.GroqBaseURL, key, llm.AuthBearer), nil
-	case "ollama":
-		base := os.Getenv("OLLAMA_BASE_URL")
-		return llm.NewOllama(base), nil
+// runRequest runs one queued natural-language request on the queue's goroutine and logs the outcome.
+// App fields are only read on the main thread, so the current agent is fetched through MainThread.
+func (app *App) runRequest(ctx context.Context, r agent.Request) {
+	var a *agent.Agent
+	_ = app.MainThread.Do(context.Background(), func() error {
+		a = app.Agent
+		return nil
+	})
+	var summary string
+	var err error
+	label := r.Line
+	if r.Decision == agent.DecisionApply {
+		label = "cmd apply"
+	}
+	// All actions of the request undo as one step (see agentThread).
+	app.requestMu.Lock()
+	app.requestChange = &scene.ChangeGroup{Label: label}
+	app.requestMu.Unlock()
+	defer func() {
+		app.requestMu.Lock()
+		app.requestChange = nil
+		app.requestMu.Unlock()
+	}()
+	switch r.Decision {
+	case agent.DecisionApply:
+		app.setAgentStatus("Applying preview…")
+		summary, err = a.Apply(ctx)
+	case agent.DecisionReject:
+		label = "cmd reject"
+		summary, err = a.Reject()
+	default:
+		app.setAgentStatus("Thinking…")
+		summary, err = a.Run(ctx, r.Line, r.ViewContext)
+	}
+	app.setAgentStatus("")
+	switch {
+	case errors.Is(err, context.Canceled):
+		app.Log.Log("Request cancelled: " + label)
+	case errors.Is(err, context.DeadlineExceeded):
+		app.Log.Log(fmt.Sprintf("Request timed out after %s: %s (cmd timeout <seconds> to change)", r.Timeout, label))
+	case err != nil:
+		app.Log.Log(err.Error())
 	default:
-		return nil, fmt.Errorf("unknown provider %q (use: ollama, openai, groq)", provider)
+		app.Log.Log(summary)
 	}
 }
 
-func (app *App) Update() {
-	drainChan(app.PendingRunCmd, func(args []string) {
-		if err := app.Registry.Execute(args); err != nil {
-			app.Log.Log(err.Error())
+// agentThread is the MainThread the agent handlers run their scene work through. Each batch runs inside
+// the undo step of the running request, so the request's actions undo as one step while the commands,
+// drags and physics motion of the user between them stay out of it.
+type agentThread struct{ app *App }
+
+func (t agentThread) Do(ctx context.Context, fn func() error) error {
+	t.app.requestMu.Lock()
+	g := t.app.requestChange
+	t.app.requestMu.Unlock()
+	return t.app.MainThread.Do(ctx, func() error {
+		if g != nil {
+			t.app.Scene.BeginGroupChange(g)
+			defer t.app.Scene.EndGroupChange(g)
+		}
+		return fn()
+	})
+}
+
+// showPreview draws a pending agent plan in the editor, or clears it when effects is nil. Called from the
+// queue goroutine.
+func (app *App) showPreview(effects []agent.Effect) {
+	var adds []scene.ObjectInstance
+	var deletes []scene.ObjectID
+	for _, e := range effects {
+		adds = append(adds, e.Adds...)
+		deletes = append(deletes, e.Deletes...)
+	}
+	// Not tied to a request: clearing the overlay must happen even after a cancel.
+	_ = app.MainThread.Do(context.Background(), func() error {
+		if effects == nil {
+			app.View.ClearPreview()
+		} else {
+			app.View.SetPreview(adds, deletes)
 		}
+		return nil
 	})
+}
+
+// setAgentStatus shows the agent's progress line, followed by the number of queued requests.
+// An empty line with nothing queued clears the status.
+func (app *App) setAgentStatus(line string) {
+	app.statusMu.Lock()
+	app.agentStatus = line
+	app.statusMu.Unlock()
+	app.refreshAgentStatus()
+}
+
+// refreshAgentStatus redraws the status line after the queue changed (submit, cancel).
+func (app *App) refreshAgentStatus() {
+	app.statusMu.Lock()
+	line := app.agentStatus
+	app.statusMu.Unlock()
+	_, pending := app.Queue.Status()
+	if pending > 0 {
+		line = strings.TrimSpace(fmt.Sprintf("%s [%d queued]", line, pending))
+	}
+	app.Log.SetStatus(line)
+}
+
+func (app *App) Update() {
+	app.MainThread.Drain()
+	app.autosave()
 
 	drainChan(app.DownloadDone, func(res *downloadResult) {
 		if res.Err != nil {
 			app.Log.Log(res.Err.Error())
-		} else if err := app.Scene.SetObjectTexture(res.Index, res.Path); err != nil {
-			app.Log.Log(err.Error())
-		} else {
-			app.Log.Log("Texture applied: " + res.Path)
+			return
+		}
+		// Objects deleted while the download ran are skipped; the rest change as one undo step.
+		app.Scene.BeginChange("texture")
+		defer app.Scene.EndChange()
+		applied := 0
+		for _, id := range res.IDs {
+			if app.Scene.SetObjectTexture(id, res.Path) == nil {
+				applied++
+			}
+		}
+		if applied == 0 {
+			app.Log.Log("Texture not applied: the selected object(s) were deleted")
+			return
 		}
+		app.Log.Log(fmt.Sprintf("Texture applied to %d object(s): %s", applied, res.Path))
 	})
 
 	drainChan(app.SkyboxDone, func(res *skyboxResult) {
 		if res.Err != nil {
 			app.Log.Log(res.Err.Error())
 		} else {
-			app.Scene.SetSkyboxPath(res.Path)
+			app.View.SetSkyboxPath(res.Path)
 			app.Log.Log("Skybox set: " + res.Path)
 		}
 	})
@@ -175,36 +384,62 @@ func (app *App) Update() {
 	app.Terminal.Update()
 
 	if app.Terminal.IsOpen() {
-		app.Scene.UpdateEditor(true, terminal.BarHeight)
-		if obj, ok := app.Scene.SelectedObject(); ok {
-			if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
-				screenH := int32(rl.GetScreenHeight())
-				mouseY := rl.GetMouseY()
-				if mouseY < screenH-int32(terminal.BarHeight) {
-					hitNode, hit := app.UI.HitTest(rl.GetMouseX(), mouseY)
-					if hit && hitNode != nil && hitNode.Class == "inspector-physics" {
-						_ = app.Scene.SetSelectedPhysics(!scene.PhysicsEnabledForObject(obj))
-					}
+		// A click on the inspector's physics field toggles physics for the whole selection (on unless all
+		// are on) and is not passed to the editor, so it neither picks nor starts a marquee.
+		inspectorClick := false
+		if _, ok := app.Scene.SelectedObject(); ok && rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
+			screenH := int32(rl.GetScreenHeight())
+			mouseY := rl.GetMouseY()
+			if mouseY < screenH-int32(terminal.BarHeight) {
+				hitNode, hit := app.UI.HitTest(rl.GetMouseX(), mouseY)
+				if hit && hitNode != nil && hitNode.Class == "inspector-physics" {
+					inspectorClick = true
+					sel := app.selectionSummary()
+					_ = app.Scene.SetSelectedPhysics(sel.PhysicsMixed || !sel.Physics)
 				}
 			}
 		}
+		app.View.UpdateEditor(!inspectorClick, terminal.BarHeight)
 	} else {
-		app.Scene.Update()
+		app.View.Update()
 	}
 }
 
-func (app *App) Draw() {
-	app.Scene.Draw(app.Terminal.IsOpen())
-	app.Debug.Draw()
-
-	obj, ok := app.Scene.SelectedObject()
-	nodes := app.Inspector.AppendNodes(app.baseNodes, app.Terminal.IsOpen() && ok, ui.Selection{
+// selectionSummary describes the selection for the inspector: the primary object's fields, or for several
+// objects their count, center and which fields differ.
+func (app *App) selectionSummary() ui.Selection {
+	obj, _ := app.Scene.SelectedObject()
+	sel := ui.Selection{
+		Count:    len(app.Scene.Selection()),
 		Name:     obj.Type,
 		Position: obj.Position,
 		Scale:    obj.Scale,
 		Physics:  scene.PhysicsEnabledForObject(obj),
 		Texture:  obj.Texture,
-	})
+	}
+	if sel.Count < 2 {
+		return sel
+	}
+	if box, ok := app.Scene.SelectionBounds(); ok {
+		for k := 0; k < 3; k++ {
+			sel.Position[k] = (box.Min[k] + box.Max[k]) / 2
+		}
+	}
+	for _, i := range app.Scene.Selection() {
+		o, _ := app.Scene.ObjectAt(i)
+		sel.NameMixed = sel.NameMixed || o.Type != obj.Type
+		sel.ScaleMixed = sel.ScaleMixed || o.Scale != obj.Scale
+		sel.PhysicsMixed = sel.PhysicsMixed || scene.PhysicsEnabledForObject(o) != sel.Physics
+		sel.TextureMixed = sel.TextureMixed || o.Texture != obj.Texture
+	}
+	return sel
+}
+
+func (app *App) Draw() {
+	app.View.Draw(app.Terminal.IsOpen())
+	app.Debug.Draw()
+
+	nodes := app.Inspector.AppendNodes(app.baseNodes, app.Terminal.IsOpen() && len(app.Scene.Selection()) > 0, app.selectionSummary())
 
 	if !app.uiFontTried {
 		app.uiFontTried = true
@@ -3,13 +3,20 @@ package main
 import (
 	"flag"
 	"fmt"
+	"game-engine/internal/agent"
+	"game-engine/internal/commands"
 	"game-engine/internal/download"
 	"game-engine/internal/fonts"
 	"game-engine/internal/googlefonts"
+	"game-engine/internal/llm"
 	"game-engine/internal/mapgen"
+	"game-engine/internal/primitives"
+	"game-engine/internal/render"
 	"game-engine/internal/scene"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -26,14 +33,19 @@ func registerCommands(app *App) {
 	gridFS := flag.NewFlagSet("grid", flag.ContinueOnError)
 	gridFS.BoolVar(&showGrid, "show", false, "show grid")
 	gridFS.BoolVar(&hideGrid, "hide", false, "hide grid")
-	reg.Register("grid", gridFS, func() error {
+	reg.Register("grid", gridFS, commands.Help{
+		Description: "Show or hide the 3D editor grid.",
+		Examples:    [][]string{{"grid", "--show"}, {"grid", "--hide"}},
+		Args:        []commands.Arg{{Name: "--show", Optional: true}, {Name: "--hide", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		s, h := showGrid, hideGrid
 		showGrid, hideGrid = false, false
 		if s {
-			scn.SetGridVisible(true)
+			app.View.SetGridVisible(true)
 		}
 		if h {
-			scn.SetGridVisible(false)
+			app.View.SetGridVisible(false)
 		}
 		app.SaveEnginePrefs()
 		return nil
@@ -44,7 +56,12 @@ func registerCommands(app *App) {
 	fpsFS := flag.NewFlagSet("fps", flag.ContinueOnError)
 	fpsFS.BoolVar(&showFPS, "show", false, "show FPS")
 	fpsFS.BoolVar(&hideFPS, "hide", false, "hide FPS")
-	reg.Register("fps", fpsFS, func() error {
+	reg.Register("fps", fpsFS, commands.Help{
+		Description: "Show or hide the FPS counter.",
+		Examples:    [][]string{{"fps", "--show"}, {"fps", "--hide"}},
+		Args:        []commands.Arg{{Name: "--show", Optional: true}, {Name: "--hide", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		s, h := showFPS, hideFPS
 		showFPS, hideFPS = false, false
 		if s {
@@ -62,7 +79,12 @@ func registerCommands(app *App) {
 	memallocFS := flag.NewFlagSet("memalloc", flag.ContinueOnError)
 	memallocFS.BoolVar(&showMemAlloc, "show", false, "show memory allocation")
 	memallocFS.BoolVar(&hideMemAlloc, "hide", false, "hide memory allocation")
-	reg.Register("memalloc", memallocFS, func() error {
+	reg.Register("memalloc", memallocFS, commands.Help{
+		Description: "Show or hide the memory usage counter.",
+		Examples:    [][]string{{"memalloc", "--show"}, {"memalloc", "--hide"}},
+		Args:        []commands.Arg{{Name: "--show", Optional: true}, {Name: "--hide", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		s, h := showMemAlloc, hideMemAlloc
 		showMemAlloc, hideMemAlloc = false, false
 		if s {
@@ -81,17 +103,8 @@ func registerCommands(app *App) {
 	// spawn: add a primitive at a position. Usage: cmd spawn <type> <x> <y> <z> [sx sy sz]
 	registerSpawnCmd(app)
 
-	// save: write current scene to YAML
-	saveFS := flag.NewFlagSet("save", flag.ContinueOnError)
-	reg.Register("save", saveFS, func() error {
-		return scn.SaveScene()
-	})
-
-	// newscene: clear all primitives and save an empty scene
-	newsceneFS := flag.NewFlagSet("newscene", flag.ContinueOnError)
-	reg.Register("newscene", newsceneFS, func() error {
-		return scn.NewScene()
-	})
+	// save, load, scenes, newscene, validate: the scene library in assets/scenes
+	registerSceneCmds(app)
 
 	// provider: switch LLM provider at runtime
 	registerProviderCmd(app)
@@ -99,9 +112,28 @@ func registerCommands(app *App) {
 	// model: set AI model for natural-language commands
 	registerModelCmd(app)
 
+	// chat: show or reset the LLM conversation history
+	registerChatCmd(app)
+
+	// retries: how many rounds the agent may use to correct failed actions
+	registerRetriesCmd(app)
+
+	// cancel, timeout: control queued natural-language requests
+	registerCancelCmd(app)
+	registerTimeoutCmd(app)
+
+	// apply, reject, preview: confirm or discard previewed LLM actions
+	registerPreviewCmds(app)
+
 	// physics: enable or disable falling/collision for the selected object
 	physicsFS := flag.NewFlagSet("physics", flag.ContinueOnError)
-	reg.Register("physics", physicsFS, func() error {
+	reg.Register("physics", physicsFS, commands.Help{
+		Description: "Enable or disable physics (gravity/collision) on the selected object(s). User must select first.",
+		Usage:       "on | off",
+		Examples:    [][]string{{"physics", "on"}, {"physics", "off"}},
+		Args:        []commands.Arg{{Name: "state", Enum: []string{"on", "off"}}},
+		LLM:         true,
+	}, func() error {
 		args := physicsFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd physics on | cmd physics off (select an object first)")
@@ -123,13 +155,39 @@ func registerCommands(app *App) {
 
 	// inspect: print details about an object
 	inspectFS := flag.NewFlagSet("inspect", flag.ContinueOnError)
-	reg.Register("inspect", inspectFS, func() error {
+	reg.Register("inspect", inspectFS, commands.Help{
+		Description: "Print type, name, position, scale, rotation, color, physics, motion and texture of the selected object (the primary one when several are selected), or the closest in view.",
+		Examples:    [][]string{{"inspect"}},
+		LLM:         true,
+	}, func() error {
 		args := inspectFS.Args()
 		if len(args) != 0 {
 			return fmt.Errorf("usage: cmd inspect (no arguments)")
 		}
 		if obj, ok := scn.SelectedObject(); ok {
 			log.Log(formatObjectInfo("Selected", obj))
+			if n := len(scn.Selection()); n > 1 {
+				log.Log(fmt.Sprintf("  (primary of %d selected objects)", n))
+			}
+			sel := scn.SelectedIndex()
+			if p := scn.Parent(sel); p >= 0 {
+				log.Log(fmt.Sprintf("  parent=%s (position and scale are local to it)", objectLabel(scn, p)))
+			}
+			if n := len(scn.Children(sel)); n > 0 {
+				log.Log(fmt.Sprintf("  children=%d", n))
+			}
+			if obj.Prefab != "" {
+				log.Log(fmt.Sprintf("  linked to prefab %s (cmd prefab unlink detaches it)", obj.Prefab))
+			}
+			if obj.Model != "" {
+				log.Log(fmt.Sprintf("  model=%s", obj.Model))
+			}
+			if obj.Material != "" {
+				log.Log(fmt.Sprintf("  material=%s", obj.Material))
+			}
+			if obj.Light != nil {
+				log.Log("  " + formatLight(*obj.Light))
+			}
 			return nil
 		}
 		visible := scn.ObjectsInView()
@@ -140,14 +198,21 @@ func registerCommands(app *App) {
 		return nil
 	})
 
-	// download, texture, skybox
+	// download, texture, material, skybox
 	registerDownloadCmd(app)
 	registerTextureCmd(app)
+	registerMaterialCmd(app)
 	registerSkyboxCmd(app)
 
 	// color: set RGB (0-1) on selected object
 	colorFS := flag.NewFlagSet("color", flag.ContinueOnError)
-	reg.Register("color", colorFS, func() error {
+	reg.Register("color", colorFS, commands.Help{
+		Description: "Set the RGB color (0-1) of every selected object. Use for \"make it red\", \"paint selected green\". User must select first.",
+		Usage:       "<r> <g> <b>",
+		Examples:    [][]string{{"color", "1", "0", "0"}},
+		Args:        []commands.Arg{{Name: "r", Description: "0-1"}, {Name: "g", Description: "0-1"}, {Name: "b", Description: "0-1"}},
+		LLM:         true,
+	}, func() error {
 		args := colorFS.Args()
 		if len(args) < 3 {
 			return fmt.Errorf("usage: cmd color <r> <g> <b> (0-1, e.g. cmd color 1 0 0)")
@@ -165,7 +230,13 @@ func registerCommands(app *App) {
 
 	// duplicate: clone selected object N times with offset
 	duplicateFS := flag.NewFlagSet("duplicate", flag.ContinueOnError)
-	reg.Register("duplicate", duplicateFS, func() error {
+	reg.Register("duplicate", duplicateFS, commands.Help{
+		Description: "Clone each selected object N times (N=1 if not specified). Use for \"duplicate this\", \"clone it 5 times\". User must select first.",
+		Usage:       "[N]",
+		Examples:    [][]string{{"duplicate", "5"}},
+		Args:        []commands.Arg{{Name: "N", Description: "number of copies (max 20)", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		n := 1
 		if args := duplicateFS.Args(); len(args) >= 1 {
 			if v, err := strconv.Atoi(args[0]); err == nil && v >= 1 {
@@ -186,18 +257,35 @@ func registerCommands(app *App) {
 
 	// lighting: set time-of-day profile
 	lightingFS := flag.NewFlagSet("lighting", flag.ContinueOnError)
-	reg.Register("lighting", lightingFS, func() error {
+	reg.Register("lighting", lightingFS, commands.Help{
+		Description: "Set the time-of-day lighting: the sun's direction, color and brightness and the ambient light. Night is dark but for light objects (cmd light). Use for \"sunset lighting\", \"make it night\".",
+		Usage:       "noon | sunset | night",
+		Examples:    [][]string{{"lighting", "sunset"}},
+		Args:        []commands.Arg{{Name: "profile", Enum: scene.LightingProfiles}},
+		LLM:         true,
+	}, func() error {
 		args := lightingFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd lighting noon | sunset | night")
 		}
-		scn.SetLighting(args[0])
-		return nil
+		return scn.SetLighting(args[0])
 	})
 
+	// light: add point and spot lights, set their intensity, range and cone
+	registerLightCmd(app)
+
+	// shadows: sun shadows on/off and quality (saved in engine config)
+	registerShadowsCmd(app)
+
 	// name: set name on selected object
 	nameFS := flag.NewFlagSet("name", flag.ContinueOnError)
-	reg.Register("name", nameFS, func() error {
+	reg.Register("name", nameFS, commands.Help{
+		Description: "Set the name of the selected object(s). Use for \"name this Tower\", \"call it Building1\". User must select first.",
+		Usage:       "<name>",
+		Examples:    [][]string{{"name", "Tower"}},
+		Args:        []commands.Arg{{Name: "name"}},
+		LLM:         true,
+	}, func() error {
 		args := nameFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd name <name>")
@@ -207,33 +295,119 @@ func registerCommands(app *App) {
 
 	// motion: set motion on selected
 	motionFS := flag.NewFlagSet("motion", flag.ContinueOnError)
-	reg.Register("motion", motionFS, func() error {
+	reg.Register("motion", motionFS, commands.Help{
+		Description: "Set motion on the selected object(s): bob (gentle up/down), spin (turn about Y) or off. Use for \"make it bounce\", \"make it spin\". User must select first.",
+		Usage:       "bob | spin | off",
+		Examples:    [][]string{{"motion", "bob"}, {"motion", "spin"}, {"motion", "off"}},
+		Args:        []commands.Arg{{Name: "motion", Enum: []string{"bob", "spin", "off"}}},
+		LLM:         true,
+	}, func() error {
 		args := motionFS.Args()
 		if len(args) < 1 {
-			return fmt.Errorf("usage: cmd motion off | bob")
+			return fmt.Errorf("usage: cmd motion off | bob | spin")
 		}
 		m := args[0]
-		if m == "off" {
+		switch m {
+		case "off":
 			m = ""
+		case "bob", "spin":
+		default:
+			return fmt.Errorf("unknown motion %q (use bob, spin or off)", m)
 		}
 		return scn.SetSelectedMotion(m)
 	})
 
-	// undo: revert last add or delete
-	undoFS := flag.NewFlagSet("undo", flag.ContinueOnError)
-	reg.Register("undo", undoFS, func() error {
-		return scn.Undo()
+	// rotate: set or add rotation (degrees) on selected
+	var rotateBy bool
+	rotateFS := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	rotateFS.BoolVar(&rotateBy, "by", false, "turn by the angles (about world X, Y, Z) instead of setting them")
+	reg.Register("rotate", rotateFS, commands.Help{
+		Description: "Set the rotation of the selected object(s) in degrees about X, then Y, then Z; with --by, turn each by those angles instead. Use for \"tilt it 20 degrees\", \"turn it around\", ramps and roofs. User must select first.",
+		Usage:       "[--by] <rx> <ry> <rz>",
+		Examples:    [][]string{{"rotate", "0", "45", "0"}, {"rotate", "--by", "0", "90", "0"}, {"rotate", "0", "0", "0"}},
+		Args: []commands.Arg{
+			{Name: "--by", Description: "relative turn", Optional: true},
+			{Name: "rx", Description: "degrees"}, {Name: "ry", Description: "degrees"}, {Name: "rz", Description: "degrees"},
+		},
+		LLM: true,
+	}, func() error {
+		args := rotateFS.Args()
+		by := rotateBy
+		rotateBy = false
+		if len(args) != 3 {
+			return fmt.Errorf("usage: cmd rotate [--by] <rx> <ry> <rz> (degrees, e.g. cmd rotate 0 45 0)")
+		}
+		var r [3]float32
+		for i := 0; i < 3; i++ {
+			f, err := strconv.ParseFloat(args[i], 32)
+			if err != nil {
+				return fmt.Errorf("invalid angle %q: %w", args[i], err)
+			}
+			r[i] = float32(f)
+		}
+		if !by {
+			return scn.SetSelectedRotation(r)
+		}
+		// X, then Y, then Z, matching the absolute form.
+		for i, axis := range [][3]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+			if r[i] == 0 {
+				continue
+			}
+			if err := scn.RotateSelected(axis, r[i]); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 
+	// gizmo: choose what dragging the selection does in the editor
+	gizmoFS := flag.NewFlagSet("gizmo", flag.ContinueOnError)
+	reg.Register("gizmo", gizmoFS, commands.Help{
+		Description: "Choose what dragging the selection does: move (default) or rotate (drag sideways to turn about Y, up/down to tip; press Shift during the drag to snap to 15°). No argument prints the current mode.",
+		Usage:       "[move | rotate]",
+		Examples:    [][]string{{"gizmo", "rotate"}, {"gizmo", "move"}},
+		Args:        []commands.Arg{{Name: "mode", Enum: []string{"move", "rotate"}, Optional: true}},
+	}, func() error {
+		args := gizmoFS.Args()
+		if len(args) == 0 {
+			mode := "move"
+			if app.View.GizmoMode() == render.GizmoRotate {
+				mode = "rotate"
+			}
+			log.Log("Gizmo: " + mode)
+			return nil
+		}
+		switch args[0] {
+		case "move":
+			app.View.SetGizmoMode(render.GizmoMove)
+		case "rotate":
+			app.View.SetGizmoMode(render.GizmoRotate)
+		default:
+			return fmt.Errorf("usage: cmd gizmo move | rotate")
+		}
+		return nil
+	})
+
+	// undo, redo, history: multi-level scene history (one step per command, LLM turn or mouse drag)
+	registerHistoryCmds(app)
+
 	// focus: point camera at selected object
 	focusFS := flag.NewFlagSet("focus", flag.ContinueOnError)
-	reg.Register("focus", focusFS, func() error {
+	reg.Register("focus", focusFS, commands.Help{
+		Description: "Point the camera at the center of the selection. Use for \"focus on selected\". User must select first.",
+		Examples:    [][]string{{"focus"}},
+		LLM:         true,
+	}, func() error {
 		return scn.FocusOnSelected()
 	})
 
 	// view: list objects currently visible to the camera
 	viewFS := flag.NewFlagSet("view", flag.ContinueOnError)
-	reg.Register("view", viewFS, func() error {
+	reg.Register("view", viewFS, commands.Help{
+		Description: "List the objects currently visible to the camera (printed to the terminal).",
+		Examples:    [][]string{{"view"}},
+		LLM:         true,
+	}, func() error {
 		visible := scn.ObjectsInView()
 		if len(visible) == 0 {
 			log.Log("No objects in view. Move the camera to look at primitives.")
@@ -243,17 +417,23 @@ func registerCommands(app *App) {
 		for _, v := range visible {
 			name := v.Object.Name
 			if name == "" {
-				name = fmt.Sprintf("#%d", v.Index)
+				name = fmt.Sprintf("#%d", v.ID)
 			}
 			log.Log(fmt.Sprintf("  %s — %s — distance %.2f — screen (%.0f, %.0f)",
-				name, v.Object.Type, v.Distance, v.ScreenPos.X, v.ScreenPos.Y))
+				name, v.Object.Type, v.Distance, v.ScreenPos[0], v.ScreenPos[1]))
 		}
 		return nil
 	})
 
 	// gravity: set gravity strength/direction
 	gravityFS := flag.NewFlagSet("gravity", flag.ContinueOnError)
-	reg.Register("gravity", gravityFS, func() error {
+	reg.Register("gravity", gravityFS, commands.Help{
+		Description: "Set physics gravity Y (negative = down, 0 = zero-g). Use for \"zero gravity\", \"low gravity\".",
+		Usage:       "<y>",
+		Examples:    [][]string{{"gravity", "-9.8"}, {"gravity", "0"}},
+		Args:        []commands.Arg{{Name: "y", Description: "gravity along Y"}},
+		LLM:         true,
+	}, func() error {
 		args := gravityFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd gravity <y> (e.g. cmd gravity -9.8 or 0 for zero-g)")
@@ -269,11 +449,17 @@ func registerCommands(app *App) {
 	// heightmap: procedurally generate a random height map
 	registerHeightmapCmd(app)
 
-	// terrain_repeat: set texture repeat for heightmap terrain
+	// terrain_repeat: set texture repeat for heightmap terrain (its material's uv_scale)
 	registerTerrainRepeatCmd(app)
 
-	// template: spawn a preset (e.g. tree)
+	// template, prefab: place and manage prefabs (assets/prefabs)
 	registerTemplateCmd(app)
+	registerPrefabCmd(app)
+	registerImportCmd(app)
+	registerExportCmd(app)
+
+	// group, ungroup: build and dissolve object hierarchies
+	registerGroupCmds(app)
 
 	// font: set or show active UI font
 	registerFontCmd(app)
@@ -281,12 +467,349 @@ func registerCommands(app *App) {
 
 // --- Individual command registration helpers (for commands with more complex logic) ---
 
+func registerSceneCmds(app *App) {
+	scn := app.Scene
+
+	var saveForce bool
+	saveFS := flag.NewFlagSet("save", flag.ContinueOnError)
+	saveFS.BoolVar(&saveForce, "force", false, "replace another scene of that name in the library")
+	app.Registry.Register("save", saveFS, commands.Help{
+		Description: "Save the scene to the scene library (assets/scenes/<name>.yaml). Without a name it saves under the current name; with one it saves a copy under that name and keeps working on it. A name taken by another scene is refused unless --force is given.",
+		Usage:       "[--force] [name]",
+		Examples:    [][]string{{"save"}, {"save", "castle"}},
+		Args:        []commands.Arg{{Name: "--force", Description: "replace another scene of that name", Optional: true}, {Name: "name", Description: "letters, digits, - _ .", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		args := saveFS.Args()
+		force := saveForce
+		saveForce = false
+		var err error
+		switch len(args) {
+		case 0:
+			err = scn.SaveScene()
+		case 1:
+			if _, err = saveReplaces(scn, args[0], force); err == nil {
+				err = scn.SaveAs(args[0])
+			}
+		default:
+			return fmt.Errorf("usage: cmd save [--force] [name]")
+		}
+		if err != nil {
+			return err
+		}
+		app.SaveEnginePrefs()
+		app.Log.Log(fmt.Sprintf("Saved scene %q (%d object(s)).", scn.Name(), scn.ObjectCount()))
+		return nil
+	})
+	app.Registry.SetPreview("save", func() (commands.Preview, error) {
+		args := saveFS.Args()
+		force := saveForce
+		saveForce = false
+		if len(args) != 1 {
+			return commands.Preview{Summary: "cmd save " + strings.Join(args, " ")}, nil
+		}
+		replaces, err := saveReplaces(scn, args[0], force)
+		if err != nil {
+			return commands.Preview{}, err
+		}
+		if replaces {
+			return commands.Preview{Summary: fmt.Sprintf("save the scene over %q in the library", args[0]), Destructive: true}, nil
+		}
+		return commands.Preview{Summary: fmt.Sprintf("save the scene as %q", args[0])}, nil
+	})
+
+	var loadBackup bool
+	loadFS := flag.NewFlagSet("load", flag.ContinueOnError)
+	loadFS.BoolVar(&loadBackup, "backup", false, "load an autosave snapshot (cmd scenes --backups) instead of a named scene")
+	app.Registry.Register("load", loadFS, commands.Help{
+		Description: "Open a scene from the library, replacing the current one (unsaved changes are kept in an autosave snapshot; undo history is cleared). --backup opens an autosave snapshot as a new unnamed scene.",
+		Usage:       "[--backup] <name>",
+		Examples:    [][]string{{"load", "castle"}, {"load", "--backup", "castle-20260101-120000"}},
+		Args:        []commands.Arg{{Name: "--backup", Description: "name is an autosave snapshot", Optional: true}, {Name: "name"}},
+		LLM:         true,
+		Destructive: true,
+	}, func() error {
+		args := loadFS.Args()
+		backup := loadBackup
+		loadBackup = false
+		if len(args) != 1 {
+			return fmt.Errorf("usage: cmd load [--backup] <name> (cmd scenes lists them)")
+		}
+		if scn.Modified() {
+			name, err := scn.Autosave(app.AutosaveKeep)
+			if err != nil {
+				return fmt.Errorf("unsaved changes could not be backed up, not loading: %w", err)
+			}
+			app.Log.Log("Unsaved changes kept in backup " + name + " (cmd load --backup " + name + ").")
+		}
+		var err error
+		if backup {
+			err = scn.LoadBackup(args[0])
+		} else {
+			err = scn.Load(args[0])
+		}
+		if err != nil {
+			return err
+		}
+		app.autosaveRev = scn.Revision()
+		app.SaveEnginePrefs()
+		app.Log.Log(fmt.Sprintf("Loaded %q (%d object(s)).", args[0], scn.ObjectCount()))
+		logSceneIssues(app.Log.Log, scn.LoadIssues())
+		return nil
+	})
+	app.Registry.SetPreview("load", func() (commands.Preview, error) {
+		args := loadFS.Args()
+		if len(args) != 1 {
+			return commands.Preview{}, fmt.Errorf("usage: cmd load [--backup] <name>")
+		}
+		return commands.Preview{Summary: fmt.Sprintf("replace the scene (%d object(s)) with %q", scn.ObjectCount(), args[0]), Deletes: allObjectIDs(scn)}, nil
+	})
+
+	var scenesBackups bool
+	scenesFS := flag.NewFlagSet("scenes", flag.ContinueOnError)
+	scenesFS.BoolVar(&scenesBackups, "backups", false, "list autosave snapshots instead, newest first")
+	app.Registry.Register("scenes", scenesFS, commands.Help{
+		Description: "List the scenes in the library with object counts and modification times (* = open); --backups lists autosave snapshots.",
+		Usage:       "[--backups]",
+		Examples:    [][]string{{"scenes"}, {"scenes", "--backups"}},
+		Args:        []commands.Arg{{Name: "--backups", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		backups := scenesBackups
+		scenesBackups = false
+		list, err := scene.ListScenes()
+		if backups {
+			list, err = scene.ListBackups()
+		}
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			app.Log.Log("No scenes saved yet.")
+			return nil
+		}
+		for _, info := range list {
+			mark := " "
+			if !backups && info.Name == scn.Name() {
+				mark = "*"
+			}
+			app.Log.Log(fmt.Sprintf("%s %s  %d object(s)  %s", mark, info.Name, info.Objects, info.Modified.Format("2006-01-02 15:04")))
+		}
+		return nil
+	})
+
+	newsceneFS := flag.NewFlagSet("newscene", flag.ContinueOnError)
+	app.Registry.Register("newscene", newsceneFS, commands.Help{
+		Description: "Clear all objects and start a new scene, optionally named (saved files are not touched; cmd save writes it). Use for \"clear scene\", \"new scene\".",
+		Usage:       "[name]",
+		Examples:    [][]string{{"newscene"}, {"newscene", "island"}},
+		Args:        []commands.Arg{{Name: "name", Description: "name for the new scene", Optional: true}},
+		LLM:         true,
+		Destructive: true,
+	}, func() error {
+		args := newsceneFS.Args()
+		if len(args) > 1 {
+			return fmt.Errorf("usage: cmd newscene [name]")
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if err := scn.NewScene(name); err != nil {
+			return err
+		}
+		app.SaveEnginePrefs()
+		return nil
+	})
+	app.Registry.SetPreview("newscene", func() (commands.Preview, error) {
+		return commands.Preview{Summary: fmt.Sprintf("clear the scene (%d object(s))", scn.ObjectCount()), Deletes: allObjectIDs(scn)}, nil
+	})
+
+	validateFS := flag.NewFlagSet("validate", flag.ContinueOnError)
+	app.Registry.Register("validate", validateFS, commands.Help{
+		Description: "Check a scene for problems (unknown types, NaN positions, negative scales, missing texture files, ...) and list them with line and column. Without an argument it checks the open scene; otherwise a scene name from the library or a path to a .yaml file.",
+		Usage:       "[name|file]",
+		Examples:    [][]string{{"validate"}, {"validate", "castle"}, {"validate", "assets/scenes/castle.yaml"}},
+		Args:        []commands.Arg{{Name: "file", Description: "scene name or .yaml path", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		args := validateFS.Args()
+		var issues []scene.Issue
+		switch len(args) {
+		case 0:
+			issues = scn.Validate()
+		case 1:
+			var err error
+			if issues, err = scene.ValidateFile(sceneFilePath(args[0])); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("usage: cmd validate [name|file]")
+		}
+		if len(issues) == 0 {
+			app.Log.Log("No problems found.")
+			return nil
+		}
+		logSceneIssues(app.Log.Log, issues)
+		return nil
+	})
+}
+
+// sceneFilePath returns arg as a path if it names a file or ends in .yaml, else the library file of the
+// scene named arg.
+func sceneFilePath(arg string) string {
+	if _, err := os.Stat(arg); err == nil || strings.HasSuffix(arg, ".yaml") {
+		return arg
+	}
+	return filepath.Join(scene.SceneDir(), arg+".yaml")
+}
+
+// logSceneIssues logs the problems found in a scene file, one per line, with a count first.
+func logSceneIssues(log func(string), issues []scene.Issue) {
+	if len(issues) == 0 {
+		return
+	}
+	log(fmt.Sprintf("Scene: %d problem(s):", len(issues)))
+	for _, is := range issues {
+		log("  " + is.String())
+	}
+}
+
+// saveReplaces reports whether cmd save name would replace another scene in the library, and refuses that
+// without force. Saving under the scene's own name is not a replacement.
+func saveReplaces(scn *scene.Scene, name string, force bool) (bool, error) {
+	if strings.TrimSuffix(name, ".yaml") == scn.Name() || !scene.SceneExists(name) {
+		return false, nil
+	}
+	if !force {
+		return false, fmt.Errorf("scene %q already exists (cmd save --force %s replaces it)", name, name)
+	}
+	return true, nil
+}
+
+// allObjectIDs returns the IDs of every object in the scene, for Preview.Deletes.
+func allObjectIDs(scn *scene.Scene) []uint64 {
+	all := make([]int, scn.ObjectCount())
+	for i := range all {
+		all[i] = i
+	}
+	return previewIDs(scn.IDs(all))
+}
+
+// previewIDs converts object IDs for Preview.Deletes, which the command registry keeps as plain numbers.
+func previewIDs(ids []scene.ObjectID) []uint64 {
+	out := make([]uint64, len(ids))
+	for i, id := range ids {
+		out[i] = uint64(id)
+	}
+	return out
+}
+
+func registerHistoryCmds(app *App) {
+	// Every command runs as one history step; nested steps (the commands of an LLM turn, the mutators a
+	// command calls) join the outermost one.
+	app.Registry.SetWrapper(func(args []string, run func() error) error {
+		app.Scene.BeginChange("cmd " + strings.Join(args, " "))
+		defer app.Scene.EndChange()
+		return run()
+	})
+
+	undoFS := flag.NewFlagSet("undo", flag.ContinueOnError)
+	app.Registry.Register("undo", undoFS, commands.Help{
+		Description: "Revert the last N changes (default 1). A change is one command, one natural-language request (all its actions) or one mouse drag. Use for \"undo\", \"revert last\".",
+		Usage:       "[N]",
+		Examples:    [][]string{{"undo"}, {"undo", "3"}},
+		Args:        []commands.Arg{{Name: "N", Description: "number of steps", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		n, err := historySteps(undoFS.Args(), "undo")
+		if err != nil {
+			return err
+		}
+		n, err = app.Scene.Undo(n)
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Undid %d step(s).", n))
+		return nil
+	})
+
+	redoFS := flag.NewFlagSet("redo", flag.ContinueOnError)
+	app.Registry.Register("redo", redoFS, commands.Help{
+		Description: "Re-apply the last N undone changes (default 1). Any new change clears the redo list.",
+		Usage:       "[N]",
+		Examples:    [][]string{{"redo"}, {"redo", "2"}},
+		Args:        []commands.Arg{{Name: "N", Description: "number of steps", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		n, err := historySteps(redoFS.Args(), "redo")
+		if err != nil {
+			return err
+		}
+		n, err = app.Scene.Redo(n)
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Redid %d step(s).", n))
+		return nil
+	})
+
+	var historyDepth int
+	historyFS := flag.NewFlagSet("history", flag.ContinueOnError)
+	historyFS.IntVar(&historyDepth, "depth", 0, "number of undo steps to keep")
+	app.Registry.Register("history", historyFS, commands.Help{
+		Description: "List the changes that undo and redo would revert or re-apply; --depth N sets how many undo steps are kept (saved in engine config).",
+		Usage:       "[--depth N]",
+		Examples:    [][]string{{"history"}, {"history", "--depth", "200"}},
+		Args:        []commands.Arg{{Name: "--depth N", Description: "1-10000", Optional: true}},
+	}, func() error {
+		depth := historyDepth
+		historyDepth = 0
+		if depth != 0 {
+			if depth < 1 || depth > 10000 {
+				return fmt.Errorf("usage: cmd history --depth <1-10000>")
+			}
+			app.UndoDepth = depth
+			app.Scene.SetHistoryDepth(depth)
+			app.SaveEnginePrefs()
+			app.Log.Log(fmt.Sprintf("Undo depth set: %d", depth))
+			return nil
+		}
+		undo, redo := app.Scene.History()
+		app.Log.Log(fmt.Sprintf("History: %d undo, %d redo (depth %d)", len(undo), len(redo), app.Scene.HistoryDepth()))
+		for i := len(redo) - 1; i >= 0; i-- {
+			app.Log.Log(fmt.Sprintf("  redo %d: %s", i+1, redo[i]))
+		}
+		for i, label := range undo {
+			app.Log.Log(fmt.Sprintf("  undo %d: %s", i+1, label))
+		}
+		return nil
+	})
+}
+
+// historySteps parses the optional step count of cmd undo / cmd redo.
+func historySteps(args []string, name string) (int, error) {
+	if len(args) == 0 {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("usage: cmd %s [N] (N >= 1)", name)
+	}
+	return n, nil
+}
+
 func registerWindowCmd(app *App) {
 	var wantFullscreen, wantWindowed bool
 	windowFS := flag.NewFlagSet("window", flag.ContinueOnError)
 	windowFS.BoolVar(&wantFullscreen, "fullscreen", false, "switch to fullscreen")
 	windowFS.BoolVar(&wantWindowed, "windowed", false, "switch to windowed")
-	app.Registry.Register("window", windowFS, func() error {
+	app.Registry.Register("window", windowFS, commands.Help{
+		Description: "Switch between fullscreen and windowed mode.",
+		Examples:    [][]string{{"window", "--fullscreen"}, {"window", "--windowed"}},
+		Args:        []commands.Arg{{Name: "--fullscreen", Optional: true}, {Name: "--windowed", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		f, w := wantFullscreen, wantWindowed
 		wantFullscreen, wantWindowed = false, false
 		if f == w {
@@ -303,9 +826,133 @@ func registerWindowCmd(app *App) {
 	})
 }
 
+func registerShadowsCmd(app *App) {
+	shadowsFS := flag.NewFlagSet("shadows", flag.ContinueOnError)
+	usage := fmt.Sprintf("usage: cmd shadows [on | off | quality <%d-%d>]", primitives.MinShadowQuality, primitives.MaxShadowQuality)
+	app.Registry.Register("shadows", shadowsFS, commands.Help{
+		Description: fmt.Sprintf("Turn the sun's shadows on or off, or set their quality (%d-%d: shadow map resolution and edge softness; default %d). Saved in engine config. Without arguments, shows the setting.", primitives.MinShadowQuality, primitives.MaxShadowQuality, primitives.DefaultShadowQuality),
+		Usage:       fmt.Sprintf("[on | off | quality <%d-%d>]", primitives.MinShadowQuality, primitives.MaxShadowQuality),
+		Examples:    [][]string{{"shadows", "off"}, {"shadows", "quality", "3"}},
+		Args:        []commands.Arg{{Name: "setting", Enum: []string{"on", "off", "quality"}, Optional: true}, {Name: "n", Description: "quality level", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		args := shadowsFS.Args()
+		on, quality := app.View.Shadows()
+		switch {
+		case len(args) == 0:
+		case len(args) == 1 && (args[0] == "on" || args[0] == "off"):
+			on = args[0] == "on"
+		case len(args) == 2 && args[0] == "quality":
+			q, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("%s", usage)
+			}
+			quality = q
+		default:
+			return fmt.Errorf("%s", usage)
+		}
+		if len(args) > 0 {
+			if err := app.View.SetShadows(on, quality); err != nil {
+				return err
+			}
+			app.SaveEnginePrefs()
+		}
+		state := "off"
+		if on {
+			state = "on"
+		}
+		app.Log.Log(fmt.Sprintf("Shadows: %s (quality %d)", state, quality))
+		return nil
+	})
+}
+
+func registerLightCmd(app *App) {
+	lightFS := flag.NewFlagSet("light", flag.ContinueOnError)
+	usage := "usage: cmd light [add point|spot [x y z] | intensity <v> | range <v> | cone <degrees>]"
+	app.Registry.Register("light", lightFS, commands.Help{
+		Description: fmt.Sprintf("Add a point or spot light object (default: 3 units above the camera target; a spot shines down, rotate it to aim), or set the intensity, range or cone angle of the selected lights. Color them with cmd color. The %d lights nearest the camera target are drawn. Without arguments, lists the lights.", primitives.MaxLights),
+		Usage:       "[add point|spot [x y z] | intensity <v> | range <v> | cone <degrees>]",
+		Examples:    [][]string{{"light", "add", "point", "0", "3", "0"}, {"light", "add", "spot"}, {"light", "range", "15"}, {"light", "cone", "30"}},
+		Args: []commands.Arg{
+			{Name: "action", Enum: append([]string{"add"}, scene.LightProperties...), Optional: true},
+			{Name: "kind | value", Description: "point or spot for add, else the new value", Optional: true},
+			{Name: "x y z", Description: "position for add", Optional: true},
+		},
+		LLM: true,
+	}, func() error {
+		args := lightFS.Args()
+		switch {
+		case len(args) == 0:
+			lights := app.Scene.Lights(app.Scene.Camera.Target)
+			if len(lights) == 0 {
+				app.Log.Log("No lights (cmd light add point|spot).")
+				return nil
+			}
+			for n, l := range lights {
+				obj, _ := app.Scene.ObjectAt(l.Index)
+				note := ""
+				if n >= primitives.MaxLights {
+					note = " (not drawn: too far from the view)"
+				}
+				app.Log.Log(fmt.Sprintf("  %s at %v: %s%s", objectLabel(app.Scene, l.Index), l.Position, formatLight(*obj.Light), note))
+			}
+			return nil
+		case args[0] == "add" && (len(args) == 2 || len(args) == 5):
+			t := app.Scene.Camera.Target
+			pos := [3]float32{t[0], t[1] + 3, t[2]}
+			if len(args) == 5 {
+				for i := range pos {
+					f, err := strconv.ParseFloat(args[2+i], 32)
+					if err != nil {
+						return fmt.Errorf("position: %q is not a number", args[2+i])
+					}
+					pos[i] = float32(f)
+				}
+			}
+			id, err := app.Scene.AddLight(args[1], pos)
+			if err != nil {
+				return err
+			}
+			app.Log.Log(fmt.Sprintf("Added %s at %v.", objectLabel(app.Scene, app.Scene.IndexOf(id)), pos))
+			return nil
+		case len(args) == 2 && slices.Contains(scene.LightProperties, args[0]):
+			f, err := strconv.ParseFloat(args[1], 32)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", args[0], args[1])
+			}
+			n, err := app.Scene.SetSelectedLight(args[0], float32(f))
+			if err != nil {
+				return err
+			}
+			app.Log.Log(fmt.Sprintf("Set %s of %d light(s) to %s.", args[0], n, args[1]))
+			return nil
+		}
+		return fmt.Errorf("%s", usage)
+	})
+}
+
+// formatLight describes a light's kind and values, defaults filled in.
+func formatLight(l scene.Light) string {
+	s := fmt.Sprintf("light=%s intensity=%g range=%g", l.Kind, l.IntensityValue(), l.RangeValue())
+	if l.Kind == scene.SpotLight {
+		s += fmt.Sprintf(" cone=%g", l.ConeValue())
+	}
+	return s
+}
+
 func registerSpawnCmd(app *App) {
 	spawnFS := flag.NewFlagSet("spawn", flag.ContinueOnError)
-	app.Registry.Register("spawn", spawnFS, func() error {
+	app.Registry.Register("spawn", spawnFS, commands.Help{
+		Description: "Add one primitive at a position, with optional scale.",
+		Usage:       "<type> <x> <y> <z> [sx sy sz]",
+		Examples:    [][]string{{"spawn", "cube", "0", "0", "0"}, {"spawn", "sphere", "1", "0", "1", "2", "2", "2"}},
+		Args: []commands.Arg{
+			{Name: "type", Enum: []string{"cube", "sphere", "cylinder", "plane"}},
+			{Name: "x"}, {Name: "y"}, {Name: "z"},
+			{Name: "sx sy sz", Description: "scale", Optional: true},
+		},
+		LLM: true,
+	}, func() error {
 		args := spawnFS.Args()
 		if len(args) != 4 && len(args) != 7 {
 			return fmt.Errorf("usage: cmd spawn <type> <x> <y> <z> [sx sy sz]")
@@ -333,36 +980,213 @@ func registerSpawnCmd(app *App) {
 			}
 		}
 		app.Scene.AddPrimitive(typ, pos, scale)
-		app.Scene.RecordAdd(1)
 		return nil
 	})
 }
 
 func registerModelCmd(app *App) {
 	modelFS := flag.NewFlagSet("model", flag.ContinueOnError)
-	app.Registry.Register("model", modelFS, func() error {
+	app.Registry.Register("model", modelFS, commands.Help{
+		Description: "Show or set the AI model for natural-language input. Changed manually only.",
+		Usage:       "[name]",
+		Examples:    [][]string{{"model", "gpt-4o-mini"}},
+		Args:        []commands.Arg{{Name: "name", Optional: true}},
+	}, func() error {
 		args := modelFS.Args()
 		if len(args) < 1 {
 			app.Log.Log(fmt.Sprintf("Current model: %s (provider: %s)", app.CurrentAIModel, app.CurrentProvider))
 			return nil
 		}
 		app.CurrentAIModel = args[0]
+		app.RebuildAgent()
 		app.SaveEnginePrefs()
 		app.Log.Log("Model set: " + args[0])
 		return nil
 	})
 }
 
+func registerRetriesCmd(app *App) {
+	retriesFS := flag.NewFlagSet("retries", flag.ContinueOnError)
+	app.Registry.Register("retries", retriesFS, commands.Help{
+		Description: "Show or set how many rounds the agent may use to correct failed actions (0 = off).",
+		Usage:       "[n]",
+		Examples:    [][]string{{"retries"}, {"retries", "3"}},
+		Args:        []commands.Arg{{Name: "n", Description: "0-10", Optional: true}},
+	}, func() error {
+		args := retriesFS.Args()
+		if len(args) < 1 {
+			app.Log.Log(fmt.Sprintf("Agent retries: %d", app.AgentRetries))
+			return nil
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 || n > 10 {
+			return fmt.Errorf("usage: cmd retries <n> (0-10)")
+		}
+		app.AgentRetries = n
+		if app.Agent != nil {
+			app.Agent.SetRetries(n)
+		}
+		app.SaveEnginePrefs()
+		app.Log.Log(fmt.Sprintf("Agent retries set: %d", n))
+		return nil
+	})
+}
+
+func registerCancelCmd(app *App) {
+	cancelFS := flag.NewFlagSet("cancel", flag.ContinueOnError)
+	app.Registry.Register("cancel", cancelFS, commands.Help{
+		Description: "Cancel the running natural-language request (also Ctrl+C in the terminal); all also drops queued ones.",
+		Usage:       "[all]",
+		Examples:    [][]string{{"cancel"}, {"cancel", "all"}},
+		Args:        []commands.Arg{{Name: "scope", Enum: []string{"all"}, Optional: true}},
+	}, func() error {
+		if app.Queue == nil {
+			return fmt.Errorf("no LLM agent (check provider and API key)")
+		}
+		args := cancelFS.Args()
+		all := len(args) > 0 && args[0] == "all"
+		if len(args) > 0 && !all {
+			return fmt.Errorf("usage: cmd cancel [all]")
+		}
+		cancelled, dropped := app.Queue.Cancel(all)
+		switch {
+		case !cancelled && dropped == 0:
+			app.Log.Log("No request running.")
+		case dropped > 0:
+			app.Log.Log(fmt.Sprintf("Dropped %d queued request(s).", dropped))
+		}
+		app.refreshAgentStatus()
+		return nil
+	})
+}
+
+func registerTimeoutCmd(app *App) {
+	timeoutFS := flag.NewFlagSet("timeout", flag.ContinueOnError)
+	app.Registry.Register("timeout", timeoutFS, commands.Help{
+		Description: "Show or set the natural-language request timeout in seconds for the current provider (0 = none).",
+		Usage:       "[seconds]",
+		Examples:    [][]string{{"timeout"}, {"timeout", "120"}},
+		Args:        []commands.Arg{{Name: "seconds", Optional: true}},
+	}, func() error {
+		args := timeoutFS.Args()
+		if len(args) < 1 {
+			app.Log.Log(fmt.Sprintf("Request timeout (%s): %s", app.CurrentProvider, app.RequestTimeout()))
+			return nil
+		}
+		secs, err := strconv.Atoi(args[0])
+		if err != nil || secs < 0 {
+			return fmt.Errorf("usage: cmd timeout <seconds> (0 = none)")
+		}
+		if app.AITimeouts == nil {
+			app.AITimeouts = make(map[string]int)
+		}
+		app.AITimeouts[app.CurrentProvider] = secs
+		app.SaveEnginePrefs()
+		app.Log.Log(fmt.Sprintf("Request timeout (%s) set: %s", app.CurrentProvider, app.RequestTimeout()))
+		return nil
+	})
+}
+
+func registerPreviewCmds(app *App) {
+	applyFS := flag.NewFlagSet("apply", flag.ContinueOnError)
+	app.Registry.Register("apply", applyFS, commands.Help{
+		Description: "Run the LLM actions waiting in the preview (ghosted adds, red-outlined deletes).",
+		Examples:    [][]string{{"apply"}},
+	}, func() error {
+		return submitDecision(app, agent.DecisionApply)
+	})
+
+	rejectFS := flag.NewFlagSet("reject", flag.ContinueOnError)
+	app.Registry.Register("reject", rejectFS, commands.Help{
+		Description: "Discard the LLM actions waiting in the preview.",
+		Examples:    [][]string{{"reject"}},
+	}, func() error {
+		return submitDecision(app, agent.DecisionReject)
+	})
+
+	previewFS := flag.NewFlagSet("preview", flag.ContinueOnError)
+	app.Registry.Register("preview", previewFS, commands.Help{
+		Description: "Show or set when LLM actions are previewed before running: off, destructive (delete all, newscene, large adds; default) or all.",
+		Usage:       "[off | destructive | all]",
+		Examples:    [][]string{{"preview"}, {"preview", "all"}},
+		Args:        []commands.Arg{{Name: "mode", Enum: []string{agent.PreviewOff, agent.PreviewDestructive, agent.PreviewAll}, Optional: true}},
+	}, func() error {
+		args := previewFS.Args()
+		if len(args) < 1 {
+			app.Log.Log("Preview mode: " + app.PreviewMode)
+			return nil
+		}
+		switch args[0] {
+		case agent.PreviewOff, agent.PreviewDestructive, agent.PreviewAll:
+		default:
+			return fmt.Errorf("usage: cmd preview off | destructive | all")
+		}
+		app.PreviewMode = args[0]
+		if app.Agent != nil {
+			app.Agent.SetPreviewMode(args[0])
+		}
+		app.SaveEnginePrefs()
+		app.Log.Log("Preview mode set: " + args[0])
+		return nil
+	})
+}
+
+// submitDecision queues cmd apply or cmd reject behind any running request, since the pending preview
+// belongs to the agent's goroutine and applying it waits for the main thread.
+func submitDecision(app *App, decision string) error {
+	if app.Queue == nil {
+		return fmt.Errorf("no LLM agent (check provider and API key)")
+	}
+	if ahead := app.Queue.Submit(agent.Request{Decision: decision, Timeout: app.RequestTimeout()}); ahead > 0 {
+		app.Log.Log(fmt.Sprintf("Queued cmd %s (%d ahead).", decision, ahead))
+	}
+	app.refreshAgentStatus()
+	return nil
+}
+
+func registerChatCmd(app *App) {
+	chatFS := flag.NewFlagSet("chat", flag.ContinueOnError)
+	app.Registry.Register("chat", chatFS, commands.Help{
+		Description: "Show how many conversation turns the agent remembers, or forget them.",
+		Usage:       "[reset]",
+		Examples:    [][]string{{"chat"}, {"chat", "reset"}},
+		Args:        []commands.Arg{{Name: "action", Enum: []string{"reset"}, Optional: true}},
+	}, func() error {
+		if app.Agent == nil {
+			return fmt.Errorf("no LLM agent (check provider and API key)")
+		}
+		conv := app.Agent.Conversation()
+		args := chatFS.Args()
+		if len(args) < 1 {
+			app.Log.Log(fmt.Sprintf("Conversation: %d turn(s) remembered (max %d turns, ~%d tokens). Use cmd chat reset to forget.", conv.Len(), conv.MaxTurns, conv.MaxTokens))
+			return nil
+		}
+		switch args[0] {
+		case "reset":
+			conv.Reset()
+			app.Log.Log("Conversation history cleared.")
+			return nil
+		default:
+			return fmt.Errorf("usage: cmd chat | cmd chat reset")
+		}
+	})
+}
+
 func registerProviderCmd(app *App) {
 	providerFS := flag.NewFlagSet("provider", flag.ContinueOnError)
-	app.Registry.Register("provider", providerFS, func() error {
+	app.Registry.Register("provider", providerFS, commands.Help{
+		Description: "Show or switch the LLM provider. Changed manually only.",
+		Usage:       "[" + strings.Join(llm.ProviderNames(), " | ") + "]",
+		Examples:    [][]string{{"provider", "ollama"}},
+		Args:        []commands.Arg{{Name: "name", Enum: llm.ProviderNames(), Optional: true}},
+	}, func() error {
 		args := providerFS.Args()
 		if len(args) < 1 {
-			app.Log.Log(fmt.Sprintf("Current provider: %s (model: %s). Available: ollama, openai, groq", app.CurrentProvider, app.CurrentAIModel))
+			app.Log.Log(fmt.Sprintf("Current provider: %s (model: %s). Available: %s", app.CurrentProvider, app.CurrentAIModel, strings.Join(llm.ProviderNames(), ", ")))
 			return nil
 		}
 		name := strings.ToLower(args[0])
-		client, err := BuildLLMClient(name)
+		client, err := llm.NewClient(name)
 		if err != nil {
 			return err
 		}
@@ -378,7 +1202,16 @@ func registerProviderCmd(app *App) {
 
 func registerDeleteCmd(app *App) {
 	deleteFS := flag.NewFlagSet("delete", flag.ContinueOnError)
-	app.Registry.Register("delete", deleteFS, func() error {
+	app.Registry.Register("delete", deleteFS, commands.Help{
+		Description: "Remove object(s); no selection needed except for selected (removes every selected object). Positions: left, right, top, bottom, closest, farthest (pick them from the current camera view). Use [\"delete\",\"all\",...] for \"all buildings\", \"every cube I see\"; \"buildings\" usually means [\"delete\",\"all\",\"building\"] or [\"delete\",\"all\",\"cube\"].",
+		Usage:       "selected | look | random | name <name> | <position> | [color] <type> [position] | <name_substring> <position> | all [type | color type | name_substring]",
+		Examples: [][]string{
+			{"delete", "selected"}, {"delete", "look"}, {"delete", "name", "Tower"}, {"delete", "right"},
+			{"delete", "red", "cube"}, {"delete", "cube", "right"}, {"delete", "building", "left"},
+			{"delete", "all"}, {"delete", "all", "cube"}, {"delete", "all", "building"},
+		},
+		LLM: true,
+	}, func() error {
 		args := deleteFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd delete selected | look | random | name <name> | left|right|top|bottom | [color] <type> [position] | all [type|name]")
@@ -421,6 +1254,131 @@ func registerDeleteCmd(app *App) {
 
 		return fmt.Errorf("use selected, look, random, name <name>, left|right|top|bottom, [color] <type> [position], or all [type|name]")
 	})
+	app.Registry.SetPreview("delete", func() (commands.Preview, error) {
+		return previewDelete(app.Scene, deleteFS.Args())
+	})
+}
+
+// previewDelete returns the objects cmd delete would remove with the given args, resolved the same way.
+// Applying the preview deletes exactly those objects, so a confirmed "delete right" or "delete random"
+// removes what was highlighted even if the camera or the scene changed in between.
+func previewDelete(scn *scene.Scene, args []string) (commands.Preview, error) {
+	p, err := resolveDelete(scn, args)
+	if err != nil {
+		return commands.Preview{}, err
+	}
+	ids := make([]scene.ObjectID, len(p.Deletes))
+	for i, id := range p.Deletes {
+		ids[i] = scene.ObjectID(id)
+	}
+	p.Apply = func() error {
+		existing := scn.IDs(scn.Indices(ids)) // objects deleted since the preview are skipped
+		if len(existing) == 0 {
+			return nil
+		}
+		return scn.DeleteObjects(existing)
+	}
+	return p, nil
+}
+
+// resolveDelete resolves the objects cmd delete would remove with the given args.
+func resolveDelete(scn *scene.Scene, args []string) (commands.Preview, error) {
+	if len(args) < 1 {
+		return commands.Preview{}, fmt.Errorf("usage: cmd delete selected | look | random | name <name> | left|right|top|bottom | [color] <type> [position] | all [type|name]")
+	}
+	one := func(idx int, err error) (commands.Preview, error) {
+		if err != nil {
+			return commands.Preview{}, err
+		}
+		return commands.Preview{Summary: "delete " + objectLabel(scn, idx), Deletes: previewIDs(scn.IDs([]int{idx}))}, nil
+	}
+	switch args[0] {
+	case "selected":
+		sel := scn.Selection()
+		if len(sel) == 0 {
+			return commands.Preview{}, fmt.Errorf("no object selected (click an object with terminal open)")
+		}
+		if len(sel) > 1 {
+			return commands.Preview{Summary: fmt.Sprintf("delete %d selected objects", len(sel)), Deletes: previewIDs(scn.IDs(sel))}, nil
+		}
+		return one(sel[0], nil)
+	case "look", "camera":
+		idx := scn.LookTarget()
+		if idx < 0 {
+			return commands.Preview{}, fmt.Errorf("no object in view (camera not looking at any object)")
+		}
+		return one(idx, nil)
+	case "random":
+		if scn.ObjectCount() == 0 {
+			return commands.Preview{}, fmt.Errorf("no objects in scene")
+		}
+		return one(rand.Intn(scn.ObjectCount()), nil) // picked now, so the preview shows the victim
+	case "name":
+		if len(args) < 2 {
+			return commands.Preview{}, fmt.Errorf("usage: cmd delete name <name>")
+		}
+		idx := scn.FindByName(args[1])
+		if idx < 0 {
+			return commands.Preview{}, fmt.Errorf("no object named %q", args[1])
+		}
+		return one(idx, nil)
+	case "all":
+		indices, err := previewDeleteAll(scn, args[1:])
+		if err != nil {
+			return commands.Preview{}, err
+		}
+		return commands.Preview{Summary: fmt.Sprintf("delete %d object(s) in view", len(indices)), Deletes: previewIDs(scn.IDs(indices)), Destructive: true}, nil
+	}
+
+	q := parseObjectArgs(args)
+	if q.Position != "" && q.Type == "" && q.Name == "" {
+		return one(scn.FindVisibleByPosition(q.Position))
+	}
+	if q.Type != "" && q.Position == "" {
+		return one(scn.FindVisibleByDescription(q.Type, q.Color))
+	}
+	if q.Type != "" && q.Position != "" {
+		return one(scn.FindVisible(q.Type, q.Color, "", q.Position))
+	}
+	if q.Name != "" && q.Position != "" {
+		return one(scn.FindVisible("", nil, q.Name, q.Position))
+	}
+	if q.Name != "" {
+		return one(scn.FindVisibleByDescription(q.Name, nil))
+	}
+	return commands.Preview{}, fmt.Errorf("use selected, look, random, name <name>, left|right|top|bottom, [color] <type> [position], or all [type|name]")
+}
+
+// previewDeleteAll returns the indices cmd delete all would remove; args are the ones after "all".
+func previewDeleteAll(scn *scene.Scene, args []string) ([]int, error) {
+	switch len(args) {
+	case 0:
+		return scn.FindAllVisible("", nil, "")
+	case 1:
+		a := strings.ToLower(args[0])
+		if primTypes[a] {
+			return scn.FindAllVisible(a, nil, "")
+		}
+		return scn.FindAllVisible("", nil, a)
+	case 2:
+		typ := strings.ToLower(args[1])
+		if c, ok := colorNames[strings.ToLower(args[0])]; ok && primTypes[typ] {
+			return scn.FindAllVisible(typ, &c, "")
+		}
+	}
+	return nil, fmt.Errorf("usage: cmd delete all [type] or delete all [color] [type] or delete all <name_substring>")
+}
+
+// objectLabel describes the object at index for preview summaries, e.g. "cube \"Tower\"".
+func objectLabel(scn *scene.Scene, index int) string {
+	obj, ok := scn.ObjectAt(index)
+	if !ok {
+		return fmt.Sprintf("object %d", index)
+	}
+	if obj.Name != "" {
+		return fmt.Sprintf("%s %q", obj.Type, obj.Name)
+	}
+	return obj.Type
 }
 
 func deleteAll(scn *scene.Scene, args []string) error {
@@ -467,17 +1425,37 @@ func deleteAll(scn *scene.Scene, args []string) error {
 }
 
 func registerSelectCmd(app *App) {
+	var selectAdd bool
 	selectFS := flag.NewFlagSet("select", flag.ContinueOnError)
-	app.Registry.Register("select", selectFS, func() error {
+	selectFS.BoolVar(&selectAdd, "add", false, "add the matches of all/name to the current selection")
+	app.Registry.Register("select", selectFS, commands.Help{
+		Description: "Select a visible object by position, type, color and type, or name substring. \"all\" and \"name\" select every matching object in the scene (in view or not; name takes a glob such as building*), so later commands (color, physics, motion, texture, duplicate, rotate, delete selected) apply to all of them; --add keeps the current selection.",
+		Usage:       "none | <position> | [color] <type> [position] | <name_substring> [position] | [--add] all [type | color type | name_glob] | [--add] name <glob>",
+		Examples: [][]string{
+			{"select", "right"}, {"select", "red", "cube"}, {"select", "building", "right"}, {"select", "none"},
+			{"select", "all", "cube"}, {"select", "name", "building*"}, {"select", "--add", "all", "red", "sphere"},
+		},
+		LLM: true,
+	}, func() error {
 		args := selectFS.Args()
+		add := selectAdd
+		selectAdd = false
 		if len(args) < 1 {
-			return fmt.Errorf("usage: cmd select none | left|right|... | [color] <type> [position] | <name> [position]")
+			return fmt.Errorf("usage: cmd select none | left|right|... | [color] <type> [position] | <name> [position] | [--add] all [type|color type|name] | [--add] name <glob>")
 		}
 		scn := app.Scene
 
-		if strings.ToLower(args[0]) == "none" {
+		switch strings.ToLower(args[0]) {
+		case "none":
 			scn.ClearSelection()
 			return nil
+		case "all":
+			return selectAll(app, args[1:], add)
+		case "name":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: cmd select [--add] name <glob> (e.g. building*)")
+			}
+			return selectAll(app, args[1:], add)
 		}
 
 		q := parseObjectArgs(args)
@@ -488,9 +1466,45 @@ func registerSelectCmd(app *App) {
 	})
 }
 
+// selectAll selects every object in the scene matching [type | color type | name_glob] (no args = all
+// objects) and logs how many were selected.
+func selectAll(app *App, args []string, add bool) error {
+	var typ, name string
+	var color *[3]float32
+	switch len(args) {
+	case 0:
+	case 1:
+		if a := strings.ToLower(args[0]); primTypes[a] || a == scene.GroupType {
+			typ = a
+		} else {
+			name = args[0]
+		}
+	case 2:
+		c, ok := colorNames[strings.ToLower(args[0])]
+		typ = strings.ToLower(args[1])
+		if !ok || !primTypes[typ] {
+			return fmt.Errorf("usage: cmd select all [type | color type | name_glob]")
+		}
+		color = &c
+	default:
+		return fmt.Errorf("usage: cmd select all [type | color type | name_glob]")
+	}
+	n, err := app.Scene.SelectMatching(typ, color, name, add)
+	if err != nil {
+		return err
+	}
+	app.Log.Log(fmt.Sprintf("Selected %d object(s); %d in selection.", n, len(app.Scene.Selection())))
+	return nil
+}
+
 func registerLookCmd(app *App) {
 	lookFS := flag.NewFlagSet("look", flag.ContinueOnError)
-	app.Registry.Register("look", lookFS, func() error {
+	app.Registry.Register("look", lookFS, commands.Help{
+		Description: "Point the camera at a visible object by position, type, or name substring (selection unchanged).",
+		Usage:       "<position> | [color] <type> [position] | <name_substring> [position]",
+		Examples:    [][]string{{"look", "left"}, {"look", "cube"}, {"look", "building", "right"}},
+		LLM:         true,
+	}, func() error {
 		args := lookFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd look left|right|... | [color] <type> [position] | <name> [position]")
@@ -507,7 +1521,13 @@ func registerLookCmd(app *App) {
 
 func registerDownloadCmd(app *App) {
 	downloadFS := flag.NewFlagSet("download", flag.ContinueOnError)
-	app.Registry.Register("download", downloadFS, func() error {
+	app.Registry.Register("download", downloadFS, commands.Help{
+		Description: "Download an image from a URL and apply it as texture to the selected object(s). User must select first.",
+		Usage:       "image <url>",
+		Examples:    [][]string{{"download", "image", "https://example.com/image.png"}},
+		Args:        []commands.Arg{{Name: "kind", Enum: []string{"image"}}, {Name: "url"}},
+		LLM:         true,
+	}, func() error {
 		args := downloadFS.Args()
 		if len(args) < 2 {
 			return fmt.Errorf("usage: cmd download image <url> (select an object first)")
@@ -519,13 +1539,13 @@ func registerDownloadCmd(app *App) {
 		if url == "" {
 			return fmt.Errorf("url is required")
 		}
-		idx := app.Scene.SelectedIndex()
-		if idx < 0 {
+		ids := app.Scene.SelectionIDs()
+		if len(ids) == 0 {
 			return fmt.Errorf("no object selected (click an object with terminal open)")
 		}
 		go func() {
 			relPath, err := download.Download(url, "assets/textures/downloaded")
-			app.DownloadDone <- &downloadResult{Index: idx, Path: relPath, Err: err}
+			app.DownloadDone <- &downloadResult{IDs: ids, Path: relPath, Err: err}
 		}()
 		return nil
 	})
@@ -533,7 +1553,13 @@ func registerDownloadCmd(app *App) {
 
 func registerTextureCmd(app *App) {
 	textureFS := flag.NewFlagSet("texture", flag.ContinueOnError)
-	app.Registry.Register("texture", textureFS, func() error {
+	app.Registry.Register("texture", textureFS, commands.Help{
+		Description: "Apply an image file as texture to the selected object(s) (e.g. a downloaded image). User must select first.",
+		Usage:       "<path>",
+		Examples:    [][]string{{"texture", "assets/textures/downloaded/foo.png"}},
+		Args:        []commands.Arg{{Name: "path"}},
+		LLM:         true,
+	}, func() error {
 		args := textureFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd texture <path>")
@@ -542,16 +1568,101 @@ func registerTextureCmd(app *App) {
 		if path == "" {
 			return fmt.Errorf("path is required")
 		}
-		if app.Scene.SelectedIndex() < 0 {
-			return fmt.Errorf("no object selected (click an object with terminal open)")
-		}
 		return app.Scene.SetSelectedTexture(path)
 	})
 }
 
+func registerMaterialCmd(app *App) {
+	materialFS := flag.NewFlagSet("material", flag.ContinueOnError)
+	usage := "usage: cmd material <name> | none | list | show <name> | set <name> <property> <values...> | reload"
+	app.Registry.Register("material", materialFS, commands.Help{
+		Description: "Apply or edit PBR materials (assets/materials/<name>.yaml: albedo, albedo_map, normal_map, roughness, metallic, metallic_roughness_map, emissive, emissive_map, uv_scale). <name> applies a material to the selected object(s), none removes it; set creates or edits a material and saves it; reload rereads edited files. The object's own color and texture override the material's albedo.",
+		Usage:       "<name> | none | list | show <name> | set <name> <property> <values...> | reload",
+		Examples:    [][]string{{"material", "metal"}, {"material", "list"}, {"material", "set", "gold", "albedo", "1", "0.8", "0.3"}, {"material", "set", "gold", "metallic", "1"}, {"material", "set", "bricks", "uv_scale", "4", "4"}},
+		Args:        []commands.Arg{{Name: "name or action", Description: "material name, none, list, show, set or reload"}, {Name: "name property values", Description: "for show and set", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		args := materialFS.Args()
+		if len(args) == 0 {
+			return fmt.Errorf("%s", usage)
+		}
+		switch {
+		case args[0] == "list" && len(args) == 1:
+			names := scene.MaterialNames()
+			if len(names) == 0 {
+				app.Log.Log("No materials yet (cmd material set <name> <property> <values...> creates one).")
+				return nil
+			}
+			app.Log.Log("Materials: " + strings.Join(names, ", "))
+		case args[0] == "show" && len(args) == 2:
+			m, err := scene.LoadMaterial(args[1])
+			if err != nil {
+				return err
+			}
+			app.Log.Log(formatMaterial(args[1], m))
+		case args[0] == "set" && len(args) >= 4:
+			name := strings.TrimSuffix(args[1], ".yaml")
+			m, err := scene.LoadMaterial(name)
+			if err != nil && scene.MaterialExists(name) {
+				return err
+			}
+			if err := m.Set(args[2], args[3:]...); err != nil {
+				return err
+			}
+			if err := scene.SaveMaterial(name, m); err != nil {
+				return err
+			}
+			app.Log.Log(formatMaterial(name, m))
+		case args[0] == "reload" && len(args) == 1:
+			scene.ReloadMaterials()
+			app.Log.Log("Materials reloaded.")
+		case args[0] == "none" && len(args) == 1:
+			return app.Scene.SetSelectedMaterial("")
+		case len(args) == 1 && args[0] != "show" && args[0] != "set":
+			return app.Scene.SetSelectedMaterial(args[0])
+		default:
+			return fmt.Errorf("%s", usage)
+		}
+		return nil
+	})
+	app.Registry.SetPreview("material", func() (commands.Preview, error) {
+		args := materialFS.Args()
+		p := commands.Preview{Summary: "cmd material " + strings.Join(args, " ")}
+		if len(args) >= 4 && args[0] == "set" {
+			// The file is shared by every object and scene that uses the material.
+			if name := strings.TrimSuffix(args[1], ".yaml"); scene.MaterialExists(name) {
+				p = commands.Preview{Summary: fmt.Sprintf("overwrite material %s (%s)", name, strings.Join(args[2:], " ")), Destructive: true}
+			}
+		}
+		return p, nil
+	})
+}
+
+// formatMaterial returns a one-line summary of a material: its values and the maps it sets.
+func formatMaterial(name string, m scene.Material) string {
+	a := m.AlbedoColor()
+	uv := m.UV()
+	s := fmt.Sprintf("%s: albedo=(%.2f, %.2f, %.2f) roughness=%.2f metallic=%.2f uv_scale=(%g, %g)", strings.TrimSuffix(name, ".yaml"), a[0], a[1], a[2], m.RoughnessValue(), m.Metallic, uv[0], uv[1])
+	if m.Emissive != ([3]float32{}) {
+		s += fmt.Sprintf(" emissive=(%.2f, %.2f, %.2f)", m.Emissive[0], m.Emissive[1], m.Emissive[2])
+	}
+	for _, mp := range []struct{ key, path string }{{"albedo_map", m.AlbedoMap}, {"normal_map", m.NormalMap}, {"metallic_roughness_map", m.MetallicRoughnessMap}, {"emissive_map", m.EmissiveMap}} {
+		if mp.path != "" {
+			s += fmt.Sprintf(" %s=%s", mp.key, mp.path)
+		}
+	}
+	return s
+}
+
 func registerSkyboxCmd(app *App) {
 	skyboxFS := flag.NewFlagSet("skybox", flag.ContinueOnError)
-	app.Registry.Register("skybox", skyboxFS, func() error {
+	app.Registry.Register("skybox", skyboxFS, commands.Help{
+		Description: "Set the skybox from an image URL (panorama or cubemap; downloads in background).",
+		Usage:       "<url>",
+		Examples:    [][]string{{"skybox", "https://example.com/panorama.jpg"}},
+		Args:        []commands.Arg{{Name: "url"}},
+		LLM:         true,
+	}, func() error {
 		args := skyboxFS.Args()
 		if len(args) < 1 {
 			return fmt.Errorf("usage: cmd skybox <url>")
@@ -570,7 +1681,11 @@ func registerSkyboxCmd(app *App) {
 
 func registerScreenshotCmd(app *App) {
 	screenshotFS := flag.NewFlagSet("screenshot", flag.ContinueOnError)
-	app.Registry.Register("screenshot", screenshotFS, func() error {
+	app.Registry.Register("screenshot", screenshotFS, commands.Help{
+		Description: "Capture the current view to screenshot.png.",
+		Examples:    [][]string{{"screenshot"}},
+		LLM:         true,
+	}, func() error {
 		rl.TakeScreenshot("screenshot.png")
 		app.Log.Log("Screenshot saved: screenshot.png")
 		return nil
@@ -587,7 +1702,19 @@ func registerHeightmapCmd(app *App) {
 	heightmapFS.Float64Var(&hmTileSize, "tile", 0, "tile size on X/Z")
 	heightmapFS.Float64Var(&hmMaxHeight, "h", 0, "max height")
 	heightmapFS.Int64Var(&hmSeed, "seed", 0, "random seed (0 = random)")
-	app.Registry.Register("heightmap", heightmapFS, func() error {
+	app.Registry.Register("heightmap", heightmapFS, commands.Help{
+		Description: "Generate static procedural terrain (hills, bumpy ground). Prefer this over composing cubes.",
+		Usage:       "[--w N] [--d N] [--tile size] [--h height] [--seed N]",
+		Examples:    [][]string{{"heightmap"}, {"heightmap", "--w", "32", "--d", "32", "--tile", "1", "--h", "3"}},
+		Args: []commands.Arg{
+			{Name: "--w", Description: "width in tiles", Optional: true},
+			{Name: "--d", Description: "depth in tiles", Optional: true},
+			{Name: "--tile", Description: "tile size", Optional: true},
+			{Name: "--h", Description: "max height", Optional: true},
+			{Name: "--seed", Description: "random seed", Optional: true},
+		},
+		LLM: true,
+	}, func() error {
 		opts := mapgen.DefaultHeightMapOptions()
 		if hmWidth > 0 {
 			opts.Width = hmWidth
@@ -604,17 +1731,25 @@ func registerHeightmapCmd(app *App) {
 		if hmSeed != 0 {
 			opts.Seed = hmSeed
 		}
-		if err := mapgen.ApplyHeightmapTerrain(app.Scene, opts); err != nil {
+		// The seed is picked now and saved with the terrain, so undo, redo and loading regenerate the same mesh.
+		opts = mapgen.TerrainOptions(opts)
+		if err := app.Scene.SetTerrain(opts); err != nil {
 			return err
 		}
-		app.Log.Log(fmt.Sprintf("Heightmap generated (terrain mesh %dx%d).", opts.Width, opts.Depth))
+		app.Log.Log(fmt.Sprintf("Heightmap generated (terrain mesh %dx%d, seed %d).", opts.Width, opts.Depth, opts.Seed))
 		return nil
 	})
 }
 
 func registerTerrainRepeatCmd(app *App) {
 	terrainRepeatFS := flag.NewFlagSet("terrain_repeat", flag.ContinueOnError)
-	app.Registry.Register("terrain_repeat", terrainRepeatFS, func() error {
+	app.Registry.Register("terrain_repeat", terrainRepeatFS, commands.Help{
+		Description: "Set how many times textures repeat across the heightmap terrain: saves a copy of the terrain's material with this uv_scale (\"terrain\", \"terrain-2\", ...) and switches the terrain to it, leaving the shared material unchanged.",
+		Usage:       "<u> <v>",
+		Examples:    [][]string{{"terrain_repeat", "4", "4"}},
+		Args:        []commands.Arg{{Name: "u", Description: "> 0"}, {Name: "v", Description: "> 0"}},
+		LLM:         true,
+	}, func() error {
 		args := terrainRepeatFS.Args()
 		if len(args) < 2 {
 			return fmt.Errorf("usage: cmd terrain_repeat <u> <v> (e.g. cmd terrain_repeat 4 4)")
@@ -627,42 +1762,332 @@ func registerTerrainRepeatCmd(app *App) {
 		if u <= 0 || v <= 0 {
 			return fmt.Errorf("terrain_repeat: u and v must be > 0")
 		}
-		app.Scene.SetTerrainTextureRepeat(float32(u), float32(v))
-		app.Log.Log(fmt.Sprintf("Terrain texture repeat set to %.2fx, %.2fy.", u, v))
+		name, err := app.Scene.SetTerrainUVScale(float32(u), float32(v))
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Terrain texture repeat set to %.2fx, %.2fy (material %s).", u, v, name))
 		return nil
 	})
 }
 
 func registerTemplateCmd(app *App) {
+	var templateLinked bool
 	templateFS := flag.NewFlagSet("template", flag.ContinueOnError)
-	app.Registry.Register("template", templateFS, func() error {
+	templateFS.BoolVar(&templateLinked, "linked", false, "keep the placement linked to the prefab (it updates when the prefab is saved again)")
+	app.Registry.Register("template", templateFS, commands.Help{
+		Description: "Place a prefab from the prefab library (assets/prefabs; cmd prefab list lists them) at an optional position, as a group named after it. --linked keeps the placement in sync with the prefab.",
+		Usage:       "[--linked] <prefab> [x y z]",
+		Examples:    [][]string{{"template", "tree"}, {"template", "tree", "4", "0", "2"}, {"template", "--linked", "house", "10", "0", "0"}},
+		Args:        []commands.Arg{{Name: "--linked", Optional: true}, {Name: "prefab"}, {Name: "x y z", Optional: true}},
+		LLM:         true,
+	}, func() error {
 		args := templateFS.Args()
-		if len(args) < 1 {
-			return fmt.Errorf("usage: cmd template tree [x y z]")
+		linked := templateLinked
+		templateLinked = false
+		if len(args) != 1 && len(args) != 4 {
+			return fmt.Errorf("usage: cmd template [--linked] <prefab> [x y z]")
 		}
-		x, y, z := 0.0, 0.0, 0.0
-		if len(args) >= 4 {
-			for i, s := range []*float64{&x, &y, &z} {
-				if f, err := strconv.ParseFloat(args[1+i], 32); err == nil {
-					*s = f
+		var pos [3]float32
+		if len(args) == 4 {
+			for i := range pos {
+				f, err := strconv.ParseFloat(args[1+i], 32)
+				if err != nil {
+					return fmt.Errorf("position: %q is not a number", args[1+i])
 				}
+				pos[i] = float32(f)
 			}
 		}
-		switch args[0] {
-		case "tree":
-			_ = app.Registry.Execute([]string{"spawn", "cylinder", strconv.FormatFloat(x, 'f', -1, 32), strconv.FormatFloat(y, 'f', -1, 32), strconv.FormatFloat(z, 'f', -1, 32), "0.3", "2", "0.3"})
-			_ = app.Registry.Execute([]string{"spawn", "sphere", strconv.FormatFloat(x, 'f', -1, 32), strconv.FormatFloat(y+1.5, 'f', -1, 32), strconv.FormatFloat(z, 'f', -1, 32), "1.2", "1.2", "1.2"})
-			app.Log.Log("Spawned tree.")
+		p, err := scene.LoadPrefab(args[0])
+		if err != nil {
+			return err
+		}
+		logSceneIssues(app.Log.Log, p.Issues)
+		app.Scene.PlacePrefab(p, pos, [3]float32{}, linked)
+		app.Log.Log(fmt.Sprintf("Placed %s at %v.", p.Name, pos))
+		return nil
+	})
+}
+
+func registerImportCmd(app *App) {
+	var importPhysics bool
+	importFS := flag.NewFlagSet("import", flag.ContinueOnError)
+	importFS.BoolVar(&importPhysics, "physics", false, "let the model fall and collide (default: static scenery)")
+	app.Registry.Register("import", importFS, commands.Help{
+		Description: "Import a 3D model (glTF .gltf/.glb or .obj with its materials and textures): copies it into assets/models and places it standing at an optional position (default origin), at its own size. Models already in assets/models are placed without copying.",
+		Usage:       "[--physics] <path> [x y z]",
+		Examples:    [][]string{{"import", "~/Downloads/hut.glb"}, {"import", "hut.glb", "4", "0", "2"}, {"import", "--physics", "crate.obj", "0", "5", "0"}},
+		Args:        []commands.Arg{{Name: "--physics", Optional: true}, {Name: "path"}, {Name: "x y z", Optional: true}},
+	}, func() error {
+		args := importFS.Args()
+		phys := importPhysics
+		importPhysics = false
+		if len(args) != 1 && len(args) != 4 {
+			return fmt.Errorf("usage: cmd import [--physics] <path> [x y z]")
+		}
+		var pos [3]float32
+		if len(args) == 4 {
+			for i := range pos {
+				f, err := strconv.ParseFloat(args[1+i], 32)
+				if err != nil {
+					return fmt.Errorf("position: %q is not a number", args[1+i])
+				}
+				pos[i] = float32(f)
+			}
+		}
+		src := args[0]
+		if strings.HasPrefix(src, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				src = filepath.Join(home, src[2:])
+			}
+		}
+		if _, err := os.Stat(src); err != nil {
+			if file, ok := scene.ResolveModelPath(src); ok {
+				src = file
+			}
+		}
+		path, err := scene.ImportModel(src)
+		if err != nil {
+			return err
+		}
+		id, err := app.Scene.AddModel(path, pos, [3]float32{}, phys)
+		if err != nil {
+			return err
+		}
+		obj, _ := app.Scene.Object(id)
+		app.Log.Log(fmt.Sprintf("Imported %s (size %.2f x %.2f x %.2f) at %v.", path, obj.Scale[0], obj.Scale[1], obj.Scale[2], pos))
+		return nil
+	})
+}
+
+func registerExportCmd(app *App) {
+	exportFS := flag.NewFlagSet("export", flag.ContinueOnError)
+	app.Registry.Register("export", exportFS, commands.Help{
+		Description: "Export the scene as glTF 2.0 for Blender and other engines: every object as a named node with its mesh, transform, color and texture (terrain included). A .glb file is one binary file; a .gltf file is JSON with everything embedded. Without an extension, .glb is used.",
+		Usage:       "gltf <file>",
+		Examples:    [][]string{{"export", "gltf", "village.glb"}, {"export", "gltf", "exports/village.gltf"}},
+		Args:        []commands.Arg{{Name: "format", Enum: []string{"gltf"}}, {Name: "file"}},
+	}, func() error {
+		args := exportFS.Args()
+		if len(args) != 2 || args[0] != "gltf" {
+			return fmt.Errorf("usage: cmd export gltf <file>")
+		}
+		path := args[1]
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".glb", ".gltf":
+		case "":
+			path += ".glb"
 		default:
-			return fmt.Errorf("unknown template (use tree)")
+			return fmt.Errorf("export gltf: file must end in .glb or .gltf")
+		}
+		n, warnings, err := app.View.ExportGLTF(path)
+		for _, w := range warnings {
+			app.Log.Log("  " + w)
 		}
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Exported %d object(s) to %s.", n, path))
 		return nil
 	})
 }
 
+func registerPrefabCmd(app *App) {
+	var prefabForce bool
+	prefabFS := flag.NewFlagSet("prefab", flag.ContinueOnError)
+	prefabFS.BoolVar(&prefabForce, "force", false, "save: replace an existing prefab (and update its linked placements)")
+	app.Registry.Register("prefab", prefabFS, commands.Help{
+		Description: "Manage prefabs (reusable object templates in assets/prefabs). save stores the selected objects (a single selected group: its contents) as a prefab; replacing an existing prefab, which also rebuilds its linked placements, needs --force. list shows the prefabs; unlink detaches the selected placements from their prefab. Place prefabs with template.",
+		Usage:       "[--force] save <name> | list | unlink",
+		Examples:    [][]string{{"prefab", "save", "house"}, {"prefab", "--force", "save", "house"}, {"prefab", "list"}, {"prefab", "unlink"}},
+		Args: []commands.Arg{
+			{Name: "--force", Description: "save over an existing prefab", Optional: true},
+			{Name: "action", Enum: []string{"save", "list", "unlink"}},
+			{Name: "name", Description: "prefab name (save)", Optional: true},
+		},
+		LLM: true,
+	}, func() error {
+		args := prefabFS.Args()
+		force := prefabForce
+		prefabForce = false
+		scn := app.Scene
+		if len(args) == 0 {
+			return fmt.Errorf("usage: cmd prefab [--force] save <name> | list | unlink")
+		}
+		switch {
+		case args[0] == "save" && len(args) == 2:
+			if _, err := prefabReplaces(args[1], force); err != nil {
+				return err
+			}
+			n, updated, err := scn.SavePrefab(args[1])
+			if err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("Saved prefab %q (%d object(s)).", strings.TrimSuffix(args[1], ".yaml"), n)
+			if updated > 0 {
+				msg += fmt.Sprintf(" Updated %d linked placement(s).", updated)
+			}
+			app.Log.Log(msg)
+		case args[0] == "list" && len(args) == 1:
+			list, err := scene.ListPrefabs()
+			if err != nil {
+				return err
+			}
+			if len(list) == 0 {
+				app.Log.Log("No prefabs yet (select objects, then cmd prefab save <name>).")
+				return nil
+			}
+			for _, info := range list {
+				app.Log.Log(fmt.Sprintf("  %s  %d object(s)", info.Name, info.Objects))
+			}
+		case args[0] == "unlink" && len(args) == 1:
+			n, err := scn.UnlinkSelected()
+			if err != nil {
+				return err
+			}
+			app.Log.Log(fmt.Sprintf("Unlinked %d placement(s).", n))
+		default:
+			return fmt.Errorf("usage: cmd prefab [--force] save <name> | list | unlink")
+		}
+		return nil
+	})
+	app.Registry.SetPreview("prefab", func() (commands.Preview, error) {
+		args := prefabFS.Args()
+		force := prefabForce
+		prefabForce = false
+		p := commands.Preview{Summary: "cmd prefab " + strings.Join(args, " ")}
+		if len(args) == 2 && args[0] == "save" {
+			replaces, err := prefabReplaces(args[1], force)
+			if err != nil {
+				return commands.Preview{}, err
+			}
+			if replaces {
+				p = commands.Preview{Summary: fmt.Sprintf("replace prefab %q and rebuild its linked placements", args[1]), Destructive: true}
+			}
+		}
+		return p, nil
+	})
+}
+
+// prefabReplaces reports whether cmd prefab save name would replace an existing prefab, and refuses that
+// without force.
+func prefabReplaces(name string, force bool) (bool, error) {
+	if !scene.PrefabExists(name) {
+		return false, nil
+	}
+	if !force {
+		return false, fmt.Errorf("prefab %q already exists (cmd prefab --force save %s replaces it and updates its linked placements)", name, name)
+	}
+	return true, nil
+}
+
+func registerGroupCmds(app *App) {
+	var groupLast int
+	groupFS := flag.NewFlagSet("group", flag.ContinueOnError)
+	groupFS.IntVar(&groupLast, "last", 0, "group the N most recently added objects")
+	app.Registry.Register("group", groupFS, commands.Help{
+		Description: "Put objects under a new named group so they move, duplicate and delete together. Objects are names or #id (as listed by view and inspect); --last N groups the N most recently added objects (e.g. the parts you just spawned); with neither, the selection is grouped.",
+		Usage:       "[--last N] <name> [object...]",
+		Examples:    [][]string{{"group", "House", "Walls", "Roof"}, {"group", "--last", "3", "Lamp"}, {"group", "Street"}},
+		Args: []commands.Arg{
+			{Name: "--last N", Description: "number of most recently added objects", Optional: true},
+			{Name: "name", Description: "name of the new group"},
+			{Name: "object", Description: "object name or #id (repeatable)", Optional: true},
+		},
+		LLM: true,
+	}, func() error {
+		args := groupFS.Args()
+		last := groupLast
+		groupLast = 0
+		if len(args) < 1 {
+			return fmt.Errorf("usage: cmd group [--last N] <name> [object...]")
+		}
+		scn := app.Scene
+		var indices []int
+		if last <= 0 && len(args) == 1 {
+			indices = scn.Selection()
+			if len(indices) == 0 {
+				return fmt.Errorf("nothing to group (list objects, use --last N, or select objects first)")
+			}
+		}
+		if last > 0 {
+			n := scn.ObjectCount()
+			for i := max(0, n-last); i < n; i++ {
+				indices = append(indices, i)
+			}
+		}
+		for _, a := range args[1:] {
+			idx, err := resolveObjectRef(scn, a)
+			if err != nil {
+				return err
+			}
+			indices = append(indices, idx)
+		}
+		g, err := scn.Group(scn.IDs(indices), args[0])
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Grouped %d object(s) as %q.", len(scn.Children(scn.IndexOf(g))), args[0]))
+		return nil
+	})
+
+	ungroupFS := flag.NewFlagSet("ungroup", flag.ContinueOnError)
+	app.Registry.Register("ungroup", ungroupFS, commands.Help{
+		Description: "Release a group's children into its parent (the scene if it has none) and remove the group. Defaults to the selected object.",
+		Usage:       "[object]",
+		Examples:    [][]string{{"ungroup"}, {"ungroup", "House"}},
+		Args:        []commands.Arg{{Name: "object", Description: "group name or #id", Optional: true}},
+		LLM:         true,
+	}, func() error {
+		args := ungroupFS.Args()
+		scn := app.Scene
+		idx := scn.SelectedIndex()
+		if len(args) >= 1 {
+			var err error
+			if idx, err = resolveObjectRef(scn, args[0]); err != nil {
+				return err
+			}
+		} else if idx < 0 {
+			return fmt.Errorf("no object selected (select a group or pass its name)")
+		}
+		n, err := scn.Ungroup(scn.IDAt(idx))
+		if err != nil {
+			return err
+		}
+		app.Log.Log(fmt.Sprintf("Released %d object(s).", n))
+		return nil
+	})
+}
+
+// resolveObjectRef returns the index of the object named ref, or of the object with ID "#<id>".
+func resolveObjectRef(scn *scene.Scene, ref string) (int, error) {
+	if strings.HasPrefix(ref, "#") {
+		id, err := strconv.ParseUint(ref[1:], 10, 64)
+		if err != nil {
+			return -1, fmt.Errorf("invalid object ID %q", ref)
+		}
+		idx := scn.IndexOf(scene.ObjectID(id))
+		if idx < 0 {
+			return -1, fmt.Errorf("no object with ID %s", ref)
+		}
+		return idx, nil
+	}
+	idx := scn.FindByName(ref)
+	if idx < 0 {
+		return -1, fmt.Errorf("no object named %q", ref)
+	}
+	return idx, nil
+}
+
 func registerFontCmd(app *App) {
 	fontFS := flag.NewFlagSet("font", flag.ContinueOnError)
-	app.Registry.Register("font", fontFS, func() error {
+	app.Registry.Register("font", fontFS, commands.Help{
+		Description: "Set the UI font by family name; downloads from Google Fonts if not in assets/fonts/. Never use URLs.",
+		Usage:       "<name>",
+		Examples:    [][]string{{"font", "Inter"}, {"font", "Open Sans"}},
+		Args:        []commands.Arg{{Name: "name"}},
+		LLM:         true,
+	}, func() error {
 		args := fontFS.Args()
 		if len(args) < 1 {
 			app.Log.Log("Current font: " + app.CurrentFont)
@@ -734,11 +2159,12 @@ func registerFontCmd(app *App) {
 }
 
 func formatObjectInfo(label string, obj scene.ObjectInstance) string {
-	return fmt.Sprintf("%s: type=%s name=%q pos=[%.2f,%.2f,%.2f] scale=[%.2f,%.2f,%.2f] color=[%.2f,%.2f,%.2f] physics=%v motion=%q texture=%q",
+	return fmt.Sprintf("%s: id=%d type=%s name=%q pos=[%.2f,%.2f,%.2f] scale=[%.2f,%.2f,%.2f] rotation=[%.1f,%.1f,%.1f] color=[%.2f,%.2f,%.2f] physics=%v motion=%q texture=%q",
 		label,
-		obj.Type, obj.Name,
+		obj.ID, obj.Type, obj.Name,
 		obj.Position[0], obj.Position[1], obj.Position[2],
 		obj.Scale[0], obj.Scale[1], obj.Scale[2],
+		obj.Rotation[0], obj.Rotation[1], obj.Rotation[2],
 		obj.Color[0], obj.Color[1], obj.Color[2],
 		scene.PhysicsEnabledForObject(obj), obj.Motion, obj.Texture)
 }
@@ -1,23 +1,35 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"game-engine/internal/commands"
 	"game-engine/internal/debug"
 	"game-engine/internal/engineconfig"
 	"game-engine/internal/env"
 	"game-engine/internal/graphics"
+	"game-engine/internal/llm"
 	"game-engine/internal/logger"
+	"game-engine/internal/mainthread"
+	"game-engine/internal/render"
 	"game-engine/internal/scene"
 	"game-engine/internal/terminal"
 	"game-engine/internal/ui"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 func main() {
+	validate := flag.Bool("validate", false, "validate scene files (arguments: names or .yaml paths; none = every scene in the library) and exit, 1 on problems")
+	flag.Parse()
+	if *validate {
+		os.Exit(validateScenes(flag.Args()))
+	}
+
 	_ = env.Load(".env")
 	_ = env.Load("../../.env")
 
@@ -28,7 +40,18 @@ func main() {
 	log := logger.New()
 	rl.SetTraceLogCallback(log.LogEngine)
 
-	scn := scene.New()
+	// Reopen the scene from the last session (engine config), falling back to the default scene.
+	prefs, _ := engineconfig.Load()
+	scn, err := scene.Open(prefs.Scene)
+	if err != nil && prefs.Scene != "" && prefs.Scene != scene.DefaultSceneName {
+		log.Log("Scene: " + err.Error() + "; opening the default scene")
+		scn, err = scene.Open(scene.DefaultSceneName)
+	}
+	if err != nil {
+		log.Log("Scene: " + err.Error())
+	}
+	logSceneIssues(log.Log, scn.LoadIssues())
+	view := render.New(scn)
 	dbg := debug.New()
 	reg := commands.NewRegistry()
 
@@ -37,15 +60,21 @@ func main() {
 	}
 
 	// Apply persisted engine prefs.
-	prefs, _ := engineconfig.Load()
 	dbg.SetShowFPS(prefs.ShowFPS)
 	dbg.SetShowMemAlloc(prefs.ShowMemAlloc)
-	scn.SetGridVisible(prefs.GridVisible)
+	view.SetGridVisible(prefs.GridVisible)
+	if err := view.SetShadows(prefs.Shadows != "off", prefs.ShadowQuality); err != nil {
+		log.Log("Shadows: " + err.Error())
+	}
+	scn.SetHistoryDepth(prefs.UndoDepth)
+
+	// Custom endpoints first, so detection and cmd provider see them.
+	RegisterEndpoints(prefs.AIEndpoints, log.Log)
 
 	// Resolve provider: use persisted value, or auto-detect from env on first run.
 	provider := prefs.AIProvider
 	if provider == "" {
-		provider = detectProvider()
+		provider = llm.DetectProvider()
 	}
 
 	model := prefs.AIModel
@@ -61,6 +90,7 @@ func main() {
 	app := &App{
 		Log:              log,
 		Scene:            scn,
+		View:             view,
 		Debug:            dbg,
 		Registry:         reg,
 		UI:               ui.New(),
@@ -68,17 +98,25 @@ func main() {
 		CurrentProvider:  provider,
 		CurrentAIModel:   model,
 		CurrentFont:      currentFont,
+		AgentRetries:     prefs.AgentRetries,
+		AITimeouts:       prefs.AITimeouts,
+		PreviewMode:      prefs.AIPreview,
+		AIEndpoints:      prefs.AIEndpoints,
+		UndoDepth:        prefs.UndoDepth,
+		AutosaveSecs:     prefs.AutosaveSecs,
+		AutosaveKeep:     prefs.AutosaveKeep,
+		autosaveAt:       time.Now(),
 		DownloadDone:     make(chan *downloadResult, 8),
 		SkyboxDone:       make(chan *skyboxResult, 4),
 		FontDownloadDone: make(chan *fontDownloadResult, 2),
-		PendingRunCmd:    make(chan []string, 64),
+		MainThread:       mainthread.New(64),
 		baseNodes:        []*ui.Node{},
 	}
 
 	registerCommands(app)
 
 	// Build LLM client from provider config.
-	client, err := BuildLLMClient(app.CurrentProvider)
+	client, err := llm.NewClient(app.CurrentProvider)
 	if err != nil {
 		log.Log("LLM: " + err.Error())
 	} else {
@@ -105,14 +143,36 @@ func main() {
 	graphics.Run(app.Update, app.Draw)
 }
 
-// detectProvider picks the best available provider based on env vars.
-func detectProvider() string {
-	switch {
-	case os.Getenv("GROQ_API_KEY") != "":
-		return "groq"
-	case os.Getenv("OPENAI_API_KEY") != "":
-		return "openai"
-	default:
-		return "ollama"
+// validateScenes checks scene files for the -validate flag (e.g. in CI) without opening a window: the
+// given names or paths, or every scene in the library. Prints each problem and returns the exit code.
+func validateScenes(args []string) int {
+	if len(args) == 0 {
+		list, err := scene.ListScenes()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, info := range list {
+			args = append(args, info.Name)
+		}
+	}
+	code := 0
+	for _, arg := range args {
+		path := sceneFilePath(arg)
+		issues, err := scene.ValidateFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+			continue
+		}
+		for _, is := range issues {
+			fmt.Fprintln(os.Stderr, is.String())
+		}
+		if len(issues) > 0 {
+			code = 1
+			continue
+		}
+		fmt.Println(path + ": ok")
 	}
+	return code
 }
@@ -3,22 +3,91 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
+	"game-engine/internal/commands"
 	"game-engine/internal/llm"
 )
 
-// Handler applies one action. Payload is the action object (e.g. {"action":"add_object", "type":"cube", ...}).
+// Handler applies one action. Payload is the action object (e.g. {"action":"add_object", "type":"cube", ...});
+// ctx is the request's, so a handler waiting for the main thread stops when the request is cancelled.
 // Returns an error to report to the user; the agent will still process remaining actions.
-type Handler func(payload map[string]interface{}) error
+type Handler func(ctx context.Context, payload map[string]interface{}) error
+
+// HandlerSpec describes an action to the LLM when handlers are exposed as tools.
+// Parameters is the JSON schema of the payload without the "action" field; nil = any object.
+// Enums fills in the enum of string parameters whose values change at runtime (e.g. prefab names); it is
+// called each time the prompt and tools are built.
+type HandlerSpec struct {
+	Description string
+	Parameters  map[string]interface{}
+	Enums       map[string]func() []string
+}
+
+// parameters returns Parameters with the current Enums filled in. Parameters itself is not modified.
+func (s HandlerSpec) parameters() map[string]interface{} {
+	if len(s.Enums) == 0 {
+		return s.Parameters
+	}
+	props, _ := s.Parameters["properties"].(map[string]interface{})
+	newProps := make(map[string]interface{}, len(props))
+	for name, p := range props {
+		newProps[name] = p
+	}
+	for name, values := range s.Enums {
+		p, ok := props[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		vals := values()
+		if len(vals) == 0 {
+			continue
+		}
+		np := make(map[string]interface{}, len(p)+1)
+		for k, v := range p {
+			np[k] = v
+		}
+		np["enum"] = vals
+		newProps[name] = np
+	}
+	out := make(map[string]interface{}, len(s.Parameters))
+	for k, v := range s.Parameters {
+		out[k] = v
+	}
+	out["properties"] = newProps
+	return out
+}
+
+type registeredHandler struct {
+	spec HandlerSpec
+	run  Handler
+}
 
 // Agent turns natural language into game updates via an LLM and a registry of action handlers.
+// When the client implements llm.ToolClient, handlers are offered as tools; otherwise (or when the
+// model rejects tools) the reply text is parsed for a JSON "actions" array.
 type Agent struct {
 	client   llm.Client
 	getModel func() string
-	handlers map[string]Handler
+	handlers map[string]registeredHandler
+	commands *commands.Registry // LLM-visible commands listed in the system prompt; may be nil
+	conv     *Conversation
+	log      func(string) // round logging; may be nil
+	status   func(string) // transient progress line while streaming ("" clears); may be nil
+
+	mu      sync.Mutex
+	noTools map[string]bool // models that rejected tool calling; use text parsing for them
+	retries int             // extra rounds to correct failed actions; 0 = no retry
+
+	previewers  map[string]Previewer
+	previewMode string         // PreviewOff, PreviewDestructive, PreviewAll
+	pending     *Plan          // previewed plan waiting for Apply or Reject
+	showPreview func([]Effect) // shows/clears a pending plan in the editor; may be nil
 }
 
 // New returns an Agent that uses the given LLM client and model getter.
@@ -27,134 +96,306 @@ func New(client llm.Client, getModel func() string) *Agent {
 	return &Agent{
 		client:   client,
 		getModel: getModel,
-		handlers: make(map[string]Handler),
+		handlers: make(map[string]registeredHandler),
+		conv:     NewConversation(),
+		noTools:  make(map[string]bool),
+
+		previewers:  make(map[string]Previewer),
+		previewMode: PreviewDestructive,
 	}
 }
 
+// Conversation returns the history replayed to the LLM on each Run (e.g. for cmd chat reset).
+func (a *Agent) Conversation() *Conversation {
+	return a.conv
+}
+
+// SetConversation replaces the history, e.g. to keep the session's history when the agent is
+// rebuilt after a provider switch. nil installs an empty conversation.
+func (a *Agent) SetConversation(c *Conversation) {
+	if c == nil {
+		c = NewConversation()
+	}
+	a.conv = c
+}
+
 // RegisterHandler adds a handler for the given action type (e.g. "add_object", "run_cmd").
-func (a *Agent) RegisterHandler(actionType string, h Handler) {
-	a.handlers[actionType] = h
+// spec is used to expose the handler as a tool to models that support tool calling.
+func (a *Agent) RegisterHandler(actionType string, spec HandlerSpec, h Handler) {
+	a.handlers[actionType] = registeredHandler{spec: spec, run: h}
+}
+
+// SetRetries sets how many follow-up rounds Run may use to let the model correct failed actions.
+// 0 disables the retry loop.
+func (a *Agent) SetRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	a.mu.Lock()
+	a.retries = n
+	a.mu.Unlock()
+}
+
+// SetLogger sets where retry rounds are logged (e.g. the terminal log).
+func (a *Agent) SetLogger(log func(string)) {
+	a.log = log
+}
+
+// SetStatus sets where streaming progress is shown (partial reply text, actions applied so far).
+// It is called with "" when the reply is complete.
+func (a *Agent) SetStatus(status func(string)) {
+	a.status = status
+}
+
+func (a *Agent) setStatus(line string) {
+	if a.status != nil {
+		a.status(line)
+	}
+}
+
+func (a *Agent) logLine(line string) {
+	if a.log != nil {
+		a.log(line)
+	}
+}
+
+// SetCommands sets the registry whose LLM-visible commands (Help.LLM) are described to the model
+// for run_cmd. RegisterSceneHandlers calls it.
+func (a *Agent) SetCommands(reg *commands.Registry) {
+	a.commands = reg
+}
+
+// tools returns one llm.Tool per registered handler, sorted by name.
+func (a *Agent) tools() []llm.Tool {
+	names := make([]string, 0, len(a.handlers))
+	for name := range a.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]llm.Tool, 0, len(names))
+	for _, name := range names {
+		spec := a.handlers[name].spec
+		out = append(out, llm.Tool{Name: name, Description: spec.Description, Parameters: spec.parameters()})
+	}
+	return out
 }
 
+// requestActions asks the LLM for actions and calls onAction for each one, in order. Streaming
+// clients (llm.StreamClient) apply actions as soon as each is complete; otherwise the whole reply is
+// awaited. Native tool calls are preferred, falling back to parsing the reply text (parseActions) for
+// clients or models without tool support. reply is the text recorded in the conversation history
+// (tool calls are rendered as an {"actions":[...]} object). err is a request failure; parseErr means
+// the model answered but the reply had no usable actions.
+func (a *Agent) requestActions(ctx context.Context, model, systemPrompt string, messages []llm.Message, onAction func(interface{})) (reply string, parseErr, err error) {
+	tc, toolsOK := a.client.(llm.ToolClient)
+	sc, streamOK := a.client.(llm.StreamClient)
+	a.mu.Lock()
+	useTools := (toolsOK || streamOK) && !a.noTools[model]
+	a.mu.Unlock()
+	if streamOK {
+		if useTools {
+			reply, parseErr, err = a.streamActions(ctx, sc, model, systemPrompt+toolsPromptSuffix, messages, a.tools(), onAction)
+			if !errors.Is(err, llm.ErrToolsUnsupported) {
+				return reply, parseErr, err
+			}
+			a.markNoTools(model)
+		}
+		return a.streamActions(ctx, sc, model, systemPrompt, messages, nil, onAction)
+	}
+	if useTools {
+		r, err := tc.ChatWithTools(ctx, model, systemPrompt+toolsPromptSuffix, messages, a.tools())
+		switch {
+		case errors.Is(err, llm.ErrToolsUnsupported):
+			a.markNoTools(model)
+		case err != nil:
+			return "", nil, err
+		case len(r.ToolCalls) > 0:
+			actions := make([]interface{}, 0, len(r.ToolCalls))
+			for _, call := range r.ToolCalls {
+				actions = append(actions, toolPayload(call))
+			}
+			for _, raw := range actions {
+				onAction(raw)
+			}
+			b, _ := json.Marshal(map[string]interface{}{"actions": actions})
+			return string(b), nil, nil
+		default:
+			// Model answered in text despite tools (e.g. JSON in content).
+			return r.Content, applyParsed(r.Content, onAction), nil
+		}
+	}
+	reply, err = a.client.Chat(ctx, model, systemPrompt, messages)
+	if err != nil {
+		return "", nil, err
+	}
+	return reply, applyParsed(reply, onAction), nil
+}
+
+// applyParsed parses a complete text reply and calls onAction for each action.
+func applyParsed(reply string, onAction func(interface{})) error {
+	actions, err := parseActions(reply)
+	if err != nil {
+		return err
+	}
+	for _, raw := range actions {
+		onAction(raw)
+	}
+	return nil
+}
+
+// markNoTools remembers that model rejected tool calling, so later requests use text parsing.
+func (a *Agent) markNoTools(model string) {
+	a.mu.Lock()
+	a.noTools[model] = true
+	a.mu.Unlock()
+}
+
+// toolsPromptSuffix is appended to the system prompt when handlers are offered as tools.
+const toolsPromptSuffix = "\n- Tools are available for every action above: call one tool per action (arguments as in the schema, without the \"action\" field) instead of replying with JSON."
+
 // Run sends the user message to the LLM, parses the JSON response, and applies each action.
 // viewContext is optional: when non-empty (e.g. current camera view summary), it is prepended to the
 // user message so the LLM can reason about what the user sees (e.g. "delete the one on the right").
+// Earlier turns (user messages, replies, and per-action results) are replayed from the Conversation so
+// follow-ups like "now make it bigger" resolve. When actions fail and retries are enabled (SetRetries),
+// the errors are sent back as a follow-up turn so the model can correct them, up to that many rounds.
+// With a streaming client each action is applied as soon as it is complete, before the reply ends.
 // Returns a short summary for the terminal log, or an error.
 func (a *Agent) Run(ctx context.Context, userMessage string, viewContext string) (summary string, err error) {
 	model := a.getModel()
 	if model == "" {
 		model = "gpt-4o-mini"
 	}
-	systemPrompt := buildSystemPrompt()
+	systemPrompt := a.buildSystemPrompt()
+	a.mu.Lock()
+	retries := a.retries
+	a.mu.Unlock()
 	prompt := userMessage
 	if viewContext != "" {
 		prompt = "Current camera view: " + viewContext + "\n\nUser: " + userMessage
 	}
-	reply, err := a.client.Complete(ctx, model, systemPrompt, prompt)
-	if err != nil {
-		return "", err
-	}
-	actions, parseErr := parseActions(reply)
-	if parseErr != nil {
-		return "", fmt.Errorf("LLM response invalid: %w", parseErr)
+	if a.Pending() != nil {
+		if msg, err := a.Reject(); err == nil {
+			a.logLine(msg + " (new request)")
+		}
 	}
+	a.mu.Lock()
+	mode := a.previewMode
+	a.mu.Unlock()
 	var applied int
 	var messages []string
-	for i, raw := range actions {
-		payload, ok := raw.(map[string]interface{})
-		if !ok {
-			messages = append(messages, fmt.Sprintf("action %d: invalid object", i+1))
-			continue
+	for round := 0; ; round++ {
+		var results []string
+		var plan *Plan
+		messages = nil
+		n := 0
+		record := func(result, msg string) {
+			results = append(results, result)
+			if msg != "" {
+				messages = append(messages, msg)
+			} else {
+				applied++
+			}
 		}
-		actionType, _ := payload["action"].(string)
-		if actionType == "" {
-			messages = append(messages, fmt.Sprintf("action %d: missing action", i+1))
-			continue
+		onAction := func(raw interface{}) {
+			n++
+			payload, actionType, result, msg := a.validate(n, raw)
+			if msg != "" {
+				record(result, msg)
+				return
+			}
+			var eff *Effect
+			if mode != PreviewOff {
+				e, err := a.effect(ctx, actionType, payload)
+				if err != nil {
+					record(failure(n, actionType, err))
+					return
+				}
+				if plan != nil || mode == PreviewAll || e.Destructive {
+					if plan == nil {
+						plan = &Plan{}
+					}
+					plan.Steps = append(plan.Steps, PlanStep{N: n, Action: actionType, Payload: payload, Effect: e})
+					return
+				}
+				eff = &e
+			}
+			if err := a.runStep(ctx, actionType, payload, eff); err != nil {
+				record(failure(n, actionType, err))
+				return
+			}
+			record(fmt.Sprintf("%d. %s: ok", n, actionType), "")
 		}
-		h, ok := a.handlers[actionType]
-		if !ok {
-			messages = append(messages, fmt.Sprintf("action %d: unknown action %q", i+1, actionType))
-			continue
+		reply, parseErr, err := a.requestActions(ctx, model, systemPrompt, a.conv.Messages(prompt), onAction)
+		if err != nil {
+			if len(results) > 0 {
+				// Streaming failed part-way: keep what was applied in the history.
+				a.conv.Add(Turn{User: userMessage, Reply: reply, Results: append(results, "interrupted: "+err.Error())})
+			}
+			return "", err
 		}
-		if err := h(payload); err != nil {
-			messages = append(messages, fmt.Sprintf("action %d (%s): %v", i+1, actionType, err))
-			continue
+		if plan != nil {
+			plan.Request, plan.Reply, plan.done, plan.applied = userMessage, reply, results, applied
+			summary := a.hold(plan)
+			if len(messages) > 0 {
+				summary = strings.Join(messages, "; ") + ". " + summary
+			}
+			return summary, nil
+		}
+		if parseErr != nil {
+			a.conv.Add(Turn{User: userMessage, Reply: reply, Results: []string{"invalid response: " + parseErr.Error()}})
+			messages = []string{"LLM response invalid: " + parseErr.Error()}
+		} else {
+			a.conv.Add(Turn{User: userMessage, Reply: reply, Results: results})
+		}
+		if len(messages) == 0 || round >= retries {
+			break
 		}
-		applied++
+		a.logLine(fmt.Sprintf("Retry %d/%d: %s", round+1, retries, strings.Join(messages, "; ")))
+		userMessage = retryMessage
+		prompt = retryMessage
 	}
 	if applied > 0 && len(messages) == 0 {
 		return fmt.Sprintf("Done. Applied %d action(s).", applied), nil
 	}
 	if len(messages) > 0 {
+		if applied == 0 && len(messages) == 1 && strings.HasPrefix(messages[0], "LLM response invalid: ") {
+			return "", errors.New(messages[0])
+		}
 		return strings.Join(messages, "; "), nil
 	}
 	return "No actions to apply.", nil
 }
 
-func buildSystemPrompt() string {
-	return "You are a game editor. The user types natural language; you reply with exactly one JSON object and nothing else. No markdown, no code block, no explanation.\n\n" +
-		"Schema:\n" +
-		"- add_object: {\"action\":\"add_object\",\"type\":\"cube|sphere|cylinder|plane\",\"position\":[x,y,z],\"scale\":[sx,sy,sz],\"physics\":true|false,\"color\":[r,g,b]} — one object. color optional (0-1 RGB). physics false = static.\n" +
-		"- add_objects: {\"action\":\"add_objects\",\"type\":\"cube|sphere|cylinder|plane|random\",\"count\":N,\"pattern\":\"grid\"|\"line\"|\"random\",\"spacing\":2,\"origin\":[x,y,z],\"scale_min\":[sx,sy,sz],\"scale_max\":[sx,sy,sz],\"physics\":true|false,\"color\":[r,g,b],\"color_random\":true} — many objects. color optional (single tint for all). color_random true = random RGB per object (e.g. colorful city). Use scale_min+scale_max for random sizes.\n" +
-		"- run_cmd: {\"action\":\"run_cmd\",\"args\":[\"subcommand\",\"arg1\",...]} — run an in-game command. Args are the tokens that would follow \"cmd \" (no \"cmd\" in the list).\n\n" +
-		"Available run_cmd commands (use these for any terminal command the user asks for):\n" +
-		"- grid: show/hide 3D editor grid → args [\"grid\",\"--show\"] or [\"grid\",\"--hide\"]\n" +
-		"- fps: show/hide FPS counter → [\"fps\",\"--show\"] or [\"fps\",\"--hide\"]\n" +
-		"- memalloc: show/hide memory usage → [\"memalloc\",\"--show\"] or [\"memalloc\",\"--hide\"]\n" +
-		"- window: fullscreen/windowed → [\"window\",\"--fullscreen\"] or [\"window\",\"--windowed\"]\n" +
-		"- spawn: add one primitive at position → [\"spawn\",\"cube\",\"0\",\"0\",\"0\"] or [\"spawn\",\"sphere\",\"1\",\"0\",\"1\",\"2\",\"2\",\"2\"] (type x y z [sx sy sz])\n" +
-		"- save: save current scene to file → [\"save\"]\n" +
-		"- newscene: clear all objects and save empty scene → [\"newscene\"]\n" +
-		"- physics: enable/disable physics on selected object → [\"physics\",\"on\"] or [\"physics\",\"off\"] (user must select an object first)\n" +
-		"- delete: remove object(s). [\"delete\",\"selected\"] | [\"delete\",\"look\"] | [\"delete\",\"random\"] | [\"delete\",\"name\",\"<name>\"] | [\"delete\",\"left\"|\"right\"|\"top\"|\"bottom\"|\"closest\"|\"farthest\"] | [\"delete\",\"<type>\"] | [\"delete\",\"<color>\",\"<type>\"] | [\"delete\",\"<type>\",\"<position>\"] (e.g. [\"delete\",\"cube\",\"right\"]) | [\"delete\",\"<color>\",\"<type>\",\"<position>\"] | [\"delete\",\"all\"] | [\"delete\",\"all\",\"<type>\"] | [\"delete\",\"all\",\"<name_substring>\"] (e.g. delete all buildings = [\"delete\",\"all\",\"building\"]). Position = left, right, top, bottom, closest, farthest. When the user says \"on the right\" or \"to the left\", use position. When they say \"all buildings\" or \"every cube in view\", use delete all.\n" +
-		"- color: set selected object RGB (0-1) → [\"color\",\"1\",\"0\",\"0\"] for red (user must select first)\n" +
-		"- duplicate: clone selected N times → [\"duplicate\",\"5\"] (user must select first)\n" +
-		"- screenshot: capture view → [\"screenshot\"]\n" +
-		"- lighting: time of day → [\"lighting\",\"noon\"] | [\"lighting\",\"sunset\"] | [\"lighting\",\"night\"]\n" +
-		"- name: set selected object name → [\"name\",\"Tower\"] (user must select first)\n" +
-		"- motion: set selected motion → [\"motion\",\"bob\"] | [\"motion\",\"off\"] (user must select first)\n" +
-		"- undo: revert last add or delete → [\"undo\"]\n" +
-		"- focus: point camera at selected → [\"focus\"] (user must select first)\n" +
-		"- gravity: set gravity Y → [\"gravity\",\"-9.8\"] or [\"gravity\",\"0\"] for zero-g\n" +
-		"- template: spawn preset → [\"template\",\"tree\"] or [\"template\",\"tree\",\"x\",\"y\",\"z\"]\n" +
-		"- download: download image from URL and apply as texture to selected object → [\"download\",\"image\",\"https://example.com/image.png\"] (user must select an object first)\n" +
-		"- texture: apply image file as texture to selected object → [\"texture\",\"<path>\"] e.g. [\"texture\",\"assets/textures/downloaded/foo.png\"] (user must select an object first)\n" +
-		"- skybox: set skybox from image URL (downloads in background, supports panorama/cubemap) → [\"skybox\",\"<url>\"] e.g. [\"skybox\",\"https://example.com/panorama.jpg\"]\n" +
-		"- font: set UI font by name (e.g. Inter, Roboto, Open Sans). If the font is in assets/fonts/, it is used; otherwise the engine downloads it from Google Fonts (safe, no user URLs). → [\"font\",\"<name>\"] e.g. [\"font\",\"Inter\"] or [\"font\",\"Open Sans\"].\n" +
-		"- heightmap: generate a static terrain made of cubes on a grid using a procedural height map → [\"heightmap\"] or [\"heightmap\",\"--w\",\"32\",\"--d\",\"32\",\"--tile\",\"1\",\"--h\",\"3\"] or similar (width/depth in tiles, tile size, max height).\n\n" +
-		"Rules:\n" +
-		"- For \"spawn 100 random primitives at random positions\" or \"add 50 random objects spread around\", use add_objects with type \"random\" and pattern \"random\".\n" +
-		"- For \"spawn 100 cubes\", \"add 50 spheres\", \"30 cubes spread around\", use ONE add_objects action with count and pattern (grid, line, or random for spread around). Do not emit many separate add_object entries.\n" +
-		"- For a single object at a specific position, use add_object with position. For \"gravity off\", \"no gravity\", \"static\", use \"physics\": false.\n" +
-		"- For \"spawn 50 cubes with gravity off\", \"add 20 spheres no gravity\", \"spawn 100 static objects\", use add_objects with \"physics\": false.\n" +
-		"- For \"create a city\", \"city with skyscrapers\", \"buildings with random heights\", \"skyline\", \"spawn buildings\", use ONE add_objects with type \"cube\", pattern \"grid\" or \"random\", count 20–80, spacing 5–8, scale_min [1,5,1] (min width, min height, min depth), scale_max [4,25,4] (max width, max height, max depth), physics false. Example: {\"action\":\"add_objects\",\"type\":\"cube\",\"count\":40,\"pattern\":\"grid\",\"spacing\":6,\"origin\":[0,0,0],\"scale_min\":[1,4,1],\"scale_max\":[5,20,5],\"physics\":false}.\n" +
-		"- Available shapes are only: cube, sphere, cylinder, plane. You must compose them to represent other things. For example, a tree can be represented as a cylinder (trunk) plus a sphere (foliage) placed above it; use add_object for each part. For \"forest\", \"trees\", \"spawn a forest\", decide how many trees and emit that many pairs of add_object: one cylinder (trunk, e.g. scale [0.3,2,0.3]) at position [x,y,z], one sphere (foliage, e.g. scale [1.2,1.2,1.2]) at [x,y+1.5,z]; use physics false. Vary x,z in a grid or spread (e.g. spacing 4–5). Put all actions in the same actions array.\n" +
-		"- For \"city with random colors\", \"colorful city\", \"spawn a city with colorful buildings\", \"buildings in random colors\", use add_objects with the same city params (type cube, scale_min, scale_max, pattern grid/random, physics false) AND \"color_random\": true so each building gets a random color.\n" +
-		"- For \"hide grid\", \"show FPS\", \"save the scene\", \"clear scene\", \"new scene\", \"fullscreen\", \"windowed\", \"show memory\", \"enable physics on selected\", \"delete selected\", \"delete what I'm looking at\", \"delete random object\" etc., use run_cmd with the appropriate args from the list above.\n" +
-		"- For \"download this image\", \"apply image from URL\", \"make that a texture from this URL\", use run_cmd [\"download\",\"image\",\"<url>\"] with the image URL. User must select an object first.\n" +
-		"- For \"make it a texture\", \"apply the downloaded image\", \"use this image as texture\", \"put this texture on the selected object\" when the image is already downloaded or user gives a path, use run_cmd [\"texture\",\"<path>\"] with the path (e.g. assets/textures/downloaded/filename.png). User must select an object first.\n" +
-		"- For \"set skybox to this url\", \"change skybox to ...\", \"use this as skybox\", \"download this skybox\", \"skybox from url\", use run_cmd [\"skybox\",\"<url>\"] with the image URL (panorama or cubemap).\n" +
-		"- For \"change font\", \"use Roboto Bold\", \"set font to X\", \"switch to Inter\", \"change UI font\", \"I want font Open Sans\", use run_cmd [\"font\",\"<name>\"] with the font family name (e.g. [\"font\",\"Inter\"], [\"font\",\"Open Sans\"], [\"font\",\"Roboto\"]). The engine uses local fonts if present, otherwise downloads from Google Fonts. Do not use URLs.\n" +
-		"- For \"generate a heightmap\", \"random height map\", \"terrain with hills\", \"make bumpy ground\", prefer the heightmap command instead of composing cubes manually: use run_cmd [\"heightmap\"] or run_cmd [\"heightmap\",\"--w\",\"32\",\"--d\",\"32\",\"--tile\",\"1\",\"--h\",\"3\"] with reasonable defaults. Heightmap tiles should be static terrain, not affected by gravity.\n" +
-		"- For \"make it red\", \"color the cube blue\", \"paint selected green\", use run_cmd [\"color\",\"r\",\"g\",\"b\"] with 0-1 values (e.g. red [\"color\",\"1\",\"0\",\"0\"]). User must select first.\n" +
-		"- For \"duplicate this\", \"clone it 5 times\", \"copy the selected object\", use run_cmd [\"duplicate\",\"N\"] (N=1 if not specified). User must select first.\n" +
-		"- For \"take a screenshot\", \"capture the screen\", use run_cmd [\"screenshot\"].\n" +
-		"- For \"sunset lighting\", \"make it night\", \"noon light\", use run_cmd [\"lighting\",\"sunset\"|\"night\"|\"noon\"].\n" +
-		"- For \"name this Tower\", \"call it Building1\", use run_cmd [\"name\",\"<name>\"]. User must select first.\n" +
-		"- For \"make it bounce\", \"bob the selected\", use run_cmd [\"motion\",\"bob\"]. To stop: [\"motion\",\"off\"]. User must select first.\n" +
-		"- For \"undo\", \"undo that\", \"revert last\", use run_cmd [\"undo\"].\n" +
-		"- For \"focus on selected\", \"look at the cube\", \"camera on selected\", use run_cmd [\"focus\"]. User must select first.\n" +
-		"- For \"zero gravity\", \"reverse gravity\", \"low gravity\", use run_cmd [\"gravity\",\"0\"] or [\"gravity\",\"4.9\"] etc.\n" +
-		"- For \"spawn a tree\", \"add a tree\", \"place a tree at 0 0 0\", compose it from primitives: use two add_object actions—one cylinder (trunk, e.g. position [x,y,z], scale [0.3,2,0.3]) and one sphere (foliage, e.g. position [x,y+1.5,z], scale [1.2,1.2,1.2]), physics false.\n" +
-		"- For \"delete the object named X\", \"remove Tower\", use run_cmd [\"delete\",\"name\",\"<name>\"].\n" +
-		"- For \"delete the plane\", \"remove the red cube\", \"delete that cube\", use run_cmd [\"delete\",\"<type>\"] or [\"delete\",\"<color>\",\"<type>\"] (e.g. [\"delete\",\"plane\"], [\"delete\",\"red\",\"cube\"]). No selection needed.\n" +
-		"- For \"delete the one on the right\", \"remove the building on the left\", \"delete the cube to the right\", use run_cmd [\"delete\",\"right\"] or [\"delete\",\"<type>\",\"right\"] or [\"delete\",\"<name_substring>\",\"right\"] (positions: left, right, top, bottom, closest, farthest). Use the Current camera view in the prompt to pick the right position.\n" +
-		"- For \"delete all buildings in view\", \"remove every cube I see\", \"get rid of all the spheres\", use run_cmd [\"delete\",\"all\"] (all in view) or [\"delete\",\"all\",\"<type>\"] or [\"delete\",\"all\",\"<name_substring>\"] (e.g. [\"delete\",\"all\",\"building\"], [\"delete\",\"all\",\"cube\"]). \"Buildings\" often means objects named with \"building\" or cubes in a city; use [\"delete\",\"all\",\"building\"] or [\"delete\",\"all\",\"cube\"] as appropriate.\n" +
-		"- Only use types: cube, sphere, cylinder, plane, or random (for add_objects).\n" +
-		"- Reply with only the JSON object."
+// retryMessage is the follow-up turn sent after a round with failed actions; the failures themselves
+// reach the model as the results of its previous actions (see Conversation.Messages).
+const retryMessage = "Some of your actions failed (see the results above). Reply with corrected actions for the failed ones only; do not repeat actions that succeeded."
+
+// validate checks action number i and returns its payload and type. If the action is unusable it returns the
+// result recorded in the conversation and the message shown to the user instead (message != "").
+func (a *Agent) validate(i int, raw interface{}) (payload map[string]interface{}, actionType, result, message string) {
+	payload, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, "", fmt.Sprintf("%d. error: invalid object", i), fmt.Sprintf("action %d: invalid object", i)
+	}
+	actionType, _ = payload["action"].(string)
+	if actionType == "" {
+		return nil, "", fmt.Sprintf("%d. error: missing action", i), fmt.Sprintf("action %d: missing action", i)
+	}
+	h, ok := a.handlers[actionType]
+	if !ok || h.run == nil {
+		return nil, "", fmt.Sprintf("%d. %s: error: unknown action", i, actionType), fmt.Sprintf("action %d: unknown action %q", i, actionType)
+	}
+	return payload, actionType, "", ""
+}
+
+// failure returns the conversation result and user message for action number i that failed with err.
+func failure(i int, actionType string, err error) (result, message string) {
+	return fmt.Sprintf("%d. %s: error: %v", i, actionType, err), fmt.Sprintf("action %d (%s): %v", i, actionType, err)
 }
 
 // parseActions extracts the "actions" array from the LLM reply. Tolerates markdown, extra text, and single-action form.
+// Fallback for clients or models without tool calling; see requestActions.
 func parseActions(reply string) ([]interface{}, error) {
 	reply = strings.TrimSpace(reply)
 	// Strip markdown code block if present
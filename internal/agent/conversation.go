@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+
+	"game-engine/internal/llm"
+)
+
+// Default conversation budget: enough for follow-ups like "now make it bigger" without
+// blowing the context window of small local models.
+const (
+	DefaultMaxTurns  = 8
+	DefaultMaxTokens = 3000
+)
+
+// Turn is one completed exchange: what the user asked, what the model replied (the raw actions JSON),
+// and the per-action results reported by the handlers (e.g. "1. add_object: ok").
+type Turn struct {
+	User    string
+	Reply   string
+	Results []string
+}
+
+// Conversation is the bounded per-session history replayed to the LLM on every Run.
+// Oldest turns are dropped when MaxTurns or the approximate MaxTokens budget is exceeded.
+// Safe for concurrent use (Run executes in a goroutine; cmd chat reset runs on the main thread).
+type Conversation struct {
+	mu        sync.Mutex
+	turns     []Turn
+	MaxTurns  int // 0 = DefaultMaxTurns
+	MaxTokens int // approximate (chars/4); 0 = DefaultMaxTokens
+}
+
+// NewConversation returns an empty conversation with the default budget.
+func NewConversation() *Conversation {
+	return &Conversation{MaxTurns: DefaultMaxTurns, MaxTokens: DefaultMaxTokens}
+}
+
+// Add appends a completed turn and trims the history to the turn budget.
+func (c *Conversation) Add(t Turn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.turns = append(c.turns, t)
+	maxTurns := c.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultMaxTurns
+	}
+	if len(c.turns) > maxTurns {
+		c.turns = append([]Turn(nil), c.turns[len(c.turns)-maxTurns:]...)
+	}
+}
+
+// Reset forgets all turns.
+func (c *Conversation) Reset() {
+	c.mu.Lock()
+	c.turns = nil
+	c.mu.Unlock()
+}
+
+// Len returns the number of stored turns.
+func (c *Conversation) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.turns)
+}
+
+// Messages returns the history followed by current as the newest user message, dropping the oldest
+// turns until the total fits in MaxTokens. Results of a turn are sent at the start of the next user
+// message so roles strictly alternate user/assistant.
+func (c *Conversation) Messages(current string) []llm.Message {
+	c.mu.Lock()
+	turns := append([]Turn(nil), c.turns...)
+	maxTokens := c.MaxTokens
+	c.mu.Unlock()
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxTokens
+	}
+	for len(turns) > 0 && estimateTokens(buildMessages(turns, current)) > maxTokens {
+		turns = turns[1:]
+	}
+	return buildMessages(turns, current)
+}
+
+func buildMessages(turns []Turn, current string) []llm.Message {
+	out := make([]llm.Message, 0, 2*len(turns)+1)
+	var pending []string
+	for _, t := range turns {
+		out = append(out, llm.Message{Role: llm.RoleUser, Content: withResults(pending, t.User)})
+		out = append(out, llm.Message{Role: llm.RoleAssistant, Content: t.Reply})
+		pending = t.Results
+	}
+	return append(out, llm.Message{Role: llm.RoleUser, Content: withResults(pending, current)})
+}
+
+// withResults prefixes text with the results of the previous turn's actions, if any.
+func withResults(results []string, text string) string {
+	if len(results) == 0 {
+		return text
+	}
+	return "Results of your previous actions: " + strings.Join(results, "; ") + "\n\n" + text
+}
+
+// estimateTokens approximates the token count of messages (about 4 characters per token).
+func estimateTokens(messages []llm.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += len(m.Content)/4 + 4
+	}
+	return n
+}
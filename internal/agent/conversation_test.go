@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"game-engine/internal/llm"
+)
+
+// pad returns s padded with dots to 40 characters, which estimateTokens counts as 14 tokens.
+func pad(s string) string {
+	return s + strings.Repeat(".", 40-len(s))
+}
+
+func user(content string) llm.Message {
+	return llm.Message{Role: llm.RoleUser, Content: content}
+}
+
+func assistant(content string) llm.Message {
+	return llm.Message{Role: llm.RoleAssistant, Content: content}
+}
+
+func TestConversationDefaults(t *testing.T) {
+	c := NewConversation()
+	if c.MaxTurns != DefaultMaxTurns || c.MaxTokens != DefaultMaxTokens || DefaultMaxTurns != 8 || DefaultMaxTokens != 3000 {
+		t.Errorf("budget = %d turns, %d tokens; want 8, 3000", c.MaxTurns, c.MaxTokens)
+	}
+	// About four characters per token, plus four per message.
+	if n := estimateTokens([]llm.Message{user(pad("")), assistant("abc"), user("")}); n != 14+4+4 {
+		t.Errorf("estimateTokens = %d; want 22", n)
+	}
+
+	// A zero budget means the default.
+	var zero Conversation
+	for range DefaultMaxTurns + 2 {
+		zero.Add(Turn{User: "u", Reply: "r"})
+	}
+	if zero.Len() != DefaultMaxTurns {
+		t.Errorf("zero-value conversation kept %d turns; want %d", zero.Len(), DefaultMaxTurns)
+	}
+	if msgs := zero.Messages("now"); len(msgs) != 2*DefaultMaxTurns+1 {
+		t.Errorf("zero-value conversation sent %d messages; want %d", len(msgs), 2*DefaultMaxTurns+1)
+	}
+}
+
+func TestConversationMessages(t *testing.T) {
+	c := &Conversation{MaxTurns: 3, MaxTokens: 1000}
+	for _, turn := range []Turn{
+		{User: "u1", Reply: "r1", Results: []string{"1. add_object: ok"}},
+		{User: "u2", Reply: "r2", Results: []string{"1. add_object: ok"}},
+		{User: "u3", Reply: "r3", Results: []string{"1. set_color: ok", "2. delete: error: nothing selected"}},
+		{User: "u4", Reply: "r4"},
+	} {
+		c.Add(turn)
+	}
+	// The oldest turn is dropped with its results; each turn's results open the next user message.
+	want := []llm.Message{
+		user("u2"), assistant("r2"),
+		user("Results of your previous actions: 1. add_object: ok\n\nu3"), assistant("r3"),
+		user("Results of your previous actions: 1. set_color: ok; 2. delete: error: nothing selected\n\nu4"), assistant("r4"),
+		user("now"),
+	}
+	if got := c.Messages("now"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages = %q\nwant %q", got, want)
+	}
+
+	c.Reset()
+	if got := c.Messages("now"); c.Len() != 0 || !reflect.DeepEqual(got, []llm.Message{user("now")}) {
+		t.Errorf("after Reset: %d turns, Messages = %q", c.Len(), got)
+	}
+}
+
+func TestConversationTokenBudget(t *testing.T) {
+	// Each turn is 28 tokens and "now" 4, so 60 tokens fit two turns; the turn budget keeps three.
+	c := &Conversation{MaxTurns: 3, MaxTokens: 60}
+	for _, n := range []string{"1", "2", "3", "4"} {
+		c.Add(Turn{User: pad("u" + n), Reply: pad("r" + n)})
+	}
+	if c.Len() != 3 {
+		t.Fatalf("kept %d turns; want 3", c.Len())
+	}
+	want := []llm.Message{user(pad("u3")), assistant(pad("r3")), user(pad("u4")), assistant(pad("r4")), user("now")}
+	got := c.Messages("now")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages = %q\nwant %q", got, want)
+	}
+	if n := estimateTokens(got); n != 60 {
+		t.Errorf("sent %d tokens; want exactly the budget (60)", n)
+	}
+
+	// A current message over the budget on its own is still sent, without history.
+	long := strings.Repeat("x", 400)
+	if got := c.Messages(long); !reflect.DeepEqual(got, []llm.Message{user(long)}) {
+		t.Errorf("Messages(long) sent %d messages; want only the current one", len(got))
+	}
+}
+
+// chatClient is a text-only llm.Client that answers Chat from a list of replies and records the
+// messages of every request.
+type chatClient struct {
+	replies []string
+	sent    [][]llm.Message
+}
+
+func (c *chatClient) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return c.Chat(ctx, model, systemPrompt, []llm.Message{user(userMessage)})
+}
+
+func (c *chatClient) Chat(ctx context.Context, model, systemPrompt string, messages []llm.Message) (string, error) {
+	c.sent = append(c.sent, messages)
+	if len(c.replies) == 0 {
+		return "", errors.New("no more replies")
+	}
+	r := c.replies[0]
+	c.replies = c.replies[1:]
+	return r, nil
+}
+
+func TestRunReplaysConversation(t *testing.T) {
+	first := `{"actions":[{"action":"ping"},{"action":"fail"}]}`
+	client := &chatClient{replies: []string{first, `{"actions":[{"action":"ping"}]}`, `{"actions":[{"action":"ping"}]}`}}
+	a := New(client, func() string { return "m" })
+	a.SetRetries(0)
+	a.SetPreviewMode(PreviewOff)
+	a.RegisterHandler("ping", HandlerSpec{}, func(ctx context.Context, payload map[string]interface{}) error { return nil })
+	a.RegisterHandler("fail", HandlerSpec{}, func(ctx context.Context, payload map[string]interface{}) error { return errors.New("boom") })
+	ctx := context.Background()
+
+	if _, err := a.Run(ctx, "add a cube", ""); err != nil {
+		t.Fatal(err)
+	}
+	// The view context goes with the current message only; the history keeps what the user typed.
+	if _, err := a.Run(ctx, "again", "2 cubes"); err != nil {
+		t.Fatal(err)
+	}
+	// cmd chat reset forgets the history.
+	a.Conversation().Reset()
+	if _, err := a.Run(ctx, "hello", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]llm.Message{
+		{user("add a cube")},
+		{
+			user("add a cube"), assistant(first),
+			user("Results of your previous actions: 1. ping: ok; 2. fail: error: boom\n\nCurrent camera view: 2 cubes\n\nUser: again"),
+		},
+		{user("hello")},
+	}
+	if !reflect.DeepEqual(client.sent, want) {
+		t.Errorf("sent %q\nwant %q", client.sent, want)
+	}
+}
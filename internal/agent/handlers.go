@@ -1,175 +1,690 @@
 package agent
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
 
 	"game-engine/internal/commands"
+	"game-engine/internal/mainthread"
 	"game-engine/internal/scene"
 )
 
 var primitiveTypes = []string{"cube", "sphere", "cylinder", "plane"}
 
-// PendingRunCmd, when non-nil, queues run_cmd args to be executed on the main thread (e.g. to avoid calling raylib from a goroutine).
-// If nil, run_cmd is executed in the caller's goroutine.
-func RegisterSceneHandlers(a *Agent, scn *scene.Scene, reg *commands.Registry, pendingRunCmd chan<- []string) {
-	a.RegisterHandler("add_object", func(payload map[string]interface{}) error {
+// bulkAddConfirm is the add_objects count from which a reply is previewed even in PreviewDestructive mode.
+const bulkAddConfirm = 100
+
+// MainThread runs scene work on the game loop's goroutine and returns its error; *mainthread.Queue is one.
+// Do gives up, returning ctx's error, when the request is cancelled or times out while it waits.
+type MainThread interface {
+	Do(ctx context.Context, fn func() error) error
+}
+
+// RegisterSceneHandlers registers add_object, add_objects, add_prefab, add_model, add_light, set_material and run_cmd and their previewers. Payloads are
+// validated on the agent's goroutine; every scene mutation and command runs on the main thread through main
+// (so raylib and the scene are never touched concurrently) and its error is reported back to the agent. A nil
+// main runs them directly.
+func RegisterSceneHandlers(a *Agent, scn *scene.Scene, reg *commands.Registry, main MainThread) {
+	if main == nil {
+		main = (*mainthread.Queue)(nil)
+	}
+	a.SetCommands(reg)
+	a.RegisterHandler("add_object", addObjectSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		sp, physics, err := parseAddObject(payload)
+		if err != nil {
+			return err
+		}
+		return addSpawns(ctx, scn, main, []spawn{sp}, physics)
+	})
+	a.RegisterPreview("add_object", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		sp, physics, err := parseAddObject(payload)
+		if err != nil {
+			return Effect{}, err
+		}
+		spawns := []spawn{sp}
+		return Effect{
+			Summary: fmt.Sprintf("add %s at %v", sp.typ, sp.pos),
+			Adds:    spawnInstances(spawns),
+			Apply:   func(ctx context.Context) error { return addSpawns(ctx, scn, main, spawns, physics) },
+		}, nil
+	})
+	a.RegisterHandler("add_objects", addObjectsSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		spawns, physics, err := parseAddObjects(payload)
+		if err != nil {
+			return err
+		}
+		return addSpawns(ctx, scn, main, spawns, physics)
+	})
+	a.RegisterPreview("add_objects", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		// The random layout, scales and colors are rolled once here, so apply adds exactly what was shown.
+		spawns, physics, err := parseAddObjects(payload)
+		if err != nil {
+			return Effect{}, err
+		}
 		typ, _ := payload["type"].(string)
-		if typ == "" {
-			return fmt.Errorf("missing type")
+		return Effect{
+			Summary:     fmt.Sprintf("add %d %s object(s)", len(spawns), typ),
+			Adds:        spawnInstances(spawns),
+			Destructive: len(spawns) >= bulkAddConfirm,
+			Apply:       func(ctx context.Context) error { return addSpawns(ctx, scn, main, spawns, physics) },
+		}, nil
+	})
+	a.RegisterHandler("add_prefab", addPrefabSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		pl, err := parseAddPrefab(payload)
+		if err != nil {
+			return err
 		}
-		switch typ {
-		case "cube", "sphere", "cylinder", "plane":
-		default:
-			return fmt.Errorf("unknown type %q", typ)
+		return placePrefab(ctx, scn, main, pl)
+	})
+	a.RegisterPreview("add_prefab", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		pl, err := parseAddPrefab(payload)
+		if err != nil {
+			return Effect{}, err
 		}
-		pos, err := parseFloat3(payload["position"])
+		return Effect{
+			Summary: fmt.Sprintf("add prefab %s at %v", pl.prefab.Name, pl.pos),
+			Adds:    scene.WorldObjects(pl.prefab.Instance(pl.pos, pl.rotation, pl.linked)),
+			Apply:   func(ctx context.Context) error { return placePrefab(ctx, scn, main, pl) },
+		}, nil
+	})
+	a.RegisterHandler("add_model", addModelSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		obj, err := parseAddModel(payload)
 		if err != nil {
-			return fmt.Errorf("position: %w", err)
+			return err
 		}
-		scale, err := parseFloat3(payload["scale"])
+		return placeObject(ctx, scn, main, obj)
+	})
+	a.RegisterPreview("add_model", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		obj, err := parseAddModel(payload)
 		if err != nil {
-			scale = [3]float32{1, 1, 1}
+			return Effect{}, err
 		}
-		physics := parseBoolOpt(payload["physics"], true)
-		var color *[3]float32
-		if c, err := parseFloat3(payload["color"]); err == nil && (c[0] != 0 || c[1] != 0 || c[2] != 0) {
-			color = &c
+		return Effect{
+			Summary: fmt.Sprintf("add model %s at %v", obj.Model, obj.Position),
+			Adds:    []scene.ObjectInstance{obj},
+			Apply:   func(ctx context.Context) error { return placeObject(ctx, scn, main, obj) },
+		}, nil
+	})
+	a.RegisterHandler("add_light", addLightSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		obj, err := parseAddLight(payload)
+		if err != nil {
+			return err
 		}
-		scn.AddPrimitiveWithPhysics(typ, pos, scale, physics, color)
-		scn.RecordAdd(1)
-		return nil
+		return placeObject(ctx, scn, main, obj)
 	})
-	a.RegisterHandler("add_objects", func(payload map[string]interface{}) error {
-		typ, _ := payload["type"].(string)
-		if typ == "" {
-			return fmt.Errorf("missing type")
-		}
-		randomType := typ == "random" || typ == "any"
-		if !randomType {
-			switch typ {
-			case "cube", "sphere", "cylinder", "plane":
-			default:
-				return fmt.Errorf("unknown type %q (use cube, sphere, cylinder, plane, or random)", typ)
-			}
+	a.RegisterPreview("add_light", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		obj, err := parseAddLight(payload)
+		if err != nil {
+			return Effect{}, err
 		}
-		count := 1
-		if n, ok := payload["count"].(float64); ok && n >= 1 {
-			count = int(n)
+		return Effect{
+			Summary: fmt.Sprintf("add %s light at %v", obj.Light.Kind, obj.Position),
+			Adds:    []scene.ObjectInstance{obj},
+			Apply:   func(ctx context.Context) error { return placeObject(ctx, scn, main, obj) },
+		}, nil
+	})
+	a.RegisterHandler("set_material", setMaterialSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		ed, err := parseSetMaterial(payload)
+		if err != nil {
+			return err
 		}
-		if count > 500 {
-			count = 500
+		return applyMaterial(ctx, scn, main, ed)
+	})
+	a.RegisterPreview("set_material", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		ed, err := parseSetMaterial(payload)
+		if err != nil {
+			return Effect{}, err
 		}
-		spacing := float32(2)
-		if s, err := parseFloat1(payload["spacing"]); err == nil && s > 0 {
-			spacing = s
+		summary := fmt.Sprintf("material %s", ed.name)
+		// Overwriting an existing file changes every object and scene that shares the material.
+		overwrite := ed.save && scene.MaterialExists(ed.name)
+		switch {
+		case overwrite:
+			summary = fmt.Sprintf("overwrite material %s", ed.name)
+		case ed.save:
+			summary = fmt.Sprintf("save material %s", ed.name)
 		}
-		origin, _ := parseFloat3(payload["origin"])
-		pattern, _ := payload["pattern"].(string)
-		if pattern == "" {
-			pattern = "grid"
+		if ed.apply {
+			summary += " on the selection"
 		}
-		scale := [3]float32{1, 1, 1}
-		if s, err := parseFloat3(payload["scale"]); err == nil {
-			scale = s
+		return Effect{Summary: summary, Destructive: overwrite, Apply: func(ctx context.Context) error { return applyMaterial(ctx, scn, main, ed) }}, nil
+	})
+	a.RegisterHandler("run_cmd", runCmdSpec, func(ctx context.Context, payload map[string]interface{}) error {
+		args, err := parseCmdArgs(payload, reg)
+		if err != nil {
+			return err
 		}
-		scaleMin, errMin := parseFloat3(payload["scale_min"])
-		scaleMax, errMax := parseFloat3(payload["scale_max"])
-		useScaleRange := errMin == nil && errMax == nil
-		if useScaleRange {
-			for i := 0; i < 3; i++ {
-				if scaleMax[i] < scaleMin[i] {
-					scaleMin[i], scaleMax[i] = scaleMax[i], scaleMin[i]
-				}
-			}
+		return main.Do(ctx, func() error { return reg.Execute(args) })
+	})
+	a.RegisterPreview("run_cmd", func(ctx context.Context, payload map[string]interface{}) (Effect, error) {
+		args, err := parseCmdArgs(payload, reg)
+		if err != nil {
+			return Effect{}, err
 		}
-		physics := parseBoolOpt(payload["physics"], true)
-		var color *[3]float32
-		if c, err := parseFloat3(payload["color"]); err == nil && (c[0] != 0 || c[1] != 0 || c[2] != 0) {
-			color = &c
-		}
-		colorRandom := parseBoolOpt(payload["color_random"], false)
-		for i := 0; i < count; i++ {
-			var pos [3]float32
-			switch pattern {
-			case "line":
-				pos = [3]float32{origin[0] + float32(i)*spacing, origin[1], origin[2]}
-			case "random", "spread":
-				half := spacing * float32(count) / 4
-				if half < 5 {
-					half = 5
-				}
-				pos = [3]float32{
-					origin[0] + (rand.Float32()*2-1)*half,
-					origin[1],
-					origin[2] + (rand.Float32()*2-1)*half,
-				}
-			case "grid":
-				cols := int(math.Ceil(math.Sqrt(float64(count))))
-				row, col := i/cols, i%cols
-				pos = [3]float32{origin[0] + float32(col)*spacing, origin[1], origin[2] + float32(row)*spacing}
-			default:
-				cols := int(math.Ceil(math.Sqrt(float64(count))))
-				row, col := i/cols, i%cols
-				pos = [3]float32{origin[0] + float32(col)*spacing, origin[1], origin[2] + float32(row)*spacing}
-			}
-			objScale := scale
-			if useScaleRange {
-				for j := 0; j < 3; j++ {
-					objScale[j] = scaleMin[j] + rand.Float32()*(scaleMax[j]-scaleMin[j])
-					if objScale[j] < 0.1 {
-						objScale[j] = 0.1
-					}
-				}
-			}
-			objColor := color
-			if colorRandom {
-				// Random RGB in 0.35–1.0 so colors stay visible
-				c := [3]float32{
-					0.35 + rand.Float32()*0.65,
-					0.35 + rand.Float32()*0.65,
-					0.35 + rand.Float32()*0.65,
+		var p commands.Preview
+		if err := main.Do(ctx, func() (err error) {
+			p, err = reg.PreviewArgs(args)
+			return err
+		}); err != nil {
+			return Effect{}, err
+		}
+		e := Effect{Summary: p.Summary, Destructive: p.Destructive}
+		for _, id := range p.Deletes {
+			e.Deletes = append(e.Deletes, scene.ObjectID(id))
+		}
+		if p.Apply != nil {
+			e.Apply = func(ctx context.Context) error { return main.Do(ctx, p.Apply) }
+		}
+		return e, nil
+	})
+}
+
+// spawn is one primitive that add_object or add_objects will add.
+type spawn struct {
+	typ        string
+	pos, scale [3]float32
+	rotation   [3]float32 // Euler degrees
+	color      *[3]float32
+}
+
+// addSpawns adds the primitives on the main thread as one undo step.
+func addSpawns(ctx context.Context, scn *scene.Scene, main MainThread, spawns []spawn, physics bool) error {
+	return main.Do(ctx, func() error {
+		scn.BeginChange(fmt.Sprintf("add %d object(s)", len(spawns)))
+		defer scn.EndChange()
+		for _, sp := range spawns {
+			id := scn.AddPrimitiveWithPhysics(sp.typ, sp.pos, sp.scale, physics, sp.color)
+			if sp.rotation != ([3]float32{}) {
+				if err := scn.SetObjectRotation(id, sp.rotation); err != nil {
+					return err
 				}
-				objColor = &c
 			}
-			spawnTyp := typ
-			if randomType {
-				spawnTyp = primitiveTypes[rand.Intn(len(primitiveTypes))]
-			}
-			scn.AddPrimitiveWithPhysics(spawnTyp, pos, objScale, physics, objColor)
 		}
-		scn.RecordAdd(count)
 		return nil
 	})
-	a.RegisterHandler("run_cmd", func(payload map[string]interface{}) error {
-		args, ok := payload["args"].([]interface{})
-		if !ok || len(args) == 0 {
-			return fmt.Errorf("missing or empty args")
+}
+
+// spawnInstances returns the objects the spawns would create, for the editor preview.
+func spawnInstances(spawns []spawn) []scene.ObjectInstance {
+	out := make([]scene.ObjectInstance, len(spawns))
+	for i, sp := range spawns {
+		out[i] = scene.ObjectInstance{Type: sp.typ, Position: sp.pos, Scale: sp.scale, Rotation: sp.rotation}
+		if sp.color != nil {
+			out[i].Color = *sp.color
+		}
+	}
+	return out
+}
+
+// parseAddObject validates an add_object payload.
+func parseAddObject(payload map[string]interface{}) (sp spawn, physics bool, err error) {
+	typ, _ := payload["type"].(string)
+	if typ == "" {
+		return spawn{}, false, fmt.Errorf("missing type")
+	}
+	switch typ {
+	case "cube", "sphere", "cylinder", "plane":
+	default:
+		return spawn{}, false, fmt.Errorf("unknown type %q", typ)
+	}
+	pos, err := parseFloat3(payload["position"])
+	if err != nil {
+		return spawn{}, false, fmt.Errorf("position: %w", err)
+	}
+	scale, err := parseFloat3(payload["scale"])
+	if err != nil {
+		scale = [3]float32{1, 1, 1}
+	}
+	var rotation [3]float32 // optional; zero = unrotated
+	if payload["rotation"] != nil {
+		if rotation, err = parseFloat3(payload["rotation"]); err != nil {
+			return spawn{}, false, fmt.Errorf("rotation: %w", err)
+		}
+	}
+	physics = parseBoolOpt(payload["physics"], true)
+	var color *[3]float32
+	if c, err := parseFloat3(payload["color"]); err == nil && (c[0] != 0 || c[1] != 0 || c[2] != 0) {
+		color = &c
+	}
+	return spawn{typ: typ, pos: pos, scale: scale, rotation: rotation, color: color}, physics, nil
+}
+
+// prefabPlacement is one instance that add_prefab will add.
+type prefabPlacement struct {
+	prefab        scene.Prefab
+	pos, rotation [3]float32 // rotation in Euler degrees
+	linked        bool
+}
+
+// placePrefab adds the prefab instance on the main thread.
+func placePrefab(ctx context.Context, scn *scene.Scene, main MainThread, pl prefabPlacement) error {
+	return main.Do(ctx, func() error {
+		scn.PlacePrefab(pl.prefab, pl.pos, pl.rotation, pl.linked)
+		return nil
+	})
+}
+
+// parseAddPrefab validates an add_prefab payload and reads the prefab from the library.
+func parseAddPrefab(payload map[string]interface{}) (prefabPlacement, error) {
+	name, _ := payload["name"].(string)
+	if name == "" {
+		return prefabPlacement{}, fmt.Errorf("missing name")
+	}
+	p, err := scene.LoadPrefab(name)
+	if err != nil {
+		return prefabPlacement{}, err
+	}
+	pos, err := parseFloat3(payload["position"])
+	if err != nil {
+		return prefabPlacement{}, fmt.Errorf("position: %w", err)
+	}
+	var rotation [3]float32
+	if payload["rotation"] != nil {
+		if rotation, err = parseFloat3(payload["rotation"]); err != nil {
+			return prefabPlacement{}, fmt.Errorf("rotation: %w", err)
+		}
+	}
+	return prefabPlacement{prefab: p, pos: pos, rotation: rotation, linked: parseBoolOpt(payload["linked"], false)}, nil
+}
+
+// placeObject adds one object (a model or a light) on the main thread and selects it.
+func placeObject(ctx context.Context, scn *scene.Scene, main MainThread, obj scene.ObjectInstance) error {
+	return main.Do(ctx, func() error {
+		scn.SelectID(scn.AddObject(obj))
+		return nil
+	})
+}
+
+// parseAddModel validates an add_model payload and returns the object to add (sized from the model file
+// unless a scale is given).
+func parseAddModel(payload map[string]interface{}) (scene.ObjectInstance, error) {
+	model, _ := payload["model"].(string)
+	if model == "" {
+		return scene.ObjectInstance{}, fmt.Errorf("missing model")
+	}
+	pos, err := parseFloat3(payload["position"])
+	if err != nil {
+		return scene.ObjectInstance{}, fmt.Errorf("position: %w", err)
+	}
+	var scale, rotation [3]float32
+	if payload["scale"] != nil {
+		if scale, err = parseFloat3(payload["scale"]); err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("scale: %w", err)
 		}
-		strs := make([]string, 0, len(args))
-		for _, v := range args {
+	}
+	if payload["rotation"] != nil {
+		if rotation, err = parseFloat3(payload["rotation"]); err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("rotation: %w", err)
+		}
+	}
+	obj, err := scene.ModelInstance(model, pos, scale, parseBoolOpt(payload["physics"], false))
+	if err != nil {
+		return scene.ObjectInstance{}, err
+	}
+	obj.Rotation = rotation
+	return obj, nil
+}
+
+// parseAddLight validates an add_light payload and returns the light object to add.
+func parseAddLight(payload map[string]interface{}) (scene.ObjectInstance, error) {
+	kind, _ := payload["kind"].(string)
+	pos, err := parseFloat3(payload["position"])
+	if err != nil {
+		return scene.ObjectInstance{}, fmt.Errorf("position: %w", err)
+	}
+	obj, err := scene.LightInstance(kind, pos)
+	if err != nil {
+		return scene.ObjectInstance{}, err
+	}
+	if payload["rotation"] != nil {
+		if obj.Rotation, err = parseFloat3(payload["rotation"]); err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("rotation: %w", err)
+		}
+	}
+	if payload["color"] != nil {
+		if obj.Color, err = parseFloat3(payload["color"]); err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("color: %w", err)
+		}
+	}
+	if payload["intensity"] != nil {
+		f, err := parseFloat1(payload["intensity"])
+		if err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("intensity: %w", err)
+		}
+		obj.Light.Intensity = &f
+	}
+	for key, dst := range map[string]*float32{"range": &obj.Light.Range, "cone": &obj.Light.Cone} {
+		if payload[key] == nil {
+			continue
+		}
+		if *dst, err = parseFloat1(payload[key]); err != nil {
+			return scene.ObjectInstance{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := obj.Light.Check(); err != nil {
+		return scene.ObjectInstance{}, err
+	}
+	return obj, nil
+}
+
+// materialEdit is what a set_material action does: save the material (when it is new or changed) and
+// apply it to the selected objects.
+type materialEdit struct {
+	name        string
+	m           scene.Material
+	save, apply bool
+}
+
+// materialProperties maps set_material payload keys to Material.Set keys and their number of values (0 = a
+// texture path).
+var materialProperties = []struct {
+	key string
+	n   int
+}{{"albedo", 3}, {"roughness", 1}, {"metallic", 1}, {"emissive", 3}, {"uv_scale", 2}, {"albedo_map", 0}, {"normal_map", 0}}
+
+// parseSetMaterial validates a set_material payload: the material starts from base (if given), else from
+// the library material of that name (if any), and the given properties are set on it.
+func parseSetMaterial(payload map[string]interface{}) (materialEdit, error) {
+	name, _ := payload["name"].(string)
+	if name == "" {
+		return materialEdit{}, fmt.Errorf("missing name")
+	}
+	if err := scene.ValidMaterialName(name); err != nil {
+		return materialEdit{}, err
+	}
+	ed := materialEdit{name: name, apply: parseBoolOpt(payload["apply"], true)}
+	var err error
+	if base, _ := payload["base"].(string); base != "" {
+		if ed.m, err = scene.LoadMaterial(base); err != nil {
+			return materialEdit{}, fmt.Errorf("base: %w", err)
+		}
+		ed.save = base != name
+	} else if scene.MaterialExists(name) {
+		if ed.m, err = scene.LoadMaterial(name); err != nil {
+			return materialEdit{}, err
+		}
+	} else {
+		ed.save = true
+	}
+	for _, p := range materialProperties {
+		v, ok := payload[p.key]
+		if !ok || v == nil {
+			continue
+		}
+		var values []string
+		if p.n == 0 {
 			s, ok := v.(string)
 			if !ok {
-				return fmt.Errorf("args must be strings")
+				return materialEdit{}, fmt.Errorf("%s: expected a texture path", p.key)
+			}
+			values = []string{s}
+		} else {
+			arr, ok := v.([]interface{})
+			if !ok {
+				arr = []interface{}{v}
+			}
+			if len(arr) != p.n {
+				return materialEdit{}, fmt.Errorf("%s: expected %d number(s)", p.key, p.n)
+			}
+			for _, x := range arr {
+				f, err := parseFloat1(x)
+				if err != nil {
+					return materialEdit{}, fmt.Errorf("%s: %w", p.key, err)
+				}
+				values = append(values, strconv.FormatFloat(float64(f), 'g', -1, 32))
 			}
-			strs = append(strs, s)
 		}
-		// Block commands that should not be changed by the LLM.
-		if len(strs) > 0 {
-			switch strs[0] {
-			case "model", "provider":
-				return fmt.Errorf("%s can only be changed manually (use cmd %s)", strs[0], strs[0])
+		if err := ed.m.Set(p.key, values...); err != nil {
+			return materialEdit{}, err
+		}
+		ed.save = true
+	}
+	return ed, nil
+}
+
+// applyMaterial saves and applies the material on the main thread. Nothing selected is not an error: the
+// material is only saved.
+func applyMaterial(ctx context.Context, scn *scene.Scene, main MainThread, ed materialEdit) error {
+	return main.Do(ctx, func() error {
+		if ed.save {
+			if err := scene.SaveMaterial(ed.name, ed.m); err != nil {
+				return err
 			}
 		}
-		if pendingRunCmd != nil {
-			pendingRunCmd <- strs
+		if !ed.apply || len(scn.Selection()) == 0 {
 			return nil
 		}
-		return reg.Execute(strs)
+		return scn.SetSelectedMaterial(ed.name)
 	})
 }
 
+// parseAddObjects validates an add_objects payload and lays out its objects. Random patterns, scales,
+// colors and types are rolled here.
+func parseAddObjects(payload map[string]interface{}) (spawns []spawn, physics bool, err error) {
+	typ, _ := payload["type"].(string)
+	if typ == "" {
+		return nil, false, fmt.Errorf("missing type")
+	}
+	randomType := typ == "random" || typ == "any"
+	if !randomType {
+		switch typ {
+		case "cube", "sphere", "cylinder", "plane":
+		default:
+			return nil, false, fmt.Errorf("unknown type %q (use cube, sphere, cylinder, plane, or random)", typ)
+		}
+	}
+	count := 1
+	if n, ok := payload["count"].(float64); ok && n >= 1 {
+		count = int(n)
+	}
+	if count > 500 {
+		count = 500
+	}
+	spacing := float32(2)
+	if s, err := parseFloat1(payload["spacing"]); err == nil && s > 0 {
+		spacing = s
+	}
+	origin, _ := parseFloat3(payload["origin"])
+	pattern, _ := payload["pattern"].(string)
+	if pattern == "" {
+		pattern = "grid"
+	}
+	scale := [3]float32{1, 1, 1}
+	if s, err := parseFloat3(payload["scale"]); err == nil {
+		scale = s
+	}
+	scaleMin, errMin := parseFloat3(payload["scale_min"])
+	scaleMax, errMax := parseFloat3(payload["scale_max"])
+	useScaleRange := errMin == nil && errMax == nil
+	if useScaleRange {
+		for i := 0; i < 3; i++ {
+			if scaleMax[i] < scaleMin[i] {
+				scaleMin[i], scaleMax[i] = scaleMax[i], scaleMin[i]
+			}
+		}
+	}
+	physics = parseBoolOpt(payload["physics"], true)
+	var color *[3]float32
+	if c, err := parseFloat3(payload["color"]); err == nil && (c[0] != 0 || c[1] != 0 || c[2] != 0) {
+		color = &c
+	}
+	colorRandom := parseBoolOpt(payload["color_random"], false)
+	spawns = make([]spawn, 0, count)
+	for i := 0; i < count; i++ {
+		var pos [3]float32
+		switch pattern {
+		case "line":
+			pos = [3]float32{origin[0] + float32(i)*spacing, origin[1], origin[2]}
+		case "random", "spread":
+			half := spacing * float32(count) / 4
+			if half < 5 {
+				half = 5
+			}
+			pos = [3]float32{
+				origin[0] + (rand.Float32()*2-1)*half,
+				origin[1],
+				origin[2] + (rand.Float32()*2-1)*half,
+			}
+		case "grid":
+			cols := int(math.Ceil(math.Sqrt(float64(count))))
+			row, col := i/cols, i%cols
+			pos = [3]float32{origin[0] + float32(col)*spacing, origin[1], origin[2] + float32(row)*spacing}
+		default:
+			cols := int(math.Ceil(math.Sqrt(float64(count))))
+			row, col := i/cols, i%cols
+			pos = [3]float32{origin[0] + float32(col)*spacing, origin[1], origin[2] + float32(row)*spacing}
+		}
+		objScale := scale
+		if useScaleRange {
+			for j := 0; j < 3; j++ {
+				objScale[j] = scaleMin[j] + rand.Float32()*(scaleMax[j]-scaleMin[j])
+				if objScale[j] < 0.1 {
+					objScale[j] = 0.1
+				}
+			}
+		}
+		objColor := color
+		if colorRandom {
+			// Random RGB in 0.35–1.0 so colors stay visible
+			c := [3]float32{
+				0.35 + rand.Float32()*0.65,
+				0.35 + rand.Float32()*0.65,
+				0.35 + rand.Float32()*0.65,
+			}
+			objColor = &c
+		}
+		spawnTyp := typ
+		if randomType {
+			spawnTyp = primitiveTypes[rand.Intn(len(primitiveTypes))]
+		}
+		spawns = append(spawns, spawn{typ: spawnTyp, pos: pos, scale: objScale, color: objColor})
+	}
+	return spawns, physics, nil
+}
+
+// parseCmdArgs validates a run_cmd payload and returns its args. Only commands marked LLM-visible may be
+// run by the model (e.g. model/provider are manual only).
+func parseCmdArgs(payload map[string]interface{}, reg *commands.Registry) ([]string, error) {
+	args, ok := payload["args"].([]interface{})
+	if !ok || len(args) == 0 {
+		return nil, fmt.Errorf("missing or empty args")
+	}
+	strs := make([]string, 0, len(args))
+	for _, v := range args {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("args must be strings")
+		}
+		strs = append(strs, s)
+	}
+	c, ok := reg.Lookup(strs[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown command: %s", strs[0])
+	}
+	if !c.Help.LLM {
+		return nil, fmt.Errorf("%s can only be changed manually (use cmd %s)", strs[0], strs[0])
+	}
+	return strs, nil
+}
+
+var addObjectSpec = HandlerSpec{
+	Description: "Add one primitive to the scene.",
+	Parameters: objectSchema(map[string]interface{}{
+		"type":     stringSchema("Primitive type.", primitiveTypes...),
+		"position": vec3Schema("Center position [x,y,z]."),
+		"scale":    vec3Schema("Size [sx,sy,sz]; default [1,1,1]."),
+		"rotation": vec3Schema("Optional rotation in degrees [rx,ry,rz] about X, then Y, then Z (e.g. [0,0,20] tilts a ramp or roof)."),
+		"physics":  boolSchema("true = falls and collides; false = static. Default true."),
+		"color":    vec3Schema("Optional RGB tint, each 0-1."),
+	}, "type", "position"),
+}
+
+var addObjectsSpec = HandlerSpec{
+	Description: "Add many primitives at once in a grid, line, or random spread (cities, crowds, 'spawn 50 cubes').",
+	Parameters: objectSchema(map[string]interface{}{
+		"type":         stringSchema("Primitive type, or random for a mix.", append(append([]string{}, primitiveTypes...), "random")...),
+		"count":        integerSchema("Number of objects (max 500)."),
+		"pattern":      stringSchema("Layout.", "grid", "line", "random"),
+		"spacing":      numberSchema("Distance between objects; default 2."),
+		"origin":       vec3Schema("Start/center of the layout [x,y,z]."),
+		"scale":        vec3Schema("Size for every object when no scale range is given."),
+		"scale_min":    vec3Schema("Minimum random size [sx,sy,sz] (use with scale_max)."),
+		"scale_max":    vec3Schema("Maximum random size [sx,sy,sz] (use with scale_min)."),
+		"physics":      boolSchema("true = falls and collides; false = static. Default true."),
+		"color":        vec3Schema("Optional RGB tint for all objects, each 0-1."),
+		"color_random": boolSchema("true = random color per object."),
+	}, "type", "count"),
+}
+
+var addPrefabSpec = HandlerSpec{
+	Description: "Place a prefab: a saved multi-object template (e.g. a tree or a house) from the prefab library. Prefer it over composing primitives when one fits.",
+	Parameters: objectSchema(map[string]interface{}{
+		"name":     stringSchema("Prefab name."),
+		"position": vec3Schema("Position of the prefab's origin (the center of its base) [x,y,z]."),
+		"rotation": vec3Schema("Optional rotation in degrees [rx,ry,rz]; ry turns it to face another direction."),
+		"linked":   boolSchema("true = the placement changes when the prefab is edited and saved again. Default false."),
+	}, "name", "position"),
+	Enums: map[string]func() []string{"name": scene.PrefabNames},
+}
+
+var addModelSpec = HandlerSpec{
+	Description: "Place an imported 3D model (a file in the model library, e.g. a hut or a car) standing on the given position.",
+	Parameters: objectSchema(map[string]interface{}{
+		"model":    stringSchema("Model file name."),
+		"position": vec3Schema("Position of the center of the model's base [x,y,z] (y=0 stands it on the ground)."),
+		"scale":    vec3Schema("Optional size [sx,sy,sz]; default the model's own size."),
+		"rotation": vec3Schema("Optional rotation in degrees [rx,ry,rz]; ry turns it to face another direction."),
+		"physics":  boolSchema("true = falls and collides; false = static. Default false."),
+	}, "model", "position"),
+	Enums: map[string]func() []string{"model": scene.ModelNames},
+}
+
+var addLightSpec = HandlerSpec{
+	Description: "Add a point light (shines all around, e.g. a street lamp bulb or a campfire) or a spot light (a cone, e.g. a stage light). Lights are invisible; they light nearby objects and matter most at night.",
+	Parameters: objectSchema(map[string]interface{}{
+		"kind":      stringSchema("Light kind.", scene.LightKinds...),
+		"position":  vec3Schema("Position of the light [x,y,z]; e.g. y=3 for a lamp on a post."),
+		"color":     vec3Schema("Optional light color RGB, each 0-1; default white (warm lamps: [1,0.8,0.5])."),
+		"intensity": numberSchema("Brightness at the light, fading to 0 at range; 0 = off; default 1."),
+		"range":     numberSchema("Distance the light reaches; default 10."),
+		"cone":      numberSchema("Spot only: full cone angle in degrees (1-179); default 45."),
+		"rotation":  vec3Schema("Spot only: rotation in degrees [rx,ry,rz]; an unrotated spot shines straight down, rx or rz tilts it (e.g. [30,0,0])."),
+	}, "kind", "position"),
+}
+
+var setMaterialSpec = HandlerSpec{
+	Description: "Create or edit a PBR material (saved to the material library) and apply it to the selected objects: metal, plastic, glowing or textured surfaces. Give only the properties to change.",
+	Parameters: objectSchema(map[string]interface{}{
+		"name":       stringSchema("Material name, lowercase (e.g. gold, wet_stone); an existing name edits that material."),
+		"base":       stringSchema("Optional existing material to start from (its values are copied, then the given properties set)."),
+		"albedo":     vec3Schema("Base color RGB, each 0-1."),
+		"roughness":  numberSchema("0 = mirror-smooth, 1 = matte."),
+		"metallic":   numberSchema("0 = non-metal (plastic, stone, wood), 1 = metal."),
+		"emissive":   vec3Schema("Emitted light RGB (glow), 0 = none; may exceed 1."),
+		"albedo_map": stringSchema("Optional base color texture path, or none."),
+		"normal_map": stringSchema("Optional normal map texture path, or none."),
+		"uv_scale": map[string]interface{}{
+			"type":        "array",
+			"description": "How often textures repeat across the surface [u,v]; default [1,1].",
+			"items":       map[string]interface{}{"type": "number"},
+			"minItems":    2,
+			"maxItems":    2,
+		},
+		"apply": boolSchema("true = apply it to the selected objects (if any). Default true."),
+	}, "name"),
+	Enums: map[string]func() []string{"base": scene.MaterialNames},
+}
+
+var runCmdSpec = HandlerSpec{
+	Description: "Run an in-game terminal command. args are the tokens after \"cmd \" (e.g. [\"delete\",\"all\",\"cube\"]).",
+	Parameters: objectSchema(map[string]interface{}{
+		"args": stringArraySchema("Subcommand and its arguments, e.g. [\"lighting\",\"sunset\"]."),
+	}, "args"),
+}
+
 func parseBoolOpt(v interface{}, defaultVal bool) bool {
 	if v == nil {
 		return defaultVal
@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"game-engine/internal/commands"
+	"game-engine/internal/llm"
+	"game-engine/internal/scene"
+)
+
+// harness runs an Agent against a headless scene (no window, handlers run directly instead of through
+// the main thread) with LLM replies served from testdata/<name>.json.
+//
+// To re-record a cassette against a live provider, run with AGENT_RECORD=1 and an API key in the
+// environment (e.g. AGENT_RECORD=1 OPENAI_API_KEY=... go test ./internal/agent -run TestName).
+type harness struct {
+	t      *testing.T
+	scene  *scene.Scene
+	agent  *Agent
+	replay *llm.Replayer // nil when recording
+}
+
+func newHarness(t *testing.T, cassette string) *harness {
+	t.Helper()
+	path := filepath.Join("testdata", cassette+".json")
+	h := &harness{t: t, scene: scene.NewEmpty()}
+	var client llm.Client
+	model := "test-model"
+	if os.Getenv("AGENT_RECORD") != "" {
+		provider := llm.DetectProvider()
+		live, err := llm.NewClient(provider)
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+		p, _ := llm.LookupProvider(provider)
+		model = p.DefaultModel
+		client = llm.NewRecorder(live, path)
+	} else {
+		c, err := llm.LoadCassette(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.replay = llm.NewReplayer(c)
+		client = h.replay
+	}
+	h.agent = New(client, func() string { return model })
+	h.agent.SetRetries(0)
+	h.agent.SetPreviewMode(PreviewOff)
+	RegisterSceneHandlers(h.agent, h.scene, commands.NewRegistry(), nil)
+	return h
+}
+
+// run sends one natural-language request and fails the test on a request error.
+func (h *harness) run(request string) string {
+	h.t.Helper()
+	summary, err := h.agent.Run(context.Background(), request, "")
+	if err != nil {
+		h.t.Fatalf("Run(%q): %v", request, err)
+	}
+	return summary
+}
+
+// done fails the test if the cassette still has unserved replies (the agent made fewer requests than recorded).
+func (h *harness) done() {
+	h.t.Helper()
+	if h.replay != nil && h.replay.Remaining() != 0 {
+		h.t.Errorf("%d recorded interaction(s) not replayed", h.replay.Remaining())
+	}
+}
+
+// objects returns the scene objects, failing the test unless there are exactly n.
+func (h *harness) objects(n int) []scene.ObjectInstance {
+	h.t.Helper()
+	objs := h.scene.Objects()
+	if len(objs) != n {
+		h.t.Fatalf("scene has %d object(s), want %d: %+v", len(objs), n, objs)
+	}
+	return objs
+}
+
+func TestRunAddObjectToolCall(t *testing.T) {
+	h := newHarness(t, "add_red_cube")
+	h.run("put a red cube at 1 0.5 2")
+	h.done()
+	obj := h.objects(1)[0]
+	if obj.Type != "cube" || obj.Position != [3]float32{1, 0.5, 2} {
+		t.Errorf("object = %+v, want cube at [1 0.5 2]", obj)
+	}
+	if obj.Color != [3]float32{1, 0, 0} {
+		t.Errorf("color = %v, want red", obj.Color)
+	}
+}
+
+func TestRunTextFallback(t *testing.T) {
+	// The model rejects tools, so the agent asks again without them and parses the JSON reply.
+	h := newHarness(t, "line_of_cubes_text")
+	h.run("four cubes in a line, 3 apart")
+	h.done()
+	for i, obj := range h.objects(4) {
+		if want := [3]float32{float32(3 * i), 0, 0}; obj.Type != "cube" || obj.Position != want {
+			t.Errorf("object %d = %+v, want cube at %v", i, obj, want)
+		}
+	}
+}
+
+func TestRunRetryCorrectsFailedAction(t *testing.T) {
+	h := newHarness(t, "retry_unknown_type")
+	h.agent.SetRetries(1)
+	h.run("add a pyramid at the origin")
+	h.done()
+	if obj := h.objects(1)[0]; obj.Type != "cylinder" {
+		t.Errorf("object = %+v, want the corrected cylinder", obj)
+	}
+	turns := h.agent.Conversation().Len()
+	if turns != 2 {
+		t.Errorf("conversation has %d turn(s), want 2 (request and retry)", turns)
+	}
+}
+
+func TestPreviewHoldsBulkAddUntilApply(t *testing.T) {
+	h := newHarness(t, "bulk_add_preview")
+	h.agent.SetPreviewMode(PreviewDestructive)
+	h.run("fill the floor with 150 cubes")
+	h.done()
+	h.objects(0)
+	plan := h.agent.Pending()
+	if plan == nil || len(plan.Steps) != 1 || len(plan.Steps[0].Effect.Adds) != 150 {
+		t.Fatalf("pending plan = %+v, want one add_objects step with 150 adds", plan)
+	}
+	if _, err := h.agent.Apply(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if h.agent.Pending() != nil {
+		t.Error("plan still pending after Apply")
+	}
+	objs := h.objects(150)
+	if objs[0].Position != plan.Steps[0].Effect.Adds[0].Position {
+		t.Errorf("applied %v, previewed %v", objs[0].Position, plan.Steps[0].Effect.Adds[0].Position)
+	}
+}
+
+func TestPromptListsCommandArgs(t *testing.T) {
+	reg := commands.NewRegistry()
+	reg.Register("lighting", flag.NewFlagSet("lighting", flag.ContinueOnError), commands.Help{
+		Description: "Set the time of day.",
+		Usage:       "noon | sunset | night",
+		Args:        []commands.Arg{{Name: "profile", Enum: []string{"noon", "sunset", "night"}}, {Name: "--fade", Description: "blend over a second", Optional: true}},
+		LLM:         true,
+	}, func() error { return nil })
+	a := New(nil, func() string { return "test-model" })
+	a.SetCommands(reg)
+	prompt := a.buildSystemPrompt()
+	for _, want := range []string{"    profile (noon|sunset|night)\n", "    --fade (optional): blend over a second\n"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("system prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestRunAddPrefab(t *testing.T) {
+	h := newHarness(t, "place_tree_prefab")
+	if !strings.Contains(h.agent.buildSystemPrompt(), "name (tree") {
+		t.Error("system prompt does not list the tree prefab")
+	}
+	h.run("plant a tree at 3 0 -2")
+	h.done()
+	objs := h.objects(3)
+	if objs[0].Type != scene.GroupType || objs[0].Position != [3]float32{3, 0, -2} {
+		t.Errorf("instance = %+v, want a group at [3 0 -2]", objs[0])
+	}
+	if n := len(h.scene.Children(0)); n != 2 {
+		t.Errorf("instance has %d children, want 2 (trunk and foliage)", n)
+	}
+}
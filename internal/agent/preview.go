@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"game-engine/internal/scene"
+)
+
+// Preview modes (SetPreviewMode). In a preview, proposed actions are shown in the editor and only run after
+// the user confirms with cmd apply (or are discarded with cmd reject).
+const (
+	PreviewOff         = "off"         // apply everything immediately
+	PreviewDestructive = "destructive" // preview from the first destructive action on (delete all, newscene); default
+	PreviewAll         = "all"         // preview every reply
+)
+
+// Effect is what an action would do, computed without changing the scene.
+type Effect struct {
+	Summary     string                 // one line for the terminal, e.g. "add 40 cube(s) in a grid"
+	Adds        []scene.ObjectInstance // drawn ghosted in the editor
+	Deletes     []scene.ObjectID       // objects highlighted in the editor
+	Destructive bool                   // needs confirmation unless the preview mode is off
+	// Apply performs exactly the previewed change (e.g. the same random positions) with the context of the
+	// request that applies it. nil = run the handler.
+	Apply func(ctx context.Context) error
+}
+
+// Previewer computes the Effect of an action payload. An error fails the action like a handler error.
+type Previewer func(ctx context.Context, payload map[string]interface{}) (Effect, error)
+
+// Plan is a previewed reply waiting for cmd apply or cmd reject.
+type Plan struct {
+	Request string
+	Reply   string
+	Steps   []PlanStep
+	done    []string // results of actions applied before the first previewed one
+	applied int
+}
+
+// PlanStep is one previewed action; N is its number in the reply.
+type PlanStep struct {
+	N       int
+	Action  string
+	Payload map[string]interface{}
+	Effect  Effect
+}
+
+// RegisterPreview sets how actions of the given type are previewed. Actions without a previewer are shown by
+// name and run through their handler on apply.
+func (a *Agent) RegisterPreview(actionType string, p Previewer) {
+	a.previewers[actionType] = p
+}
+
+// SetPreviewMode sets PreviewOff, PreviewDestructive or PreviewAll. Unknown values select PreviewDestructive.
+func (a *Agent) SetPreviewMode(mode string) {
+	switch mode {
+	case PreviewOff, PreviewAll:
+	default:
+		mode = PreviewDestructive
+	}
+	a.mu.Lock()
+	a.previewMode = mode
+	a.mu.Unlock()
+}
+
+// SetPreviewDisplay sets the function that shows a pending plan's effects in the editor; it is called with
+// nil when the plan is applied or rejected.
+func (a *Agent) SetPreviewDisplay(show func(effects []Effect)) {
+	a.showPreview = show
+}
+
+// Pending returns the plan waiting for confirmation, or nil.
+func (a *Agent) Pending() *Plan {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pending
+}
+
+// effect previews one validated action. Actions without a previewer are described by their type.
+func (a *Agent) effect(ctx context.Context, actionType string, payload map[string]interface{}) (Effect, error) {
+	p := a.previewers[actionType]
+	if p == nil {
+		return Effect{Summary: actionType}, nil
+	}
+	return p(ctx, payload)
+}
+
+// runStep applies one action, using the previewed Effect.Apply when there is one.
+func (a *Agent) runStep(ctx context.Context, actionType string, payload map[string]interface{}, eff *Effect) error {
+	if eff != nil && eff.Apply != nil {
+		return eff.Apply(ctx)
+	}
+	return a.handlers[actionType].run(ctx, payload)
+}
+
+// hold stores plan as pending, shows it in the editor and returns the summary asking for confirmation.
+func (a *Agent) hold(plan *Plan) string {
+	a.mu.Lock()
+	a.pending = plan
+	a.mu.Unlock()
+	effects := make([]Effect, len(plan.Steps))
+	lines := make([]string, len(plan.Steps))
+	for i, st := range plan.Steps {
+		effects[i] = st.Effect
+		lines[i] = fmt.Sprintf("%d. %s", st.N, st.Effect.Summary)
+	}
+	if a.showPreview != nil {
+		a.showPreview(effects)
+	}
+	head := fmt.Sprintf("Preview: %d action(s) waiting", len(plan.Steps))
+	if plan.applied > 0 {
+		head = fmt.Sprintf("Applied %d action(s). %s", plan.applied, head)
+	}
+	return head + " — cmd apply to run, cmd reject to discard: " + strings.Join(lines, "; ")
+}
+
+// take removes and returns the pending plan and clears the editor preview.
+func (a *Agent) take() *Plan {
+	a.mu.Lock()
+	plan := a.pending
+	a.pending = nil
+	a.mu.Unlock()
+	if plan != nil && a.showPreview != nil {
+		a.showPreview(nil)
+	}
+	return plan
+}
+
+// Apply runs the pending plan and records it in the conversation. Call it from the same goroutine as Run
+// (the request queue), since handlers wait for the main thread.
+func (a *Agent) Apply(ctx context.Context) (summary string, err error) {
+	plan := a.take()
+	if plan == nil {
+		return "", fmt.Errorf("nothing to apply (no pending preview)")
+	}
+	results := append([]string(nil), plan.done...)
+	applied := plan.applied
+	var messages []string
+	for i := range plan.Steps {
+		st := &plan.Steps[i]
+		if err := ctx.Err(); err != nil {
+			results = append(results, fmt.Sprintf("%d. %s: not applied: %v", st.N, st.Action, err))
+			messages = append(messages, fmt.Sprintf("action %d (%s): %v", st.N, st.Action, err))
+			continue
+		}
+		if err := a.runStep(ctx, st.Action, st.Payload, &st.Effect); err != nil {
+			results = append(results, fmt.Sprintf("%d. %s: error: %v", st.N, st.Action, err))
+			messages = append(messages, fmt.Sprintf("action %d (%s): %v", st.N, st.Action, err))
+			continue
+		}
+		results = append(results, fmt.Sprintf("%d. %s: ok (confirmed by user)", st.N, st.Action))
+		applied++
+	}
+	a.conv.Add(Turn{User: plan.Request, Reply: plan.Reply, Results: results})
+	if len(messages) > 0 {
+		return strings.Join(messages, "; "), nil
+	}
+	return fmt.Sprintf("Done. Applied %d action(s).", applied), nil
+}
+
+// Reject discards the pending plan and records the rejection in the conversation.
+func (a *Agent) Reject() (summary string, err error) {
+	plan := a.take()
+	if plan == nil {
+		return "", fmt.Errorf("nothing to reject (no pending preview)")
+	}
+	results := append([]string(nil), plan.done...)
+	for _, st := range plan.Steps {
+		results = append(results, fmt.Sprintf("%d. %s: rejected by user", st.N, st.Action))
+	}
+	a.conv.Add(Turn{User: plan.Request, Reply: plan.Reply, Results: results})
+	return fmt.Sprintf("Discarded %d previewed action(s).", len(plan.Steps)), nil
+}
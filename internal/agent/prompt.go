@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"game-engine/internal/commands"
+)
+
+// buildSystemPrompt generates the system prompt from the registered handlers (HandlerSpec) and the
+// LLM-visible commands in the registry (commands.Help), so new actions and commands are picked up
+// without editing the prompt.
+func (a *Agent) buildSystemPrompt() string {
+	var b strings.Builder
+	b.WriteString("You are a game editor. The user types natural language; you reply with exactly one JSON object and nothing else. No markdown, no code block, no explanation.\n")
+	b.WriteString("The object has the form {\"actions\":[{\"action\":\"<name>\", ...fields}, ...]}.\n\n")
+
+	b.WriteString("Actions:\n")
+	names := make([]string, 0, len(a.handlers))
+	for name := range a.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		spec := a.handlers[name].spec
+		fmt.Fprintf(&b, "- %s: %s\n", name, spec.Description)
+		for _, line := range describeParams(spec.parameters()) {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+
+	if a.commands != nil {
+		b.WriteString("\nAvailable run_cmd commands (args are the tokens that would follow \"cmd \"; use these for any terminal command the user asks for):\n")
+		for _, c := range a.commands.Commands() {
+			if !c.Help.LLM {
+				continue
+			}
+			line := "- " + c.Name
+			if c.Help.Usage != "" {
+				line += " " + c.Help.Usage
+			}
+			if c.Help.Description != "" {
+				line += ": " + c.Help.Description
+			}
+			if len(c.Help.Examples) > 0 {
+				ex := make([]string, 0, len(c.Help.Examples))
+				for _, e := range c.Help.Examples {
+					j, _ := json.Marshal(e)
+					ex = append(ex, string(j))
+				}
+				line += " → " + strings.Join(ex, " | ")
+			}
+			b.WriteString(line + "\n")
+			for _, arg := range describeArgs(c.Help.Args) {
+				b.WriteString("    " + arg + "\n")
+			}
+		}
+	}
+
+	b.WriteString("\nRules:\n")
+	b.WriteString(promptRules)
+	return b.String()
+}
+
+// describeArgs renders a command's arguments (commands.Help.Args) like describeParams renders action
+// fields: "name (a|b, optional): description". Arguments with nothing to add beyond the usage line (no
+// values, not optional, no description) are left out.
+func describeArgs(args []commands.Arg) []string {
+	var out []string
+	for _, a := range args {
+		var kind []string
+		if len(a.Enum) > 0 {
+			kind = append(kind, strings.Join(a.Enum, "|"))
+		}
+		if a.Optional {
+			kind = append(kind, "optional")
+		}
+		if len(kind) == 0 && a.Description == "" {
+			continue
+		}
+		line := a.Name
+		if len(kind) > 0 {
+			line += " (" + strings.Join(kind, ", ") + ")"
+		}
+		if a.Description != "" {
+			line += ": " + a.Description
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// describeParams renders the properties of an object schema, required fields first, as
+// "name (type, required): description" lines.
+func describeParams(schema map[string]interface{}) []string {
+	props, _ := schema["properties"].(map[string]interface{})
+	if len(props) == 0 {
+		return nil
+	}
+	required := map[string]bool{}
+	var order []string
+	if req, ok := schema["required"].([]string); ok {
+		for _, r := range req {
+			required[r] = true
+			order = append(order, r)
+		}
+	}
+	var rest []string
+	for name := range props {
+		if !required[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	out := make([]string, 0, len(order))
+	for _, name := range order {
+		p, _ := props[name].(map[string]interface{})
+		typ, _ := p["type"].(string)
+		switch {
+		case typ == "array" && p["minItems"] == 3:
+			typ = "[x,y,z]"
+		case typ == "array":
+			typ = "array of strings"
+		}
+		if enum, ok := p["enum"].([]string); ok {
+			typ = strings.Join(enum, "|")
+		}
+		if required[name] {
+			typ += ", required"
+		}
+		desc, _ := p["description"].(string)
+		out = append(out, fmt.Sprintf("%s (%s): %s", name, typ, desc))
+	}
+	return out
+}
+
+// promptRules are general composition rules that don't belong to a single action or command.
+const promptRules = "- Earlier messages are the conversation so far (your previous JSON replies and the results of those actions). Resolve references like \"it\", \"that\", \"there\" or \"undo what you just did\" against them; positions you used before are in your previous replies.\n" +
+	"- For \"spawn 100 cubes\", \"add 50 spheres\", \"30 cubes spread around\", use ONE add_objects action with count and pattern (grid, line, or random for spread around). Do not emit many separate add_object entries. For \"100 random primitives\", use type \"random\" and pattern \"random\".\n" +
+	"- For a single object at a specific position, use add_object. For \"gravity off\", \"no gravity\", \"static\", use \"physics\": false.\n" +
+	"- For \"create a city\", \"skyline\", \"buildings with random heights\", use ONE add_objects with type \"cube\", pattern \"grid\" or \"random\", count 20–80, spacing 5–8, scale_min [1,5,1], scale_max [4,25,4], physics false. For a colorful city add \"color_random\": true.\n" +
+	"- Available shapes are only: cube, sphere, cylinder, plane, plus the prefabs listed for add_prefab and the models listed for add_model. When a prefab fits (e.g. tree), place it with add_prefab; when an imported model fits (e.g. car.glb for a car), place it with add_model; for a forest or a street emit one add_prefab per placement, spread 4–5 apart, all in the same actions array. Otherwise compose primitives: e.g. a tree is a cylinder trunk (scale [0.3,2,0.3]) at [x,y,z] plus a sphere of foliage (scale [1.2,1.2,1.2]) at [x,y+1.5,z], physics false.\n" +
+	"- For lamps, lanterns, fires and other light sources use add_light (one per light; a point light for a lamp bulb at its height, e.g. y=3 on a post, warm color [1,0.8,0.5]); for \"put a street lamp at each corner\" place, at each corner, a thin cylinder post and an add_light at its top. Lights matter most with run_cmd [\"lighting\",\"night\"], which darkens everything else.\n" +
+	"- For surface looks (\"make it shiny metal\", \"glowing\", \"matte plastic\") use set_material on the selected objects, starting from a listed base material when one fits; color alone is for plain tints.\n" +
+	"- For slopes and angles (ramps, tilted roofs, leaning fences) give add_object a rotation in degrees [rx,ry,rz], e.g. a ramp is a cube with scale [4,0.3,2] and rotation [0,0,20]; ry turns an object to face another direction.\n" +
+	"- Positions for select, look and delete are left, right, top, bottom, closest, farthest; use the Current camera view in the prompt to pick them. Commands marked \"User must select first\" act on every selected object; use select all <type> or select name <glob> (e.g. building*) to select many at once.\n" +
+	"- Reply with only the JSON object."
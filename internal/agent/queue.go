@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Request is one natural-language line waiting for the agent, with the camera view and timeout captured
+// on the main thread when it was submitted.
+type Request struct {
+	Line        string
+	ViewContext string
+	Timeout     time.Duration // <= 0 = no timeout
+	Decision    string        // DecisionApply or DecisionReject: resolve the pending preview instead of sending Line
+}
+
+// Decisions on a pending preview (cmd apply, cmd reject), queued so they run after the request that made it.
+const (
+	DecisionApply  = "apply"
+	DecisionReject = "reject"
+)
+
+// Queue runs requests one at a time, in submission order, on a background goroutine. The running
+// request gets a context that Cancel cancels and that expires after Request.Timeout.
+type Queue struct {
+	mu      sync.Mutex
+	pending []Request
+	running bool
+	cancel  context.CancelFunc
+	wake    chan struct{}
+
+	run func(ctx context.Context, r Request)
+}
+
+// NewQueue starts the worker goroutine. run is called for each request (typically Agent.Run plus logging).
+func NewQueue(run func(ctx context.Context, r Request)) *Queue {
+	q := &Queue{run: run, wake: make(chan struct{}, 1)}
+	go q.loop()
+	return q
+}
+
+// Submit appends r to the queue. It returns the number of requests ahead of it (0 = starts now).
+func (q *Queue) Submit(r Request) int {
+	q.mu.Lock()
+	ahead := len(q.pending)
+	if q.running {
+		ahead++
+	}
+	q.pending = append(q.pending, r)
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return ahead
+}
+
+// Cancel cancels the running request. With all, pending requests are dropped too.
+// It returns whether a request was running and how many pending requests were dropped.
+func (q *Queue) Cancel(all bool) (cancelled bool, dropped int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if all {
+		dropped = len(q.pending)
+		q.pending = nil
+	}
+	if q.running && q.cancel != nil {
+		q.cancel()
+		cancelled = true
+	}
+	return cancelled, dropped
+}
+
+// Status reports whether a request is running and how many are waiting.
+func (q *Queue) Status() (running bool, pending int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.running, len(q.pending)
+}
+
+func (q *Queue) loop() {
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			r := q.pending[0]
+			q.pending = q.pending[1:]
+			var ctx context.Context
+			var cancel context.CancelFunc
+			if r.Timeout > 0 {
+				ctx, cancel = context.WithTimeout(context.Background(), r.Timeout)
+			} else {
+				ctx, cancel = context.WithCancel(context.Background())
+			}
+			q.running = true
+			q.cancel = cancel
+			q.mu.Unlock()
+
+			q.run(ctx, r)
+
+			q.mu.Lock()
+			cancel()
+			q.running = false
+			q.cancel = nil
+			q.mu.Unlock()
+		}
+	}
+}
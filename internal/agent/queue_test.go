@@ -0,0 +1,142 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// queueRecorder is a Queue run func that records each request's line and the error its context ended
+// with. Requests whose line is in block wait for their context (cancel or timeout) before returning.
+type queueRecorder struct {
+	mu      sync.Mutex
+	lines   []string
+	errs    map[string]error
+	started chan string
+	done    chan string
+	block   map[string]bool
+}
+
+func newQueueRecorder(block ...string) *queueRecorder {
+	r := &queueRecorder{
+		errs:    map[string]error{},
+		started: make(chan string, 16),
+		done:    make(chan string, 16),
+		block:   map[string]bool{},
+	}
+	for _, line := range block {
+		r.block[line] = true
+	}
+	return r
+}
+
+func (r *queueRecorder) run(ctx context.Context, req Request) {
+	r.started <- req.Line
+	if r.block[req.Line] {
+		<-ctx.Done()
+	}
+	r.mu.Lock()
+	r.lines = append(r.lines, req.Line)
+	r.errs[req.Line] = ctx.Err()
+	r.mu.Unlock()
+	r.done <- req.Line
+}
+
+// wait returns the next line sent on ch, failing the test after a second.
+func (r *queueRecorder) wait(t *testing.T, ch chan string) string {
+	t.Helper()
+	select {
+	case line := <-ch:
+		return line
+	case <-time.After(time.Second):
+		t.Fatal("queue did not run the next request")
+		return ""
+	}
+}
+
+func TestQueueRunsInSubmissionOrder(t *testing.T) {
+	rec := newQueueRecorder("first")
+	q := NewQueue(rec.run)
+	if ahead := q.Submit(Request{Line: "first"}); ahead != 0 {
+		t.Fatalf("first request: %d ahead, want 0", ahead)
+	}
+	rec.wait(t, rec.started)
+	for i, line := range []string{"second", "third", "fourth"} {
+		if ahead := q.Submit(Request{Line: line}); ahead != i+1 {
+			t.Fatalf("%s: %d ahead, want %d", line, ahead, i+1)
+		}
+	}
+	if running, pending := q.Status(); !running || pending != 3 {
+		t.Fatalf("status = running %v, %d pending; want running, 3 pending", running, pending)
+	}
+	q.Cancel(false)
+	for range 4 {
+		rec.wait(t, rec.done)
+	}
+	want := []string{"first", "second", "third", "fourth"}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for i := range want {
+		if rec.lines[i] != want[i] {
+			t.Fatalf("ran %v, want %v", rec.lines, want)
+		}
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	rec := newQueueRecorder("running", "dropped")
+	q := NewQueue(rec.run)
+	if cancelled, dropped := q.Cancel(false); cancelled || dropped != 0 {
+		t.Fatalf("cancel on idle queue = %v, %d; want false, 0", cancelled, dropped)
+	}
+	q.Submit(Request{Line: "running"})
+	rec.wait(t, rec.started)
+	q.Submit(Request{Line: "dropped"})
+	q.Submit(Request{Line: "dropped too"})
+
+	// Cancel(true) stops the running request and drops the waiting ones.
+	cancelled, dropped := q.Cancel(true)
+	if !cancelled || dropped != 2 {
+		t.Fatalf("cancel all = %v, %d; want true, 2", cancelled, dropped)
+	}
+	rec.wait(t, rec.done)
+
+	// The queue keeps working after a cancel.
+	q.Submit(Request{Line: "after"})
+	if line := rec.wait(t, rec.done); line != "after" {
+		t.Fatalf("ran %q after cancel, want %q", line, "after")
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.lines) != 2 {
+		t.Fatalf("ran %v, want only the running request and the one after the cancel", rec.lines)
+	}
+}
+
+func TestQueueReportsErrorPerRequest(t *testing.T) {
+	rec := newQueueRecorder("cancelled", "timed out")
+	q := NewQueue(rec.run)
+	q.Submit(Request{Line: "cancelled"})
+	rec.wait(t, rec.started)
+	q.Submit(Request{Line: "timed out", Timeout: 10 * time.Millisecond})
+	q.Submit(Request{Line: "ok", Timeout: time.Minute})
+	q.Cancel(false)
+	for range 3 {
+		rec.wait(t, rec.done)
+	}
+
+	// Each request sees only its own outcome: the cancel and the timeout do not carry over.
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for line, want := range map[string]error{
+		"cancelled": context.Canceled,
+		"timed out": context.DeadlineExceeded,
+		"ok":        nil,
+	} {
+		if err := rec.errs[line]; !errors.Is(err, want) {
+			t.Errorf("%s: context error %v, want %v", line, err, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package agent
+
+// JSON schema helpers for HandlerSpec.Parameters. Schemas are plain maps so they marshal directly
+// into the OpenAI/Ollama "parameters" field.
+
+func objectSchema(props map[string]interface{}, required ...string) map[string]interface{} {
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func stringSchema(desc string, enum ...string) map[string]interface{} {
+	s := map[string]interface{}{"type": "string", "description": desc}
+	if len(enum) > 0 {
+		s["enum"] = enum
+	}
+	return s
+}
+
+func numberSchema(desc string) map[string]interface{} {
+	return map[string]interface{}{"type": "number", "description": desc}
+}
+
+func integerSchema(desc string) map[string]interface{} {
+	return map[string]interface{}{"type": "integer", "description": desc}
+}
+
+func boolSchema(desc string) map[string]interface{} {
+	return map[string]interface{}{"type": "boolean", "description": desc}
+}
+
+// vec3Schema is a fixed-length [x,y,z] number array (positions, scales, RGB colors).
+func vec3Schema(desc string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"description": desc,
+		"items":       map[string]interface{}{"type": "number"},
+		"minItems":    3,
+		"maxItems":    3,
+	}
+}
+
+func stringArraySchema(desc string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "array",
+		"description": desc,
+		"items":       map[string]interface{}{"type": "string"},
+	}
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"game-engine/internal/llm"
+)
+
+// actionScanner extracts complete action objects from the "actions" array of a reply that is still
+// being streamed, so each action can be applied as soon as its closing brace arrives.
+type actionScanner struct {
+	buf     []byte
+	pos     int // next byte to examine
+	inArray bool
+	done    bool // closing ']' of the actions array seen
+	depth   int
+	inStr   bool
+	esc     bool
+	start   int // offset of the '{' of the object being read
+}
+
+// feed appends a text delta and returns the action objects completed by it. An object that is not
+// valid JSON is returned as nil so the caller can report it.
+func (s *actionScanner) feed(text string) []interface{} {
+	s.buf = append(s.buf, text...)
+	if !s.inArray {
+		i := bytes.Index(s.buf, []byte(`"actions"`))
+		if i < 0 {
+			return nil
+		}
+		j := bytes.IndexByte(s.buf[i:], '[')
+		if j < 0 {
+			return nil
+		}
+		s.pos = i + j + 1
+		s.inArray = true
+	}
+	var out []interface{}
+	for ; !s.done && s.pos < len(s.buf); s.pos++ {
+		c := s.buf[s.pos]
+		if s.inStr {
+			switch {
+			case s.esc:
+				s.esc = false
+			case c == '\\':
+				s.esc = true
+			case c == '"':
+				s.inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inStr = true
+		case '{':
+			if s.depth == 0 {
+				s.start = s.pos
+			}
+			s.depth++
+		case '}':
+			if s.depth == 0 {
+				continue
+			}
+			s.depth--
+			if s.depth == 0 {
+				var v map[string]interface{}
+				if err := json.Unmarshal(s.buf[s.start:s.pos+1], &v); err != nil {
+					out = append(out, nil)
+				} else {
+					out = append(out, v)
+				}
+			}
+		case ']':
+			if s.depth == 0 {
+				s.done = true
+			}
+		}
+	}
+	return out
+}
+
+// streamActions requests actions with llm.StreamClient and calls onAction for each one as soon as it
+// is complete: tool calls when the client reports them, text-mode actions when actionScanner closes
+// an object. Streamed text is shown via the status callback. If nothing could be extracted while
+// streaming, the full reply is parsed with parseActions as in the non-streaming path.
+func (a *Agent) streamActions(ctx context.Context, sc llm.StreamClient, model, systemPrompt string, messages []llm.Message, tools []llm.Tool, onAction func(interface{})) (reply string, parseErr, err error) {
+	var scan actionScanner
+	var text strings.Builder
+	var calls []interface{}
+	streamed := 0
+	a.setStatus("Thinking…")
+	defer a.setStatus("")
+	r, err := sc.ChatStream(ctx, model, systemPrompt, messages, tools, func(ev llm.StreamEvent) {
+		if ev.ToolCall != nil {
+			payload := toolPayload(*ev.ToolCall)
+			calls = append(calls, payload)
+			streamed++
+			a.setStatus(statusLine(text.String(), streamed))
+			onAction(payload)
+			return
+		}
+		text.WriteString(ev.Text)
+		for _, raw := range scan.feed(ev.Text) {
+			streamed++
+			onAction(raw)
+		}
+		a.setStatus(statusLine(text.String(), streamed))
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if len(calls) > 0 {
+		b, _ := json.Marshal(map[string]interface{}{"actions": calls})
+		return string(b), nil, nil
+	}
+	if streamed == 0 {
+		actions, parseErr := parseActions(r.Content)
+		if parseErr != nil {
+			return r.Content, parseErr, nil
+		}
+		for _, raw := range actions {
+			onAction(raw)
+		}
+	}
+	return r.Content, nil, nil
+}
+
+// statusLine is the transient "Thinking…" line: the tail of the streamed text and the count of
+// actions applied so far.
+func statusLine(text string, applied int) string {
+	const maxTail = 80
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > maxTail {
+		text = "…" + string(r[len(r)-maxTail:])
+	}
+	line := "Thinking…"
+	if applied > 0 {
+		line += fmt.Sprintf(" (%d action(s) so far)", applied)
+	}
+	if text != "" {
+		line += " " + text
+	}
+	return line
+}
+
+// toolPayload converts a tool call into an action payload ("action" = tool name).
+func toolPayload(call llm.ToolCall) map[string]interface{} {
+	payload := call.Arguments
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	payload["action"] = call.Name
+	return payload
+}
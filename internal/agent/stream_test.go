@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestActionScanner(t *testing.T) {
+	clearScene := map[string]interface{}{"action": "clear_scene"}
+	for _, tc := range []struct {
+		name   string
+		chunks []string
+		want   [][]interface{} // actions completed by each chunk
+	}{
+		{
+			name:   "actions split across chunks",
+			chunks: []string{`Sure. {"act`, `ions": [{"action": "add_`, `object", "type": "cube"}, {"action"`, `: "clear_scene"}]}`},
+			want: [][]interface{}{nil, nil,
+				{map[string]interface{}{"action": "add_object", "type": "cube"}},
+				{clearScene},
+			},
+		},
+		{
+			name:   "escaped quotes and braces in strings",
+			chunks: []string{`{"actions":[{"action":"set_name","name":"say \"}{\" ]"},{"action":"set_name","name":"C:\\"}]}`},
+			want: [][]interface{}{{
+				map[string]interface{}{"action": "set_name", "name": `say "}{" ]`},
+				map[string]interface{}{"action": "set_name", "name": `C:\`},
+			}},
+		},
+		{
+			name:   "escape split across chunks",
+			chunks: []string{`{"actions":[{"action":"set_name","name":"a\`, `"}"}]}`},
+			want:   [][]interface{}{nil, {map[string]interface{}{"action": "set_name", "name": `a"}`}}},
+		},
+		{
+			name:   "nested objects",
+			chunks: []string{`{"actions":[{"action":"add_light","light":{"kind":"spot","color":[1,0,0]}}`, `]}`},
+			want: [][]interface{}{
+				{map[string]interface{}{"action": "add_light", "light": map[string]interface{}{"kind": "spot", "color": []interface{}{1.0, 0.0, 0.0}}}},
+				nil,
+			},
+		},
+		{
+			name:   "malformed object then a valid one",
+			chunks: []string{`{"actions":[{"action":"add_object",},`, `{"action":"clear_scene"}]}`},
+			want:   [][]interface{}{{nil}, {clearScene}},
+		},
+		{
+			name:   "objects after the array are ignored",
+			chunks: []string{`{"actions":[{"action":"clear_scene"}],"note":{"action":"add_object"}}`},
+			want:   [][]interface{}{{clearScene}},
+		},
+		{
+			name:   "no actions key",
+			chunks: []string{`{"reply":`, `{"text":"hi"}}`},
+			want:   [][]interface{}{nil, nil},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var s actionScanner
+			for i, chunk := range tc.chunks {
+				if got := s.feed(chunk); !reflect.DeepEqual(got, tc.want[i]) {
+					t.Errorf("feed(%q) = %v; want %v", chunk, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
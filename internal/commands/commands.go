@@ -3,6 +3,7 @@ package commands
 import (
 	"flag"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -10,15 +11,48 @@ const prefix = "cmd "
 
 // Command is a subcommand with its own flags and a Run function.
 // Flags are defined on FlagSet; Run is called after Parse and can read flag state.
+// Help describes the command to users and, when Help.LLM is true, to the LLM agent (run_cmd).
 type Command struct {
 	Name    string
 	FlagSet *flag.FlagSet
 	Run     func() error
+	Help    Help
+	Preview func() (Preview, error) // optional dry run; see SetPreview
+}
+
+// Help is the self-description of a command. The agent's system prompt is generated from it,
+// so adding a command with LLM true teaches the model about it.
+type Help struct {
+	Description string     // one line; may include phrasing hints (e.g. "Use for \"make it red\"")
+	Usage       string     // arguments after the command name, e.g. "<type> <x> <y> <z> [sx sy sz]"
+	Examples    [][]string // full args lists including the name, e.g. {"spawn", "cube", "0", "0", "0"}
+	Args        []Arg      // positional arguments and flags; listed under the command in the system prompt
+	LLM         bool       // visible to (and runnable by) the LLM via run_cmd
+	Destructive bool       // when proposed by the LLM, runs only after the user confirms (cmd apply)
+}
+
+// Preview describes what a command would do, computed without running it (agent preview mode).
+type Preview struct {
+	Summary     string   // e.g. "delete 12 object(s)"
+	Deletes     []uint64 // IDs of the scene objects that would be removed (highlighted in the editor)
+	Destructive bool     // this invocation needs confirmation even if Help.Destructive is false (e.g. delete all)
+	// Apply performs exactly the previewed change (e.g. deletes the objects resolved now, not the ones
+	// "right" or "random" resolve to when the user confirms). Runs on the main thread; nil = run the command.
+	Apply func() error
+}
+
+// Arg describes one positional argument or flag of a command.
+type Arg struct {
+	Name        string // e.g. "type" or "--show"
+	Description string
+	Enum        []string // allowed values, if fixed
+	Optional    bool
 }
 
 // Registry holds subcommands by name. Add commands with Register; run with Execute.
 type Registry struct {
-	cmds map[string]*Command
+	cmds    map[string]*Command
+	wrapper func(args []string, run func() error) error
 }
 
 // NewRegistry returns an empty command registry.
@@ -27,9 +61,64 @@ func NewRegistry() *Registry {
 }
 
 // Register adds a subcommand. name is the first token after "cmd" (e.g. "grid").
-// fs is that command's FlagSet; run is called after fs.Parse(args[1:]) succeeds.
-func (r *Registry) Register(name string, fs *flag.FlagSet, run func() error) {
-	r.cmds[name] = &Command{Name: name, FlagSet: fs, Run: run}
+// fs is that command's FlagSet; help describes it; run is called after fs.Parse(args[1:]) succeeds.
+func (r *Registry) Register(name string, fs *flag.FlagSet, help Help, run func() error) {
+	r.cmds[name] = &Command{Name: name, FlagSet: fs, Run: run, Help: help}
+}
+
+// SetWrapper sets a function that every Execute runs the command through (e.g. to record it as one undo
+// step); it must call run. nil removes it.
+func (r *Registry) SetWrapper(wrap func(args []string, run func() error) error) {
+	r.wrapper = wrap
+}
+
+// SetPreview attaches a dry-run function to a registered command. Like run, it is called after the
+// command's FlagSet has parsed the arguments. Unknown names are ignored.
+func (r *Registry) SetPreview(name string, preview func() (Preview, error)) {
+	if c, ok := r.cmds[name]; ok {
+		c.Preview = preview
+	}
+}
+
+// PreviewArgs parses args like Execute and returns what the command would do without running it.
+// Commands without a preview function are described by their arguments. Destructive is set when either
+// the command's Help or its preview says so.
+func (r *Registry) PreviewArgs(args []string) (Preview, error) {
+	if len(args) == 0 {
+		return Preview{}, fmt.Errorf("missing subcommand")
+	}
+	cmd, ok := r.cmds[args[0]]
+	if !ok {
+		return Preview{}, fmt.Errorf("unknown command: %s", args[0])
+	}
+	if err := cmd.FlagSet.Parse(args[1:]); err != nil {
+		return Preview{}, err
+	}
+	p := Preview{Summary: "cmd " + strings.Join(args, " ")}
+	if cmd.Preview != nil {
+		var err error
+		if p, err = cmd.Preview(); err != nil {
+			return Preview{}, err
+		}
+	}
+	p.Destructive = p.Destructive || cmd.Help.Destructive
+	return p, nil
+}
+
+// Lookup returns the command registered under name.
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	c, ok := r.cmds[name]
+	return c, ok
+}
+
+// Commands returns all registered commands sorted by name.
+func (r *Registry) Commands() []*Command {
+	out := make([]*Command, 0, len(r.cmds))
+	for _, c := range r.cmds {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
 }
 
 // Parse interprets line as a terminal line. If line starts with "cmd " (case-sensitive),
@@ -59,5 +148,8 @@ func (r *Registry) Execute(args []string) error {
 	if err := cmd.FlagSet.Parse(args[1:]); err != nil {
 		return err
 	}
+	if r.wrapper != nil {
+		return r.wrapper(args, cmd.Run)
+	}
 	return cmd.Run()
 }
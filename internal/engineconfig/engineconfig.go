@@ -12,15 +12,36 @@ const EngineConfigPath = "config/engine.json"
 // EnginePrefs holds engine-only preferences (debug overlays, grid, AI model, font, etc.). Persisted across runs.
 // In-game save data is separate and handled elsewhere.
 type EnginePrefs struct {
-	ShowFPS      bool   `json:"show_fps"`
-	ShowMemAlloc bool   `json:"show_memalloc"`
-	GridVisible  bool   `json:"grid_visible"`
-	AIProvider   string `json:"ai_provider,omitempty"` // "ollama", "openai", "groq", or "" (auto-detect from env)
-	AIModel      string `json:"ai_model,omitempty"`
-	Font         string `json:"font,omitempty"` // path under assets/fonts/ (e.g. Roboto/static/Roboto-Regular.ttf)
+	ShowFPS       bool           `json:"show_fps"`
+	ShowMemAlloc  bool           `json:"show_memalloc"`
+	GridVisible   bool           `json:"grid_visible"`
+	AIProvider    string         `json:"ai_provider,omitempty"` // name registered in llm (e.g. "ollama", "anthropic"), or "" (auto-detect from env)
+	AIModel       string         `json:"ai_model,omitempty"`
+	Font          string         `json:"font,omitempty"`             // path under assets/fonts/ (e.g. Roboto/static/Roboto-Regular.ttf)
+	AgentRetries  int            `json:"agent_retries"`              // rounds the agent may use to correct failed actions; 0 = off
+	AITimeouts    map[string]int `json:"ai_timeouts,omitempty"`      // request timeout in seconds per provider; 0 = none
+	AIPreview     string         `json:"ai_preview,omitempty"`       // when LLM actions wait for cmd apply: off, destructive (default), all
+	AIEndpoints   []AIEndpoint   `json:"ai_endpoints,omitempty"`     // custom OpenAI-compatible providers (cmd provider <name>)
+	UndoDepth     int            `json:"undo_depth,omitempty"`       // undo steps kept (cmd history --depth); 0 = scene default
+	Scene         string         `json:"scene,omitempty"`            // scene opened at startup (cmd load/save); "" = default
+	AutosaveSecs  int            `json:"autosave_seconds,omitempty"` // seconds between autosave snapshots of a changed scene; 0 = 120, < 0 = off
+	AutosaveKeep  int            `json:"autosave_keep,omitempty"`    // autosave snapshots kept in assets/scenes/backups; 0 = 10
+	Shadows       string         `json:"shadows,omitempty"`          // directional light shadows: on (default) or off
+	ShadowQuality int            `json:"shadow_quality,omitempty"`   // shadow map quality 1-4 (cmd shadows quality); 0 = 2
 }
 
-// Default returns default engine preferences (debug overlays off, grid on, Roboto font).
+// AIEndpoint is a named OpenAI-compatible server (llama.cpp, vLLM, LM Studio, a corporate gateway).
+// The API key is never stored here; KeyEnv names the .env variable that holds it.
+type AIEndpoint struct {
+	Name         string            `json:"name"`
+	BaseURL      string            `json:"base_url"`              // chat completions URL or API root (e.g. http://localhost:8080/v1)
+	KeyEnv       string            `json:"api_key_env,omitempty"` // "" = no key
+	Auth         string            `json:"auth,omitempty"`        // bearer (default), basic, x-api-key, none
+	Headers      map[string]string `json:"headers,omitempty"`     // extra request headers
+	DefaultModel string            `json:"default_model,omitempty"`
+}
+
+// Default returns default engine preferences (debug overlays off, grid on, Roboto font, 2 agent retries).
 func Default() EnginePrefs {
 	return EnginePrefs{
 		ShowFPS:      false,
@@ -28,17 +49,18 @@ func Default() EnginePrefs {
 		GridVisible:  true,
 		AIModel:      "gpt-4o-mini",
 		Font:         "Roboto/static/Roboto-Regular.ttf",
+		AgentRetries: 2,
 	}
 }
 
-// Load reads engine preferences from config/engine.json. If the file is missing or invalid,
-// returns Default() and does not create a file.
+// Load reads engine preferences from config/engine.json over Default(), so keys missing from an older file
+// keep their defaults. If the file is missing or invalid, returns Default() and does not create a file.
 func Load() (EnginePrefs, error) {
 	data, err := os.ReadFile(EngineConfigPath)
 	if err != nil {
 		return Default(), nil
 	}
-	var p EnginePrefs
+	p := Default()
 	if err := json.Unmarshal(data, &p); err != nil {
 		return Default(), nil
 	}
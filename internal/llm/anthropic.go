@@ -0,0 +1,285 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// AnthropicBaseURL is the Anthropic Messages API endpoint.
+const AnthropicBaseURL = "https://api.anthropic.com/v1/messages"
+
+// anthropicVersion is sent as the anthropic-version header.
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens caps each reply; the API requires max_tokens. Large add_objects batches fit easily.
+const anthropicMaxTokens = 4096
+
+// Anthropic implements Client, ToolClient and StreamClient for the Anthropic Messages API. Unlike
+// OpenAI-compatible APIs the system prompt is a top-level field, the key goes in x-api-key, and replies
+// are lists of content blocks (text and tool_use).
+type Anthropic struct {
+	BaseURL string
+	APIKey  string
+	client  *http.Client
+}
+
+// NewAnthropic returns a client for the Messages API at baseURL ("" = AnthropicBaseURL).
+func NewAnthropic(baseURL, apiKey string) *Anthropic {
+	if baseURL == "" {
+		baseURL = AnthropicBaseURL
+	}
+	return &Anthropic{BaseURL: baseURL, APIKey: apiKey, client: http.DefaultClient}
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+	Stream    bool               `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is one content block: {"type":"text","text":...} or {"type":"tool_use","id","name","input"}.
+type anthropicBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	Content []anthropicBlock `json:"content"`
+}
+
+// Complete sends system and user messages and returns the assistant reply.
+func (c *Anthropic) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return c.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+// Chat sends the system prompt and the conversation and returns the text of the reply.
+func (c *Anthropic) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	reply, err := c.ChatWithTools(ctx, model, systemPrompt, messages, nil)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// ChatWithTools sends the conversation with tools and returns the joined text blocks and the tool_use
+// blocks as tool calls. A 400 response mentioning tools is reported as ErrToolsUnsupported.
+func (c *Anthropic) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	resp, err := c.post(ctx, c.request(model, systemPrompt, messages, tools, false))
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Reply{}, fmt.Errorf("anthropic: %w", err)
+	}
+	var reply Reply
+	var text strings.Builder
+	for _, b := range out.Content {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			call, err := anthropicToolCall(b.ID, b.Name, b.Input)
+			if err != nil {
+				return Reply{}, err
+			}
+			reply.ToolCalls = append(reply.ToolCalls, call)
+		}
+	}
+	reply.Content = text.String()
+	return reply, nil
+}
+
+// anthropicStreamEvent is the data of one SSE event (message_start, content_block_start/delta/stop,
+// message_delta, message_stop, ping, error).
+type anthropicStreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	ContentBlock anthropicBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ChatStream is ChatWithTools with "stream": true. Text deltas are forwarded as they arrive; a tool_use
+// block is reported once its content_block_stop arrives and its input JSON is complete.
+func (c *Anthropic) ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error) {
+	resp, err := c.post(ctx, c.request(model, systemPrompt, messages, tools, true))
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var reply Reply
+	var text strings.Builder
+	type toolBlock struct {
+		id, name string
+		input    strings.Builder
+	}
+	open := map[int]*toolBlock{}
+	var streamErr error
+	err = scanLines(resp.Body, func(line string) bool {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			return true // "event:" lines; the type is repeated in the data
+		}
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			streamErr = fmt.Errorf("anthropic: invalid stream event: %w", err)
+			return false
+		}
+		switch ev.Type {
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				open[ev.Index] = &toolBlock{id: ev.ContentBlock.ID, name: ev.ContentBlock.Name}
+			} else if ev.ContentBlock.Text != "" {
+				text.WriteString(ev.ContentBlock.Text)
+				onEvent(StreamEvent{Text: ev.ContentBlock.Text})
+			}
+		case "content_block_delta":
+			switch ev.Delta.Type {
+			case "text_delta":
+				text.WriteString(ev.Delta.Text)
+				onEvent(StreamEvent{Text: ev.Delta.Text})
+			case "input_json_delta":
+				if tb := open[ev.Index]; tb != nil {
+					tb.input.WriteString(ev.Delta.PartialJSON)
+				}
+			}
+		case "content_block_stop":
+			tb := open[ev.Index]
+			if tb == nil {
+				return true
+			}
+			delete(open, ev.Index)
+			call, err := anthropicToolCall(tb.id, tb.name, json.RawMessage(tb.input.String()))
+			if err != nil {
+				streamErr = err
+				return false
+			}
+			reply.ToolCalls = append(reply.ToolCalls, call)
+			onEvent(StreamEvent{ToolCall: &reply.ToolCalls[len(reply.ToolCalls)-1]})
+		case "message_stop":
+			return false
+		case "error":
+			streamErr = fmt.Errorf("anthropic: %s: %s", ev.Error.Type, ev.Error.Message)
+			return false
+		}
+		return true
+	})
+	if err == nil {
+		err = streamErr
+	}
+	if err != nil {
+		return Reply{}, err
+	}
+	reply.Content = text.String()
+	return reply, nil
+}
+
+// request builds a Messages API request. Empty turns are sent as a placeholder since the API rejects
+// empty text blocks.
+func (c *Anthropic) request(model, systemPrompt string, messages []Message, tools []Tool, stream bool) anthropicRequest {
+	req := anthropicRequest{
+		Model:     model,
+		System:    systemPrompt,
+		MaxTokens: anthropicMaxTokens,
+		Stream:    stream,
+	}
+	for _, m := range messages {
+		content := m.Content
+		if strings.TrimSpace(content) == "" {
+			content = "(empty)"
+		}
+		req.Messages = append(req.Messages, anthropicMessage{Role: m.Role, Content: []anthropicBlock{{Type: "text", Text: content}}})
+	}
+	for _, t := range tools {
+		schema := t.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		req.Tools = append(req.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	return req
+}
+
+// anthropicToolCall decodes a tool_use block's input into a ToolCall.
+func anthropicToolCall(id, name string, input json.RawMessage) (ToolCall, error) {
+	call := ToolCall{ID: id, Name: name, Arguments: map[string]interface{}{}}
+	if len(input) > 0 && string(input) != "null" {
+		if err := json.Unmarshal(input, &call.Arguments); err != nil {
+			return ToolCall{}, fmt.Errorf("anthropic: tool %s: invalid input: %w", name, err)
+		}
+	}
+	return call, nil
+}
+
+// post sends a Messages API request and returns the response if the status is 200 OK. The caller closes the body.
+func (c *Anthropic) post(ctx context.Context, reqBody anthropicRequest) (*http.Response, error) {
+	if c.APIKey == "" {
+		return nil, fmt.Errorf("anthropic: API key not set")
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	setAuth(req, AuthAPIKey, c.APIKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusBadRequest && len(reqBody.Tools) > 0 && isToolsRejection(b) {
+			return nil, fmt.Errorf("anthropic: %w", ErrToolsUnsupported)
+		}
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(b, &apiErr) == nil && apiErr.Error.Message != "" {
+			return nil, fmt.Errorf("anthropic: %s: %s", resp.Status, apiErr.Error.Message)
+		}
+		return nil, fmt.Errorf("anthropic: %s", resp.Status)
+	}
+	return resp, nil
+}
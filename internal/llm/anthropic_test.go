@@ -0,0 +1,166 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// anthropicServer answers every request with body and records the headers and decoded body of the last one.
+type anthropicServer struct {
+	header http.Header
+	req    anthropicRequest
+	raw    map[string]interface{}
+}
+
+func newAnthropicServer(t *testing.T, contentType, body string) (*anthropicServer, *Anthropic) {
+	t.Helper()
+	s := &anthropicServer{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.header = r.Header.Clone()
+		var b json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		json.Unmarshal(b, &s.req)
+		json.Unmarshal(b, &s.raw)
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return s, NewAnthropic(srv.URL, "sk-test")
+}
+
+var anthropicTestMessages = []Message{
+	{Role: RoleUser, Content: "add a cube"},
+	{Role: RoleAssistant, Content: `{"actions":[{"action":"add_object","type":"cube"}]}`},
+	{Role: RoleUser, Content: "Results of your previous actions: 1. add_object: ok\n\nmake it red"},
+}
+
+var anthropicTestTools = []Tool{{Name: "set_color", Description: "Color the selection.", Parameters: map[string]interface{}{"type": "object"}}, {Name: "clear_scene"}}
+
+// checkAnthropicRequest checks what every request sends: the key and version headers, the system
+// prompt as a top-level field, the conversation as text blocks and the tools with input schemas.
+func checkAnthropicRequest(t *testing.T, s *anthropicServer, stream bool) {
+	t.Helper()
+	if got := s.header.Get("x-api-key"); got != "sk-test" {
+		t.Errorf("x-api-key = %q", got)
+	}
+	if got := s.header.Get("anthropic-version"); got != anthropicVersion {
+		t.Errorf("anthropic-version = %q; want %q", got, anthropicVersion)
+	}
+	if got := s.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q; want none", got)
+	}
+	if s.raw["system"] != "sys" || s.req.Model != "claude-test" || s.req.MaxTokens != anthropicMaxTokens || s.req.Stream != stream {
+		t.Errorf("request = system %v, model %q, max_tokens %d, stream %v", s.raw["system"], s.req.Model, s.req.MaxTokens, s.req.Stream)
+	}
+	// Action results are replayed as text in the next user turn, so the history holds no tool_result blocks.
+	want := []anthropicMessage{
+		{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: anthropicTestMessages[0].Content}}},
+		{Role: RoleAssistant, Content: []anthropicBlock{{Type: "text", Text: anthropicTestMessages[1].Content}}},
+		{Role: RoleUser, Content: []anthropicBlock{{Type: "text", Text: anthropicTestMessages[2].Content}}},
+	}
+	if !reflect.DeepEqual(s.req.Messages, want) {
+		t.Errorf("messages = %+v\nwant %+v", s.req.Messages, want)
+	}
+	wantTools := []anthropicTool{
+		{Name: "set_color", Description: "Color the selection.", InputSchema: map[string]interface{}{"type": "object"}},
+		{Name: "clear_scene", InputSchema: map[string]interface{}{"type": "object"}},
+	}
+	if !reflect.DeepEqual(s.req.Tools, wantTools) {
+		t.Errorf("tools = %+v\nwant %+v", s.req.Tools, wantTools)
+	}
+}
+
+func TestAnthropicChatWithTools(t *testing.T) {
+	s, c := newAnthropicServer(t, "application/json", `{
+		"id": "msg_1", "type": "message", "role": "assistant", "stop_reason": "tool_use",
+		"content": [
+			{"type": "text", "text": "Coloring it."},
+			{"type": "tool_use", "id": "toolu_1", "name": "set_color", "input": {"color": [1, 0, 0]}},
+			{"type": "tool_use", "id": "toolu_2", "name": "clear_scene", "input": {}}
+		]}`)
+	r, err := c.ChatWithTools(context.Background(), "claude-test", "sys", anthropicTestMessages, anthropicTestTools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAnthropicRequest(t, s, false)
+	want := Reply{Content: "Coloring it.", ToolCalls: []ToolCall{
+		{ID: "toolu_1", Name: "set_color", Arguments: map[string]interface{}{"color": []interface{}{1.0, 0.0, 0.0}}},
+		{ID: "toolu_2", Name: "clear_scene", Arguments: map[string]interface{}{}},
+	}}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("reply = %+v\nwant %+v", r, want)
+	}
+}
+
+func TestAnthropicChatStream(t *testing.T) {
+	s, c := newAnthropicServer(t, "text/event-stream", `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[]}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Color"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ing it."}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"set_color","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"color\": [1,"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":" 0, 0]}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"after stop"}}
+`)
+	var events []StreamEvent
+	r, err := c.ChatStream(context.Background(), "claude-test", "sys", anthropicTestMessages, anthropicTestTools, func(ev StreamEvent) {
+		events = append(events, ev)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAnthropicRequest(t, s, true)
+	call := ToolCall{ID: "toolu_1", Name: "set_color", Arguments: map[string]interface{}{"color": []interface{}{1.0, 0.0, 0.0}}}
+	if want := []StreamEvent{textEvent("Color"), textEvent("ing it."), {ToolCall: &call}}; !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %s; want %s", describeEvents(events), describeEvents(want))
+	}
+	if want := (Reply{Content: "Coloring it.", ToolCalls: []ToolCall{call}}); !reflect.DeepEqual(r, want) {
+		t.Errorf("reply = %+v\nwant %+v", r, want)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	_, c := newAnthropicServer(t, "text/event-stream", `event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+`)
+	_, err := c.ChatStream(context.Background(), "claude-test", "sys", anthropicTestMessages, nil, func(StreamEvent) {})
+	if err == nil || err.Error() != "anthropic: overloaded_error: Overloaded" {
+		t.Errorf("err = %v; want the overloaded error", err)
+	}
+}
@@ -0,0 +1,291 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// Cassette is a recorded sequence of LLM requests and replies, stored as JSON. Recorder writes one while
+// talking to a live provider; Replayer serves it back so agent behavior can be tested offline.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request (model, system prompt, conversation, offered tool names) and its reply.
+// Error is set instead of Reply when the request failed; ErrToolsUnsupported is stored as errToolsUnsupported.
+type Interaction struct {
+	Model    string        `json:"model"`
+	System   string        `json:"system"`
+	Messages []Message     `json:"messages"`
+	Tools    []string      `json:"tools,omitempty"`
+	Reply    cassetteReply `json:"reply"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type cassetteReply struct {
+	Content   string             `json:"content,omitempty"`
+	ToolCalls []cassetteToolCall `json:"tool_calls,omitempty"`
+}
+
+type cassetteToolCall struct {
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// errToolsUnsupported is how ErrToolsUnsupported is written to a cassette.
+const errToolsUnsupported = "tools_unsupported"
+
+// LoadCassette reads a cassette written by Recorder (or by hand).
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, creating the directory if needed.
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func toCassetteReply(r Reply) cassetteReply {
+	out := cassetteReply{Content: r.Content}
+	for _, tc := range r.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, cassetteToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Arguments})
+	}
+	return out
+}
+
+func (r cassetteReply) reply() Reply {
+	out := Reply{Content: r.Content}
+	for _, tc := range r.ToolCalls {
+		args := make(map[string]interface{}, len(tc.Arguments))
+		for k, v := range tc.Arguments {
+			args[k] = v
+		}
+		out.ToolCalls = append(out.ToolCalls, ToolCall{ID: tc.ID, Name: tc.Name, Arguments: args})
+	}
+	return out
+}
+
+func toolNames(tools []Tool) []string {
+	if len(tools) == 0 {
+		return nil
+	}
+	names := make([]string, len(tools))
+	for i, t := range tools {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Recorder wraps a live Client and appends every request and reply to a cassette file, rewriting it
+// after each interaction so a crashed run still leaves a usable recording. It offers tool calling and
+// streaming whenever the wrapped client does, so the agent takes the same path as without it.
+type Recorder struct {
+	inner Client
+	path  string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder that writes to path (replacing any existing file on the first interaction).
+func NewRecorder(inner Client, path string) *Recorder {
+	return &Recorder{inner: inner, path: path}
+}
+
+// Complete records a single-turn Chat.
+func (r *Recorder) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return r.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+// Chat forwards to the wrapped client and records the reply text.
+func (r *Recorder) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	text, err := r.inner.Chat(ctx, model, systemPrompt, messages)
+	r.record(model, systemPrompt, messages, nil, Reply{Content: text}, err)
+	return text, err
+}
+
+// ChatWithTools forwards to the wrapped client's ChatWithTools, or reports ErrToolsUnsupported (without
+// recording) if it has none, so the agent falls back to Chat as it would without the recorder.
+func (r *Recorder) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	tc, ok := r.inner.(ToolClient)
+	if !ok {
+		if len(tools) > 0 {
+			return Reply{}, ErrToolsUnsupported
+		}
+		text, err := r.Chat(ctx, model, systemPrompt, messages)
+		return Reply{Content: text}, err
+	}
+	reply, err := tc.ChatWithTools(ctx, model, systemPrompt, messages, tools)
+	r.record(model, systemPrompt, messages, tools, reply, err)
+	return reply, err
+}
+
+// ChatStream forwards to the wrapped client's ChatStream and records the complete reply. Without a
+// streaming client it falls back to ChatWithTools and replays the reply as events.
+func (r *Recorder) ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error) {
+	sc, ok := r.inner.(StreamClient)
+	if !ok {
+		reply, err := r.ChatWithTools(ctx, model, systemPrompt, messages, tools)
+		if err != nil {
+			return Reply{}, err
+		}
+		emitReply(reply, onEvent)
+		return reply, nil
+	}
+	reply, err := sc.ChatStream(ctx, model, systemPrompt, messages, tools, onEvent)
+	r.record(model, systemPrompt, messages, tools, reply, err)
+	return reply, err
+}
+
+// record appends one interaction and rewrites the cassette. Cancelled requests are not recorded.
+func (r *Recorder) record(model, systemPrompt string, messages []Message, tools []Tool, reply Reply, err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	in := Interaction{
+		Model:    model,
+		System:   systemPrompt,
+		Messages: append([]Message(nil), messages...),
+		Tools:    toolNames(tools),
+	}
+	switch {
+	case errors.Is(err, ErrToolsUnsupported):
+		in.Error = errToolsUnsupported
+	case err != nil:
+		in.Error = err.Error()
+	default:
+		in.Reply = toCassetteReply(reply)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	_ = r.cassette.Save(r.path)
+}
+
+// Replayer is a fake Client, ToolClient and StreamClient that serves a cassette's replies in order,
+// without network access. With Strict, each request must match the recorded model, system prompt,
+// conversation and tool names; otherwise only the order matters, so prompt wording can change without
+// re-recording.
+type Replayer struct {
+	Strict bool
+
+	mu   sync.Mutex
+	c    *Cassette
+	next int
+}
+
+// NewReplayer returns a Replayer that serves c from the first interaction.
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{c: c}
+}
+
+// Remaining returns how many recorded interactions have not been served yet.
+func (p *Replayer) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.c.Interactions) - p.next
+}
+
+// Complete serves the next interaction's text.
+func (p *Replayer) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return p.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+// Chat serves the next interaction's text.
+func (p *Replayer) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	reply, err := p.serve(ctx, model, systemPrompt, messages, nil)
+	return reply.Content, err
+}
+
+// ChatWithTools serves the next interaction's text and tool calls.
+func (p *Replayer) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	return p.serve(ctx, model, systemPrompt, messages, tools)
+}
+
+// ChatStream serves the next interaction, emitting its text as one delta followed by its tool calls.
+func (p *Replayer) ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error) {
+	reply, err := p.serve(ctx, model, systemPrompt, messages, tools)
+	if err != nil {
+		return Reply{}, err
+	}
+	emitReply(reply, onEvent)
+	return reply, nil
+}
+
+func (p *Replayer) serve(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	if err := ctx.Err(); err != nil {
+		return Reply{}, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next >= len(p.c.Interactions) {
+		return Reply{}, fmt.Errorf("replay: cassette exhausted after %d interaction(s)", len(p.c.Interactions))
+	}
+	in := p.c.Interactions[p.next]
+	if p.Strict {
+		if err := in.match(model, systemPrompt, messages, tools); err != nil {
+			return Reply{}, fmt.Errorf("replay: interaction %d: %w", p.next+1, err)
+		}
+	}
+	p.next++
+	switch in.Error {
+	case "":
+		return in.Reply.reply(), nil
+	case errToolsUnsupported:
+		return Reply{}, fmt.Errorf("replay: %w", ErrToolsUnsupported)
+	default:
+		return Reply{}, fmt.Errorf("replay: %s", in.Error)
+	}
+}
+
+// match reports the first difference between the recorded request and this one.
+func (in Interaction) match(model, systemPrompt string, messages []Message, tools []Tool) error {
+	switch {
+	case in.Model != model:
+		return fmt.Errorf("model %q, recorded %q", model, in.Model)
+	case in.System != systemPrompt:
+		return fmt.Errorf("system prompt differs from the recording")
+	case len(in.Messages) != len(messages):
+		return fmt.Errorf("%d message(s), recorded %d", len(messages), len(in.Messages))
+	case !reflect.DeepEqual(in.Tools, toolNames(tools)):
+		return fmt.Errorf("tools %v, recorded %v", toolNames(tools), in.Tools)
+	}
+	for i := range messages {
+		if messages[i] != in.Messages[i] {
+			return fmt.Errorf("message %d (%s) differs from the recording", i+1, messages[i].Role)
+		}
+	}
+	return nil
+}
+
+// emitReply reports a complete reply as stream events: the text, then each tool call.
+func emitReply(reply Reply, onEvent func(StreamEvent)) {
+	if reply.Content != "" {
+		onEvent(StreamEvent{Text: reply.Content})
+	}
+	for i := range reply.ToolCalls {
+		onEvent(StreamEvent{ToolCall: &reply.ToolCalls[i]})
+	}
+}
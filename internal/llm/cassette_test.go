@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scriptedClient is a live-client stand-in for Recorder: it answers ChatWithTools from a list of replies
+// and rejects tools for models in noTools.
+type scriptedClient struct {
+	replies []Reply
+	noTools map[string]bool
+}
+
+func (c *scriptedClient) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return c.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	r, err := c.ChatWithTools(ctx, model, systemPrompt, messages, nil)
+	return r.Content, err
+}
+
+func (c *scriptedClient) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	if len(tools) > 0 && c.noTools[model] {
+		return Reply{}, ErrToolsUnsupported
+	}
+	if len(c.replies) == 0 {
+		return Reply{}, errors.New("no more replies")
+	}
+	r := c.replies[0]
+	c.replies = c.replies[1:]
+	return r, nil
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	live := &scriptedClient{replies: []Reply{
+		{ToolCalls: []ToolCall{{ID: "1", Name: "add_object", Arguments: map[string]interface{}{"type": "cube", "position": []interface{}{0.0, 1.0, 0.0}}}}},
+		{Content: `{"actions":[]}`},
+	}}
+	rec := NewRecorder(live, path)
+	ctx := context.Background()
+	tools := []Tool{{Name: "add_object"}}
+	msgs := []Message{{Role: RoleUser, Content: "add a cube"}}
+
+	var streamed []ToolCall
+	if _, err := rec.ChatStream(ctx, "m", "sys", msgs, tools, func(ev StreamEvent) {
+		if ev.ToolCall != nil {
+			streamed = append(streamed, *ev.ToolCall)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0].Name != "add_object" {
+		t.Fatalf("recorder stream events = %+v", streamed)
+	}
+	if _, err := rec.Chat(ctx, "m", "sys", msgs); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 2 {
+		t.Fatalf("recorded %d interactions, want 2", len(c.Interactions))
+	}
+	if got := c.Interactions[0].Tools; len(got) != 1 || got[0] != "add_object" {
+		t.Errorf("recorded tools = %v", got)
+	}
+
+	rp := NewReplayer(c)
+	rp.Strict = true
+	r, err := rp.ChatWithTools(ctx, "m", "sys", msgs, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.ToolCalls) != 1 || r.ToolCalls[0].Arguments["type"] != "cube" {
+		t.Errorf("replayed tool calls = %+v", r.ToolCalls)
+	}
+	text, err := rp.Chat(ctx, "m", "sys", msgs)
+	if err != nil || text != `{"actions":[]}` {
+		t.Errorf("replayed text = %q, %v", text, err)
+	}
+	if _, err := rp.Chat(ctx, "m", "sys", msgs); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("after last interaction err = %v, want exhausted", err)
+	}
+}
+
+func TestReplayToolsUnsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := NewRecorder(&scriptedClient{noTools: map[string]bool{"small": true}}, path)
+	_, err := rec.ChatWithTools(context.Background(), "small", "sys", nil, []Tool{{Name: "run_cmd"}})
+	if !errors.Is(err, ErrToolsUnsupported) {
+		t.Fatalf("recorder err = %v", err)
+	}
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewReplayer(c).ChatStream(context.Background(), "small", "sys", nil, []Tool{{Name: "run_cmd"}}, func(StreamEvent) {})
+	if !errors.Is(err, ErrToolsUnsupported) {
+		t.Errorf("replay err = %v, want ErrToolsUnsupported", err)
+	}
+}
+
+func TestReplayStrictMismatch(t *testing.T) {
+	c := &Cassette{Interactions: []Interaction{{
+		Model:    "m",
+		System:   "sys",
+		Messages: []Message{{Role: RoleUser, Content: "add a cube"}},
+		Reply:    cassetteReply{Content: "ok"},
+	}}}
+	msgs := []Message{{Role: RoleUser, Content: "add a sphere"}}
+
+	strict := NewReplayer(c)
+	strict.Strict = true
+	if _, err := strict.Chat(context.Background(), "m", "sys", msgs); err == nil {
+		t.Error("strict replay accepted a different message")
+	}
+	if strict.Remaining() != 1 {
+		t.Errorf("a mismatch consumed the interaction")
+	}
+
+	loose := NewReplayer(c)
+	if text, err := loose.Chat(context.Background(), "other", "changed prompt", msgs); err != nil || text != "ok" {
+		t.Errorf("loose replay = %q, %v", text, err)
+	}
+}
@@ -2,8 +2,33 @@ package llm
 
 import "context"
 
+// Roles used in Message.Role.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation sent to Chat. The system prompt is passed separately.
+type Message struct {
+	Role    string `json:"role"` // RoleUser or RoleAssistant
+	Content string `json:"content"`
+}
+
 // Client sends a prompt to an LLM and returns the reply text.
-// Model is provider-specific (e.g. "gpt-4o-mini", "claude-3-haiku").
+// Model is provider-specific (e.g. "gpt-4o-mini", "claude-3-5-haiku-latest").
+// Complete is a single-turn shortcut; Chat replays a whole conversation (oldest message first).
 type Client interface {
 	Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error)
+	Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error)
+}
+
+// wireMessages converts the system prompt and conversation into the role/content list used by
+// OpenAI-compatible and Ollama chat endpoints (system message first).
+func wireMessages(systemPrompt string, messages []Message) []message {
+	out := make([]message, 0, len(messages)+1)
+	out = append(out, message{Role: "system", Content: systemPrompt})
+	for _, m := range messages {
+		out = append(out, message{Role: m.Role, Content: m.Content})
+	}
+	return out
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 )
@@ -32,57 +33,137 @@ func NewOllama(baseURL string) *Ollama {
 }
 
 type ollamaChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
-	Stream   bool      `json:"stream"`
+	Model    string     `json:"model"`
+	Messages []message  `json:"messages"`
+	Stream   bool       `json:"stream"`
+	Tools    []wireTool `json:"tools,omitempty"`
 }
 
 type ollamaChatResponse struct {
-	Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	} `json:"message"`
+	Message message `json:"message"`
+	Done    bool    `json:"done"`
+	Error   string  `json:"error,omitempty"`
 }
 
 // Complete sends system and user messages to Ollama and returns the assistant reply.
 func (c *Ollama) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
+	return c.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+// Chat sends the system prompt and the conversation to Ollama and returns the assistant reply.
+func (c *Ollama) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	reply, err := c.ChatWithTools(ctx, model, systemPrompt, messages, nil)
+	if err != nil {
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// ChatWithTools sends the conversation with the Ollama "tools" field and returns the reply text and
+// message.tool_calls. Models without tool support (HTTP 400 "does not support tools") yield ErrToolsUnsupported.
+func (c *Ollama) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	resp, err := c.post(ctx, model, systemPrompt, messages, tools, false)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Reply{}, fmt.Errorf("ollama: %w", err)
+	}
+	calls, err := decodeToolCalls(out.Message.ToolCalls)
+	if err != nil {
+		return Reply{}, fmt.Errorf("ollama: %w", err)
+	}
+	return Reply{Content: out.Message.Content, ToolCalls: calls}, nil
+}
+
+// ChatStream is ChatWithTools with "stream": true. Ollama sends one JSON object per line (NDJSON)
+// with a message delta, and "done": true on the last. Tool calls arrive whole in a single chunk.
+func (c *Ollama) ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error) {
+	resp, err := c.post(ctx, model, systemPrompt, messages, tools, true)
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var reply Reply
+	var text strings.Builder
+	var streamErr error
+	err = scanLines(resp.Body, func(line string) bool {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			streamErr = fmt.Errorf("ollama: invalid stream chunk: %w", err)
+			return false
+		}
+		if chunk.Error != "" {
+			streamErr = fmt.Errorf("ollama: %s", chunk.Error)
+			return false
+		}
+		if chunk.Message.Content != "" {
+			text.WriteString(chunk.Message.Content)
+			onEvent(StreamEvent{Text: chunk.Message.Content})
+		}
+		calls, err := decodeToolCalls(chunk.Message.ToolCalls)
+		if err != nil {
+			streamErr = fmt.Errorf("ollama: %w", err)
+			return false
+		}
+		for i := range calls {
+			reply.ToolCalls = append(reply.ToolCalls, calls[i])
+			onEvent(StreamEvent{ToolCall: &calls[i]})
+		}
+		return !chunk.Done
+	})
+	if err != nil {
+		return Reply{}, fmt.Errorf("ollama: %w", err)
+	}
+	if streamErr != nil {
+		return Reply{}, streamErr
+	}
+	reply.Content = text.String()
+	return reply, nil
+}
+
+// post sends an /api/chat request (empty model = qwen2.5-coder) and returns the response if the
+// status is 200 OK. The caller closes the body.
+func (c *Ollama) post(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, stream bool) (*http.Response, error) {
 	if model == "" {
 		model = "qwen2.5-coder"
 	}
 	reqBody := ollamaChatRequest{
-		Model:  model,
-		Stream: false,
-		Messages: []message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userMessage},
-		},
+		Model:    model,
+		Stream:   stream,
+		Messages: wireMessages(systemPrompt, messages),
+		Tools:    wireTools(tools),
 	}
 	body, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	url := c.baseURL + "/api/chat"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("ollama: %w", err)
+		return nil, fmt.Errorf("ollama: %w", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		if resp.StatusCode == http.StatusNotFound {
-			return "", fmt.Errorf("ollama: 404 — is Ollama running? (ollama serve). If yes, pull the model: ollama pull %s", model)
+			return nil, fmt.Errorf("ollama: 404 — is Ollama running? (ollama serve). If yes, pull the model: ollama pull %s", model)
 		}
-		return "", fmt.Errorf("ollama: %s", resp.Status)
-	}
-	var out ollamaChatResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("ollama: %w", err)
+		if resp.StatusCode == http.StatusBadRequest && len(tools) > 0 {
+			if b, _ := io.ReadAll(resp.Body); isToolsRejection(b) {
+				return nil, fmt.Errorf("ollama: %w", ErrToolsUnsupported)
+			}
+		}
+		return nil, fmt.Errorf("ollama: %s", resp.Status)
 	}
-	return out.Message.Content, nil
+	return resp, nil
 }
@@ -6,31 +6,74 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 )
 
-// AuthType controls how the API key is sent in the Authorization header.
+// AuthType controls how the API key is sent.
 type AuthType int
 
 const (
 	AuthBearer AuthType = iota // Authorization: Bearer <key>
 	AuthBasic                  // Authorization: Basic base64(<key>:)
+	AuthAPIKey                 // x-api-key: <key> (Anthropic)
+	AuthNone                   // no key sent (local servers)
 )
 
+// ParseAuthType maps a config value to an AuthType: "bearer" (or ""), "basic", "x-api-key", "none".
+func ParseAuthType(s string) (AuthType, error) {
+	switch strings.ToLower(s) {
+	case "", "bearer":
+		return AuthBearer, nil
+	case "basic":
+		return AuthBasic, nil
+	case "x-api-key", "api-key":
+		return AuthAPIKey, nil
+	case "none":
+		return AuthNone, nil
+	default:
+		return 0, fmt.Errorf("unknown auth %q (use bearer, basic, x-api-key, none)", s)
+	}
+}
+
+// setAuth adds the API key to req as auth requires.
+func setAuth(req *http.Request, auth AuthType, key string) {
+	switch auth {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+key)
+	case AuthBasic:
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(key+":")))
+	case AuthAPIKey:
+		req.Header.Set("x-api-key", key)
+	}
+}
+
 // Known provider base URLs.
 const (
-	OpenAIBaseURL  = "https://api.openai.com/v1/chat/completions"
-	GroqBaseURL    = "https://api.groq.com/openai/v1/chat/completions"
-	CursorBaseURL  = "https://api.cursor.com/v1/chat/completions"
+	OpenAIBaseURL = "https://api.openai.com/v1/chat/completions"
+	GroqBaseURL   = "https://api.groq.com/openai/v1/chat/completions"
+	CursorBaseURL = "https://api.cursor.com/v1/chat/completions"
 )
 
+// ChatCompletionsURL returns the chat completions URL for an OpenAI-compatible server given either that
+// URL or the API root (e.g. "http://localhost:8080/v1" → ".../v1/chat/completions").
+func ChatCompletionsURL(base string) string {
+	base = strings.TrimSuffix(base, "/")
+	if strings.HasSuffix(base, "/chat/completions") {
+		return base
+	}
+	return base + "/chat/completions"
+}
+
 // OpenAICompat implements Client for any OpenAI-compatible chat completions API.
 type OpenAICompat struct {
-	Name     string // provider name for error messages (e.g. "openai", "groq")
-	BaseURL  string
-	APIKey   string
-	Auth     AuthType
-	client   *http.Client
+	Name    string // provider name for error messages (e.g. "openai", "groq")
+	BaseURL string
+	APIKey  string
+	Auth    AuthType
+	Headers map[string]string // extra request headers (e.g. a gateway's routing or tenant header)
+	client  *http.Client
 }
 
 // NewOpenAICompat creates a client for an OpenAI-compatible API.
@@ -45,13 +88,16 @@ func NewOpenAICompat(name, baseURL, apiKey string, auth AuthType) *OpenAICompat
 }
 
 type openAIRequest struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
+	Model    string     `json:"model"`
+	Messages []message  `json:"messages"`
+	Tools    []wireTool `json:"tools,omitempty"`
+	Stream   bool       `json:"stream,omitempty"`
 }
 
 type message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string         `json:"role"`
+	Content   string         `json:"content"`
+	ToolCalls []wireToolCall `json:"tool_calls,omitempty"`
 }
 
 type openAIResponse struct {
@@ -60,48 +106,158 @@ type openAIResponse struct {
 	} `json:"choices"`
 }
 
+// Complete sends system and user messages and returns the assistant reply.
 func (c *OpenAICompat) Complete(ctx context.Context, model, systemPrompt, userMessage string) (string, error) {
-	if c.APIKey == "" {
-		return "", fmt.Errorf("%s: API key not set", c.Name)
+	return c.Chat(ctx, model, systemPrompt, []Message{{Role: RoleUser, Content: userMessage}})
+}
+
+// Chat sends the system prompt and the conversation and returns the assistant reply.
+func (c *OpenAICompat) Chat(ctx context.Context, model, systemPrompt string, messages []Message) (string, error) {
+	reply, err := c.ChatWithTools(ctx, model, systemPrompt, messages, nil)
+	if err != nil {
+		return "", err
 	}
-	reqBody := openAIRequest{
-		Model: model,
-		Messages: []message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userMessage},
-		},
+	return reply.Content, nil
+}
+
+// ChatWithTools sends the conversation with tools (OpenAI "tools" field) and returns the reply text and
+// any tool_calls. A 400 response mentioning tools is reported as ErrToolsUnsupported.
+func (c *OpenAICompat) ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error) {
+	resp, err := c.post(ctx, openAIRequest{
+		Model:    model,
+		Messages: wireMessages(systemPrompt, messages),
+		Tools:    wireTools(tools),
+	})
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var out openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Reply{}, err
+	}
+	if len(out.Choices) == 0 {
+		return Reply{}, fmt.Errorf("%s: no choices in response", c.Name)
+	}
+	msg := out.Choices[0].Message
+	calls, err := decodeToolCalls(msg.ToolCalls)
+	if err != nil {
+		return Reply{}, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	return Reply{Content: msg.Content, ToolCalls: calls}, nil
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string          `json:"content"`
+			ToolCalls []toolCallDelta `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// ChatStream is ChatWithTools with "stream": true. The server sends SSE "data: {chunk}" lines ending
+// with "data: [DONE]"; text deltas are forwarded as they arrive and tool calls once complete.
+func (c *OpenAICompat) ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error) {
+	resp, err := c.post(ctx, openAIRequest{
+		Model:    model,
+		Messages: wireMessages(systemPrompt, messages),
+		Tools:    wireTools(tools),
+		Stream:   true,
+	})
+	if err != nil {
+		return Reply{}, err
+	}
+	defer resp.Body.Close()
+
+	var reply Reply
+	var text strings.Builder
+	var acc toolCallAccumulator
+	emit := func(calls []ToolCall) {
+		for i := range calls {
+			reply.ToolCalls = append(reply.ToolCalls, calls[i])
+			onEvent(StreamEvent{ToolCall: &calls[i]})
+		}
+	}
+	var streamErr error
+	err = scanLines(resp.Body, func(line string) bool {
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			return true // SSE comments, event names
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return false
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			streamErr = fmt.Errorf("%s: invalid stream chunk: %w", c.Name, err)
+			return false
+		}
+		for _, ch := range chunk.Choices {
+			if ch.Delta.Content != "" {
+				text.WriteString(ch.Delta.Content)
+				onEvent(StreamEvent{Text: ch.Delta.Content})
+			}
+			for _, d := range ch.Delta.ToolCalls {
+				calls, err := acc.add(d)
+				if err != nil {
+					streamErr = fmt.Errorf("%s: %w", c.Name, err)
+					return false
+				}
+				emit(calls)
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = streamErr
+	}
+	if err != nil {
+		return Reply{}, err
+	}
+	calls, err := acc.flush()
+	if err != nil {
+		return Reply{}, fmt.Errorf("%s: %w", c.Name, err)
+	}
+	emit(calls)
+	reply.Content = text.String()
+	return reply, nil
+}
+
+// post sends a chat completions request and returns the response if the status is 200 OK.
+// The caller closes the body.
+func (c *OpenAICompat) post(ctx context.Context, reqBody openAIRequest) (*http.Response, error) {
+	if c.APIKey == "" && c.Auth != AuthNone {
+		return nil, fmt.Errorf("%s: API key not set", c.Name)
 	}
 	body, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL, bytes.NewReader(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	switch c.Auth {
-	case AuthBearer:
-		req.Header.Set("Authorization", "Bearer "+c.APIKey)
-	case AuthBasic:
-		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(c.APIKey+":")))
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
 	}
+	setAuth(req, c.Auth, c.APIKey)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("%s: %s", c.Name, resp.Status)
-	}
-	var out openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", err
-	}
-	if len(out.Choices) == 0 {
-		return "", fmt.Errorf("%s: no choices in response", c.Name)
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusBadRequest && len(reqBody.Tools) > 0 {
+			if b, _ := io.ReadAll(resp.Body); isToolsRejection(b) {
+				return nil, fmt.Errorf("%s: %w", c.Name, ErrToolsUnsupported)
+			}
+		}
+		return nil, fmt.Errorf("%s: %s", c.Name, resp.Status)
 	}
-	return out.Choices[0].Message.Content, nil
+	return resp, nil
 }
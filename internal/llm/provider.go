@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider describes one LLM backend: how to build its client and where its settings come from.
+// Providers register themselves with RegisterProvider; the engine lists, detects and builds them by name.
+type Provider struct {
+	Name         string
+	KeyEnv       string            // env var holding the API key; "" = no key needed (local server)
+	BaseURL      string            // default endpoint
+	BaseURLEnv   string            // optional env var overriding BaseURL (e.g. OLLAMA_BASE_URL)
+	Auth         AuthType          // how the key is sent
+	Headers      map[string]string // extra request headers (OpenAI-compatible providers)
+	DefaultModel string            // model used after switching to this provider
+	Timeout      time.Duration     // default request timeout; 0 = DefaultTimeout
+	New          func(e Endpoint) Client
+}
+
+// Endpoint is what a Provider's constructor receives, resolved from its defaults and env vars.
+type Endpoint struct {
+	Name    string
+	BaseURL string
+	APIKey  string
+	Auth    AuthType
+	Headers map[string]string
+}
+
+// DefaultTimeout is the request timeout for providers that do not set one.
+const DefaultTimeout = 60 * time.Second
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+	providerSeq []string // registration order; also the auto-detect order
+)
+
+// RegisterProvider adds p to the registry, replacing any provider with the same name. Providers
+// registered earlier are preferred by DetectProvider.
+func RegisterProvider(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, ok := providers[p.Name]; !ok {
+		providerSeq = append(providerSeq, p.Name)
+	}
+	providers[p.Name] = p
+}
+
+// LookupProvider returns the provider registered under name.
+func LookupProvider(name string) (Provider, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	return p, ok
+}
+
+// ProviderNames returns the registered provider names in registration order.
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	return append([]string(nil), providerSeq...)
+}
+
+// DetectProvider picks a provider from the environment: the first registered one whose API key is set,
+// else the first that needs no key (e.g. a local Ollama server). Returns "" if there is none.
+func DetectProvider() string {
+	names := ProviderNames()
+	for _, name := range names {
+		if p, _ := LookupProvider(name); p.KeyEnv != "" && os.Getenv(p.KeyEnv) != "" {
+			return name
+		}
+	}
+	for _, name := range names {
+		if p, _ := LookupProvider(name); p.KeyEnv == "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// NewClient builds the client of the named provider, reading its API key and base URL override from the environment.
+func NewClient(name string) (Client, error) {
+	p, ok := LookupProvider(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (use: %s)", name, strings.Join(ProviderNames(), ", "))
+	}
+	e := Endpoint{Name: p.Name, BaseURL: p.BaseURL, Auth: p.Auth, Headers: p.Headers}
+	if p.KeyEnv != "" {
+		e.APIKey = os.Getenv(p.KeyEnv)
+		if e.APIKey == "" {
+			return nil, fmt.Errorf("%s not set in .env", p.KeyEnv)
+		}
+	}
+	if p.BaseURLEnv != "" {
+		if u := os.Getenv(p.BaseURLEnv); u != "" {
+			e.BaseURL = u
+		}
+	}
+	return p.New(e), nil
+}
+
+// Built-in providers, in auto-detect order.
+func init() {
+	RegisterProvider(Provider{
+		Name:         "groq",
+		KeyEnv:       "GROQ_API_KEY",
+		BaseURL:      GroqBaseURL,
+		Auth:         AuthBearer,
+		DefaultModel: "llama-3.3-70b-versatile",
+		New:          newOpenAICompat,
+	})
+	RegisterProvider(Provider{
+		Name:         "openai",
+		KeyEnv:       "OPENAI_API_KEY",
+		BaseURL:      OpenAIBaseURL,
+		Auth:         AuthBearer,
+		DefaultModel: "gpt-4o-mini",
+		New:          newOpenAICompat,
+	})
+	RegisterProvider(Provider{
+		Name:         "anthropic",
+		KeyEnv:       "ANTHROPIC_API_KEY",
+		BaseURL:      AnthropicBaseURL,
+		BaseURLEnv:   "ANTHROPIC_BASE_URL",
+		Auth:         AuthAPIKey,
+		DefaultModel: "claude-3-5-haiku-latest",
+		New: func(e Endpoint) Client {
+			return NewAnthropic(e.BaseURL, e.APIKey)
+		},
+	})
+	RegisterProvider(Provider{
+		Name:         "ollama",
+		BaseURL:      DefaultOllamaBaseURL,
+		BaseURLEnv:   "OLLAMA_BASE_URL",
+		DefaultModel: "qwen3-coder:30b",
+		Timeout:      300 * time.Second, // large prompts can take minutes on a CPU
+		New: func(e Endpoint) Client {
+			return NewOllama(e.BaseURL)
+		},
+	})
+}
+
+// OpenAICompatProvider returns a provider for an OpenAI-compatible server (llama.cpp, vLLM, LM Studio, a
+// gateway). baseURL may be the chat completions URL or the API root; keyEnv "" sends no key.
+func OpenAICompatProvider(name, baseURL, keyEnv string, auth AuthType, headers map[string]string, defaultModel string) Provider {
+	if keyEnv == "" {
+		auth = AuthNone
+	}
+	return Provider{
+		Name:         name,
+		KeyEnv:       keyEnv,
+		BaseURL:      ChatCompletionsURL(baseURL),
+		Auth:         auth,
+		Headers:      headers,
+		DefaultModel: defaultModel,
+		New:          newOpenAICompat,
+	}
+}
+
+func newOpenAICompat(e Endpoint) Client {
+	c := NewOpenAICompat(e.Name, e.BaseURL, e.APIKey, e.Auth)
+	c.Headers = e.Headers
+	return c
+}
@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// keepProviders restores the provider registry when the test ends.
+func keepProviders(t *testing.T) {
+	providersMu.Lock()
+	saved, seq := maps.Clone(providers), slices.Clone(providerSeq)
+	providersMu.Unlock()
+	t.Cleanup(func() {
+		providersMu.Lock()
+		providers, providerSeq = saved, seq
+		providersMu.Unlock()
+	})
+}
+
+// setKeys clears the API key env vars of every registered provider, then sets the given ones.
+func setKeys(t *testing.T, keys ...string) {
+	for _, name := range ProviderNames() {
+		if p, _ := LookupProvider(name); p.KeyEnv != "" {
+			t.Setenv(p.KeyEnv, "")
+		}
+	}
+	for _, k := range keys {
+		t.Setenv(k, "key")
+	}
+}
+
+func TestDetectProvider(t *testing.T) {
+	for _, tc := range []struct {
+		keys []string
+		want string
+	}{
+		{nil, "ollama"},
+		{[]string{"ANTHROPIC_API_KEY"}, "anthropic"},
+		{[]string{"OPENAI_API_KEY", "ANTHROPIC_API_KEY"}, "openai"},
+		{[]string{"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "GROQ_API_KEY"}, "groq"},
+	} {
+		setKeys(t, tc.keys...)
+		if got := DetectProvider(); got != tc.want {
+			t.Errorf("DetectProvider() with %v = %q; want %q", tc.keys, got, tc.want)
+		}
+	}
+}
+
+func TestProviderDefaultModels(t *testing.T) {
+	for _, tc := range []struct{ name, model string }{
+		{"groq", "llama-3.3-70b-versatile"},
+		{"openai", "gpt-4o-mini"},
+		{"anthropic", "claude-3-5-haiku-latest"},
+		{"ollama", "qwen3-coder:30b"},
+	} {
+		if p, ok := LookupProvider(tc.name); !ok || p.DefaultModel != tc.model {
+			t.Errorf("LookupProvider(%q) = %q, %v; want default model %q", tc.name, p.DefaultModel, ok, tc.model)
+		}
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	keepProviders(t)
+	setKeys(t, "GROQ_API_KEY")
+
+	// Replacing a provider keeps its place in the detect order; a new one goes last.
+	RegisterProvider(OpenAICompatProvider("openai", "http://gateway.local/v1", "GATEWAY_KEY", AuthBearer, map[string]string{"X-Tenant": "games"}, "gw-model"))
+	RegisterProvider(OpenAICompatProvider("local", "http://localhost:8080", "", AuthBearer, nil, "llama"))
+	if names := ProviderNames(); !slices.Equal(names, []string{"groq", "openai", "anthropic", "ollama", "local"}) {
+		t.Errorf("ProviderNames() = %v", names)
+	}
+	p, _ := LookupProvider("openai")
+	if p.DefaultModel != "gw-model" || p.BaseURL != "http://gateway.local/v1/chat/completions" {
+		t.Errorf("replaced openai = %+v", p)
+	}
+	if p, _ := LookupProvider("local"); p.Auth != AuthNone {
+		t.Errorf("keyless provider auth = %v; want AuthNone", p.Auth)
+	}
+
+	// The replacement's key env var is the one detection and NewClient read.
+	t.Setenv("GROQ_API_KEY", "")
+	t.Setenv("OPENAI_API_KEY", "key")
+	if got := DetectProvider(); got != "ollama" {
+		t.Errorf("DetectProvider() with only OPENAI_API_KEY = %q; want ollama", got)
+	}
+	t.Setenv("GATEWAY_KEY", "gw")
+	if got := DetectProvider(); got != "openai" {
+		t.Errorf("DetectProvider() with GATEWAY_KEY = %q; want openai", got)
+	}
+	c, err := NewClient("openai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oc, ok := c.(*OpenAICompat); !ok || oc.APIKey != "gw" || oc.Headers["X-Tenant"] != "games" {
+		t.Errorf("NewClient(openai) = %+v", c)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewClient("anthropic"); err == nil {
+		t.Error("NewClient(anthropic) without a key succeeded")
+	}
+	t.Setenv("ANTHROPIC_API_KEY", "sk")
+	t.Setenv("ANTHROPIC_BASE_URL", "http://proxy.local/v1/messages")
+	if c, err := NewClient("anthropic"); err != nil || c.(*Anthropic).BaseURL != "http://proxy.local/v1/messages" {
+		t.Errorf("NewClient(anthropic) = %+v, %v; want the base URL from ANTHROPIC_BASE_URL", c, err)
+	}
+	if _, err := NewClient("nope"); err == nil {
+		t.Error("NewClient accepted an unknown provider")
+	}
+}
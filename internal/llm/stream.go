@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// StreamEvent is one increment of a streamed reply: a text delta or a completed tool call.
+type StreamEvent struct {
+	Text     string
+	ToolCall *ToolCall
+}
+
+// StreamClient is implemented by clients that can stream replies (SSE for OpenAI-compatible APIs,
+// NDJSON for Ollama). onEvent is called in order, on the calling goroutine, for every text delta and
+// every tool call once its arguments are complete. The returned Reply holds the full text and all
+// tool calls. tools may be nil; a rejected tools field is reported as ErrToolsUnsupported before any event.
+type StreamClient interface {
+	ChatStream(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool, onEvent func(StreamEvent)) (Reply, error)
+}
+
+// maxStreamLine bounds one SSE/NDJSON line (a chunk can carry a whole tool call).
+const maxStreamLine = 1 << 20
+
+// scanLines calls fn for each non-empty line of r until fn returns false or r ends.
+func scanLines(r io.Reader, fn func(line string) bool) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if !fn(line) {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// toolCallAccumulator joins OpenAI-style tool call deltas (id and name first, then argument fragments,
+// keyed by index). A call is complete when a delta for a later index arrives or the stream ends.
+type toolCallAccumulator struct {
+	calls   []wireToolCall
+	args    []string
+	indices []int
+	emitted int
+}
+
+type toolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// add merges one delta and returns the calls completed by it.
+func (a *toolCallAccumulator) add(d toolCallDelta) ([]ToolCall, error) {
+	n := len(a.indices)
+	if n == 0 || a.indices[n-1] != d.Index {
+		done, err := a.flush()
+		a.indices = append(a.indices, d.Index)
+		a.calls = append(a.calls, wireToolCall{ID: d.ID})
+		a.args = append(a.args, "")
+		n++
+		a.apply(n-1, d)
+		return done, err
+	}
+	a.apply(n-1, d)
+	return nil, nil
+}
+
+func (a *toolCallAccumulator) apply(i int, d toolCallDelta) {
+	if d.ID != "" {
+		a.calls[i].ID = d.ID
+	}
+	a.calls[i].Function.Name += d.Function.Name
+	a.args[i] += d.Function.Arguments
+}
+
+// flush returns every call not yet emitted.
+func (a *toolCallAccumulator) flush() ([]ToolCall, error) {
+	pending := make([]wireToolCall, 0, len(a.calls)-a.emitted)
+	for i := a.emitted; i < len(a.calls); i++ {
+		c := a.calls[i]
+		c.Function.Arguments = json.RawMessage(a.args[i])
+		pending = append(pending, c)
+	}
+	a.emitted = len(a.calls)
+	return decodeToolCalls(pending)
+}
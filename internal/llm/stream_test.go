@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// streamServer serves parts as one response body, flushing after each so lines can arrive split
+// across reads. It returns the server's URL.
+func streamServer(t *testing.T, parts []string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, p := range parts {
+			w.Write([]byte(p))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// streamCase is one streamed response and the events, reply text and tool calls it should produce.
+type streamCase struct {
+	name    string
+	parts   []string
+	events  []StreamEvent
+	content string
+	wantErr bool
+}
+
+func textEvent(s string) StreamEvent { return StreamEvent{Text: s} }
+
+func toolEvent(id, name string, args map[string]interface{}) StreamEvent {
+	return StreamEvent{ToolCall: &ToolCall{ID: id, Name: name, Arguments: args}}
+}
+
+func runStreamCases(t *testing.T, cases []streamCase, newClient func(url string) StreamClient) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []StreamEvent
+			r, err := newClient(streamServer(t, tc.parts)).ChatStream(context.Background(), "m", "sys", []Message{{Role: RoleUser, Content: "hi"}}, nil, func(ev StreamEvent) {
+				events = append(events, ev)
+			})
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ChatStream = %+v; want an error", r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(events, tc.events) {
+				t.Errorf("events = %s; want %s", describeEvents(events), describeEvents(tc.events))
+			}
+			if r.Content != tc.content {
+				t.Errorf("content = %q; want %q", r.Content, tc.content)
+			}
+			var calls []ToolCall
+			for _, ev := range tc.events {
+				if ev.ToolCall != nil {
+					calls = append(calls, *ev.ToolCall)
+				}
+			}
+			if !reflect.DeepEqual(r.ToolCalls, calls) {
+				t.Errorf("tool calls = %+v; want %+v", r.ToolCalls, calls)
+			}
+		})
+	}
+}
+
+func describeEvents(events []StreamEvent) []string {
+	out := make([]string, len(events))
+	for i, ev := range events {
+		if ev.ToolCall != nil {
+			out[i] = "tool " + ev.ToolCall.Name
+		} else {
+			out[i] = "text " + ev.Text
+		}
+	}
+	return out
+}
+
+func TestOpenAICompatStream(t *testing.T) {
+	runStreamCases(t, []streamCase{
+		{
+			name: "text deltas end at [DONE]",
+			parts: []string{
+				": keep-alive\n\nevent: message\ndata: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n",
+				"data:{\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n",
+				"data: [DONE]\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"after done\"}}]}\n\n",
+			},
+			events:  []StreamEvent{textEvent("Hel"), textEvent("lo")},
+			content: "Hello",
+		},
+		{
+			name: "line split across reads",
+			parts: []string{
+				"data: {\"choices\":[{\"delta\":{\"con",
+				"tent\":\"{\\\"actions\\\": []}\"}}]}\n",
+				"\ndata: [DONE]\n",
+			},
+			events:  []StreamEvent{textEvent(`{"actions": []}`)},
+			content: `{"actions": []}`,
+		},
+		{
+			name: "tool call arguments in fragments",
+			parts: []string{
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"a\",\"function\":{\"name\":\"add_object\",\"arguments\":\"\"}}]}}]}\n\n",
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"{\\\"type\\\":\\\"cu\"}}]}}]}\n\n",
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"be\\\"}\"}}]}}]}\n\n",
+				"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":1,\"id\":\"b\",\"function\":{\"name\":\"clear_scene\",\"arguments\":\"{}\"}}]}}]}\n\n",
+				"data: [DONE]\n\n",
+			},
+			events: []StreamEvent{
+				toolEvent("a", "add_object", map[string]interface{}{"type": "cube"}),
+				toolEvent("b", "clear_scene", map[string]interface{}{}),
+			},
+		},
+		{
+			name:    "invalid chunk",
+			parts:   []string{"data: {\"choices\":\n\n", "data: [DONE]\n\n"},
+			wantErr: true,
+		},
+	}, func(url string) StreamClient { return NewOpenAICompat("test", url, "", AuthNone) })
+}
+
+func TestOllamaStream(t *testing.T) {
+	runStreamCases(t, []streamCase{
+		{
+			name: "text lines end at done",
+			parts: []string{
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"Hel\"},\"done\":false}\n",
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"lo\"},\"done\":false}\n\n",
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"\"},\"done\":true}\n",
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"after done\"},\"done\":false}\n",
+			},
+			events:  []StreamEvent{textEvent("Hel"), textEvent("lo")},
+			content: "Hello",
+		},
+		{
+			name: "line split across reads",
+			parts: []string{
+				"{\"message\":{\"content\":\"a {\\\"b\\\"",
+				": 1}\"},\"done\":false}\n{\"done\":true}\n",
+			},
+			events:  []StreamEvent{textEvent(`a {"b": 1}`)},
+			content: `a {"b": 1}`,
+		},
+		{
+			name: "tool calls arrive whole",
+			parts: []string{
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"\",\"tool_calls\":[{\"function\":{\"name\":\"add_object\",\"arguments\":{\"type\":\"cube\"}}}]},\"done\":false}\n",
+				"{\"message\":{\"role\":\"assistant\",\"content\":\"\"},\"done\":true}\n",
+			},
+			events: []StreamEvent{toolEvent("", "add_object", map[string]interface{}{"type": "cube"})},
+		},
+		{
+			name:    "error line",
+			parts:   []string{"{\"error\":\"model not found\"}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid line",
+			parts:   []string{"{\"message\":\n"},
+			wantErr: true,
+		},
+	}, func(url string) StreamClient { return NewOllama(url) })
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrToolsUnsupported is returned (wrapped) by ChatWithTools when the server or model rejects the
+// tools field. Callers should fall back to Chat and parse the reply text.
+var ErrToolsUnsupported = errors.New("tool calling not supported by this model")
+
+// Tool describes one function the model may call. Parameters is a JSON schema object.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]interface{}
+}
+
+// ToolCall is one function call emitted by the model, with its arguments decoded from JSON.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Reply is the assistant's answer to ChatWithTools: free text, tool calls, or both.
+type Reply struct {
+	Content   string
+	ToolCalls []ToolCall
+}
+
+// ToolClient is implemented by clients that support structured tool calling
+// (OpenAI-style tools/tool_calls, Ollama /api/chat tools).
+type ToolClient interface {
+	ChatWithTools(ctx context.Context, model, systemPrompt string, messages []Message, tools []Tool) (Reply, error)
+}
+
+type wireTool struct {
+	Type     string       `json:"type"`
+	Function wireFunction `json:"function"`
+}
+
+type wireFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type wireToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// wireTools converts tools to the OpenAI/Ollama request shape. nil or empty returns nil so the field is omitted.
+func wireTools(tools []Tool) []wireTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]wireTool, len(tools))
+	for i, t := range tools {
+		params := t.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object"}
+		}
+		out[i] = wireTool{Type: "function", Function: wireFunction{Name: t.Name, Description: t.Description, Parameters: params}}
+	}
+	return out
+}
+
+// decodeToolCalls converts wire tool calls to ToolCall. Arguments may be a JSON object (Ollama)
+// or a string containing a JSON object (OpenAI).
+func decodeToolCalls(calls []wireToolCall) ([]ToolCall, error) {
+	out := make([]ToolCall, 0, len(calls))
+	for _, c := range calls {
+		raw := c.Function.Arguments
+		var s string
+		if len(raw) > 0 && raw[0] == '"' {
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return nil, fmt.Errorf("tool %s: %w", c.Function.Name, err)
+			}
+			raw = json.RawMessage(s)
+		}
+		args := map[string]interface{}{}
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, fmt.Errorf("tool %s: invalid arguments: %w", c.Function.Name, err)
+			}
+		}
+		out = append(out, ToolCall{ID: c.ID, Name: c.Function.Name, Arguments: args})
+	}
+	return out, nil
+}
+
+// toolsRejections are what servers say in a 400 body when the model or server cannot use tools at all
+// (Ollama, llama.cpp without --jinja, vLLM without auto tool choice, OpenAI and compatible gateways). Other
+// 400s that mention tools (a bad tool_choice or schema) are request errors, not a reason to stop using tools.
+var toolsRejections = []string{
+	"does not support tools",
+	"does not support tool",
+	"tools are not supported",
+	"tools is not supported",
+	"tool use is not supported",
+	"tool calling is not supported",
+	"tool calls are not supported",
+	"function calling is not supported",
+	"tools param requires --jinja",
+	"tool choice requires --enable-auto-tool-choice",
+}
+
+// isToolsRejection reports whether an HTTP 400 body says the model or server cannot use tools: one of
+// toolsRejections, or an OpenAI-style error of type or code "unsupported_parameter" for the tools parameter.
+func isToolsRejection(body []byte) bool {
+	text := strings.ToLower(string(body))
+	for _, msg := range toolsRejections {
+		if strings.Contains(text, msg) {
+			return true
+		}
+	}
+	var apiErr struct {
+		Error struct {
+			Type  string `json:"type"`
+			Code  any    `json:"code"`
+			Param string `json:"param"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) != nil || apiErr.Error.Param != "tools" {
+		return false
+	}
+	return apiErr.Error.Type == "unsupported_parameter" || apiErr.Error.Code == "unsupported_parameter"
+}
@@ -0,0 +1,23 @@
+package llm
+
+import "testing"
+
+func TestIsToolsRejection(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want bool
+	}{
+		{`{"error":"registry.ollama.ai/library/gemma:2b does not support tools"}`, true},
+		{`{"error":{"message":"tools param requires --jinja flag","type":"invalid_request_error"}}`, true},
+		{`{"error":{"message":"\"auto\" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set"}}`, true},
+		{`{"error":{"message":"Unsupported parameter","type":"invalid_request_error","param":"tools","code":"unsupported_parameter"}}`, true},
+		// Request errors that mention tools but do not mean tools are unavailable.
+		{`{"error":{"message":"Invalid value for 'tool_choice': must be one of none, auto, required","param":"tool_choice"}}`, false},
+		{`{"error":{"message":"Invalid schema for function 'add_object': 'position' is not valid under any of the given schemas","param":"tools[0].function.parameters"}}`, false},
+		{`{"error":{"message":"messages.2: tool_use ids were found without tool_result blocks"}}`, false},
+	} {
+		if got := isToolsRejection([]byte(tc.body)); got != tc.want {
+			t.Errorf("isToolsRejection(%s) = %v; want %v", tc.body, got, tc.want)
+		}
+	}
+}
@@ -20,9 +20,10 @@ const (
 // Logger stores terminal lines in memory (capped) and writes terminal logs to terminal.txt.
 // Engine/raylib output is appended to engine_log.txt and persists across game runs.
 type Logger struct {
-	mu         sync.Mutex
-	lines      []string
-	engineLog  *os.File
+	mu        sync.Mutex
+	lines     []string
+	status    string // transient line shown after lines (e.g. streamed LLM text); not written to file
+	engineLog *os.File
 }
 
 // New returns a new Logger and ensures the logs directory exists. Engine log is not cleared; output persists.
@@ -118,6 +119,21 @@ func (l *Logger) Error(msg string) {
 	l.LogEngine(5, msg) // 5 = ERROR in raylib
 }
 
+// SetStatus sets a transient status line drawn after the log lines (e.g. "Thinking…" with streamed LLM
+// text). It replaces the previous status and is not written to terminal.txt. "" clears it.
+func (l *Logger) SetStatus(line string) {
+	l.mu.Lock()
+	l.status = line
+	l.mu.Unlock()
+}
+
+// Status returns the current status line, or "" if none.
+func (l *Logger) Status() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
 // Lines returns a copy of all stored terminal lines (from Log, not game logs).
 func (l *Logger) Lines() []string {
 	l.mu.Lock()
@@ -0,0 +1,65 @@
+// Package mainthread runs work submitted from background goroutines on the game loop's goroutine.
+// raylib and the scene are not safe for concurrent use, so goroutines (e.g. the LLM agent) must not
+// touch them directly; they call Do, and the game loop calls Drain once per frame.
+package mainthread
+
+import "context"
+
+// Queue holds functions waiting to run on the main thread.
+type Queue struct {
+	tasks chan task
+}
+
+type task struct {
+	ctx  context.Context
+	fn   func() error
+	done chan error
+}
+
+// New returns a queue that buffers up to size pending functions before Do blocks.
+func New(size int) *Queue {
+	return &Queue{tasks: make(chan task, size)}
+}
+
+// Do runs fn on the main thread (at the next Drain) and returns its error. It blocks until fn has run,
+// so it must not be called from the main thread itself. When ctx ends first (e.g. the agent request was
+// cancelled or timed out), Do returns ctx's error without waiting, and fn is skipped if it has not
+// started yet. A nil Queue runs fn directly on the caller's goroutine (e.g. headless use without a game
+// loop).
+func (q *Queue) Do(ctx context.Context, fn func() error) error {
+	if q == nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn()
+	}
+	done := make(chan error, 1)
+	select {
+	case q.tasks <- task{ctx: ctx, fn: fn, done: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Drain runs all pending functions in submission order, skipping those whose context has ended. Call
+// once per frame from the main thread.
+func (q *Queue) Drain() {
+	for {
+		select {
+		case t := <-q.tasks:
+			if err := t.ctx.Err(); err != nil {
+				t.done <- err
+				continue
+			}
+			t.done <- t.fn()
+		default:
+			return
+		}
+	}
+}
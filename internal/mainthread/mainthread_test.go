@@ -0,0 +1,61 @@
+package mainthread
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoRunsOnDrain(t *testing.T) {
+	q := New(1)
+	done := make(chan error, 1)
+	ran := false
+	go func() { done <- q.Do(context.Background(), func() error { ran = true; return errors.New("boom") }) }()
+	deadline := time.After(time.Second)
+	for {
+		q.Drain()
+		select {
+		case err := <-done:
+			if !ran || err == nil || err.Error() != "boom" {
+				t.Fatalf("Do = %v, ran %v; want fn's error after it ran", err, ran)
+			}
+			return
+		case <-deadline:
+			t.Fatal("Do did not return after Drain")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestDoStopsWhenContextEnds(t *testing.T) {
+	// Nobody drains: a cancel must still end the wait, both while queued and while the queue is full.
+	q := New(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	ran := false
+	for range 2 {
+		go func() { errs <- q.Do(ctx, func() error { ran = true; return nil }) }()
+	}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	for range 2 {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Do = %v, want context.Canceled", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Do kept waiting after its context was cancelled")
+		}
+	}
+
+	// The function left in the queue is skipped, not run late.
+	q.Drain()
+	if ran {
+		t.Error("Drain ran the function of a cancelled Do")
+	}
+	if err := (*Queue)(nil).Do(ctx, func() error { ran = true; return nil }); !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("nil Queue Do with a cancelled context = %v, ran %v", err, ran)
+	}
+}
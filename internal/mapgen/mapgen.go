@@ -5,7 +5,6 @@ import (
 	"time"
 
 	"game-engine/internal/scene"
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 // HeightMapOptions controls procedural height map generation.
@@ -13,18 +12,8 @@ import (
 // HeightScale is the maximum height of the terrain in world units.
 // Seed controls randomness; Seed == 0 uses a time-based seed.
 // Octaves, Frequency, Lacunarity, and Gain control the fractal noise shape.
-type HeightMapOptions struct {
-	Width       int
-	Depth       int
-	TileSize    float32
-	HeightScale float32
-
-	Seed       int64
-	Octaves    int
-	Frequency  float32
-	Lacunarity float32
-	Gain       float32
-}
+// It is the scene's Heightmap, so a terrain object keeps the options its mesh was generated with.
+type HeightMapOptions = scene.Heightmap
 
 // DefaultHeightMapOptions returns a sane default configuration.
 func DefaultHeightMapOptions() HeightMapOptions {
@@ -122,10 +111,9 @@ func GenerateHeightMapCubes(opts HeightMapOptions) []scene.ObjectInstance {
 	return objs
 }
 
-// ApplyHeightmapTerrain generates a single deformed plane mesh using fractal noise and
-// installs it as optimized terrain in the given scene. This avoids thousands of cubes
-// and is much faster to render.
-func ApplyHeightmapTerrain(scn *scene.Scene, opts HeightMapOptions) error {
+// TerrainOptions returns opts with the defaults of a terrain mesh filled in (at least a 2x2 grid) and, if
+// Seed is 0, a time-based seed picked, so the result always generates the same terrain.
+func TerrainOptions(opts HeightMapOptions) HeightMapOptions {
 	if opts.Width <= 1 || opts.Depth <= 1 {
 		// Need at least a 2x2 grid for meaningful deformation.
 		if opts.Width <= 1 {
@@ -153,48 +141,33 @@ func ApplyHeightmapTerrain(scn *scene.Scene, opts HeightMapOptions) error {
 	if opts.Gain <= 0 {
 		opts.Gain = 0.5
 	}
-	seed := opts.Seed
-	if seed == 0 {
-		seed = time.Now().UnixNano()
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
 	}
+	return opts
+}
 
-	// World size of the plane; centered at origin.
-	widthWorld := float32(opts.Width) * opts.TileSize
-	depthWorld := float32(opts.Depth) * opts.TileSize
-
-	// Build a grayscale heightmap image using fractal noise, then let raylib
-	// turn it into a heightmapped mesh. This avoids manual vertex pointer math.
-	img := rl.GenImageColor(opts.Width, opts.Depth, rl.Black)
+// HeightField samples the fractal noise of opts (see TerrainOptions) on its Width x Depth grid and
+// returns the heights in [0,1], row by row (index z*Width + x). The renderer turns it into the terrain
+// mesh (see render.View.syncTerrain).
+func HeightField(opts HeightMapOptions) []float32 {
+	if opts.Width <= 0 || opts.Depth <= 0 {
+		return nil
+	}
+	out := make([]float32, opts.Width*opts.Depth)
 	baseFreq := opts.Frequency
 	for z := 0; z < opts.Depth; z++ {
 		for x := 0; x < opts.Width; x++ {
 			nx := float32(x)
 			nz := float32(z)
-			h := fractalValueNoise2D(nx*baseFreq, nz*baseFreq, seed, opts.Octaves, opts.Lacunarity, opts.Gain)
+			h := fractalValueNoise2D(nx*baseFreq, nz*baseFreq, opts.Seed, opts.Octaves, opts.Lacunarity, opts.Gain)
 			if !isFinite(h) {
 				h = 0
 			}
-			if h < 0 {
-				h = 0
-			}
-			if h > 1 {
-				h = 1
-			}
-			v := uint8(h * 255)
-			c := rl.NewColor(v, v, v, 255)
-			rl.ImageDrawPixel(img, int32(x), int32(z), c)
+			out[z*opts.Width+x] = min(max(h, 0), 1)
 		}
 	}
-	size := rl.NewVector3(widthWorld, opts.HeightScale, depthWorld)
-	mesh := rl.GenMeshHeightmap(*img, size)
-	rl.UnloadImage(img)
-	if mesh.VertexCount == 0 {
-		return nil
-	}
-
-	terrainSize := [3]float32{widthWorld, opts.HeightScale, depthWorld}
-	scn.EnableTerrain(mesh, terrainSize)
-	return nil
+	return out
 }
 
 // fractalValueNoise2D is simple fractal value noise: layered smooth value noise with
@@ -268,4 +241,3 @@ func smoothStep(t float32) float32 {
 func isFinite(f float32) bool {
 	return !math.IsNaN(float64(f)) && !math.IsInf(float64(f), 0)
 }
-
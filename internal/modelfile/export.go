@@ -0,0 +1,464 @@
+package modelfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Export builds a glTF 2.0 document from triangle meshes and a node tree and writes it as .glb (one binary
+// file) or .gltf (JSON with the buffer embedded as a data: URI), so both are self-contained. Textures are
+// embedded too; glTF only allows PNG and JPEG, so other images are left out with a warning and the object
+// keeps its color. Materials are glTF's metallic-roughness model, which the engine's PBR materials follow.
+type Export struct {
+	geometries []Geometry
+	nodes      []Node
+	roots      []int
+}
+
+// Geometry is a triangle mesh in model space.
+type Geometry struct {
+	Positions []float32 // x, y, z per vertex
+	Normals   []float32 // x, y, z per vertex; nil = none
+	UVs       []float32 // u, v per vertex; nil = none
+	Indices   []uint32  // three per triangle; nil = the vertices in order
+}
+
+// Material is a metallic-roughness material: base color (RGBA 0-1), metallic and roughness factors,
+// emitted color, and optional textures (image file paths; "" = none).
+type Material struct {
+	Color                [4]float32
+	Texture              string // base color
+	Metallic             float32
+	Roughness            float32
+	Emissive             [3]float32
+	NormalMap            string
+	MetallicRoughnessMap string // G = roughness, B = metallic
+	EmissiveMap          string
+}
+
+// Part is one geometry drawn with one material (a glTF mesh primitive).
+type Part struct {
+	Geometry int // index returned by AddGeometry
+	Material Material
+}
+
+// Node is a named transform with optional geometry. Children are composed with its transform as in glTF:
+// translation, rotation and scale as one matrix.
+type Node struct {
+	Name        string
+	Translation [3]float32
+	Rotation    [4]float32 // quaternion x, y, z, w; zero = none
+	Scale       [3]float32 // zero components count as 1
+	Parts       []Part
+	children    []int
+}
+
+// AddGeometry adds a mesh that nodes can use (any number of times) and returns its index.
+func (e *Export) AddGeometry(g Geometry) int {
+	e.geometries = append(e.geometries, g)
+	return len(e.geometries) - 1
+}
+
+// AddNode adds a node under parent (an index returned by AddNode; -1 = a root of the scene) and returns
+// its index.
+func (e *Export) AddNode(n Node, parent int) int {
+	n.children = nil
+	e.nodes = append(e.nodes, n)
+	i := len(e.nodes) - 1
+	if parent >= 0 && parent < i {
+		e.nodes[parent].children = append(e.nodes[parent].children, i)
+	} else {
+		e.roots = append(e.roots, i)
+	}
+	return i
+}
+
+// WriteFile writes the document to path: GLB if it ends in .glb, else glTF JSON. Problems that do not stop
+// the export (e.g. a texture that cannot be embedded) are returned as warnings.
+func (e *Export) WriteFile(path string) (warnings []string, err error) {
+	glb := strings.EqualFold(filepath.Ext(path), ".glb")
+	doc, bin, warnings, err := e.encode()
+	if err != nil {
+		return warnings, err
+	}
+	var data []byte
+	// An export with no geometry has no buffer at all: glTF does not allow an empty one.
+	if glb {
+		if len(bin) > 0 {
+			doc.Buffers = []gltfBuffer{{ByteLength: len(bin)}}
+		}
+		data, err = glbFile(doc, bin)
+	} else {
+		if len(bin) > 0 {
+			doc.Buffers = []gltfBuffer{{ByteLength: len(bin), URI: "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)}}
+		}
+		data, err = json.MarshalIndent(doc, "", "  ")
+	}
+	if err != nil {
+		return warnings, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return warnings, err
+	}
+	return warnings, os.WriteFile(path, data, 0644)
+}
+
+// The glTF 2.0 JSON written by Export (only the properties it uses).
+type gltfOut struct {
+	Asset       gltfAsset        `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []gltfScene      `json:"scenes"`
+	Nodes       []gltfNode       `json:"nodes,omitempty"`
+	Meshes      []gltfMesh       `json:"meshes,omitempty"`
+	Materials   []gltfMaterial   `json:"materials,omitempty"`
+	Textures    []gltfTexture    `json:"textures,omitempty"`
+	Samplers    []gltfSampler    `json:"samplers,omitempty"`
+	Images      []gltfImage      `json:"images,omitempty"`
+	Accessors   []gltfAccessor   `json:"accessors,omitempty"`
+	BufferViews []gltfBufferView `json:"bufferViews,omitempty"`
+	Buffers     []gltfBuffer     `json:"buffers,omitempty"`
+}
+
+type gltfAsset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+type gltfScene struct {
+	Nodes []int `json:"nodes,omitempty"` // omitted when empty: glTF requires at least one
+}
+
+type gltfNode struct {
+	Name        string    `json:"name,omitempty"`
+	Mesh        *int      `json:"mesh,omitempty"`
+	Children    []int     `json:"children,omitempty"`
+	Translation []float32 `json:"translation,omitempty"`
+	Rotation    []float32 `json:"rotation,omitempty"`
+	Scale       []float32 `json:"scale,omitempty"`
+}
+
+type gltfMesh struct {
+	Name       string          `json:"name,omitempty"`
+	Primitives []gltfPrimitive `json:"primitives"`
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+}
+
+type gltfMaterial struct {
+	PBR             gltfPBR         `json:"pbrMetallicRoughness"`
+	NormalTexture   *gltfTextureRef `json:"normalTexture,omitempty"`
+	EmissiveTexture *gltfTextureRef `json:"emissiveTexture,omitempty"`
+	EmissiveFactor  []float32       `json:"emissiveFactor,omitempty"`
+	AlphaMode       string          `json:"alphaMode,omitempty"`
+}
+
+type gltfPBR struct {
+	BaseColorFactor          [4]float32      `json:"baseColorFactor"`
+	BaseColorTexture         *gltfTextureRef `json:"baseColorTexture,omitempty"`
+	MetallicFactor           float32         `json:"metallicFactor"`
+	RoughnessFactor          float32         `json:"roughnessFactor"`
+	MetallicRoughnessTexture *gltfTextureRef `json:"metallicRoughnessTexture,omitempty"`
+}
+
+type gltfTextureRef struct {
+	Index int `json:"index"`
+}
+
+type gltfTexture struct {
+	Sampler int `json:"sampler"`
+	Source  int `json:"source"`
+}
+
+type gltfSampler struct {
+	MagFilter int `json:"magFilter"`
+	MinFilter int `json:"minFilter"`
+	WrapS     int `json:"wrapS"`
+	WrapT     int `json:"wrapT"`
+}
+
+type gltfImage struct {
+	Name       string `json:"name,omitempty"`
+	BufferView int    `json:"bufferView"`
+	MimeType   string `json:"mimeType"`
+}
+
+type gltfAccessor struct {
+	BufferView    int       `json:"bufferView"`
+	ComponentType int       `json:"componentType"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float32 `json:"min,omitempty"`
+	Max           []float32 `json:"max,omitempty"`
+}
+
+type gltfBufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset"`
+	ByteLength int `json:"byteLength"`
+	Target     int `json:"target,omitempty"`
+}
+
+type gltfBuffer struct {
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri,omitempty"`
+}
+
+// glTF enum values used by Export.
+const (
+	gltfFloat        = 5126
+	gltfUnsignedInt  = 5125
+	gltfArrayBuffer  = 34962
+	gltfElementArray = 34963
+	gltfLinear       = 9729
+	gltfLinearMipmap = 9987
+	gltfRepeat       = 10497
+	glbChunkBIN      = 0x004E4942 // "BIN\0"
+)
+
+// encoder accumulates the binary buffer and the JSON arrays that index into it.
+type encoder struct {
+	doc      gltfOut
+	bin      bytes.Buffer
+	warnings []string
+	prims    []gltfPrimitive // per geometry: its primitive without a material
+	images   map[string]int  // texture path -> texture index (-1 = could not be embedded)
+	mats     map[Material]int
+	meshes   map[string]int // parts key -> mesh index
+}
+
+// encode builds the JSON document and the binary buffer (without doc.Buffers).
+func (e *Export) encode() (gltfOut, []byte, []string, error) {
+	enc := &encoder{
+		doc: gltfOut{
+			Asset:  gltfAsset{Version: "2.0", Generator: "game-engine"},
+			Scenes: []gltfScene{{Nodes: append([]int{}, e.roots...)}},
+		},
+		images: map[string]int{},
+		mats:   map[Material]int{},
+		meshes: map[string]int{},
+	}
+	for i, g := range e.geometries {
+		p, err := enc.geometry(g)
+		if err != nil {
+			return gltfOut{}, nil, nil, fmt.Errorf("geometry %d: %w", i, err)
+		}
+		enc.prims = append(enc.prims, p)
+	}
+	for _, n := range e.nodes {
+		out := gltfNode{Name: n.Name, Children: n.children}
+		if n.Translation != ([3]float32{}) {
+			out.Translation = n.Translation[:]
+		}
+		if n.Rotation != ([4]float32{}) && n.Rotation != ([4]float32{0, 0, 0, 1}) {
+			out.Rotation = n.Rotation[:]
+		}
+		scale := n.Scale
+		for k := range scale {
+			if scale[k] == 0 {
+				scale[k] = 1
+			}
+		}
+		if scale != ([3]float32{1, 1, 1}) {
+			out.Scale = scale[:]
+		}
+		if len(n.Parts) > 0 {
+			m, err := enc.mesh(n.Name, n.Parts)
+			if err != nil {
+				return gltfOut{}, nil, nil, fmt.Errorf("node %q: %w", n.Name, err)
+			}
+			out.Mesh = &m
+		}
+		enc.doc.Nodes = append(enc.doc.Nodes, out)
+	}
+	return enc.doc, enc.bin.Bytes(), enc.warnings, nil
+}
+
+// view appends data to the binary buffer (4-byte aligned) as a new buffer view and returns its index.
+func (enc *encoder) view(data []byte, target int) int {
+	for enc.bin.Len()%4 != 0 {
+		enc.bin.WriteByte(0)
+	}
+	enc.doc.BufferViews = append(enc.doc.BufferViews, gltfBufferView{ByteOffset: enc.bin.Len(), ByteLength: len(data), Target: target})
+	enc.bin.Write(data)
+	return len(enc.doc.BufferViews) - 1
+}
+
+// floats adds a float vertex attribute accessor (n components per vertex) and returns its index.
+func (enc *encoder) floats(v []float32, n int, typ string, minMax bool) int {
+	data := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(f))
+	}
+	acc := gltfAccessor{BufferView: enc.view(data, gltfArrayBuffer), ComponentType: gltfFloat, Count: len(v) / n, Type: typ}
+	if minMax {
+		acc.Min, acc.Max = make([]float32, n), make([]float32, n)
+		for k := 0; k < n; k++ {
+			acc.Min[k], acc.Max[k] = float32(math.Inf(1)), float32(math.Inf(-1))
+		}
+		for i, f := range v {
+			acc.Min[i%n], acc.Max[i%n] = min(acc.Min[i%n], f), max(acc.Max[i%n], f)
+		}
+	}
+	enc.doc.Accessors = append(enc.doc.Accessors, acc)
+	return len(enc.doc.Accessors) - 1
+}
+
+// geometry writes a mesh's vertex data and returns its primitive (without a material).
+func (enc *encoder) geometry(g Geometry) (gltfPrimitive, error) {
+	count := len(g.Positions) / 3
+	switch {
+	case count == 0 || len(g.Positions)%3 != 0:
+		return gltfPrimitive{}, fmt.Errorf("%d position components (want 3 per vertex, at least one vertex)", len(g.Positions))
+	case g.Normals != nil && len(g.Normals) != 3*count:
+		return gltfPrimitive{}, fmt.Errorf("%d normal components for %d vertices", len(g.Normals), count)
+	case g.UVs != nil && len(g.UVs) != 2*count:
+		return gltfPrimitive{}, fmt.Errorf("%d UV components for %d vertices", len(g.UVs), count)
+	}
+	p := gltfPrimitive{Attributes: map[string]int{"POSITION": enc.floats(g.Positions, 3, "VEC3", true)}}
+	if g.Normals != nil {
+		p.Attributes["NORMAL"] = enc.floats(g.Normals, 3, "VEC3", false)
+	}
+	if g.UVs != nil {
+		p.Attributes["TEXCOORD_0"] = enc.floats(g.UVs, 2, "VEC2", false)
+	}
+	if g.Indices != nil {
+		data := make([]byte, 4*len(g.Indices))
+		for i, ix := range g.Indices {
+			if int(ix) >= count {
+				return gltfPrimitive{}, fmt.Errorf("index %d out of range (%d vertices)", ix, count)
+			}
+			binary.LittleEndian.PutUint32(data[4*i:], ix)
+		}
+		enc.doc.Accessors = append(enc.doc.Accessors, gltfAccessor{BufferView: enc.view(data, gltfElementArray), ComponentType: gltfUnsignedInt, Count: len(g.Indices), Type: "SCALAR"})
+		ix := len(enc.doc.Accessors) - 1
+		p.Indices = &ix
+	}
+	return p, nil
+}
+
+// mesh returns the glTF mesh for a list of parts, shared by nodes with the same parts.
+func (enc *encoder) mesh(name string, parts []Part) (int, error) {
+	key := fmt.Sprint(parts)
+	if m, ok := enc.meshes[key]; ok {
+		return m, nil
+	}
+	mesh := gltfMesh{Name: name}
+	for _, part := range parts {
+		if part.Geometry < 0 || part.Geometry >= len(enc.prims) {
+			return 0, fmt.Errorf("unknown geometry %d", part.Geometry)
+		}
+		p := enc.prims[part.Geometry]
+		mat := enc.material(part.Material)
+		p.Material = &mat
+		mesh.Primitives = append(mesh.Primitives, p)
+	}
+	enc.doc.Meshes = append(enc.doc.Meshes, mesh)
+	enc.meshes[key] = len(enc.doc.Meshes) - 1
+	return len(enc.doc.Meshes) - 1, nil
+}
+
+// material returns the index of the glTF material for m, adding it on first use.
+func (enc *encoder) material(m Material) int {
+	if i, ok := enc.mats[m]; ok {
+		return i
+	}
+	out := gltfMaterial{PBR: gltfPBR{BaseColorFactor: m.Color, MetallicFactor: m.Metallic, RoughnessFactor: m.Roughness}}
+	if m.Color[3] < 1 {
+		out.AlphaMode = "BLEND"
+	}
+	if m.Emissive != ([3]float32{}) {
+		// glTF caps the factor at 1 per component (brighter needs KHR_materials_emissive_strength).
+		out.EmissiveFactor = []float32{min(m.Emissive[0], 1), min(m.Emissive[1], 1), min(m.Emissive[2], 1)}
+	}
+	for _, t := range []struct {
+		path string
+		ref  **gltfTextureRef
+	}{
+		{m.Texture, &out.PBR.BaseColorTexture},
+		{m.MetallicRoughnessMap, &out.PBR.MetallicRoughnessTexture},
+		{m.NormalMap, &out.NormalTexture},
+		{m.EmissiveMap, &out.EmissiveTexture},
+	} {
+		if t.path == "" {
+			continue
+		}
+		if tex := enc.texture(t.path); tex >= 0 {
+			*t.ref = &gltfTextureRef{Index: tex}
+		}
+	}
+	enc.doc.Materials = append(enc.doc.Materials, out)
+	enc.mats[m] = len(enc.doc.Materials) - 1
+	return enc.mats[m]
+}
+
+// texture embeds an image file and returns its texture index, or -1 (with a warning) if it cannot be used.
+func (enc *encoder) texture(path string) int {
+	if i, ok := enc.images[path]; ok {
+		return i
+	}
+	enc.images[path] = -1
+	var mime string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		mime = "image/png"
+	case ".jpg", ".jpeg":
+		mime = "image/jpeg"
+	default:
+		enc.warnings = append(enc.warnings, fmt.Sprintf("texture %s left out: glTF only embeds PNG and JPEG", path))
+		return -1
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		enc.warnings = append(enc.warnings, fmt.Sprintf("texture left out: %v", err))
+		return -1
+	}
+	if len(enc.doc.Samplers) == 0 {
+		enc.doc.Samplers = []gltfSampler{{MagFilter: gltfLinear, MinFilter: gltfLinearMipmap, WrapS: gltfRepeat, WrapT: gltfRepeat}}
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	enc.doc.Images = append(enc.doc.Images, gltfImage{Name: name, BufferView: enc.view(data, 0), MimeType: mime})
+	enc.doc.Textures = append(enc.doc.Textures, gltfTexture{Sampler: 0, Source: len(enc.doc.Images) - 1})
+	enc.images[path] = len(enc.doc.Textures) - 1
+	return enc.images[path]
+}
+
+// glbFile returns the GLB container for doc and its binary buffer: header, JSON chunk (space padded),
+// BIN chunk (zero padded; left out when there is no binary data).
+func glbFile(doc gltfOut, bin []byte) ([]byte, error) {
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	for len(js)%4 != 0 {
+		js = append(js, ' ')
+	}
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+	size := 12 + 8 + len(js)
+	if len(bin) > 0 {
+		size += 8 + len(bin)
+	}
+	var out bytes.Buffer
+	for _, v := range []uint32{glbMagic, 2, uint32(size), uint32(len(js)), glbChunkJSON} {
+		binary.Write(&out, binary.LittleEndian, v)
+	}
+	out.Write(js)
+	if len(bin) > 0 {
+		binary.Write(&out, binary.LittleEndian, uint32(len(bin)))
+		binary.Write(&out, binary.LittleEndian, uint32(glbChunkBIN))
+		out.Write(bin)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,380 @@
+// Package modelfile reads what the engine needs to know about 3D model files (glTF 2.0 .gltf/.glb and
+// Wavefront .obj) without a GPU: their bounds, for picking and physics, and the files they refer to, for
+// copying a model into the assets directory. Drawing them is done by raylib in internal/render. Export
+// (export.go) writes glTF 2.0 files, for scenes exported to other tools.
+package modelfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Extensions are the model file extensions the engine loads.
+var Extensions = []string{".gltf", ".glb", ".obj"}
+
+// Supported reports whether path has one of Extensions (case-insensitive).
+func Supported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Bounds returns the axis-aligned bounds of the geometry in a model file, in model units, with glTF node
+// transforms applied (as raylib applies them when loading).
+func Bounds(path string) (lo, hi [3]float32, err error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gltf", ".glb":
+		doc, err := readGLTF(path)
+		if err != nil {
+			return lo, hi, err
+		}
+		return doc.bounds(path)
+	case ".obj":
+		return objBounds(path)
+	default:
+		return lo, hi, fmt.Errorf("%s: unsupported model format (use %s)", path, strings.Join(Extensions, ", "))
+	}
+}
+
+// Dependencies returns the files a model file refers to, relative to its directory: glTF buffers and
+// images, or OBJ material libraries and the textures they use. Embedded data (GLB chunks, data: URIs) is
+// not listed.
+func Dependencies(path string) ([]string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gltf", ".glb":
+		doc, err := readGLTF(path)
+		if err != nil {
+			return nil, err
+		}
+		var out []string
+		for _, b := range doc.Buffers {
+			out = appendURI(out, b.URI)
+		}
+		for _, im := range doc.Images {
+			out = appendURI(out, im.URI)
+		}
+		return out, nil
+	case ".obj":
+		return objDependencies(path)
+	default:
+		return nil, fmt.Errorf("%s: unsupported model format (use %s)", path, strings.Join(Extensions, ", "))
+	}
+}
+
+// appendURI appends a relative file URI (percent-decoded) to out; data: URIs and absolute URLs are skipped.
+func appendURI(out []string, uri string) []string {
+	if uri == "" || strings.HasPrefix(uri, "data:") || strings.Contains(uri, "://") {
+		return out
+	}
+	return append(out, filepath.FromSlash(unescape(uri)))
+}
+
+// unescape decodes %XX sequences in a glTF URI (e.g. "my%20texture.png").
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// gltfDoc is the part of a glTF 2.0 document needed for bounds and dependencies.
+type gltfDoc struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Mesh        *int      `json:"mesh"`
+		Children    []int     `json:"children"`
+		Matrix      []float64 `json:"matrix"`
+		Translation []float64 `json:"translation"`
+		Rotation    []float64 `json:"rotation"`
+		Scale       []float64 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		Min []float64 `json:"min"`
+		Max []float64 `json:"max"`
+	} `json:"accessors"`
+	Buffers []struct {
+		URI string `json:"uri"`
+	} `json:"buffers"`
+	Images []struct {
+		URI string `json:"uri"`
+	} `json:"images"`
+}
+
+// GLB container constants (glTF 2.0 spec, "Binary glTF Layout").
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+)
+
+// readGLTF parses the JSON of a .gltf file or of the first chunk of a .glb file.
+func readGLTF(path string) (*gltfDoc, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		if len(data) < 20 {
+			return nil, fmt.Errorf("%s: truncated GLB header", path)
+		}
+		if v := binary.LittleEndian.Uint32(data[4:]); v != 2 {
+			return nil, fmt.Errorf("%s: GLB version %d (only glTF 2.0 is supported)", path, v)
+		}
+		n := binary.LittleEndian.Uint32(data[12:])
+		if binary.LittleEndian.Uint32(data[16:]) != glbChunkJSON || uint64(20)+uint64(n) > uint64(len(data)) {
+			return nil, fmt.Errorf("%s: GLB has no JSON chunk", path)
+		}
+		data = data[20 : 20+n]
+	}
+	var doc gltfDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// bounds walks the default scene (or every root node) and unions the POSITION accessor bounds of each
+// mesh, transformed by its node's world matrix. glTF requires min and max on POSITION accessors.
+func (d *gltfDoc) bounds(path string) (lo, hi [3]float32, err error) {
+	var roots []int
+	switch {
+	case d.Scene != nil && *d.Scene < len(d.Scenes):
+		roots = d.Scenes[*d.Scene].Nodes
+	case len(d.Scenes) > 0:
+		roots = d.Scenes[0].Nodes
+	default:
+		child := make(map[int]bool)
+		for _, n := range d.Nodes {
+			for _, c := range n.Children {
+				child[c] = true
+			}
+		}
+		for i := range d.Nodes {
+			if !child[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+	b := newBox()
+	visited := make(map[int]bool)
+	var walk func(i int, parent mat4) error
+	walk = func(i int, parent mat4) error {
+		if i < 0 || i >= len(d.Nodes) || visited[i] {
+			return fmt.Errorf("%s: invalid or cyclic node %d", path, i)
+		}
+		visited[i] = true
+		n := d.Nodes[i]
+		m := parent.mul(nodeMatrix(n.Matrix, n.Translation, n.Rotation, n.Scale))
+		if n.Mesh != nil {
+			if *n.Mesh < 0 || *n.Mesh >= len(d.Meshes) {
+				return fmt.Errorf("%s: node %d has invalid mesh %d", path, i, *n.Mesh)
+			}
+			for _, p := range d.Meshes[*n.Mesh].Primitives {
+				a, ok := p.Attributes["POSITION"]
+				if !ok || a < 0 || a >= len(d.Accessors) || len(d.Accessors[a].Min) < 3 || len(d.Accessors[a].Max) < 3 {
+					return fmt.Errorf("%s: mesh %d has no POSITION accessor with min/max", path, *n.Mesh)
+				}
+				acc := d.Accessors[a]
+				for c := 0; c < 8; c++ {
+					corner := [3]float64{acc.Min[0], acc.Min[1], acc.Min[2]}
+					for k := 0; k < 3; k++ {
+						if c&(1<<k) != 0 {
+							corner[k] = acc.Max[k]
+						}
+					}
+					b.add(m.apply(corner))
+				}
+			}
+		}
+		for _, c := range n.Children {
+			if err := walk(c, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, r := range roots {
+		if err := walk(r, identity()); err != nil {
+			return lo, hi, err
+		}
+	}
+	if b.empty() {
+		return lo, hi, fmt.Errorf("%s: model has no meshes", path)
+	}
+	return b.result()
+}
+
+// objBounds scans the vertex positions ("v x y z") of an OBJ file.
+func objBounds(path string) (lo, hi [3]float32, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return lo, hi, err
+	}
+	defer f.Close()
+	b := newBox()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 4 || fields[0] != "v" {
+			continue
+		}
+		var p [3]float64
+		for k := 0; k < 3; k++ {
+			if p[k], err = strconv.ParseFloat(fields[1+k], 64); err != nil {
+				return lo, hi, fmt.Errorf("%s:%d: bad vertex %q", path, line, fields[1+k])
+			}
+		}
+		b.add(p)
+	}
+	if err := sc.Err(); err != nil {
+		return lo, hi, fmt.Errorf("%s: %w", path, err)
+	}
+	if b.empty() {
+		return lo, hi, fmt.Errorf("%s: model has no vertices", path)
+	}
+	return b.result()
+}
+
+// objDependencies lists the material libraries of an OBJ file and the texture maps they use.
+func objDependencies(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, lib := range directives(data, "mtllib") {
+		out = append(out, lib)
+		mtl, err := os.ReadFile(filepath.Join(filepath.Dir(path), lib))
+		if err != nil {
+			continue // a missing library only loses its materials
+		}
+		for _, key := range []string{"map_Kd", "map_Ka", "map_Ks", "map_Ns", "map_d", "map_Bump", "map_bump", "bump", "disp", "norm"} {
+			out = append(out, directives(mtl, key)...)
+		}
+	}
+	return out, nil
+}
+
+// directives returns the last field of every line starting with key (the file name; options such as
+// "-bm 1" come before it).
+func directives(data []byte, key string) []string {
+	var out []string
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) >= 2 && fields[0] == key {
+			out = append(out, filepath.FromSlash(fields[len(fields)-1]))
+		}
+	}
+	return out
+}
+
+// box accumulates an AABB.
+type box struct{ lo, hi [3]float64 }
+
+func newBox() box {
+	return box{lo: [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}, hi: [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}}
+}
+
+func (b *box) add(p [3]float64) {
+	for k := 0; k < 3; k++ {
+		b.lo[k] = math.Min(b.lo[k], p[k])
+		b.hi[k] = math.Max(b.hi[k], p[k])
+	}
+}
+
+func (b *box) empty() bool { return b.lo[0] > b.hi[0] }
+
+func (b *box) result() (lo, hi [3]float32, err error) {
+	for k := 0; k < 3; k++ {
+		lo[k], hi[k] = float32(b.lo[k]), float32(b.hi[k])
+	}
+	return lo, hi, nil
+}
+
+// mat4 is a column-major 4x4 matrix, as glTF stores them.
+type mat4 [16]float64
+
+func identity() mat4 {
+	return mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+// mul returns m * n (n is applied first).
+func (m mat4) mul(n mat4) mat4 {
+	var out mat4
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			var s float64
+			for k := 0; k < 4; k++ {
+				s += m[k*4+r] * n[c*4+k]
+			}
+			out[c*4+r] = s
+		}
+	}
+	return out
+}
+
+// apply transforms the point p.
+func (m mat4) apply(p [3]float64) [3]float64 {
+	var out [3]float64
+	for r := 0; r < 3; r++ {
+		out[r] = m[r]*p[0] + m[4+r]*p[1] + m[8+r]*p[2] + m[12+r]
+	}
+	return out
+}
+
+// nodeMatrix returns a node's local matrix: matrix if given, else translation * rotation * scale.
+func nodeMatrix(matrix, t, r, s []float64) mat4 {
+	if len(matrix) == 16 {
+		var m mat4
+		copy(m[:], matrix)
+		return m
+	}
+	m := identity()
+	if len(s) == 3 {
+		m[0], m[5], m[10] = s[0], s[1], s[2]
+	}
+	if len(r) == 4 {
+		x, y, z, w := r[0], r[1], r[2], r[3]
+		rot := mat4{
+			1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+			2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+			2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+			0, 0, 0, 1,
+		}
+		m = rot.mul(m)
+	}
+	if len(t) == 3 {
+		m[12], m[13], m[14] = t[0], t[1], t[2]
+	}
+	return m
+}
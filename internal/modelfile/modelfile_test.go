@@ -0,0 +1,182 @@
+package modelfile
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// twoNodes is a glTF document with one mesh (a unit cube around the origin) used by two nodes: one moved
+// up by 2, one scaled by 3 under a parent rotated 90° about Y and moved along X.
+const twoNodes = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0, 1]}],
+	"nodes": [
+		{"mesh": 0, "translation": [0, 2, 0]},
+		{"translation": [10, 0, 0], "rotation": [0, 0.7071068, 0, 0.7071068], "children": [2]},
+		{"mesh": 0, "scale": [3, 1, 1]}
+	],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}}]}],
+	"accessors": [{"min": [-0.5, -0.5, -0.5], "max": [0.5, 0.5, 0.5]}],
+	"buffers": [{"uri": "cube.bin"}],
+	"images": [{"uri": "bark%20dark.png"}, {"uri": "data:image/png;base64,AAAA"}]
+}`
+
+func TestGLTFBounds(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "scene.gltf")
+	if err := os.WriteFile(path, []byte(twoNodes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lo, hi, err := Bounds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The scaled cube is 3 long in X before the 90° turn, so it spans Z -1.5..1.5 at X 9.5..10.5.
+	wantLo, wantHi := [3]float32{-0.5, -0.5, -1.5}, [3]float32{10.5, 2.5, 1.5}
+	for k := 0; k < 3; k++ {
+		if d := lo[k] - wantLo[k]; d > 1e-4 || d < -1e-4 {
+			t.Errorf("min = %v, want %v", lo, wantLo)
+			break
+		}
+		if d := hi[k] - wantHi[k]; d > 1e-4 || d < -1e-4 {
+			t.Errorf("max = %v, want %v", hi, wantHi)
+			break
+		}
+	}
+	deps, err := Dependencies(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cube.bin", "bark dark.png"}; !reflect.DeepEqual(deps, want) {
+		t.Errorf("Dependencies = %v, want %v", deps, want)
+	}
+
+	// The same document as a GLB container.
+	glb := filepath.Join(dir, "scene.glb")
+	body := []byte(twoNodes)
+	for len(body)%4 != 0 {
+		body = append(body, ' ')
+	}
+	data := make([]byte, 20, 20+len(body))
+	binary.LittleEndian.PutUint32(data[0:], glbMagic)
+	binary.LittleEndian.PutUint32(data[4:], 2)
+	binary.LittleEndian.PutUint32(data[8:], uint32(20+len(body)))
+	binary.LittleEndian.PutUint32(data[12:], uint32(len(body)))
+	binary.LittleEndian.PutUint32(data[16:], glbChunkJSON)
+	if err := os.WriteFile(glb, append(data, body...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if glo, ghi, err := Bounds(glb); err != nil || glo != lo || ghi != hi {
+		t.Errorf("GLB bounds = %v %v, %v; want %v %v", glo, ghi, err, lo, hi)
+	}
+}
+
+func TestOBJBounds(t *testing.T) {
+	dir := t.TempDir()
+	obj := filepath.Join(dir, "hut.obj")
+	os.WriteFile(obj, []byte("mtllib hut.mtl\nv -1 0 -2\nv 1 3 2\nvt 0 0\nf 1 2 1\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "hut.mtl"), []byte("newmtl wood\nmap_Kd textures/wood.png\nmap_Bump -bm 0.5 wood_n.png\n"), 0644)
+	lo, hi, err := Bounds(obj)
+	if err != nil || lo != [3]float32{-1, 0, -2} || hi != [3]float32{1, 3, 2} {
+		t.Errorf("Bounds = %v %v, %v", lo, hi, err)
+	}
+	deps, err := Dependencies(obj)
+	want := []string{"hut.mtl", filepath.FromSlash("textures/wood.png"), "wood_n.png"}
+	if err != nil || !reflect.DeepEqual(deps, want) {
+		t.Errorf("Dependencies = %v, %v; want %v", deps, err, want)
+	}
+	if _, _, err := Bounds(filepath.Join(dir, "hut.fbx")); err == nil {
+		t.Error("Bounds accepted an unsupported format")
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "bark.png")
+	os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0644)
+	bmp := filepath.Join(dir, "old.bmp")
+	os.WriteFile(bmp, []byte("BM"), 0644)
+
+	// A unit quad in XZ used by three nodes: a root moved up, and two children of a group moved along X,
+	// one of them stretched.
+	var e Export
+	quad := e.AddGeometry(Geometry{
+		Positions: []float32{-0.5, 0, -0.5, 0.5, 0, -0.5, 0.5, 0, 0.5, -0.5, 0, 0.5},
+		Normals:   []float32{0, 1, 0, 0, 1, 0, 0, 1, 0, 0, 1, 0},
+		UVs:       []float32{0, 0, 1, 0, 1, 1, 0, 1},
+		Indices:   []uint32{0, 2, 1, 0, 3, 2},
+	})
+	red := Material{Color: [4]float32{1, 0, 0, 1}, Texture: png, Metallic: 1, Roughness: 0.25, NormalMap: png}
+	e.AddNode(Node{Name: "Floor", Translation: [3]float32{0, 2, 0}, Parts: []Part{{quad, red}}}, -1)
+	g := e.AddNode(Node{Name: "Group", Translation: [3]float32{10, 0, 0}}, -1)
+	e.AddNode(Node{Name: "A", Parts: []Part{{quad, red}}}, g)
+	e.AddNode(Node{Name: "B", Translation: [3]float32{0, 0, 5}, Scale: [3]float32{4, 1, 1}, Parts: []Part{{quad, Material{Color: [4]float32{1, 1, 1, 1}, Texture: bmp}}}}, g)
+
+	wantLo, wantHi := [3]float32{-0.5, 0, -0.5}, [3]float32{12, 2, 5.5}
+	for _, name := range []string{"scene.glb", "scene.gltf"} {
+		path := filepath.Join(dir, name)
+		warnings, err := e.WriteFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(warnings) != 1 {
+			t.Errorf("%s: warnings = %v; want one for the BMP texture", name, warnings)
+		}
+		if lo, hi, err := Bounds(path); err != nil || lo != wantLo || hi != wantHi {
+			t.Errorf("%s: Bounds = %v %v, %v; want %v %v", name, lo, hi, err, wantLo, wantHi)
+		}
+		if deps, err := Dependencies(path); err != nil || len(deps) != 0 {
+			t.Errorf("%s: Dependencies = %v, %v; want none (self-contained)", name, deps, err)
+		}
+	}
+
+	// Nodes with the same parts share a mesh; the PNG is embedded once though used as two maps.
+	data, _ := os.ReadFile(filepath.Join(dir, "scene.gltf"))
+	var doc gltfOut
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Meshes) != 2 || len(doc.Materials) != 2 || len(doc.Images) != 1 || *doc.Nodes[0].Mesh != *doc.Nodes[2].Mesh {
+		t.Errorf("meshes %d, materials %d, images %d; want 2, 2, 1 with Floor and A sharing a mesh", len(doc.Meshes), len(doc.Materials), len(doc.Images))
+	}
+	if m := doc.Materials[0]; m.PBR.MetallicFactor != 1 || m.PBR.RoughnessFactor != 0.25 || m.NormalTexture == nil || m.NormalTexture.Index != m.PBR.BaseColorTexture.Index {
+		t.Errorf("material 0 = %+v; want metallic 1, roughness 0.25, the PNG as base color and normal map", m)
+	}
+	if doc.Nodes[1].Name != "Group" || !reflect.DeepEqual(doc.Nodes[1].Children, []int{2, 3}) {
+		t.Errorf("group node = %+v", doc.Nodes[1])
+	}
+
+	// An empty export has no buffer and no empty node list (glTF allows neither), and the GLB no BIN chunk.
+	var empty Export
+	for _, name := range []string{"empty.gltf", "empty.glb"} {
+		path := filepath.Join(dir, name)
+		if _, err := empty.WriteFile(path); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path)
+		if name == "empty.glb" {
+			if size := binary.LittleEndian.Uint32(data[8:]); int(size) != len(data) || len(data) != 20+int(binary.LittleEndian.Uint32(data[12:])) {
+				t.Errorf("%s: %d bytes, header size %d; want only the header and the JSON chunk", name, len(data), size)
+			}
+			data = data[20:]
+		}
+		var raw map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, ok := raw["buffers"]; ok {
+			t.Errorf("%s: has buffers %v", name, raw["buffers"])
+		}
+		if _, ok := raw["nodes"]; ok {
+			t.Errorf("%s: has nodes %v", name, raw["nodes"])
+		}
+		if scenes := raw["scenes"].([]any); len(scenes) != 1 || len(scenes[0].(map[string]any)) != 0 {
+			t.Errorf("%s: scenes = %v; want one scene with no node list", name, scenes)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package physics
+
+// AABB is an axis-aligned bounding box in world space.
+type AABB struct {
+	Min [3]float32
+	Max [3]float32
+}
+
+// BoxAt returns the AABB of a box centered at center with the given size. Zero components of scale are treated as 1.
+func BoxAt(center, scale [3]float32) AABB {
+	half := [3]float32{scale[0] * 0.5, scale[1] * 0.5, scale[2] * 0.5}
+	for i := range half {
+		if half[i] == 0 {
+			half[i] = 0.5
+		}
+	}
+	return AABB{
+		Min: [3]float32{center[0] - half[0], center[1] - half[1], center[2] - half[2]},
+		Max: [3]float32{center[0] + half[0], center[1] + half[1], center[2] + half[2]},
+	}
+}
+
+// Overlaps reports whether a and b intersect (touching counts).
+func (a AABB) Overlaps(b AABB) bool {
+	return a.Min[0] <= b.Max[0] && a.Max[0] >= b.Min[0] &&
+		a.Min[1] <= b.Max[1] && a.Max[1] >= b.Min[1] &&
+		a.Min[2] <= b.Max[2] && a.Max[2] >= b.Min[2]
+}
+
+// Expand returns the box grown by d on every side.
+func (a AABB) Expand(d float32) AABB {
+	return AABB{
+		Min: [3]float32{a.Min[0] - d, a.Min[1] - d, a.Min[2] - d},
+		Max: [3]float32{a.Max[0] + d, a.Max[1] + d, a.Max[2] + d},
+	}
+}
+
+// RayHit is where a ray enters a box: distance along the (normalized) direction, the hit point, and the
+// outward normal of the face that was hit.
+type RayHit struct {
+	Distance float32
+	Point    [3]float32
+	Normal   [3]float32
+}
+
+// IntersectRay returns where the ray from origin along dir enters the box. Rays starting inside the box or
+// pointing away from it do not hit.
+func (a AABB) IntersectRay(origin, dir [3]float32) (RayHit, bool) {
+	tmin, tmax := float32(-1e30), float32(1e30)
+	axis := -1
+	for i := 0; i < 3; i++ {
+		if dir[i] > -1e-8 && dir[i] < 1e-8 {
+			if origin[i] < a.Min[i] || origin[i] > a.Max[i] {
+				return RayHit{}, false
+			}
+			continue
+		}
+		t1 := (a.Min[i] - origin[i]) / dir[i]
+		t2 := (a.Max[i] - origin[i]) / dir[i]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		if t1 > tmin {
+			tmin = t1
+			axis = i
+		}
+		if t2 < tmax {
+			tmax = t2
+		}
+		if tmin > tmax {
+			return RayHit{}, false
+		}
+	}
+	if axis < 0 || tmin <= 0 {
+		return RayHit{}, false
+	}
+	hit := RayHit{Distance: tmin}
+	for i := 0; i < 3; i++ {
+		hit.Point[i] = origin[i] + dir[i]*tmin
+	}
+	if dir[axis] > 0 {
+		hit.Normal[axis] = -1
+	} else {
+		hit.Normal[axis] = 1
+	}
+	return hit, true
+}
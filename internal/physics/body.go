@@ -8,6 +8,7 @@ type Body struct {
 	Scale    [3]float32
 	Mass     float32
 	Static   bool
+	Disabled bool // not simulated and ignored by collisions (e.g. parts of a group, which has one body for all)
 }
 
 // NewBody returns a body with the given position and scale. Velocity is zero.
@@ -0,0 +1,77 @@
+package physics
+
+// OBB is an oriented bounding box: a box of half-size Half around Center, rotated by Rotation.
+type OBB struct {
+	Center   [3]float32
+	Half     [3]float32
+	Rotation Quat
+}
+
+// OrientedBoxAt returns the box of size scale centered at center and rotated by rot. Zero components of
+// scale are treated as 1, as in BoxAt.
+func OrientedBoxAt(center, scale [3]float32, rot Quat) OBB {
+	b := OBB{Center: center, Rotation: rot}
+	for i, s := range scale {
+		if s == 0 {
+			s = 1
+		}
+		b.Half[i] = s * 0.5
+	}
+	return b
+}
+
+// Corners returns the eight corners of the box in world space. Corner i uses +Half on axis k when bit k
+// of i is set.
+func (b OBB) Corners() [8][3]float32 {
+	var out [8][3]float32
+	for i := range out {
+		var local [3]float32
+		for k := 0; k < 3; k++ {
+			local[k] = -b.Half[k]
+			if i&(1<<k) != 0 {
+				local[k] = b.Half[k]
+			}
+		}
+		p := b.Rotation.Rotate(local)
+		out[i] = [3]float32{b.Center[0] + p[0], b.Center[1] + p[1], b.Center[2] + p[2]}
+	}
+	return out
+}
+
+// AABB returns the smallest axis-aligned box containing b (used for physics and group bounds).
+func (b OBB) AABB() AABB {
+	var ext [3]float32
+	for k := 0; k < 3; k++ {
+		var axis [3]float32
+		axis[k] = b.Half[k]
+		r := b.Rotation.Rotate(axis)
+		for j := 0; j < 3; j++ {
+			if r[j] < 0 {
+				r[j] = -r[j]
+			}
+			ext[j] += r[j]
+		}
+	}
+	return AABB{
+		Min: [3]float32{b.Center[0] - ext[0], b.Center[1] - ext[1], b.Center[2] - ext[2]},
+		Max: [3]float32{b.Center[0] + ext[0], b.Center[1] + ext[1], b.Center[2] + ext[2]},
+	}
+}
+
+// IntersectRay is AABB.IntersectRay for the rotated box: the ray is tested in the box's own frame and the
+// hit point and face normal are returned in world space.
+func (b OBB) IntersectRay(origin, dir [3]float32) (RayHit, bool) {
+	inv := b.Rotation.Inverse()
+	lo := inv.Rotate([3]float32{origin[0] - b.Center[0], origin[1] - b.Center[1], origin[2] - b.Center[2]})
+	ld := inv.Rotate(dir)
+	local := AABB{Min: [3]float32{-b.Half[0], -b.Half[1], -b.Half[2]}, Max: b.Half}
+	hit, ok := local.IntersectRay(lo, ld)
+	if !ok {
+		return RayHit{}, false
+	}
+	for i := 0; i < 3; i++ {
+		hit.Point[i] = origin[i] + dir[i]*hit.Distance
+	}
+	hit.Normal = b.Rotation.Rotate(hit.Normal)
+	return hit, true
+}
@@ -0,0 +1,93 @@
+package physics
+
+import "math"
+
+// Quat is a rotation quaternion (x, y, z, w). The zero value is not a valid rotation; use QuatIdentity.
+type Quat [4]float32
+
+// QuatIdentity is the rotation that leaves vectors unchanged.
+var QuatIdentity = Quat{0, 0, 0, 1}
+
+// QuatFromEuler returns the rotation for Euler angles in degrees, applied about the world X axis, then Y,
+// then Z (the convention of the scene file's rotation field).
+func QuatFromEuler(deg [3]float32) Quat {
+	x := QuatAxisAngle([3]float32{1, 0, 0}, deg[0])
+	y := QuatAxisAngle([3]float32{0, 1, 0}, deg[1])
+	z := QuatAxisAngle([3]float32{0, 0, 1}, deg[2])
+	return z.Mul(y.Mul(x))
+}
+
+// QuatAxisAngle returns the rotation of deg degrees about axis (need not be normalized).
+func QuatAxisAngle(axis [3]float32, deg float32) Quat {
+	l := float32(math.Sqrt(float64(axis[0]*axis[0] + axis[1]*axis[1] + axis[2]*axis[2])))
+	if l == 0 || deg == 0 {
+		return QuatIdentity
+	}
+	half := float64(deg) * math.Pi / 360
+	s := float32(math.Sin(half)) / l
+	return Quat{axis[0] * s, axis[1] * s, axis[2] * s, float32(math.Cos(half))}
+}
+
+// Mul returns q*r: the rotation r followed by q.
+func (q Quat) Mul(r Quat) Quat {
+	return Quat{
+		q[3]*r[0] + q[0]*r[3] + q[1]*r[2] - q[2]*r[1],
+		q[3]*r[1] - q[0]*r[2] + q[1]*r[3] + q[2]*r[0],
+		q[3]*r[2] + q[0]*r[1] - q[1]*r[0] + q[2]*r[3],
+		q[3]*r[3] - q[0]*r[0] - q[1]*r[1] - q[2]*r[2],
+	}
+}
+
+// Inverse returns the opposite rotation (the conjugate; q is assumed normalized).
+func (q Quat) Inverse() Quat {
+	return Quat{-q[0], -q[1], -q[2], q[3]}
+}
+
+// Rotate returns v rotated by q.
+func (q Quat) Rotate(v [3]float32) [3]float32 {
+	// v' = v + w*t + u×t with u = (x,y,z), t = 2 u×v
+	t := [3]float32{
+		2 * (q[1]*v[2] - q[2]*v[1]),
+		2 * (q[2]*v[0] - q[0]*v[2]),
+		2 * (q[0]*v[1] - q[1]*v[0]),
+	}
+	return [3]float32{
+		v[0] + q[3]*t[0] + q[1]*t[2] - q[2]*t[1],
+		v[1] + q[3]*t[1] + q[2]*t[0] - q[0]*t[2],
+		v[2] + q[3]*t[2] + q[0]*t[1] - q[1]*t[0],
+	}
+}
+
+// Euler returns the rotation as Euler angles in degrees in the QuatFromEuler convention, rounded to
+// 1/1000 degree so saved scenes stay readable.
+func (q Quat) Euler() [3]float32 {
+	x, y, z, w := float64(q[0]), float64(q[1]), float64(q[2]), float64(q[3])
+	r20 := 2 * (x*z - w*y)
+	var ax, ay, az float64
+	if r20 > 0.99999 || r20 < -0.99999 {
+		// Gimbal lock (Y = ±90°): only X+Z is defined; put it all in X.
+		ay = -math.Copysign(math.Pi/2, r20)
+		ax = math.Atan2(-2*(y*z-w*x), 1-2*(x*x+z*z))
+	} else {
+		ay = math.Asin(-r20)
+		ax = math.Atan2(2*(y*z+w*x), 1-2*(x*x+y*y))
+		az = math.Atan2(2*(x*y+w*z), 1-2*(y*y+z*z))
+	}
+	deg := func(r float64) float32 {
+		d := math.Round(r*180/math.Pi*1000) / 1000
+		if d == 0 {
+			d = 0 // no -0 in saved scenes
+		}
+		return float32(d)
+	}
+	return [3]float32{deg(ax), deg(ay), deg(az)}
+}
+
+// Normalize returns q scaled to unit length, or QuatIdentity for a zero quaternion.
+func (q Quat) Normalize() Quat {
+	l := float32(math.Sqrt(float64(q[0]*q[0] + q[1]*q[1] + q[2]*q[2] + q[3]*q[3])))
+	if l == 0 {
+		return QuatIdentity
+	}
+	return Quat{q[0] / l, q[1] / l, q[2] / l, q[3] / l}
+}
@@ -1,9 +1,5 @@
 package physics
 
-import (
-	rl "github.com/gen2brain/raylib-go/raylib"
-)
-
 // World holds a set of bodies and runs a simple 3D physics step: gravity, integration, AABB collision.
 type World struct {
 	Gravity [3]float32
@@ -24,36 +20,32 @@ func (w *World) SetGravity(g [3]float32) {
 	w.Gravity = g
 }
 
-// AddBody appends a body to the world. Order is preserved for syncing with scene objects.
+// AddBody appends a body to the world.
 func (w *World) AddBody(b *Body) {
 	w.Bodies = append(w.Bodies, b)
 }
 
-// bodyAABB returns the AABB for a body (center position, half extents from scale).
-func bodyAABB(b *Body) rl.BoundingBox {
-	sx, sy, sz := b.Scale[0], b.Scale[1], b.Scale[2]
-	if sx == 0 {
-		sx = 1
-	}
-	if sy == 0 {
-		sy = 1
-	}
-	if sz == 0 {
-		sz = 1
+// RemoveBody removes b from the world (no-op if it is not in it).
+func (w *World) RemoveBody(b *Body) {
+	for i, o := range w.Bodies {
+		if o == b {
+			w.Bodies = append(w.Bodies[:i], w.Bodies[i+1:]...)
+			return
+		}
 	}
-	half := [3]float32{sx * 0.5, sy * 0.5, sz * 0.5}
-	return rl.NewBoundingBox(
-		rl.NewVector3(b.Position[0]-half[0], b.Position[1]-half[1], b.Position[2]-half[2]),
-		rl.NewVector3(b.Position[0]+half[0], b.Position[1]+half[1], b.Position[2]+half[2]),
-	)
+}
+
+// bodyAABB returns the AABB for a body (center position, half extents from scale).
+func bodyAABB(b *Body) AABB {
+	return BoxAt(b.Position, b.Scale)
 }
 
 // penetrationAxis returns the overlap amount and axis index (0=X, 1=Y, 2=Z) for the minimum penetration.
 // If no overlap, returns (0, -1).
-func penetrationAxis(a, b rl.BoundingBox) (depth float32, axis int) {
-	overlapX := min(a.Max.X, b.Max.X) - max(a.Min.X, b.Min.X)
-	overlapY := min(a.Max.Y, b.Max.Y) - max(a.Min.Y, b.Min.Y)
-	overlapZ := min(a.Max.Z, b.Max.Z) - max(a.Min.Z, b.Min.Z)
+func penetrationAxis(a, b AABB) (depth float32, axis int) {
+	overlapX := min(a.Max[0], b.Max[0]) - max(a.Min[0], b.Min[0])
+	overlapY := min(a.Max[1], b.Max[1]) - max(a.Min[1], b.Min[1])
+	overlapZ := min(a.Max[2], b.Max[2]) - max(a.Min[2], b.Min[2])
 	if overlapX <= 0 || overlapY <= 0 || overlapZ <= 0 {
 		return 0, -1
 	}
@@ -75,7 +67,7 @@ func penetrationAxis(a, b rl.BoundingBox) (depth float32, axis int) {
 func (w *World) Step(dt float32) {
 	// Apply gravity and integrate for dynamic bodies
 	for _, b := range w.Bodies {
-		if b.Static {
+		if b.Static || b.Disabled {
 			continue
 		}
 		b.Velocity[0] += w.Gravity[0] * dt
@@ -89,10 +81,13 @@ func (w *World) Step(dt float32) {
 	// AABB collision: resolve overlapping pairs (push apart along minimum penetration axis)
 	for i := 0; i < len(w.Bodies); i++ {
 		bi := w.Bodies[i]
+		if bi.Disabled {
+			continue
+		}
 		boxI := bodyAABB(bi)
 		for j := i + 1; j < len(w.Bodies); j++ {
 			bj := w.Bodies[j]
-			if !rl.CheckCollisionBoxes(boxI, bodyAABB(bj)) {
+			if bj.Disabled || !boxI.Overlaps(bodyAABB(bj)) {
 				continue
 			}
 			boxJ := bodyAABB(bj)
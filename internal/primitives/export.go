@@ -0,0 +1,75 @@
+package primitives
+
+import (
+	"unsafe"
+
+	"game-engine/internal/modelfile"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Geometry returns the mesh of a primitive type as Draw renders it, for export: the same generated mesh,
+// with the model-space offset Draw applies baked in, so a scale and position place it exactly as drawn, and
+// the UVs scaled by uv (a material's tiling). "terrain" is the installed heightmap mesh in world space. ok
+// is false for unknown types and for terrain when no heightmap is installed. Needs the GL context, like Draw.
+func (r *Registry) Geometry(primType string, uv [2]float32) (g modelfile.Geometry, ok bool) {
+	mesh, offset, ok := r.primitiveMesh(primType)
+	if !ok {
+		return g, false
+	}
+	g = meshGeometry(mesh, offset, uv)
+	return g, len(g.Positions) > 0
+}
+
+// ModelGeometry returns the meshes of a loaded model for export, each with the index of its material, with
+// offset added to every vertex (DrawModel's centering offset). A mesh without CPU-side vertices is empty.
+func ModelGeometry(m rl.Model, offset [3]float32) (meshes []modelfile.Geometry, materials []int) {
+	meshMaterial := unsafe.Slice(m.MeshMaterial, m.MeshCount)
+	for i, mesh := range m.GetMeshes() {
+		meshes = append(meshes, meshGeometry(mesh, offset, [2]float32{1, 1}))
+		materials = append(materials, int(meshMaterial[i]))
+	}
+	return meshes, materials
+}
+
+// BaseColor returns the color Draw gives an object with the given tint (nil = default): the default gray
+// untextured, white (the texture's own colors) textured. RGBA 0-1.
+func BaseColor(tint *[4]float32, textured bool) [4]float32 {
+	if tint != nil {
+		return *tint
+	}
+	if textured {
+		return [4]float32{1, 1, 1, 1}
+	}
+	c := defaultPrimitiveColor
+	return [4]float32{float32(c.R) / 255, float32(c.G) / 255, float32(c.B) / 255, float32(c.A) / 255}
+}
+
+// meshGeometry copies a mesh's CPU-side vertex data (raylib keeps it after upload), moving every vertex by
+// offset and scaling the UVs by uv.
+func meshGeometry(mesh rl.Mesh, offset [3]float32, uv [2]float32) modelfile.Geometry {
+	n := int(mesh.VertexCount)
+	var g modelfile.Geometry
+	if mesh.Vertices == nil || n == 0 {
+		return g
+	}
+	g.Positions = append([]float32(nil), unsafe.Slice(mesh.Vertices, 3*n)...)
+	for i := range g.Positions {
+		g.Positions[i] += offset[i%3]
+	}
+	if mesh.Normals != nil {
+		g.Normals = append([]float32(nil), unsafe.Slice(mesh.Normals, 3*n)...)
+	}
+	if mesh.Texcoords != nil {
+		g.UVs = append([]float32(nil), unsafe.Slice(mesh.Texcoords, 2*n)...)
+		for i := range g.UVs {
+			g.UVs[i] *= uv[i%2]
+		}
+	}
+	if mesh.Indices != nil {
+		for _, ix := range unsafe.Slice(mesh.Indices, 3*int(mesh.TriangleCount)) {
+			g.Indices = append(g.Indices, uint32(ix))
+		}
+	}
+	return g
+}
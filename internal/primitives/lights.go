@@ -0,0 +1,109 @@
+package primitives
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Lighting: the sun (a directional light with color and intensity, plus ambient light; see SetSunLight) and
+// up to MaxLights local point and spot lights (SetLights), evaluated per fragment by every lit shader in the
+// same pass (forward rendering). Local lights fade to zero at their range and cast no shadows.
+
+// MaxLights is how many local lights the lit shaders evaluate; SetLights keeps the first MaxLights.
+const MaxLights = 8
+
+// Light is a local light for SetLights.
+type Light struct {
+	Spot      bool       // false = point light
+	Position  [3]float32 // world space
+	Direction [3]float32 // spot only: direction the cone points (unit length)
+	Color     [3]float32 // RGB 0-1
+	Intensity float32    // brightness at the light; falls off as (1 - distance/range)^2
+	Range     float32    // world units
+	Cone      float32    // spot only: full cone angle in degrees
+}
+
+// lightUniforms is the packed uniform data for the local lights of this frame (see lightsGLSL).
+type lightUniforms struct {
+	count    int
+	posRange [4 * MaxLights]float32 // xyz position, w range
+	radiance [3 * MaxLights]float32 // color * intensity
+	dirCone  [4 * MaxLights]float32 // xyz spot direction, w cos of the half cone angle (-2 = point light)
+}
+
+// SetSunLight sets the directional light's color and intensity and the ambient light (RGB 0-1). The
+// direction is set each frame by SetView.
+func (r *Registry) SetSunLight(color [3]float32, intensity float32, ambient [3]float32) {
+	r.sunColor = color
+	r.sunIntensity = intensity
+	r.ambient = ambient
+}
+
+// SetLights sets the local lights for the following draws; only the first MaxLights are used, so pass the
+// most important (nearest) first. Lights with no intensity or range are skipped.
+func (r *Registry) SetLights(lights []Light) {
+	u := &r.lights
+	u.count = 0
+	for _, l := range lights {
+		if u.count == MaxLights {
+			break
+		}
+		if l.Intensity <= 0 || l.Range <= 0 {
+			continue
+		}
+		i := u.count
+		copy(u.posRange[4*i:], []float32{l.Position[0], l.Position[1], l.Position[2], l.Range})
+		copy(u.radiance[3*i:], []float32{l.Color[0] * l.Intensity, l.Color[1] * l.Intensity, l.Color[2] * l.Intensity})
+		cosHalf := float32(-2)
+		if l.Spot {
+			cosHalf = float32(math.Cos(float64(l.Cone) / 2 * math.Pi / 180))
+		}
+		copy(u.dirCone[4*i:], []float32{l.Direction[0], l.Direction[1], l.Direction[2], cosHalf})
+		u.count++
+	}
+}
+
+// setLightUniforms sets the local light uniforms of a lit shader.
+func (r *Registry) setLightUniforms(shader rl.Shader) {
+	u := &r.lights
+	if loc := rl.GetShaderLocation(shader, "lightCount"); loc >= 0 {
+		rl.SetShaderValue(shader, loc, []float32{float32(u.count)}, rl.ShaderUniformFloat)
+	}
+	if u.count == 0 {
+		return
+	}
+	n := int32(u.count)
+	if loc := rl.GetShaderLocation(shader, "lightPosRange"); loc >= 0 {
+		rl.SetShaderValueV(shader, loc, u.posRange[:], rl.ShaderUniformVec4, n)
+	}
+	if loc := rl.GetShaderLocation(shader, "lightRadiance"); loc >= 0 {
+		rl.SetShaderValueV(shader, loc, u.radiance[:], rl.ShaderUniformVec3, n)
+	}
+	if loc := rl.GetShaderLocation(shader, "lightDirCone"); loc >= 0 {
+		rl.SetShaderValueV(shader, loc, u.dirCone[:], rl.ShaderUniformVec4, n)
+	}
+}
+
+// lightsGLSL declares the local light uniforms and localLight, which returns the light arriving at the
+// fragment from light i and sets L to the direction towards it. Spot cones fade over their outer quarter.
+// MAX_LIGHTS is MaxLights.
+const lightsGLSL = `#define MAX_LIGHTS 8
+uniform float lightCount;
+uniform vec4 lightPosRange[MAX_LIGHTS];
+uniform vec3 lightRadiance[MAX_LIGHTS];
+uniform vec4 lightDirCone[MAX_LIGHTS];
+vec3 localLight(int i, out vec3 L) {
+  vec3 d = lightPosRange[i].xyz - fragPosition;
+  float dist = length(d);
+  L = d / max(dist, 1e-4);
+  float att = clamp(1.0 - dist / lightPosRange[i].w, 0.0, 1.0);
+  att *= att;
+  float cosHalf = lightDirCone[i].w;
+  if (cosHalf > -1.5) {
+    float edge = max((1.0 - cosHalf) * 0.25, 1e-4);
+    att *= smoothstep(cosHalf, cosHalf + edge, dot(-L, lightDirCone[i].xyz));
+  }
+  return lightRadiance[i] * att;
+}
+`
@@ -0,0 +1,74 @@
+package primitives
+
+import (
+	"unsafe"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// minModelExtent keeps flat models (e.g. a single quad) from dividing by zero when normalized.
+const minModelExtent = 1e-4
+
+// PrepareModel switches every material of a loaded model (glTF, OBJ) to the lit textured shader so models
+// are shaded like primitives. The materials keep their albedo textures and colors; materials without a
+// texture use raylib's default white texture. Call once after rl.LoadModel.
+func (r *Registry) PrepareModel(m *rl.Model) {
+	if !rl.IsShaderValid(r.modelShader) {
+		r.modelShader = loadLitTexturedShader()
+	}
+	if !rl.IsShaderValid(r.modelShader) {
+		return
+	}
+	for i := range m.GetMaterials() {
+		m.GetMaterials()[i].Shader = r.modelShader
+	}
+}
+
+// DrawModel draws a loaded model so that its bounds fill the box of the given size (scale, as for
+// primitives) centered at position, rotated by the quaternion rotation. Each mesh keeps its material's
+// color and texture; tint (RGBA 0-1, nil = none) multiplies them. colors are the materials' own albedo
+// colors (saved when the model was loaded, since the tint is written into the material).
+func (r *Registry) DrawModel(m rl.Model, bounds rl.BoundingBox, colors []rl.Color, position, scale [3]float32, rotation [4]float32, tint *[4]float32) {
+	lo, hi := bounds.Min, bounds.Max
+	extent := [3]float32{max(hi.X-lo.X, minModelExtent), max(hi.Y-lo.Y, minModelExtent), max(hi.Z-lo.Z, minModelExtent)}
+	for i := range scale {
+		if scale[i] == 0 {
+			scale[i] = 1
+		}
+	}
+	offset := [3]float32{-(lo.X + hi.X) / 2, -(lo.Y + hi.Y) / 2, -(lo.Z + hi.Z) / 2}
+	transform := modelTransform(position, [3]float32{scale[0] / extent[0], scale[1] / extent[1], scale[2] / extent[2]}, rotation, offset)
+	mul := [4]float32{1, 1, 1, 1}
+	if tint != nil {
+		mul = *tint
+	}
+	materials := m.GetMaterials()
+	meshMaterial := unsafe.Slice(m.MeshMaterial, m.MeshCount)
+	for i, mesh := range m.GetMeshes() {
+		if r.shadow.pass {
+			r.drawDepth(mesh, transform)
+			continue
+		}
+		k := int(meshMaterial[i])
+		if k < 0 || k >= len(materials) {
+			k = 0
+		}
+		mtl := materials[k]
+		base := rl.White
+		if k < len(colors) {
+			base = colors[k]
+		}
+		c := [4]float32{float32(base.R) / 255 * mul[0], float32(base.G) / 255 * mul[1], float32(base.B) / 255 * mul[2], float32(base.A) / 255 * mul[3]}
+		if albedo := mtl.GetMap(rl.MapAlbedo); albedo != nil {
+			albedo.Color = tintToColor(&c)
+		}
+		r.setLitShaderUniforms(mtl.Shader)
+		r.setColDiffuse(mtl.Shader, c)
+		if loc := rl.GetShaderLocation(mtl.Shader, "uvScale"); loc >= 0 {
+			uv := [2]float32{1, 1}
+			rl.SetShaderValueV(mtl.Shader, loc, uv[:], rl.ShaderUniformVec2, 1)
+		}
+		r.bindShadowMap(&mtl)
+		rl.DrawMesh(mesh, mtl, transform)
+	}
+}
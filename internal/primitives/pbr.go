@@ -0,0 +1,204 @@
+package primitives
+
+import (
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// PBRMaterial is what DrawPBR needs of a material: metallic-roughness parameters as in glTF, and GPU
+// textures (the zero Texture2D = none).
+type PBRMaterial struct {
+	Albedo               [4]float32 // base color RGBA 0-1, multiplied by AlbedoMap
+	AlbedoMap            rl.Texture2D
+	NormalMap            rl.Texture2D // tangent space, OpenGL convention (+Y up)
+	MetallicRoughnessMap rl.Texture2D // G = roughness, B = metallic, multiplied by Roughness and Metallic
+	EmissiveMap          rl.Texture2D // multiplied by Emissive
+	Metallic             float32
+	Roughness            float32
+	Emissive             [3]float32
+	UVScale              [2]float32 // texture repeats (u, v)
+}
+
+// primitiveMesh returns the mesh of a primitive type and the model-space offset that centers it (see
+// drawCached), creating it on first use. "terrain" is the installed heightmap mesh, if any.
+func (r *Registry) primitiveMesh(primType string) (rl.Mesh, [3]float32, bool) {
+	var offset [3]float32
+	switch primType {
+	case "cube":
+		r.ensureCube()
+	case "sphere":
+		r.ensureSphere()
+	case "cylinder":
+		r.ensureCylinder()
+		offset = [3]float32{0, -0.5, 0}
+	case "plane":
+		r.ensurePlane()
+	case "terrain":
+	default:
+		return rl.Mesh{}, offset, false
+	}
+	c, ok := r.cache[primType]
+	return c.mesh, offset, ok
+}
+
+// ensurePBR creates the shared PBR material on first use. Its texture slots are bound to the shader's
+// samplers through the shader's map locations, which DrawMesh uses to bind every map of the material.
+func (r *Registry) ensurePBR() bool {
+	if r.pbrLoaded {
+		return rl.IsShaderValid(r.pbrMtl.Shader)
+	}
+	r.pbrLoaded = true
+	shader := useShadowMapSlot(rl.LoadShaderFromMemory(litVS, pbrFS))
+	if !rl.IsShaderValid(shader) {
+		return false
+	}
+	shader.UpdateLocation(rl.ShaderLocMapAlbedo, rl.GetShaderLocation(shader, "albedoMap"))
+	shader.UpdateLocation(rl.ShaderLocMapMetalness, rl.GetShaderLocation(shader, "metallicRoughnessMap"))
+	shader.UpdateLocation(rl.ShaderLocMapNormal, rl.GetShaderLocation(shader, "normalMap"))
+	shader.UpdateLocation(rl.ShaderLocMapEmission, rl.GetShaderLocation(shader, "emissiveMap"))
+	r.pbrMtl = rl.LoadMaterialDefault()
+	r.pbrMtl.Shader = shader
+	return true
+}
+
+// whiteTexture is raylib's default 1x1 white texture, bound in place of missing maps so they multiply by 1.
+func whiteTexture() rl.Texture2D {
+	return rl.Texture2D{ID: rl.GetTextureIdDefault(), Width: 1, Height: 1, Mipmaps: 1, Format: rl.UncompressedR8g8b8a8}
+}
+
+// DrawPBR draws one instance of the given type (see Draw) with a PBR material: Cook-Torrance (GGX)
+// specular and Lambert diffuse for the directional light and the local lights, the ambient term, emission, and an optional normal
+// map (tangent frame from screen-space derivatives, so meshes need no tangents). Falls back to Draw with the
+// albedo color if the shader cannot be compiled. SetView must be called once per frame before drawing.
+func (r *Registry) DrawPBR(primType string, position, scale [3]float32, rotation [4]float32, m *PBRMaterial) {
+	mesh, offset, ok := r.primitiveMesh(primType)
+	if !ok {
+		return
+	}
+	if r.shadow.pass {
+		r.drawDepth(mesh, modelTransform(position, scale, rotation, offset))
+		return
+	}
+	if !r.ensurePBR() {
+		tint := m.Albedo
+		r.Draw(primType, position, scale, rotation, &tint)
+		return
+	}
+	white := whiteTexture()
+	maps := []struct {
+		slot int32
+		tex  rl.Texture2D
+	}{{rl.MapAlbedo, m.AlbedoMap}, {rl.MapMetalness, m.MetallicRoughnessMap}, {rl.MapNormal, m.NormalMap}, {rl.MapEmission, m.EmissiveMap}}
+	for _, mp := range maps {
+		tex := mp.tex
+		if !rl.IsTextureValid(tex) {
+			tex = white
+		}
+		rl.SetMaterialTexture(&r.pbrMtl, mp.slot, tex)
+	}
+	if albedo := r.pbrMtl.GetMap(rl.MapAlbedo); albedo != nil {
+		albedo.Color = tintToColor(&m.Albedo)
+	}
+	shader := r.pbrMtl.Shader
+	r.setLitShaderUniforms(shader)
+	r.setColDiffuse(shader, m.Albedo)
+	useNormalMap := float32(0)
+	if rl.IsTextureValid(m.NormalMap) {
+		useNormalMap = 1
+	}
+	if loc := rl.GetShaderLocation(shader, "useNormalMap"); loc >= 0 {
+		rl.SetShaderValue(shader, loc, []float32{useNormalMap}, rl.ShaderUniformFloat)
+	}
+	if loc := rl.GetShaderLocation(shader, "metallic"); loc >= 0 {
+		rl.SetShaderValue(shader, loc, []float32{m.Metallic}, rl.ShaderUniformFloat)
+	}
+	if loc := rl.GetShaderLocation(shader, "roughness"); loc >= 0 {
+		rl.SetShaderValue(shader, loc, []float32{m.Roughness}, rl.ShaderUniformFloat)
+	}
+	if loc := rl.GetShaderLocation(shader, "emissive"); loc >= 0 {
+		emissive := [3]float32{m.Emissive[0], m.Emissive[1], m.Emissive[2]}
+		rl.SetShaderValueV(shader, loc, emissive[:], rl.ShaderUniformVec3, 1)
+	}
+	if loc := rl.GetShaderLocation(shader, "uvScale"); loc >= 0 {
+		uv := [2]float32{m.UVScale[0], m.UVScale[1]}
+		rl.SetShaderValueV(shader, loc, uv[:], rl.ShaderUniformVec2, 1)
+	}
+	r.bindShadowMap(&r.pbrMtl)
+	rl.DrawMesh(mesh, r.pbrMtl, modelTransform(position, scale, rotation, offset))
+}
+
+// pbrFS is the PBR fragment shader (metallic-roughness, the directional light, local lights and ambient).
+// Light intensity is scaled by pi so a rough white dielectric is about as bright as with the plain lit shader.
+const pbrFS = `#version 330
+in vec3 fragPosition;
+in vec2 fragTexCoord;
+in vec3 fragNormal;
+uniform vec4 colDiffuse;
+uniform vec3 viewPos;
+uniform vec3 lightDir;
+uniform vec4 ambient;
+uniform vec3 lightColor;
+uniform float lightIntensity;
+uniform sampler2D albedoMap;
+uniform sampler2D metallicRoughnessMap;
+uniform sampler2D normalMap;
+uniform sampler2D emissiveMap;
+uniform float useNormalMap;
+uniform float metallic;
+uniform float roughness;
+uniform vec3 emissive;
+uniform vec2 uvScale;
+out vec4 finalColor;
+const float PI = 3.14159265;
+` + shadowGLSL + lightsGLSL + `
+vec3 perturbNormal(vec3 N, vec3 p, vec2 uv) {
+  vec3 dp1 = dFdx(p);
+  vec3 dp2 = dFdy(p);
+  vec2 duv1 = dFdx(uv);
+  vec2 duv2 = dFdy(uv);
+  vec3 dp2perp = cross(dp2, N);
+  vec3 dp1perp = cross(N, dp1);
+  vec3 T = dp2perp * duv1.x + dp1perp * duv2.x;
+  vec3 B = dp2perp * duv1.y + dp1perp * duv2.y;
+  float invmax = inversesqrt(max(max(dot(T, T), dot(B, B)), 1e-12));
+  vec3 n = texture(normalMap, uv).xyz * 2.0 - 1.0;
+  return normalize(mat3(T * invmax, B * invmax, N) * n);
+}
+// brdf returns the light reflected towards V from a light of unit radiance in direction L, times N.L.
+vec3 brdf(vec3 N, vec3 V, vec3 L, vec3 base, float rough, float metal) {
+  vec3 H = normalize(L + V);
+  float NdotL = max(dot(N, L), 0.0);
+  float NdotV = max(dot(N, V), 0.001);
+  float NdotH = max(dot(N, H), 0.0);
+  float HdotV = max(dot(H, V), 0.0);
+  vec3 F0 = mix(vec3(0.04), base, metal);
+  float a2 = rough * rough * rough * rough;
+  float d = NdotH * NdotH * (a2 - 1.0) + 1.0;
+  float D = a2 / (PI * d * d);
+  float k = (rough + 1.0) * (rough + 1.0) / 8.0;
+  float G = (NdotV / (NdotV * (1.0 - k) + k)) * (NdotL / (NdotL * (1.0 - k) + k));
+  vec3 F = F0 + (1.0 - F0) * pow(1.0 - HdotV, 5.0);
+  vec3 specular = D * G * F / (4.0 * NdotV * max(NdotL, 0.001));
+  vec3 kd = (1.0 - F) * (1.0 - metal);
+  return (kd * base + PI * specular) * NdotL;
+}
+void main() {
+  vec2 uv = fragTexCoord * uvScale;
+  vec4 base = texture(albedoMap, uv) * colDiffuse;
+  vec4 mr = texture(metallicRoughnessMap, uv);
+  float rough = clamp(roughness * mr.g, 0.04, 1.0);
+  float metal = clamp(metallic * mr.b, 0.0, 1.0);
+  vec3 N = normalize(fragNormal);
+  if (useNormalMap > 0.5) N = perturbNormal(N, fragPosition, uv);
+  vec3 V = normalize(viewPos - fragPosition);
+  vec3 direct = brdf(N, V, normalize(lightDir), base.rgb, rough, metal) * lightColor * lightIntensity * shadowFactor(normalize(fragNormal));
+  for (int i = 0; i < MAX_LIGHTS; i++) {
+    if (float(i) >= lightCount) break;
+    vec3 L;
+    vec3 radiance = localLight(i, L);
+    direct += brdf(N, V, L, base.rgb, rough, metal) * radiance;
+  }
+  vec3 amb = ambient.rgb * base.rgb;
+  vec3 glow = emissive * texture(emissiveMap, uv).rgb;
+  finalColor = vec4(amb + direct + glow, base.a);
+}
+`
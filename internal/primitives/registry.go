@@ -7,26 +7,36 @@ import (
 // cached holds mesh and material for a primitive type. Created lazily on first Draw.
 // texturedMtl is used when drawing with an albedo texture (same mesh, different material).
 type cached struct {
-	mesh       rl.Mesh
-	mtl        rl.Material
+	mesh        rl.Mesh
+	mtl         rl.Material
 	texturedMtl rl.Material
 }
 
 // Registry maps primitive type names to mesh+material. Meshes are created on first use
 // so that GPU resources are allocated after the window/OpenGL context exists.
 type Registry struct {
-	cache          map[string]cached
-	viewPos        [3]float32 // camera position, set each frame for lighting
-	lightDir       [3]float32 // direction to light (normalized), set each frame
-	terrainUVScale [2]float32 // UV tiling for terrain mesh (u,v); defaults to (1,1)
+	cache        map[string]cached
+	viewPos      [3]float32  // camera position, set each frame for lighting
+	lightDir     [3]float32  // direction to light (normalized), set each frame
+	modelShader  rl.Shader   // lit textured shader shared by every loaded model's materials (see model.go)
+	pbrMtl       rl.Material // material with the PBR shader, shared by every DrawPBR call (see pbr.go)
+	pbrLoaded    bool
+	shadow       shadowState // directional light shadow map (see shadow.go)
+	sunColor     [3]float32  // directional light color, intensity and ambient light (see SetSunLight)
+	sunIntensity float32
+	ambient      [3]float32
+	lights       lightUniforms // local point and spot lights (see lights.go)
 }
 
 // NewRegistry returns a registry with no primitives. Cube is created on first Draw.
 func NewRegistry() *Registry {
 	return &Registry{
-		cache:          make(map[string]cached),
-		lightDir:       [3]float32{0.5, 1, 0.5}, // default: from above-right
-		terrainUVScale: [2]float32{1, 1},
+		cache:        make(map[string]cached),
+		lightDir:     [3]float32{0.5, 1, 0.5}, // default: from above-right
+		shadow:       shadowState{enabled: true, quality: DefaultShadowQuality},
+		sunColor:     defaultLightColor,
+		sunIntensity: defaultLightIntensity,
+		ambient:      defaultAmbient,
 	}
 }
 
@@ -36,8 +46,8 @@ func NewRegistry() *Registry {
 func (r *Registry) SetTerrainMesh(mesh rl.Mesh) {
 	if c, ok := r.cache["terrain"]; ok {
 		rl.UnloadMesh(&c.mesh)
-		rl.UnloadMaterial(c.mtl)
-		rl.UnloadMaterial(c.texturedMtl)
+		unloadMaterial(c.mtl)
+		unloadMaterial(c.texturedMtl)
 		delete(r.cache, "terrain")
 	}
 	mtl := rl.LoadMaterialDefault()
@@ -56,7 +66,6 @@ func (r *Registry) SetTerrainMesh(mesh rl.Mesh) {
 		texturedMtl.Shader = ts
 	}
 	r.cache["terrain"] = cached{mesh: mesh, mtl: mtl, texturedMtl: texturedMtl}
-	r.terrainUVScale = [2]float32{1, 1}
 }
 
 // ClearTerrain removes the terrain mesh from the cache and unloads GPU resources.
@@ -64,29 +73,18 @@ func (r *Registry) SetTerrainMesh(mesh rl.Mesh) {
 func (r *Registry) ClearTerrain() {
 	if c, ok := r.cache["terrain"]; ok {
 		rl.UnloadMesh(&c.mesh)
-		rl.UnloadMaterial(c.mtl)
-		rl.UnloadMaterial(c.texturedMtl)
+		unloadMaterial(c.mtl)
+		unloadMaterial(c.texturedMtl)
 		delete(r.cache, "terrain")
 	}
 }
 
-// SetTerrainUVScale sets how many times the terrain texture repeats across the X/Z extent.
-// For example, (4,4) tiles the texture 4x4 across the heightmap; (1,1) is the default (stretched).
-func (r *Registry) SetTerrainUVScale(u, v float32) {
-	if u <= 0 {
-		u = 1
-	}
-	if v <= 0 {
-		v = 1
-	}
-	r.terrainUVScale = [2]float32{u, v}
-}
-
 // SetView sets camera position and direction-to-light for this frame. Call once per frame
-// before drawing objects so lit primitives (e.g. cube) get correct shading.
+// before drawing objects (and before BeginShadowPass) so lit primitives (e.g. cube) get correct shading.
 func (r *Registry) SetView(viewPos, lightDir [3]float32) {
 	r.viewPos = viewPos
 	r.lightDir = lightDir
+	r.shadow.ready = false
 }
 
 // defaultPrimitiveColor is the albedo tint for cube and sphere (basic material).
@@ -207,13 +205,13 @@ func (r *Registry) ensurePlane() {
 // loadLitShader returns a shader that does simple directional light + ambient.
 // Used by cube and sphere. Same vertex attributes as raylib meshes: vertexPosition, vertexTexCoord, vertexNormal.
 func loadLitShader() rl.Shader {
-	return rl.LoadShaderFromMemory(litVS, litFS)
+	return useShadowMapSlot(rl.LoadShaderFromMemory(litVS, litFS))
 }
 
 // loadLitTexturedShader returns a shader that samples albedo texture and applies directional light + ambient.
 // Used when drawing primitives with a texture (MapAlbedo set on material).
 func loadLitTexturedShader() rl.Shader {
-	return rl.LoadShaderFromMemory(litVS, litTexturedFS)
+	return useShadowMapSlot(rl.LoadShaderFromMemory(litVS, litTexturedFS))
 }
 
 const (
@@ -248,7 +246,7 @@ uniform float lightIntensity;
 uniform float specularPower;
 uniform float specularStrength;
 out vec4 finalColor;
-void main() {
+` + shadowGLSL + litLightsGLSL + `void main() {
   vec4 tint = colDiffuse;
   vec3 N = normalize(fragNormal);
   vec3 L = normalize(lightDir);
@@ -260,7 +258,7 @@ void main() {
   float NdotH = max(dot(N, H), 0.0);
   float spec = pow(NdotH, specularPower) * specularStrength;
   vec3 specular = lightColor * spec * (NdotL > 0.0 ? 1.0 : 0.0);
-  finalColor = vec4(amb + diffuse + specular, tint.a);
+  finalColor = vec4(amb + (diffuse + specular) * shadowFactor(N) + localLights(N, V, tint.rgb), tint.a);
 }
 `
 	// litTexturedFS: same as litFS but tint from albedo texture * colDiffuse (for textured primitives).
@@ -279,7 +277,7 @@ uniform float specularStrength;
 uniform sampler2D albedoMap;
 uniform vec2 uvScale;
 out vec4 finalColor;
-void main() {
+` + shadowGLSL + litLightsGLSL + `void main() {
   vec2 uv = fragTexCoord * uvScale;
   vec4 texColor = texture(albedoMap, uv);
   vec4 tint = texColor * colDiffuse;
@@ -293,13 +291,29 @@ void main() {
   float NdotH = max(dot(N, H), 0.0);
   float spec = pow(NdotH, specularPower) * specularStrength;
   vec3 specular = lightColor * spec * (NdotL > 0.0 ? 1.0 : 0.0);
-  finalColor = vec4(amb + diffuse + specular, tint.a);
+  finalColor = vec4(amb + (diffuse + specular) * shadowFactor(N) + localLights(N, V, tint.rgb), tint.a);
 }
 `
 )
 
+// litLightsGLSL adds the local lights (see lights.go) to the lit shaders, with the same Blinn-Phong terms
+// as the directional light.
+const litLightsGLSL = lightsGLSL + `vec3 localLights(vec3 N, vec3 V, vec3 albedo) {
+  vec3 sum = vec3(0.0);
+  for (int i = 0; i < MAX_LIGHTS; i++) {
+    if (float(i) >= lightCount) break;
+    vec3 L;
+    vec3 radiance = localLight(i, L);
+    float NdotL = max(dot(N, L), 0.0);
+    float spec = NdotL > 0.0 ? pow(max(dot(N, normalize(L + V)), 0.0), specularPower) * specularStrength : 0.0;
+    sum += (albedo * NdotL + spec) * radiance;
+  }
+  return sum;
+}
+`
+
 // defaultAmbient is the ambient term (dim so shadowed areas aren't pure black).
-var defaultAmbient = [4]float32{0.2, 0.22, 0.26, 1.0}
+var defaultAmbient = [3]float32{0.2, 0.22, 0.26}
 
 // defaultLightColor is a soft warm-white for the directional light.
 var defaultLightColor = [3]float32{1.0, 0.98, 0.95}
@@ -313,15 +327,15 @@ const defaultSpecularPower = float32(48.0)
 // defaultSpecularStrength scales specular contribution (0–1).
 const defaultSpecularStrength = float32(0.35)
 
-// setLitShaderUniforms sets viewPos, lightDir, ambient, light color/intensity, and specular on the given shader (cgo-safe: local arrays).
+// setLitShaderUniforms sets viewPos, lightDir, ambient, light color/intensity, specular and the local lights on the given shader (cgo-safe: local arrays).
 func (r *Registry) setLitShaderUniforms(shader rl.Shader) {
 	if !rl.IsShaderValid(shader) {
 		return
 	}
 	viewPos := [3]float32{r.viewPos[0], r.viewPos[1], r.viewPos[2]}
 	lightDir := [3]float32{r.lightDir[0], r.lightDir[1], r.lightDir[2]}
-	amb := [4]float32{defaultAmbient[0], defaultAmbient[1], defaultAmbient[2], defaultAmbient[3]}
-	lightColor := [3]float32{defaultLightColor[0], defaultLightColor[1], defaultLightColor[2]}
+	amb := [4]float32{r.ambient[0], r.ambient[1], r.ambient[2], 1}
+	lightColor := [3]float32{r.sunColor[0], r.sunColor[1], r.sunColor[2]}
 	if loc := rl.GetShaderLocation(shader, "viewPos"); loc >= 0 {
 		rl.SetShaderValueV(shader, loc, viewPos[:], rl.ShaderUniformVec3, 1)
 	}
@@ -335,7 +349,7 @@ func (r *Registry) setLitShaderUniforms(shader rl.Shader) {
 		rl.SetShaderValueV(shader, loc, lightColor[:], rl.ShaderUniformVec3, 1)
 	}
 	if loc := rl.GetShaderLocation(shader, "lightIntensity"); loc >= 0 {
-		rl.SetShaderValue(shader, loc, []float32{defaultLightIntensity}, rl.ShaderUniformFloat)
+		rl.SetShaderValue(shader, loc, []float32{r.sunIntensity}, rl.ShaderUniformFloat)
 	}
 	if loc := rl.GetShaderLocation(shader, "specularPower"); loc >= 0 {
 		rl.SetShaderValue(shader, loc, []float32{defaultSpecularPower}, rl.ShaderUniformFloat)
@@ -343,6 +357,7 @@ func (r *Registry) setLitShaderUniforms(shader rl.Shader) {
 	if loc := rl.GetShaderLocation(shader, "specularStrength"); loc >= 0 {
 		rl.SetShaderValue(shader, loc, []float32{defaultSpecularStrength}, rl.ShaderUniformFloat)
 	}
+	r.setLightUniforms(shader)
 }
 
 // setColDiffuse sets the colDiffuse uniform (RGBA 0-1) for per-object tint. Call before DrawMesh when using tint.
@@ -364,12 +379,29 @@ func tintToColor(tint *[4]float32) rl.Color {
 	return rl.NewColor(r, g, b, a)
 }
 
-// drawCached draws a cached mesh with the given key at position and scale (scale 0 → 1).
+// modelTransform returns the model matrix for a primitive: offset (center the mesh), then scale (0 → 1),
+// then rotate (quaternion x, y, z, w), then translate to position. raylib's MatrixMultiply(a, b) applies a first.
+func modelTransform(position, scale [3]float32, rotation [4]float32, modelCenterOffset [3]float32) rl.Matrix {
+	for i := range scale {
+		if scale[i] == 0 {
+			scale[i] = 1
+		}
+	}
+	transform := rl.MatrixTranslate(modelCenterOffset[0], modelCenterOffset[1], modelCenterOffset[2])
+	transform = rl.MatrixMultiply(transform, rl.MatrixScale(scale[0], scale[1], scale[2]))
+	if rotation != [4]float32{0, 0, 0, 1} && rotation != ([4]float32{}) {
+		q := rl.NewQuaternion(rotation[0], rotation[1], rotation[2], rotation[3])
+		transform = rl.MatrixMultiply(transform, rl.QuaternionToMatrix(q))
+	}
+	return rl.MatrixMultiply(transform, rl.MatrixTranslate(position[0], position[1], position[2]))
+}
+
+// drawCached draws a cached mesh with the given key at position, scale (scale 0 → 1) and rotation.
 // modelCenterOffset shifts the mesh in model space before scale/translate so the scene position
 // is the primitive's center. Use (0,0,0) for cube/sphere (already centered); (0,-0.5,0) for cylinder
 // (raylib cylinder has base at Y=0, top at Y=height, so offset -height/2 centers it).
 // tint is optional (nil = default material color); otherwise RGBA 0-1.
-func (r *Registry) drawCached(key string, position, scale [3]float32, modelCenterOffset [3]float32, tint *[4]float32) {
+func (r *Registry) drawCached(key string, position, scale [3]float32, rotation [4]float32, modelCenterOffset [3]float32, tint *[4]float32) {
 	c, ok := r.cache[key]
 	if !ok {
 		return
@@ -382,37 +414,27 @@ func (r *Registry) drawCached(key string, position, scale [3]float32, modelCente
 	if tint != nil {
 		defaultTint = *tint
 	}
+	transform := modelTransform(position, scale, rotation, modelCenterOffset)
+	if r.shadow.pass {
+		r.drawDepth(c.mesh, transform)
+		return
+	}
 	r.setLitShaderUniforms(c.mtl.Shader)
 	r.setColDiffuse(c.mtl.Shader, defaultTint)
-	sx, sy, sz := scale[0], scale[1], scale[2]
-	if sx == 0 {
-		sx = 1
-	}
-	if sy == 0 {
-		sy = 1
-	}
-	if sz == 0 {
-		sz = 1
-	}
-	scaleM := rl.MatrixScale(sx, sy, sz)
-	transM := rl.MatrixTranslate(position[0], position[1], position[2])
-	var transform rl.Matrix
-	if modelCenterOffset[0] != 0 || modelCenterOffset[1] != 0 || modelCenterOffset[2] != 0 {
-		offsetM := rl.MatrixTranslate(modelCenterOffset[0], modelCenterOffset[1], modelCenterOffset[2])
-		// Order: offset (center mesh), then scale, then translate to position.
-		transform = rl.MatrixMultiply(rl.MatrixMultiply(transM, scaleM), offsetM)
-	} else {
-		transform = rl.MatrixMultiply(scaleM, transM)
-	}
+	r.bindShadowMap(&c.mtl)
 	rl.DrawMesh(c.mesh, c.mtl, transform)
 }
 
 // drawCachedWithTexture draws a cached mesh with textured material. with the given key using the textured material and the given albedo texture.
-func (r *Registry) drawCachedWithTexture(key string, position, scale [3]float32, modelCenterOffset [3]float32, tex rl.Texture2D, tint *[4]float32) {
+func (r *Registry) drawCachedWithTexture(key string, position, scale [3]float32, rotation [4]float32, modelCenterOffset [3]float32, tex rl.Texture2D, tint *[4]float32) {
 	c, ok := r.cache[key]
 	if !ok {
 		return
 	}
+	if r.shadow.pass {
+		r.drawDepth(c.mesh, modelTransform(position, scale, rotation, modelCenterOffset))
+		return
+	}
 	// For terrain we want the texture to repeat when UVs go beyond 0-1.
 	if key == "terrain" {
 		rl.SetTextureWrap(tex, rl.TextureWrapRepeat)
@@ -427,84 +449,64 @@ func (r *Registry) drawCachedWithTexture(key string, position, scale [3]float32,
 	}
 	r.setLitShaderUniforms(c.texturedMtl.Shader)
 	r.setColDiffuse(c.texturedMtl.Shader, defaultTint)
-	// UV tiling: terrain can repeat its texture; other primitives use (1,1).
+	// A plain texture is stretched once; tiling is a material setting (see DrawPBR).
 	uv := [2]float32{1, 1}
-	if key == "terrain" {
-		uv = r.terrainUVScale
-	}
 	if loc := rl.GetShaderLocation(c.texturedMtl.Shader, "uvScale"); loc >= 0 {
 		rl.SetShaderValueV(c.texturedMtl.Shader, loc, uv[:], rl.ShaderUniformVec2, 1)
 	}
-	sx, sy, sz := scale[0], scale[1], scale[2]
-	if sx == 0 {
-		sx = 1
-	}
-	if sy == 0 {
-		sy = 1
-	}
-	if sz == 0 {
-		sz = 1
-	}
-	scaleM := rl.MatrixScale(sx, sy, sz)
-	transM := rl.MatrixTranslate(position[0], position[1], position[2])
-	var transform rl.Matrix
-	if modelCenterOffset[0] != 0 || modelCenterOffset[1] != 0 || modelCenterOffset[2] != 0 {
-		offsetM := rl.MatrixTranslate(modelCenterOffset[0], modelCenterOffset[1], modelCenterOffset[2])
-		transform = rl.MatrixMultiply(rl.MatrixMultiply(transM, scaleM), offsetM)
-	} else {
-		transform = rl.MatrixMultiply(scaleM, transM)
-	}
-	rl.DrawMesh(c.mesh, c.texturedMtl, transform)
+	r.bindShadowMap(&c.texturedMtl)
+	rl.DrawMesh(c.mesh, c.texturedMtl, modelTransform(position, scale, rotation, modelCenterOffset))
 }
 
-// Draw draws one instance of the given type at position with scale. tint is optional (nil = default color). of the given type at position with scale.
+// Draw draws one instance of the given type at position with scale, rotated by the quaternion rotation
+// (x, y, z, w; the zero value counts as no rotation). tint is optional (nil = default color).
 // Must be called between BeginMode3D and EndMode3D.
 // SetView must be called once per frame before drawing so lit primitives get shading.
 // Unknown types are skipped. "cube", "sphere", "cylinder", and "plane" are created on first use.
-func (r *Registry) Draw(primType string, position, scale [3]float32, tint *[4]float32) {
+func (r *Registry) Draw(primType string, position, scale [3]float32, rotation [4]float32, tint *[4]float32) {
 	switch primType {
 	case "cube":
 		r.ensureCube()
-		r.drawCached("cube", position, scale, [3]float32{0, 0, 0}, tint)
+		r.drawCached("cube", position, scale, rotation, [3]float32{0, 0, 0}, tint)
 	case "sphere":
 		r.ensureSphere()
-		r.drawCached("sphere", position, scale, [3]float32{0, 0, 0}, tint)
+		r.drawCached("sphere", position, scale, rotation, [3]float32{0, 0, 0}, tint)
 	case "cylinder":
 		r.ensureCylinder()
-		r.drawCached("cylinder", position, scale, [3]float32{0, -0.5, 0}, tint)
+		r.drawCached("cylinder", position, scale, rotation, [3]float32{0, -0.5, 0}, tint)
 	case "plane":
 		r.ensurePlane()
-		r.drawCached("plane", position, scale, [3]float32{0, 0, 0}, tint)
+		r.drawCached("plane", position, scale, rotation, [3]float32{0, 0, 0}, tint)
 	case "terrain":
-		r.drawCached("terrain", position, scale, [3]float32{0, 0, 0}, tint)
+		r.drawCached("terrain", position, scale, rotation, [3]float32{0, 0, 0}, tint)
 	default:
 		// Unknown type; skip.
 	}
 }
 
-// DrawWithTexture draws one instance of the given type at position with scale, using the given texture as albedo.
+// DrawWithTexture draws one instance of the given type at position with scale and rotation (see Draw), using the given texture as albedo.
 // Must be called between BeginMode3D and EndMode3D. SetView must be called once per frame before drawing.
-func (r *Registry) DrawWithTexture(primType string, position, scale [3]float32, tex rl.Texture2D, tint *[4]float32) {
+func (r *Registry) DrawWithTexture(primType string, position, scale [3]float32, rotation [4]float32, tex rl.Texture2D, tint *[4]float32) {
 	if !rl.IsTextureValid(tex) {
-		r.Draw(primType, position, scale, tint)
+		r.Draw(primType, position, scale, rotation, tint)
 		return
 	}
 	switch primType {
 	case "cube":
 		r.ensureCube()
-		r.drawCachedWithTexture("cube", position, scale, [3]float32{0, 0, 0}, tex, tint)
+		r.drawCachedWithTexture("cube", position, scale, rotation, [3]float32{0, 0, 0}, tex, tint)
 	case "sphere":
 		r.ensureSphere()
-		r.drawCachedWithTexture("sphere", position, scale, [3]float32{0, 0, 0}, tex, tint)
+		r.drawCachedWithTexture("sphere", position, scale, rotation, [3]float32{0, 0, 0}, tex, tint)
 	case "cylinder":
 		r.ensureCylinder()
-		r.drawCachedWithTexture("cylinder", position, scale, [3]float32{0, -0.5, 0}, tex, tint)
+		r.drawCachedWithTexture("cylinder", position, scale, rotation, [3]float32{0, -0.5, 0}, tex, tint)
 	case "plane":
 		r.ensurePlane()
-		r.drawCachedWithTexture("plane", position, scale, [3]float32{0, 0, 0}, tex, tint)
+		r.drawCachedWithTexture("plane", position, scale, rotation, [3]float32{0, 0, 0}, tex, tint)
 	case "terrain":
-		r.drawCachedWithTexture("terrain", position, scale, [3]float32{0, 0, 0}, tex, tint)
+		r.drawCachedWithTexture("terrain", position, scale, rotation, [3]float32{0, 0, 0}, tex, tint)
 	default:
-		r.Draw(primType, position, scale, tint)
+		r.Draw(primType, position, scale, rotation, tint)
 	}
 }
@@ -0,0 +1,251 @@
+package primitives
+
+import (
+	"fmt"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Shadows from the directional light: once per frame, BeginShadowPass renders the scene's depth as seen from
+// the light (an orthographic camera covering a square region around the view) into a depth texture, and the
+// lit shaders (lit, lit textured, PBR, models) compare each fragment's light-space depth with it, averaging
+// a square of samples (percentage-closer filtering) for soft edges. The depth texture is bound to every lit
+// material through the BRDF map slot, so DrawMesh binds it with the material's other maps.
+
+// Shadow quality levels (cmd shadows quality <n>): shadow map resolution and PCF kernel.
+const (
+	MinShadowQuality     = 1
+	MaxShadowQuality     = 4
+	DefaultShadowQuality = 2
+)
+
+// shadowMapSizes and shadowPCFRadius are indexed by quality-1. A radius of r samples (2r+1)^2 texels.
+var (
+	shadowMapSizes  = [MaxShadowQuality]int32{1024, 2048, 2048, 4096}
+	shadowPCFRadius = [MaxShadowQuality]float32{1, 1, 2, 2}
+)
+
+// shadowState is the shadow map and what the lit shaders need to sample it.
+type shadowState struct {
+	enabled     bool
+	quality     int
+	target      rl.RenderTexture2D // framebuffer with only a depth attachment (target.Depth); 0 = not created
+	size        int32              // size of target
+	depthMtl    rl.Material        // depth-only material for the shadow pass
+	depthLoaded bool
+	pass        bool      // between BeginShadowPass and EndShadowPass: draws go to the shadow map
+	ready       bool      // the shadow map holds this frame's depth (reset by SetView)
+	lightVP     rl.Matrix // world -> light clip space
+	texelWorld  float32   // world size of one shadow map texel
+	bias        float32   // depth bias, in shadow map depth units
+}
+
+// SetShadows turns shadows on or off and sets their quality (MinShadowQuality-MaxShadowQuality; 0 = keep).
+func (r *Registry) SetShadows(enabled bool, quality int) error {
+	if quality != 0 && (quality < MinShadowQuality || quality > MaxShadowQuality) {
+		return fmt.Errorf("shadow quality must be %d-%d", MinShadowQuality, MaxShadowQuality)
+	}
+	r.shadow.enabled = enabled
+	if quality != 0 {
+		r.shadow.quality = quality
+	}
+	if !enabled || r.shadowSize() != r.shadow.size {
+		r.unloadShadowMap()
+	}
+	return nil
+}
+
+// Shadows reports whether shadows are on and their quality.
+func (r *Registry) Shadows() (enabled bool, quality int) {
+	return r.shadow.enabled, r.shadowQuality()
+}
+
+func (r *Registry) shadowQuality() int {
+	if r.shadow.quality == 0 {
+		return DefaultShadowQuality
+	}
+	return r.shadow.quality
+}
+
+func (r *Registry) shadowSize() int32 {
+	return shadowMapSizes[r.shadowQuality()-1]
+}
+
+// unloadShadowMap releases the shadow map; it is created again on the next shadow pass.
+func (r *Registry) unloadShadowMap() {
+	r.shadow.ready = false
+	if r.shadow.target.ID == 0 {
+		return
+	}
+	rl.UnloadTexture(r.shadow.target.Depth)
+	rl.UnloadFramebuffer(r.shadow.target.ID)
+	r.shadow.target = rl.RenderTexture2D{}
+	r.shadow.size = 0
+}
+
+// ensureShadowMap creates the depth framebuffer and the depth material on first use.
+func (r *Registry) ensureShadowMap() bool {
+	if !r.shadow.depthLoaded {
+		r.shadow.depthLoaded = true
+		shader := rl.LoadShaderFromMemory(litVS, depthFS)
+		if rl.IsShaderValid(shader) {
+			r.shadow.depthMtl = rl.LoadMaterialDefault()
+			r.shadow.depthMtl.Shader = shader
+		}
+	}
+	if !rl.IsShaderValid(r.shadow.depthMtl.Shader) {
+		return false
+	}
+	if r.shadow.target.ID != 0 {
+		return true
+	}
+	size := r.shadowSize()
+	fbo := rl.LoadFramebuffer()
+	if fbo == 0 {
+		return false
+	}
+	depth := rl.LoadTextureDepth(size, size, false)
+	rl.FramebufferAttach(fbo, depth, rl.AttachmentDepth, rl.AttachmentTexture2d, 0)
+	if depth == 0 || !rl.FramebufferComplete(fbo) {
+		rl.UnloadFramebuffer(fbo)
+		r.shadow.enabled = false
+		return false
+	}
+	// BeginTextureMode sizes the viewport from Texture, which a depth-only target does not otherwise have.
+	r.shadow.target = rl.RenderTexture2D{
+		ID:      fbo,
+		Texture: rl.Texture2D{Width: size, Height: size},
+		Depth:   rl.Texture2D{ID: depth, Width: size, Height: size, Mipmaps: 1, Format: rl.UncompressedR32},
+	}
+	r.shadow.size = size
+	return true
+}
+
+// BeginShadowPass starts rendering the shadow map for a region of the given radius around center: until
+// EndShadowPass, Draw, DrawWithTexture, DrawPBR and DrawModel only write depth as seen from the light.
+// Call after SetView and outside BeginMode3D. Returns false (nothing to draw) when shadows are off or the
+// shadow map cannot be created.
+func (r *Registry) BeginShadowPass(center [3]float32, radius float32) bool {
+	if !r.shadow.enabled || !r.ensureShadowMap() {
+		return false
+	}
+	l := rl.Vector3Normalize(rl.NewVector3(r.lightDir[0], r.lightDir[1], r.lightDir[2]))
+	// Snap the region to whole texels so the shadows do not shimmer as the camera moves.
+	texel := 2 * radius / float32(r.shadow.size)
+	for k := range center {
+		center[k] = float32(math.Floor(float64(center[k]/texel))) * texel
+	}
+	target := rl.NewVector3(center[0], center[1], center[2])
+	up := rl.NewVector3(0, 1, 0)
+	if math.Abs(float64(l.Y)) > 0.99 {
+		up = rl.NewVector3(0, 0, 1)
+	}
+	cam := rl.Camera3D{
+		Position:   rl.Vector3Add(target, rl.Vector3Scale(l, 2*radius+100)),
+		Target:     target,
+		Up:         up,
+		Fovy:       2 * radius, // orthographic: height of the view in world units
+		Projection: rl.CameraOrthographic,
+	}
+	rl.BeginTextureMode(r.shadow.target)
+	rl.ClearBackground(rl.White)
+	rl.BeginMode3D(cam)
+	proj := rl.GetMatrixProjection()
+	r.shadow.lightVP = rl.MatrixMultiply(rl.GetMatrixModelview(), proj)
+	r.shadow.texelWorld = texel
+	// proj.M10 = -2/(far-near); shadow map depth runs 0-1 over far-near. Allow one texel of slope.
+	r.shadow.bias = texel * float32(math.Abs(float64(proj.M10))) / 2
+	r.shadow.pass = true
+	return true
+}
+
+// EndShadowPass finishes the shadow map; the lit shaders sample it for the rest of the frame.
+func (r *Registry) EndShadowPass() {
+	if !r.shadow.pass {
+		return
+	}
+	rl.EndMode3D()
+	rl.EndTextureMode()
+	r.shadow.pass = false
+	r.shadow.ready = true
+}
+
+// drawDepth draws a mesh into the shadow map.
+func (r *Registry) drawDepth(mesh rl.Mesh, transform rl.Matrix) {
+	rl.DrawMesh(mesh, r.shadow.depthMtl, transform)
+}
+
+// bindShadowMap puts the shadow map (or the default white texture, when there is none this frame) in the
+// material's BRDF slot and sets the shadow uniforms of its shader. Call before DrawMesh with a lit shader.
+func (r *Registry) bindShadowMap(mtl *rl.Material) {
+	shader := mtl.Shader
+	on := float32(0)
+	tex := whiteTexture()
+	if r.shadow.ready {
+		on = 1
+		tex = r.shadow.target.Depth
+	}
+	rl.SetMaterialTexture(mtl, rl.MapBrdf, tex)
+	if loc := rl.GetShaderLocation(shader, "shadowsOn"); loc >= 0 {
+		rl.SetShaderValue(shader, loc, []float32{on}, rl.ShaderUniformFloat)
+	}
+	if !r.shadow.ready {
+		return
+	}
+	if loc := rl.GetShaderLocation(shader, "lightVP"); loc >= 0 {
+		rl.SetShaderValueMatrix(shader, loc, r.shadow.lightVP)
+	}
+	params := [4]float32{1 / float32(r.shadow.size), shadowPCFRadius[r.shadowQuality()-1], 1.5 * r.shadow.texelWorld, r.shadow.bias}
+	if loc := rl.GetShaderLocation(shader, "shadowParams"); loc >= 0 {
+		rl.SetShaderValueV(shader, loc, params[:], rl.ShaderUniformVec4, 1)
+	}
+}
+
+// useShadowMapSlot points a lit shader's BRDF map location at its shadowMap sampler (see bindShadowMap).
+func useShadowMapSlot(shader rl.Shader) rl.Shader {
+	if rl.IsShaderValid(shader) {
+		shader.UpdateLocation(rl.ShaderLocMapBrdf, rl.GetShaderLocation(shader, "shadowMap"))
+	}
+	return shader
+}
+
+// unloadMaterial unloads a lit material without the shadow map in its BRDF slot (rl.UnloadMaterial
+// unloads every map texture but the default one).
+func unloadMaterial(mtl rl.Material) {
+	rl.SetMaterialTexture(&mtl, rl.MapBrdf, rl.Texture2D{})
+	rl.UnloadMaterial(mtl)
+}
+
+// depthFS is the shadow pass fragment shader: depth is written by the rasterizer, color is ignored.
+const depthFS = `#version 330
+out vec4 finalColor;
+void main() {
+  finalColor = vec4(1.0);
+}
+`
+
+// shadowGLSL declares the shadow uniforms and shadowFactor (1 = lit, 0 = in shadow) for the lit fragment
+// shaders. shadowParams: 1/shadow map size, PCF radius in texels, normal offset in world units, depth bias.
+// Fragments outside the shadow map are lit.
+const shadowGLSL = `uniform sampler2D shadowMap;
+uniform mat4 lightVP;
+uniform float shadowsOn;
+uniform vec4 shadowParams;
+float shadowFactor(vec3 N) {
+  if (shadowsOn < 0.5) return 1.0;
+  vec4 p = lightVP * vec4(fragPosition + N * shadowParams.z, 1.0);
+  vec3 c = p.xyz / p.w * 0.5 + 0.5;
+  if (c.x <= 0.0 || c.x >= 1.0 || c.y <= 0.0 || c.y >= 1.0 || c.z >= 1.0) return 1.0;
+  int r = int(shadowParams.y);
+  float lit = 0.0;
+  for (int x = -r; x <= r; x++) {
+    for (int y = -r; y <= r; y++) {
+      float d = texture(shadowMap, c.xy + vec2(x, y) * shadowParams.x).r;
+      lit += c.z - shadowParams.w > d ? 0.0 : 1.0;
+    }
+  }
+  float n = float(2 * r + 1);
+  return lit / (n * n);
+}
+`
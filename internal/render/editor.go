@@ -0,0 +1,374 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"game-engine/internal/physics"
+	"game-engine/internal/scene"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const (
+	gridExtent     = 50
+	gridMinorStep  = 1
+	gridMajorStep  = 10
+	gridMinorAlpha = 50
+	gridMajorAlpha = 120
+	axisLineAlpha  = 220
+	// Y-drag: world units per pixel (screen-space mouse delta → vertical movement).
+	yDragSensitivity = float32(0.015)
+	// Gizmo arrows: visual-only length (no picking).
+	gizmoArrowLength = float32(1.5)
+	// Rotate drag: degrees per pixel of mouse movement; with Shift held (after the press, which would toggle
+	// the selection) the angle snaps to rotateSnapDegrees.
+	rotateDragSensitivity = float32(0.5)
+	rotateSnapDegrees     = float32(15)
+)
+
+// GizmoMode selects what dragging the selected object does in the editor.
+type GizmoMode int
+
+const (
+	// GizmoMove drags the object: top/bottom face on the XZ plane, side faces up and down.
+	GizmoMove GizmoMode = iota
+	// GizmoRotate turns the object: horizontal mouse movement about the world Y axis, vertical movement
+	// about the camera's horizontal axis.
+	GizmoRotate
+)
+
+// SetGizmoMode sets what dragging the selection does (move or rotate).
+func (v *View) SetGizmoMode(mode GizmoMode) {
+	v.gizmoMode = mode
+	v.endDrag()
+}
+
+// GizmoMode returns the current editor gizmo mode.
+func (v *View) GizmoMode() GizmoMode {
+	return v.gizmoMode
+}
+
+// rayPlaneY returns the intersection of ray with the horizontal plane Y = planeY.
+// Returns (hit point, true) if hit in front of the ray, otherwise (zero, false).
+func rayPlaneY(ray rl.Ray, planeY float32) (rl.Vector3, bool) {
+	dy := ray.Direction.Y
+	if dy > -1e-6 && dy < 1e-6 {
+		return rl.Vector3{}, false
+	}
+	t := (planeY - ray.Position.Y) / dy
+	if t < 0 {
+		return rl.Vector3{}, false
+	}
+	hit := rl.Vector3{
+		X: ray.Position.X + t*ray.Direction.X,
+		Y: ray.Position.Y + t*ray.Direction.Y,
+		Z: ray.Position.Z + t*ray.Direction.Z,
+	}
+	return hit, true
+}
+
+// rayPlane returns the intersection of ray with a plane (point + normal). Returns (hit, true) if t >= 0.
+func rayPlane(ray rl.Ray, planePoint rl.Vector3, planeNormal rl.Vector3) (rl.Vector3, bool) {
+	dn := ray.Direction.X*planeNormal.X + ray.Direction.Y*planeNormal.Y + ray.Direction.Z*planeNormal.Z
+	if dn > -1e-6 && dn < 1e-6 {
+		return rl.Vector3{}, false
+	}
+	diffX := planePoint.X - ray.Position.X
+	diffY := planePoint.Y - ray.Position.Y
+	diffZ := planePoint.Z - ray.Position.Z
+	t := (diffX*planeNormal.X + diffY*planeNormal.Y + diffZ*planeNormal.Z) / dn
+	if t < 0 {
+		return rl.Vector3{}, false
+	}
+	return rl.Vector3{
+		X: ray.Position.X + t*ray.Direction.X,
+		Y: ray.Position.Y + t*ray.Direction.Y,
+		Z: ray.Position.Z + t*ray.Direction.Z,
+	}, true
+}
+
+// UpdateEditor runs when the terminal is open (cursor visible). It handles selection and
+// movement of scene primitives. terminalBarHeight is the height in pixels of the bar at
+// the bottom; mouse events in that area are ignored so the terminal can receive input.
+// Click selects an object, Shift+click adds it to or removes it from the selection, and dragging from empty
+// space draws a marquee that selects the objects inside it (Shift adds them). Dragging a selected object moves
+// the whole selection.
+// In GizmoMove mode the drag is chosen by which face of the selection box was hit: top/bottom → XZ
+// (forward/sides), side faces → Y (up/down). In GizmoRotate mode dragging turns the object (see GizmoRotate). Only scene objects are selectable and movable; skybox and grid are not.
+// Physics is paused while editing, but the scene clock still runs so motion (bob) keeps animating.
+func (v *View) UpdateEditor(cursorVisible bool, terminalBarHeight int) {
+	v.beginFrame()
+	v.scene.AdvanceClock(rl.GetFrameTime())
+	if !cursorVisible {
+		v.endDrag()
+		return
+	}
+	if v.scene.ObjectCount() == 0 {
+		v.endDrag()
+		return
+	}
+	screenH := int32(rl.GetScreenHeight())
+	mouseY := rl.GetMouseY()
+	if mouseY >= screenH-int32(terminalBarHeight) {
+		v.endDrag()
+		return
+	}
+	mousePos := rl.GetMousePosition()
+	ray := rl.GetMouseRay(mousePos, v.camera)
+
+	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
+		if v.dragMode == 4 {
+			v.finishMarquee(mousePos)
+		}
+		v.endDrag()
+		return
+	}
+
+	sel := v.scene.SelectedIndex()
+	if v.dragMode == 3 && sel >= 0 {
+		v.rotateDrag(rl.GetMouseX(), mouseY)
+		return
+	}
+	// Y drag: move the selection up/down from screen-space mouse delta (total pixels since drag start)
+	if v.dragMode == 2 && sel >= 0 {
+		if obj, ok := v.scene.ObjectAt(sel); ok {
+			deltaPixels := mouseY - v.lastMouseY
+			y := v.dragStartObjY - float32(deltaPixels)*yDragSensitivity
+			_ = v.scene.MoveSelection([3]float32{0, y - obj.Position[1], 0})
+		}
+		return
+	}
+
+	if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
+		// Box pick: find closest hit and use hit normal to choose drag mode
+		bestIdx, bestHit := v.scene.Pick(scene.Ray{
+			Position:  [3]float32{ray.Position.X, ray.Position.Y, ray.Position.Z},
+			Direction: [3]float32{ray.Direction.X, ray.Direction.Y, ray.Direction.Z},
+		})
+		v.endDrag()
+		// Clicking any part of a group selects (and drags) the whole group.
+		hit := v.scene.Root(bestIdx)
+		shift := rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift)
+		switch {
+		case hit < 0:
+			// Empty space: start a marquee; a click without dragging clears the selection on release.
+			v.dragMode = 4
+			v.marqueeStart = mousePos
+			v.marqueeAdd = shift
+			return
+		case shift:
+			v.scene.ToggleSelection(v.scene.IDAt(hit))
+			return
+		case v.scene.IsSelected(hit):
+			// Keep the selection so the whole set can be dragged; the clicked object becomes the primary.
+			v.scene.AddToSelection([]scene.ObjectID{v.scene.IDAt(hit)})
+		default:
+			v.scene.SelectID(v.scene.IDAt(hit))
+		}
+		sel = hit
+		v.dragging = true
+		// The whole drag undoes as one step (closed by endDrag on release).
+		label := "move"
+		if v.gizmoMode == GizmoRotate {
+			label = "rotate"
+		}
+		v.scene.BeginChange(label + " " + selectionName(v.scene))
+		if v.gizmoMode == GizmoRotate {
+			v.dragMode = 3
+			v.dragRot = physics.QuatIdentity
+			v.lastMouseX, v.lastMouseY = rl.GetMouseX(), mouseY
+		} else {
+			obj, _ := v.scene.ObjectAt(sel)
+			// Top or bottom face only when normal is clearly vertical (Y ≈ ±1). All 4 side faces (Y ≈ 0) → Y drag.
+			n := bestHit.Normal
+			if n[1] > 0.99 || n[1] < -0.99 {
+				v.dragMode = 1 // top or bottom: drag on horizontal plane (XZ)
+				// Store offset from object center to click point so the clicked point stays under cursor
+				v.dragOffsetX = bestHit.Point[0] - obj.Position[0]
+				v.dragOffsetZ = bestHit.Point[2] - obj.Position[2]
+			} else {
+				v.dragMode = 2 // any of the 4 side faces: drag up/down (Y)
+				v.dragStartObjY = obj.Position[1]
+				v.lastMouseY = mouseY // store so total delta = mouseY - lastMouseY each frame
+			}
+		}
+		return
+	}
+
+	// XZ drag (top/bottom face): drag on horizontal plane at object Y, keeping click offset under cursor;
+	// the rest of the selection follows the clicked object.
+	if v.dragMode == 1 && v.dragging && sel >= 0 {
+		obj, ok := v.scene.ObjectAt(sel)
+		if !ok {
+			return
+		}
+		hit, ok := rayPlaneY(ray, obj.Position[1])
+		if ok {
+			dx := hit.X - v.dragOffsetX - obj.Position[0]
+			dz := hit.Z - v.dragOffsetZ - obj.Position[2]
+			_ = v.scene.MoveSelection([3]float32{dx, 0, dz})
+		}
+	}
+}
+
+// finishMarquee selects the objects inside the marquee that ends at end. A click without a drag (a box of
+// a few pixels) clears the selection unless Shift was held.
+func (v *View) finishMarquee(end rl.Vector2) {
+	const minSize = 4
+	if math.Abs(float64(end.X-v.marqueeStart.X)) < minSize && math.Abs(float64(end.Y-v.marqueeStart.Y)) < minSize {
+		if !v.marqueeAdd {
+			v.scene.ClearSelection()
+		}
+		return
+	}
+	v.scene.SelectInRect([2]float32{v.marqueeStart.X, v.marqueeStart.Y}, [2]float32{end.X, end.Y}, v.marqueeAdd)
+}
+
+// drawMarquee draws the box-select rectangle while a marquee drag is active. Call after EndMode3D.
+func (v *View) drawMarquee() {
+	if v.dragMode != 4 {
+		return
+	}
+	m := rl.GetMousePosition()
+	x, y := min(m.X, v.marqueeStart.X), min(m.Y, v.marqueeStart.Y)
+	w, h := max(m.X, v.marqueeStart.X)-x, max(m.Y, v.marqueeStart.Y)-y
+	rect := rl.NewRectangle(x, y, w, h)
+	rl.DrawRectangleRec(rect, rl.NewColor(255, 220, 80, 40))
+	rl.DrawRectangleLinesEx(rect, 1, rl.NewColor(255, 220, 80, 200))
+}
+
+// endDrag stops any editor drag and closes its history step.
+func (v *View) endDrag() {
+	if v.dragging {
+		v.scene.EndChange()
+	}
+	v.dragging = false
+	v.dragMode = 0
+}
+
+// selectionName names the selection for history labels: the primary object's name (or type), or
+// "N objects" when several are selected.
+func selectionName(scn *scene.Scene) string {
+	if n := len(scn.Selection()); n > 1 {
+		return fmt.Sprintf("%d objects", n)
+	}
+	obj, _ := scn.SelectedObject()
+	if obj.Name != "" {
+		return obj.Name
+	}
+	return obj.Type
+}
+
+// rotateDrag turns the selection from the total mouse movement since the rotate drag started; each
+// top-level selected object turns about its own origin.
+func (v *View) rotateDrag(mouseX, mouseY int32) {
+	yaw := float32(mouseX-v.lastMouseX) * rotateDragSensitivity
+	pitch := float32(mouseY-v.lastMouseY) * rotateDragSensitivity
+	if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+		yaw = snapAngle(yaw)
+		pitch = snapAngle(pitch)
+	}
+	// Camera's horizontal right axis, so dragging down tips the object toward the viewer.
+	c := v.scene.Camera
+	right := [3]float32{c.Position[2] - c.Target[2], 0, c.Target[0] - c.Position[0]}
+	rot := physics.QuatAxisAngle([3]float32{0, 1, 0}, yaw).Mul(physics.QuatAxisAngle(right, pitch))
+	// Apply only the change since the last frame, so every selected object keeps its own orientation.
+	if err := v.scene.RotateSelectionBy(rot.Mul(v.dragRot.Inverse())); err == nil {
+		v.dragRot = rot
+	}
+}
+
+// snapAngle rounds deg to a multiple of rotateSnapDegrees.
+func snapAngle(deg float32) float32 {
+	return float32(math.Round(float64(deg/rotateSnapDegrees))) * rotateSnapDegrees
+}
+
+// drawGizmo draws the handles for the current gizmo mode at the selected object's draw transform.
+func (v *View) drawGizmo(t scene.Transform) {
+	if v.gizmoMode != GizmoRotate {
+		drawGizmoArrows(t.Position)
+		return
+	}
+	// Rings for the two drag axes: green about Y (horizontal drag), red about the camera's right axis.
+	radius := max(t.Scale[0], t.Scale[1], t.Scale[2])*0.5 + 0.5
+	center := rl.NewVector3(t.Position[0], t.Position[1], t.Position[2])
+	rl.DrawCircle3D(center, radius, rl.NewVector3(1, 0, 0), 90, rl.NewColor(80, 220, 80, 255))
+	c := v.scene.Camera
+	// The default circle lies in the XY plane (normal +Z); turn it about Y until its normal is the right axis.
+	yaw := float32(math.Atan2(float64(c.Position[2]-c.Target[2]), float64(c.Target[0]-c.Position[0]))) * 180 / math.Pi
+	rl.DrawCircle3D(center, radius, rl.NewVector3(0, 1, 0), yaw, rl.NewColor(220, 80, 80, 255))
+}
+
+// drawGizmoArrows draws red (X), green (Y), blue (Z) arrows at pos. Visual only; no picking.
+func drawGizmoArrows(pos [3]float32) {
+	length := gizmoArrowLength
+	headSize := length * 0.2
+	red := rl.NewColor(220, 80, 80, 255)
+	green := rl.NewColor(80, 220, 80, 255)
+	blue := rl.NewColor(80, 80, 220, 255)
+	base := rl.NewVector3(pos[0], pos[1], pos[2])
+	// X
+	endX := rl.NewVector3(pos[0]+length, pos[1], pos[2])
+	rl.DrawLine3D(base, endX, red)
+	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1], pos[2]+headSize), red)
+	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1], pos[2]-headSize), red)
+	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1]+headSize, pos[2]), red)
+	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1]-headSize, pos[2]), red)
+	// Y
+	endY := rl.NewVector3(pos[0], pos[1]+length, pos[2])
+	rl.DrawLine3D(base, endY, green)
+	rl.DrawLine3D(endY, rl.NewVector3(pos[0], pos[1]+length-headSize, pos[2]+headSize), green)
+	rl.DrawLine3D(endY, rl.NewVector3(pos[0], pos[1]+length-headSize, pos[2]-headSize), green)
+	rl.DrawLine3D(endY, rl.NewVector3(pos[0]+headSize, pos[1]+length-headSize, pos[2]), green)
+	rl.DrawLine3D(endY, rl.NewVector3(pos[0]-headSize, pos[1]+length-headSize, pos[2]), green)
+	// Z
+	endZ := rl.NewVector3(pos[0], pos[1], pos[2]+length)
+	rl.DrawLine3D(base, endZ, blue)
+	rl.DrawLine3D(endZ, rl.NewVector3(pos[0]+headSize, pos[1], pos[2]+length-headSize), blue)
+	rl.DrawLine3D(endZ, rl.NewVector3(pos[0]-headSize, pos[1], pos[2]+length-headSize), blue)
+	rl.DrawLine3D(endZ, rl.NewVector3(pos[0], pos[1]+headSize, pos[2]+length-headSize), blue)
+	rl.DrawLine3D(endZ, rl.NewVector3(pos[0], pos[1]-headSize, pos[2]+length-headSize), blue)
+}
+
+// drawEditorGrid draws an infinite-style grid on the XZ plane with major/minor lines and axis lines.
+// Reuses start/end vectors to avoid per-frame allocations in the hot loop.
+func drawEditorGrid() {
+	minor := rl.NewColor(128, 128, 128, gridMinorAlpha)
+	major := rl.NewColor(160, 160, 160, gridMajorAlpha)
+	axisX := rl.NewColor(220, 80, 80, axisLineAlpha)
+	axisY := rl.NewColor(80, 220, 80, axisLineAlpha)
+	axisZ := rl.NewColor(80, 80, 220, axisLineAlpha)
+
+	var start, end rl.Vector3
+	// Grid lines on XZ plane (Y=0): lines along X (varying Z) and along Z (varying X)
+	for x := -gridExtent; x <= gridExtent; x += gridMinorStep {
+		c := major
+		if x%gridMajorStep != 0 {
+			c = minor
+		}
+		start.X, start.Y, start.Z = float32(x), 0, float32(-gridExtent)
+		end.X, end.Y, end.Z = float32(x), 0, float32(gridExtent)
+		rl.DrawLine3D(start, end, c)
+	}
+	for z := -gridExtent; z <= gridExtent; z += gridMinorStep {
+		c := major
+		if z%gridMajorStep != 0 {
+			c = minor
+		}
+		start.X, start.Y, start.Z = float32(-gridExtent), 0, float32(z)
+		end.X, end.Y, end.Z = float32(gridExtent), 0, float32(z)
+		rl.DrawLine3D(start, end, c)
+	}
+
+	// Axis lines through origin (X=red, Y=green, Z=blue)
+	start.X, start.Y, start.Z = float32(-gridExtent), 0, 0
+	end.X, end.Y, end.Z = float32(gridExtent), 0, 0
+	rl.DrawLine3D(start, end, axisX)
+	start.X, start.Y, start.Z = 0, float32(-gridExtent), 0
+	end.X, end.Y, end.Z = 0, float32(gridExtent), 0
+	rl.DrawLine3D(start, end, axisY)
+	start.X, start.Y, start.Z = 0, 0, float32(-gridExtent)
+	end.X, end.Y, end.Z = 0, 0, float32(gridExtent)
+	rl.DrawLine3D(start, end, axisZ)
+}
@@ -0,0 +1,190 @@
+package render
+
+import (
+	"fmt"
+	"slices"
+
+	"game-engine/internal/modelfile"
+	"game-engine/internal/primitives"
+	"game-engine/internal/scene"
+)
+
+// ExportGLTF writes the scene to a glTF 2.0 file (.glb, or .gltf with everything embedded; see
+// modelfile.Export) for use in other tools: every drawable object becomes a node named after it, with the
+// mesh Draw renders (primitives and the terrain heightmap as generated in internal/primitives, models as
+// loaded), its world transform, and a material: the object's PBR material with its maps, or its color tint
+// and texture (the object's color and texture override the material's albedo, as drawn). Groups and objects with
+// children become empty nodes holding their parts; since the engine scales each object in its own frame
+// (see scene/hierarchy.go), which a glTF node tree cannot express, every part keeps its world transform and
+// the holding node sits at the origin. Model textures are not exported (the models keep their colors).
+// Needs the GL context, like Draw. Returns the number of objects exported and the problems that did not
+// stop the export.
+func (v *View) ExportGLTF(path string) (objects int, warnings []string, err error) {
+	v.syncTerrain()
+	x := &exporter{view: v, geometry: map[string]int{}}
+	scn := v.scene
+	for i := 0; i < scn.ObjectCount(); i++ {
+		if scn.Parent(i) < 0 {
+			x.add(i, -1)
+		}
+	}
+	w, err := x.out.WriteFile(path)
+	return x.objects, append(x.warnings, w...), err
+}
+
+// exporter carries the state of one ExportGLTF call.
+type exporter struct {
+	view     *View
+	out      modelfile.Export
+	geometry map[string]int // primitive type + UV scale or model path + mesh index -> geometry index
+	objects  int
+	warnings []string
+}
+
+// add exports object i and its subtree under the node parent (-1 = root).
+func (x *exporter) add(i, parent int) {
+	scn := x.view.scene
+	obj, _ := scn.ObjectAt(i)
+	t, _ := scn.WorldTransform(i)
+	name := obj.Name
+	if name == "" {
+		name = fmt.Sprintf("%s #%d", obj.Type, obj.ID)
+	}
+	node := modelfile.Node{Name: name, Translation: t.Position, Rotation: [4]float32(t.Rotation), Scale: t.Scale}
+	switch obj.Type {
+	case scene.GroupType, scene.LightType:
+		// Lights are not exported: core glTF has no lights.
+	case "terrain":
+		// The heightmap mesh is in world space (see Draw).
+		node = modelfile.Node{Name: name, Parts: x.primitive("terrain", obj)}
+	case scene.ModelType:
+		x.model(obj, &node)
+	default:
+		node.Parts = x.primitive(obj.Type, obj)
+	}
+	if len(node.Parts) > 0 {
+		x.objects++
+	}
+	children := scn.Children(i)
+	if obj.Type != scene.GroupType && len(children) == 0 {
+		if len(node.Parts) > 0 {
+			x.out.AddNode(node, parent)
+		}
+		return
+	}
+	holder := x.out.AddNode(modelfile.Node{Name: name}, parent)
+	if len(node.Parts) > 0 {
+		x.out.AddNode(node, holder)
+	}
+	for _, c := range children {
+		x.add(c, holder)
+	}
+}
+
+// primitive returns the parts of a primitive (or terrain) object: the generated mesh with the object's
+// material (its UV tiling baked into the mesh, since glTF has no texture scale without an extension), or its
+// color and texture. Unknown types export nothing, as Draw skips them.
+func (x *exporter) primitive(typ string, obj scene.ObjectInstance) []modelfile.Part {
+	var mtl *scene.Material
+	uv := [2]float32{1, 1}
+	if obj.Material != "" {
+		if m, err := scene.LoadMaterial(obj.Material); err != nil {
+			x.warn(fmt.Sprintf("material %s: %v; exported without it", obj.Material, err))
+		} else {
+			mtl, uv = &m, m.UV()
+		}
+	}
+	key := fmt.Sprintf("%s@%gx%g", typ, uv[0], uv[1])
+	g, ok := x.geometry[key]
+	if !ok {
+		geom, found := x.view.primitives.Geometry(typ, uv)
+		if !found {
+			x.geometry[key] = -1
+			return nil
+		}
+		g = x.out.AddGeometry(geom)
+		x.geometry[key] = g
+	}
+	if g < 0 {
+		return nil
+	}
+	texture := obj.Texture
+	if mtl != nil && texture == "" {
+		texture = mtl.AlbedoMap
+	}
+	texture = x.texture(texture)
+	out := modelfile.Material{Color: primitives.BaseColor(objectTint(obj), texture != ""), Texture: texture, Roughness: 1}
+	if mtl != nil {
+		if objectTint(obj) == nil {
+			a := mtl.AlbedoColor()
+			out.Color = [4]float32{a[0], a[1], a[2], 1}
+		}
+		out.Metallic, out.Roughness, out.Emissive = mtl.Metallic, mtl.RoughnessValue(), mtl.Emissive
+		out.NormalMap = x.texture(mtl.NormalMap)
+		out.MetallicRoughnessMap = x.texture(mtl.MetallicRoughnessMap)
+		out.EmissiveMap = x.texture(mtl.EmissiveMap)
+	}
+	return []modelfile.Part{{Geometry: g, Material: out}}
+}
+
+// texture returns the file a texture path refers to, "" (with a warning, once) if it is not found.
+func (x *exporter) texture(path string) string {
+	if path == "" {
+		return ""
+	}
+	file := resolveTexturePath(path)
+	if file == "" {
+		x.warn(fmt.Sprintf("texture %s not found; exported without it", path))
+	}
+	return file
+}
+
+// warn records a warning unless the same one was already recorded.
+func (x *exporter) warn(msg string) {
+	if slices.Contains(x.warnings, msg) {
+		return
+	}
+	x.warnings = append(x.warnings, msg)
+}
+
+// model fills in node's parts and scale for a model object: the loaded meshes, centered and scaled to the
+// object's box as DrawModel does, with the materials' colors times the object's tint. A model that cannot
+// be loaded is exported as the cube Draw shows in its place.
+func (x *exporter) model(obj scene.ObjectInstance, node *modelfile.Node) {
+	m := x.view.EnsureModel(obj.Model)
+	if m == nil {
+		x.warn(fmt.Sprintf("model %s could not be loaded; exported as a box", obj.Model))
+		node.Parts = x.primitive("cube", obj)
+		return
+	}
+	lo, hi := m.bounds.Min, m.bounds.Max
+	extent := [3]float32{hi.X - lo.X, hi.Y - lo.Y, hi.Z - lo.Z}
+	for k := range node.Scale {
+		if node.Scale[k] == 0 {
+			node.Scale[k] = 1
+		}
+		node.Scale[k] /= max(extent[k], 1e-4)
+	}
+	meshes, materials := primitives.ModelGeometry(m.model, [3]float32{-(lo.X + hi.X) / 2, -(lo.Y + hi.Y) / 2, -(lo.Z + hi.Z) / 2})
+	tint := primitives.BaseColor(objectTint(obj), true)
+	for i, geom := range meshes {
+		if len(geom.Positions) == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s#%d", obj.Model, i)
+		g, ok := x.geometry[key]
+		if !ok {
+			g = x.out.AddGeometry(geom)
+			x.geometry[key] = g
+		}
+		c := [4]float32{1, 1, 1, 1}
+		if k := materials[i]; k >= 0 && k < len(m.colors) {
+			mc := m.colors[k]
+			c = [4]float32{float32(mc.R) / 255, float32(mc.G) / 255, float32(mc.B) / 255, float32(mc.A) / 255}
+		}
+		for k := range c {
+			c[k] *= tint[k]
+		}
+		node.Parts = append(node.Parts, modelfile.Part{Geometry: g, Material: modelfile.Material{Color: c, Roughness: 1}})
+	}
+}
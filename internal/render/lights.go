@@ -0,0 +1,84 @@
+package render
+
+import (
+	"math"
+
+	"game-engine/internal/primitives"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// lightMarkerRadius is the radius of the bulb drawn for a light object in the editor.
+const lightMarkerRadius = 0.12
+
+// setLights hands the sun of the scene's lighting profile and its light objects nearest the camera target to
+// the primitives registry, for the lit shaders. Lights that give no light are left out before the list is
+// capped, so they do not take the slot of a visible one. Call after SetView, once per frame.
+func (v *View) setLights() {
+	sun := v.scene.Sun()
+	v.primitives.SetSunLight(sun.Color, sun.Intensity, sun.Ambient)
+	sources := v.scene.Lights(v.scene.Camera.Target)
+	lights := make([]primitives.Light, 0, min(len(sources), primitives.MaxLights))
+	for _, l := range sources {
+		if len(lights) == primitives.MaxLights {
+			break
+		}
+		if l.Intensity <= 0 || l.Range <= 0 {
+			continue
+		}
+		lights = append(lights, primitives.Light{
+			Spot: l.Spot, Position: l.Position, Direction: l.Direction, Color: l.Color,
+			Intensity: l.Intensity, Range: l.Range, Cone: l.Cone,
+		})
+	}
+	v.primitives.SetLights(lights)
+}
+
+// drawLightMarkers draws every light object as a small bulb in its color, and for the selected ones the
+// reach of the light: a ring at its range for a point light, the cone out to its range for a spot light.
+// Editor only: lights are invisible in play.
+func (v *View) drawLightMarkers() {
+	for _, l := range v.scene.Lights(v.scene.Camera.Target) {
+		c := rl.NewColor(uint8(l.Color[0]*255), uint8(l.Color[1]*255), uint8(l.Color[2]*255), 255)
+		pos := rl.NewVector3(l.Position[0], l.Position[1], l.Position[2])
+		rl.DrawSphere(pos, lightMarkerRadius, c)
+		rl.DrawSphereWires(pos, 2*lightMarkerRadius, 4, 8, rl.Fade(c, 0.6))
+		if !v.scene.IsSelected(l.Index) {
+			continue
+		}
+		if !l.Spot {
+			rl.DrawCircle3D(pos, l.Range, rl.NewVector3(1, 0, 0), 90, rl.Fade(c, 0.5))
+			continue
+		}
+		drawSpotCone(pos, rl.NewVector3(l.Direction[0], l.Direction[1], l.Direction[2]), l.Cone, l.Range, rl.Fade(c, 0.5))
+	}
+}
+
+// drawSpotCone draws the cone of a spot light at pos pointing along dir: its rim at distance reach and
+// lines from the light to the rim.
+func drawSpotCone(pos, dir rl.Vector3, cone, reach float32, color rl.Color) {
+	const segments = 16
+	dir = rl.Vector3Normalize(dir)
+	// Two unit vectors across the cone axis.
+	side := rl.NewVector3(1, 0, 0)
+	if math.Abs(float64(dir.X)) > 0.9 {
+		side = rl.NewVector3(0, 0, 1)
+	}
+	u := rl.Vector3Normalize(rl.Vector3CrossProduct(dir, side))
+	w := rl.Vector3CrossProduct(dir, u)
+	half := float64(cone) / 2 * math.Pi / 180
+	center := rl.Vector3Add(pos, rl.Vector3Scale(dir, reach*float32(math.Cos(half))))
+	radius := reach * float32(math.Sin(half))
+	var prev rl.Vector3
+	for i := 0; i <= segments; i++ {
+		a := 2 * math.Pi * float64(i) / segments
+		p := rl.Vector3Add(center, rl.Vector3Add(rl.Vector3Scale(u, radius*float32(math.Cos(a))), rl.Vector3Scale(w, radius*float32(math.Sin(a)))))
+		if i > 0 {
+			rl.DrawLine3D(prev, p, color)
+		}
+		if i%4 == 0 {
+			rl.DrawLine3D(pos, p, color)
+		}
+		prev = p
+	}
+}
@@ -0,0 +1,79 @@
+package render
+
+import (
+	"log"
+
+	"game-engine/internal/primitives"
+	"game-engine/internal/scene"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// EnsureMaterial returns the named library material (see scene.LoadMaterial) ready for DrawPBR, with its
+// textures loaded. Returns nil if the material is missing or invalid; failures are cached, so they are logged
+// once. The cache is dropped when a material is saved or the library reloaded (scene.MaterialRevision).
+// Safe to call from Draw (loads on first use when GL context exists).
+func (v *View) EnsureMaterial(name string) *primitives.PBRMaterial {
+	if rev := scene.MaterialRevision(); rev != v.materialRev || v.materialCache == nil {
+		v.materialCache = map[string]*primitives.PBRMaterial{}
+		v.materialRev = rev
+	}
+	if m, ok := v.materialCache[name]; ok {
+		return m
+	}
+	v.materialCache[name] = nil
+	src, err := scene.LoadMaterial(name)
+	if err != nil {
+		log.Printf("[render] material %q: %v; drawing with color and texture", name, err)
+		return nil
+	}
+	albedo := src.AlbedoColor()
+	m := &primitives.PBRMaterial{
+		Albedo:    [4]float32{albedo[0], albedo[1], albedo[2], 1},
+		Metallic:  src.Metallic,
+		Roughness: src.RoughnessValue(),
+		Emissive:  src.Emissive,
+		UVScale:   src.UV(),
+	}
+	for _, t := range []struct {
+		path string
+		dst  *rl.Texture2D
+	}{
+		{src.AlbedoMap, &m.AlbedoMap},
+		{src.NormalMap, &m.NormalMap},
+		{src.MetallicRoughnessMap, &m.MetallicRoughnessMap},
+		{src.EmissiveMap, &m.EmissiveMap},
+	} {
+		if t.path == "" {
+			continue
+		}
+		tex, ok := v.EnsureTexture(t.path)
+		if !ok {
+			log.Printf("[render] material %q: texture %s not found; drawing without it", name, t.path)
+			continue
+		}
+		*t.dst = tex
+	}
+	v.materialCache[name] = m
+	return m
+}
+
+// drawMaterial draws a primitive with its object's material; the object's color and texture, when set,
+// replace the material's albedo color and map. Returns false if the material cannot be used.
+func (v *View) drawMaterial(typ string, obj scene.ObjectInstance, t scene.Transform) bool {
+	src := v.EnsureMaterial(obj.Material)
+	if src == nil {
+		return false
+	}
+	m := *src
+	if tint := objectTint(obj); tint != nil {
+		m.Albedo = *tint
+	}
+	if obj.Texture != "" {
+		if tex, ok := v.EnsureTexture(obj.Texture); ok {
+			m.AlbedoMap = tex
+		}
+	}
+	v.primitives.DrawPBR(typ, t.Position, t.Scale, t.Rotation, &m)
+	return true
+}
@@ -0,0 +1,59 @@
+package render
+
+import (
+	"log"
+
+	"game-engine/internal/scene"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// loadedModel is a model file on the GPU, with what DrawModel needs to fit it to an object's box.
+type loadedModel struct {
+	model  rl.Model
+	bounds rl.BoundingBox
+	colors []rl.Color // the materials' own albedo colors (DrawModel writes the tinted color into the material)
+}
+
+// EnsureModel loads and caches the model file for a model path (see scene.ResolveModelPath). raylib loads
+// glTF/GLB (with embedded or external textures) and OBJ (with its .mtl materials and their textures).
+// Returns nil if the file is missing or cannot be loaded; failures are cached, so they are logged once.
+// Safe to call from Draw (loads on first use when GL context exists).
+func (v *View) EnsureModel(path string) *loadedModel {
+	if m, ok := v.modelCache[path]; ok {
+		return m
+	}
+	v.modelCache[path] = nil
+	file, ok := scene.ResolveModelPath(path)
+	if !ok {
+		log.Printf("[render] model %q not found; drawing a placeholder box", path)
+		return nil
+	}
+	model := rl.LoadModel(file)
+	if !rl.IsModelValid(model) || model.MeshCount == 0 {
+		log.Printf("[render] could not load model %s; drawing a placeholder box", file)
+		return nil
+	}
+	v.primitives.PrepareModel(&model)
+	m := &loadedModel{model: model, bounds: rl.GetModelBoundingBox(model)}
+	for _, mtl := range model.GetMaterials() {
+		c := rl.White
+		if albedo := mtl.GetMap(rl.MapAlbedo); albedo != nil {
+			c = albedo.Color
+		}
+		m.colors = append(m.colors, c)
+	}
+	v.modelCache[path] = m
+	return m
+}
+
+// drawModel draws a model object in the box at position with size scale and rotation; a model that cannot
+// be loaded is drawn as a cube of that box.
+func (v *View) drawModel(obj scene.ObjectInstance, position, scale [3]float32, rotation [4]float32, tint *[4]float32) {
+	m := v.EnsureModel(obj.Model)
+	if m == nil {
+		v.primitives.Draw("cube", position, scale, rotation, tint)
+		return
+	}
+	v.primitives.DrawModel(m.model, m.bounds, m.colors, position, scale, rotation, tint)
+}
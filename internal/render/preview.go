@@ -0,0 +1,63 @@
+package render
+
+import (
+	"game-engine/internal/physics"
+	"game-engine/internal/scene"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Preview colors: ghosted objects that would be added and outlines of objects that would be deleted.
+var (
+	previewAddTint     = [4]float32{0.4, 0.8, 1, 0.35}
+	previewAddColor    = rl.SkyBlue
+	previewDeleteColor = rl.Red
+)
+
+// previewState is the pending agent plan drawn over the scene until it is applied or rejected.
+type previewState struct {
+	adds    []scene.ObjectInstance
+	deletes []scene.ObjectID
+}
+
+// SetPreview shows objects that a pending plan would add (ghosted) and highlights the objects with the
+// given IDs that it would delete. Replaces any previous preview.
+func (v *View) SetPreview(adds []scene.ObjectInstance, deletes []scene.ObjectID) {
+	v.preview = &previewState{adds: adds, deletes: deletes}
+}
+
+// ClearPreview removes the preview overlay.
+func (v *View) ClearPreview() {
+	v.preview = nil
+}
+
+// drawPreview draws the preview overlay. Called from Draw inside BeginMode3D, after opaque objects.
+func (v *View) drawPreview() {
+	if v.preview == nil {
+		return
+	}
+	for _, id := range v.preview.deletes {
+		b, ok := v.scene.SubtreeBounds(v.scene.IndexOf(id))
+		if !ok {
+			continue
+		}
+		box := toBoundingBox(b)
+		rl.DrawBoundingBox(box, previewDeleteColor)
+		// Slightly larger second box so the highlight reads at a distance.
+		box.Min = rl.Vector3Subtract(box.Min, rl.NewVector3(0.05, 0.05, 0.05))
+		box.Max = rl.Vector3Add(box.Max, rl.NewVector3(0.05, 0.05, 0.05))
+		rl.DrawBoundingBox(box, previewDeleteColor)
+	}
+	rl.DisableDepthMask()
+	for _, obj := range v.preview.adds {
+		tint := previewAddTint
+		rot := physics.QuatFromEuler(obj.Rotation)
+		if obj.Type == scene.ModelType {
+			v.drawModel(obj, obj.Position, obj.Scale, rot, &tint)
+		} else {
+			v.primitives.Draw(obj.Type, obj.Position, obj.Scale, rot, &tint)
+		}
+		drawOrientedBox(physics.OrientedBoxAt(obj.Position, obj.Scale, rot), previewAddColor)
+	}
+	rl.EnableDepthMask()
+}
@@ -0,0 +1,297 @@
+// Package render draws a scene.Scene with raylib and runs the mouse editor on top of it. All GPU state
+// (primitive meshes, textures, models, skybox, terrain mesh) and editor state (grid, drag, preview overlay) lives
+// here; the scene model itself stays free of raylib so it can run without a window.
+package render
+
+import (
+	"os"
+	"path/filepath"
+
+	"game-engine/internal/physics"
+	"game-engine/internal/primitives"
+	"game-engine/internal/scene"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const skyboxScale = 1000
+
+// textureBasePaths are tried as prefixes when loading an object's texture path (e.g. run from cmd/game vs repo root).
+var textureBasePaths = []string{
+	"",
+	"assets/textures/",
+	"../../assets/textures/",
+}
+
+// View renders one scene. Update runs camera logic (free camera) and physics; Draw renders between
+// BeginMode3D and EndMode3D. Based on raylib examples/core/core_3d_camera_free.
+type View struct {
+	scene       *scene.Scene
+	camera      rl.Camera3D // scene.Camera copied in each frame (see beginFrame)
+	cursorDone  bool
+	GridVisible bool
+	primitives  *primitives.Registry
+	// Editor drag state (see UpdateEditor). Drag mode from selection box face: 0=none, 1=top/bottom (XZ), 2=side (Y);
+	// 3=rotate (gizmo mode GizmoRotate); 4=marquee (box select from empty space).
+	gizmoMode     GizmoMode
+	dragging      bool
+	dragMode      int
+	dragStartObjY float32
+	dragRot       physics.Quat // rotate drag: rotation applied to the selection so far
+	lastMouseX    int32        // screen X when rotate drag started
+	lastMouseY    int32        // screen Y when Y or rotate drag started (total delta from this)
+	dragOffsetX   float32      // XZ: offset from object center to click point so drag keeps that point under cursor
+	dragOffsetZ   float32
+	marqueeStart  rl.Vector2 // marquee: screen point where the drag started
+	marqueeAdd    bool       // marquee: Shift was held, so the boxed objects are added to the selection
+	// Skybox: optional texture drawn first in 3D mode. Cubemap or equirectangular panorama.
+	skyboxTex       rl.Texture2D
+	skyboxMesh      rl.Mesh
+	skyboxMtl       rl.Material
+	skyboxLoaded    bool
+	skyboxPending   bool   // true = path known, GPU load deferred until first Draw (after window/GL exists)
+	skyboxPath      string // set when pending; used to load texture on first frame
+	skyboxEquirect  bool   // true = panorama (2D texture + shader), false = cubemap
+	skyboxShader    rl.Shader
+	skyboxCamPosLoc int32
+	skyboxTexLoc    int32
+	// textureCache: path -> GPU texture for object albedo. Loaded lazily in Draw when object has Texture set.
+	textureCache map[string]rl.Texture2D
+	// modelCache: model path -> loaded model for objects of type model (nil = failed to load). See model.go.
+	modelCache map[string]*loadedModel
+	// materialCache: material name -> material ready to draw (nil = failed to load), valid for library
+	// revision materialRev. See material.go.
+	materialCache map[string]*primitives.PBRMaterial
+	materialRev   uint64
+	// terrainEnabled: a heightmap mesh is installed in the primitives registry for the scene's terrain object,
+	// generated from terrain. See terrain.go.
+	terrainEnabled bool
+	terrain        *scene.Heightmap
+	// preview: pending agent plan (ghosted adds, highlighted deletes); nil = none. See preview.go.
+	preview *previewState
+}
+
+// New returns a view of scn. Grid is visible by default. Tries to find a skybox in assets/skybox/
+// (see skyboxPaths); GPU resources are loaded on first Draw.
+func New(scn *scene.Scene) *View {
+	v := &View{
+		scene:        scn,
+		GridVisible:  true,
+		primitives:   primitives.NewRegistry(),
+		textureCache: make(map[string]rl.Texture2D),
+		modelCache:   make(map[string]*loadedModel),
+	}
+	v.syncCamera()
+	v.loadSkybox()
+	return v
+}
+
+// Scene returns the scene this view draws.
+func (v *View) Scene() *scene.Scene {
+	return v.scene
+}
+
+// SetGridVisible sets whether the editor grid is drawn.
+func (v *View) SetGridVisible(visible bool) {
+	v.GridVisible = visible
+}
+
+// syncCamera copies the scene camera into the raylib camera.
+func (v *View) syncCamera() {
+	c := v.scene.Camera
+	v.camera = rl.Camera3D{
+		Position:   rl.NewVector3(c.Position[0], c.Position[1], c.Position[2]),
+		Target:     rl.NewVector3(c.Target[0], c.Target[1], c.Target[2]),
+		Up:         rl.NewVector3(c.Up[0], c.Up[1], c.Up[2]),
+		Fovy:       c.Fovy,
+		Projection: rl.CameraPerspective,
+	}
+}
+
+// beginFrame tells the scene the current screen size (for its "in view" queries) and picks up camera
+// changes made through the scene (e.g. focus commands).
+func (v *View) beginFrame() {
+	v.scene.SetViewport(int(rl.GetScreenWidth()), int(rl.GetScreenHeight()))
+	v.syncCamera()
+}
+
+// Update runs once per frame in game mode (terminal closed). Uses raylib UpdateCamera with CameraFree so
+// the user can move the camera with mouse (zoom, pan) and keyboard; the cursor is disabled so the mouse is
+// captured for camera control. Then steps the scene (physics, motion, view awareness).
+func (v *View) Update() {
+	v.beginFrame()
+	v.endDrag() // terminal closed mid-drag
+	if !v.cursorDone {
+		rl.DisableCursor()
+		v.cursorDone = true
+	}
+	rl.UpdateCamera(&v.camera, rl.CameraFree)
+	v.scene.Camera = scene.Camera{
+		Position: [3]float32{v.camera.Position.X, v.camera.Position.Y, v.camera.Position.Z},
+		Target:   [3]float32{v.camera.Target.X, v.camera.Target.Y, v.camera.Target.Z},
+		Up:       [3]float32{v.camera.Up.X, v.camera.Up.Y, v.camera.Up.Z},
+		Fovy:     v.camera.Fovy,
+	}
+	v.scene.Step(rl.GetFrameTime())
+}
+
+// EnsureTexture loads and caches a texture from path. Path is tried as-is and with textureBasePaths.
+// Returns the texture and true if loaded or already cached; (zero, false) if path is empty or load failed.
+// Safe to call from Draw (loads on first use when GL context exists).
+func (v *View) EnsureTexture(path string) (rl.Texture2D, bool) {
+	if path == "" {
+		return rl.Texture2D{}, false
+	}
+	if tex, ok := v.textureCache[path]; ok && rl.IsTextureValid(tex) {
+		return tex, true
+	}
+	fullPath := resolveTexturePath(path)
+	if fullPath == "" {
+		return rl.Texture2D{}, false
+	}
+	tex := rl.LoadTexture(fullPath)
+	if !rl.IsTextureValid(tex) {
+		return rl.Texture2D{}, false
+	}
+	v.textureCache[path] = tex
+	return tex, true
+}
+
+// resolveTexturePath returns the file an object's texture path refers to (tried with textureBasePaths, then
+// as-is), or "" if there is none.
+func resolveTexturePath(path string) string {
+	var fullPath string
+	for _, base := range textureBasePaths {
+		candidate := filepath.Join(base, path)
+		if base == "" {
+			candidate = path
+		}
+		candidate = filepath.Clean(candidate)
+		if _, err := os.Stat(candidate); err == nil {
+			fullPath = candidate
+			break
+		}
+	}
+	if fullPath == "" {
+		// path as-is (absolute or cwd-relative)
+		if _, err := os.Stat(path); err == nil {
+			fullPath = filepath.Clean(path)
+		}
+	}
+	return fullPath
+}
+
+// objectTint returns the draw tint for obj, or nil for the default material color.
+func objectTint(obj scene.ObjectInstance) *[4]float32 {
+	if obj.Color[0] == 0 && obj.Color[1] == 0 && obj.Color[2] == 0 {
+		return nil
+	}
+	return &[4]float32{obj.Color[0], obj.Color[1], obj.Color[2], 1}
+}
+
+// toBoundingBox converts a physics AABB to raylib.
+func toBoundingBox(b physics.AABB) rl.BoundingBox {
+	return rl.NewBoundingBox(rl.NewVector3(b.Min[0], b.Min[1], b.Min[2]), rl.NewVector3(b.Max[0], b.Max[1], b.Max[2]))
+}
+
+// drawObject draws one primitive with world transform t: with its material when it has one (see
+// drawMaterial), else textured when the object has a loadable texture.
+func (v *View) drawObject(typ string, obj scene.ObjectInstance, t scene.Transform) {
+	if obj.Material != "" && v.drawMaterial(typ, obj, t) {
+		return
+	}
+	tint := objectTint(obj)
+	if obj.Texture != "" {
+		if tex, ok := v.EnsureTexture(obj.Texture); ok {
+			v.primitives.DrawWithTexture(typ, t.Position, t.Scale, t.Rotation, tex, tint)
+			return
+		}
+	}
+	v.primitives.Draw(typ, t.Position, t.Scale, t.Rotation, tint)
+}
+
+// drawOrientedBox draws the twelve edges of b.
+func drawOrientedBox(b physics.OBB, color rl.Color) {
+	c := b.Corners()
+	for i := range c {
+		for k := 0; k < 3; k++ {
+			// Each edge joins two corners that differ in exactly one axis bit; draw it once from the lower.
+			if j := i | 1<<k; j != i {
+				rl.DrawLine3D(rl.NewVector3(c[i][0], c[i][1], c[i][2]), rl.NewVector3(c[j][0], c[j][1], c[j][2]), color)
+			}
+		}
+	}
+}
+
+// drawSceneObject draws scene object obj with draw transform t (see scene.DrawTransform).
+func (v *View) drawSceneObject(obj scene.ObjectInstance, t scene.Transform) {
+	switch obj.Type {
+	case "terrain":
+		// Optimized terrain: single deformed plane mesh in world space, using the terrain object's texture and color.
+		if v.terrainEnabled {
+			v.drawObject("terrain", obj, scene.Transform{Scale: [3]float32{1, 1, 1}, Rotation: physics.QuatIdentity})
+		}
+	case scene.GroupType:
+		// Groups only carry a transform for their children.
+	case scene.LightType:
+		// Lights light the other objects (see setLights); the editor draws their markers.
+	case scene.ModelType:
+		v.drawModel(obj, t.Position, t.Scale, t.Rotation, objectTint(obj))
+	default:
+		v.drawObject(obj.Type, obj, t)
+	}
+}
+
+// Draw renders the 3D scene. Call after ClearBackground and before 2D overlay (e.g. terminal).
+// Sets the lights (see lights.go) and renders the shadow map first (see shadow.go), then draws the skybox (if
+// loaded), the objects, light markers in editor mode, and a Unity-style grid on the XZ plane (Y=0) when
+// GridVisible is true.
+// selectionVisible should be true only when terminal is open (editor mode); the selection outline is drawn only then.
+func (v *View) Draw(selectionVisible bool) {
+	v.syncCamera()
+	v.ensureSkyboxLoaded()
+	v.syncTerrain()
+	v.primitives.SetView(v.scene.Camera.Position, v.scene.LightDir())
+	v.setLights()
+	v.drawShadowMap()
+	rl.BeginMode3D(v.camera)
+	if v.skyboxLoaded {
+		drawSkybox(v)
+	}
+	primary := v.scene.SelectedIndex()
+	n := v.scene.ObjectCount()
+	for i := 0; i < n; i++ {
+		obj, _ := v.scene.ObjectAt(i)
+		t := v.scene.DrawTransform(i)
+		v.drawSceneObject(obj, t)
+		// Outline only in terminal mode and when this object is selected: the object's rotated box, or for a
+		// group (or an object with children) the box around all its parts. The primary selection is yellow
+		// and carries the gizmo; the rest of the selection is orange.
+		if selectionVisible && v.scene.IsSelected(i) {
+			color := rl.Orange
+			if i == primary {
+				color = rl.Yellow
+			}
+			if box, ok := v.scene.ObjectBox(i); ok && len(v.scene.Children(i)) == 0 {
+				drawOrientedBox(box, color)
+			} else {
+				box, _ := v.scene.SubtreeBounds(i)
+				rl.DrawBoundingBox(toBoundingBox(box), color)
+			}
+			if i == primary {
+				v.drawGizmo(t)
+			}
+		}
+	}
+	if selectionVisible {
+		v.drawLightMarkers()
+	}
+	if v.GridVisible {
+		drawEditorGrid()
+	}
+	v.drawPreview()
+	rl.EndMode3D()
+	if selectionVisible {
+		v.drawMarquee()
+	}
+}
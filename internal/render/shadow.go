@@ -0,0 +1,49 @@
+package render
+
+import "math"
+
+// The shadow map covers a square around the camera target whose size follows the camera distance, so
+// close-ups get sharp shadows and overviews still get shadows across the scene.
+const (
+	shadowMinRadius = 12  // world units around the target covered when the camera is close
+	shadowMaxRadius = 120 // upper bound when zoomed out (the editor grid is ±50)
+)
+
+// SetShadows turns shadows from the directional light on or off and sets their quality
+// (primitives.MinShadowQuality-MaxShadowQuality: shadow map resolution and edge softness; 0 = keep).
+func (v *View) SetShadows(enabled bool, quality int) error {
+	return v.primitives.SetShadows(enabled, quality)
+}
+
+// Shadows reports whether shadows are on and their quality.
+func (v *View) Shadows() (enabled bool, quality int) {
+	return v.primitives.Shadows()
+}
+
+// drawShadowMap renders the scene's depth from the light into the shadow map (primitives.BeginShadowPass),
+// for the main pass to sample. Objects outside the covered region cast no shadows.
+func (v *View) drawShadowMap() {
+	center, radius := v.shadowRegion()
+	if !v.primitives.BeginShadowPass(center, radius) {
+		return
+	}
+	for i := 0; i < v.scene.ObjectCount(); i++ {
+		obj, _ := v.scene.ObjectAt(i)
+		v.drawSceneObject(obj, v.scene.DrawTransform(i))
+	}
+	v.primitives.EndShadowPass()
+}
+
+// shadowRegion returns the center and radius of the region the shadow map covers: the camera target and
+// twice the camera distance, clamped to shadowMinRadius-shadowMaxRadius and rounded up to a multiple of 4
+// so the covered region (and the shadow edges) do not change with every zoom step.
+func (v *View) shadowRegion() ([3]float32, float32) {
+	c := v.scene.Camera
+	var d2 float64
+	for k := range c.Position {
+		d := float64(c.Position[k] - c.Target[k])
+		d2 += d * d
+	}
+	r := min(max(2*math.Sqrt(d2), shadowMinRadius), shadowMaxRadius)
+	return c.Target, float32(4 * math.Ceil(r/4))
+}
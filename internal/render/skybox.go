@@ -0,0 +1,162 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// skyboxPaths are tried in order so the skybox is found whether run from repo root or cmd/game.
+// Skybox assets live under assets/skybox/ to keep them separate from other future assets.
+var skyboxPaths = []string{
+	"assets/skybox/skybox.png",
+	"assets/skybox/skybox.jpg",
+	"../../assets/skybox/skybox.png",
+	"../../assets/skybox/skybox.jpg",
+}
+
+// equirectAspectMin/Max: width/height ratio for equirectangular panorama (typically 2:1).
+const equirectAspectMin = 1.8
+const equirectAspectMax = 2.2
+
+// loadSkybox finds the skybox file from skyboxPaths. GPU loading and equirect detection are deferred to
+// ensureSkyboxLoaded (called from Draw) so they run after the window/OpenGL context exists.
+func (v *View) loadSkybox() {
+	for _, p := range skyboxPaths {
+		cleaned := filepath.Clean(p)
+		if _, err := os.Stat(cleaned); err == nil {
+			v.skyboxPath = cleaned
+			v.skyboxPending = true
+			return
+		}
+	}
+}
+
+// ensureSkyboxLoaded runs the first time we Draw with a pending skybox; it loads GPU resources
+// (texture, mesh, material, shader) so that LoadTexture/LoadTextureCubemap run after the window/GL context exists.
+// Only clears pending/path on success so a failed load (e.g. GL not ready on first frame) will retry next frame.
+// Detects equirect vs cubemap from image aspect ratio when loading from a dynamically set path.
+func (v *View) ensureSkyboxLoaded() {
+	if !v.skyboxPending || v.skyboxPath == "" {
+		return
+	}
+	path := v.skyboxPath
+	img := rl.LoadImage(path)
+	if img == nil || img.Width <= 0 || img.Height <= 0 {
+		return
+	}
+	aspect := float32(img.Width) / float32(img.Height)
+	v.skyboxEquirect = aspect >= equirectAspectMin && aspect <= equirectAspectMax
+
+	if !v.skyboxEquirect {
+		v.skyboxTex = rl.LoadTextureCubemap(img, rl.CubemapLayoutAutoDetect)
+		rl.UnloadImage(img)
+		if !rl.IsTextureValid(v.skyboxTex) {
+			return
+		}
+		v.skyboxMesh = rl.GenMeshCube(1, 1, 1)
+		v.skyboxMtl = rl.LoadMaterialDefault()
+		rl.SetMaterialTexture(&v.skyboxMtl, rl.MapCubemap, v.skyboxTex)
+		v.skyboxPending = false
+		v.skyboxPath = ""
+		v.skyboxLoaded = true
+		return
+	}
+
+	rl.UnloadImage(img)
+	v.skyboxTex = rl.LoadTexture(path)
+	if !rl.IsTextureValid(v.skyboxTex) {
+		return
+	}
+	shader := loadEquirectSkyboxShader()
+	if !rl.IsShaderValid(shader) {
+		rl.UnloadTexture(v.skyboxTex)
+		return
+	}
+	v.skyboxMesh = rl.GenMeshCube(1, 1, 1)
+	v.skyboxMtl = rl.LoadMaterialDefault()
+	v.skyboxMtl.Shader = shader
+	v.skyboxCamPosLoc = rl.GetShaderLocation(shader, "cameraPosition")
+	v.skyboxTexLoc = rl.GetShaderLocation(shader, "skybox")
+	v.skyboxShader = shader
+	v.skyboxPending = false
+	v.skyboxPath = ""
+	v.skyboxLoaded = true
+}
+
+// UnloadSkybox releases GPU resources for the current skybox. Call before setting a new skybox path.
+// UnloadMaterial unloads the material's attached shader, so we must not call UnloadShader separately (double-free).
+func (v *View) UnloadSkybox() {
+	if !v.skyboxLoaded {
+		return
+	}
+	rl.UnloadTexture(v.skyboxTex)
+	rl.UnloadMesh(&v.skyboxMesh)
+	rl.UnloadMaterial(v.skyboxMtl)
+	v.skyboxLoaded = false
+}
+
+// SetSkyboxPath sets the skybox to the given image path (e.g. from a downloaded file). Loads in the next Draw.
+// Supports equirectangular panoramas (2:1 aspect) and cubemaps. Call UnloadSkybox is not required; SetSkyboxPath unloads the current skybox first.
+func (v *View) SetSkyboxPath(path string) {
+	v.UnloadSkybox()
+	v.skyboxPath = path
+	v.skyboxPending = true
+}
+
+// Equirectangular skybox shader: samples a 2D panorama by view direction.
+const (
+	equirectVS = `#version 330
+in vec3 vertexPosition;
+uniform mat4 matProjection;
+uniform mat4 matView;
+uniform mat4 matModel;
+out vec3 fragWorldPos;
+void main() {
+  vec4 worldPos = matModel * vec4(vertexPosition, 1.0);
+  fragWorldPos = worldPos.xyz;
+  gl_Position = matProjection * matView * worldPos;
+}
+`
+	equirectFS = `#version 330
+in vec3 fragWorldPos;
+out vec4 finalColor;
+uniform sampler2D skybox;
+uniform vec3 cameraPosition;
+void main() {
+  vec3 dir = normalize(fragWorldPos - cameraPosition);
+  float lon = atan(dir.z, dir.x);
+  float lat = asin(clamp(dir.y, -1.0, 1.0));
+  float u = lon / 6.28318530718 + 0.5;
+  float v = 0.5 - lat / 3.14159265359;
+  finalColor = texture(skybox, vec2(u, v));
+}
+`
+)
+
+func loadEquirectSkyboxShader() rl.Shader {
+	return rl.LoadShaderFromMemory(equirectVS, equirectFS)
+}
+
+// drawSkybox draws the skybox as a large cube centered on the camera (cubemap or equirect).
+func drawSkybox(v *View) {
+	rl.DisableDepthMask()
+	rl.DisableBackfaceCulling()
+	pos := v.camera.Position
+	scale := rl.MatrixScale(skyboxScale, skyboxScale, skyboxScale)
+	trans := rl.MatrixTranslate(pos.X, pos.Y, pos.Z)
+	transform := rl.MatrixMultiply(scale, trans)
+	if v.skyboxEquirect {
+		if v.skyboxCamPosLoc >= 0 {
+			camPos := []float32{pos.X, pos.Y, pos.Z}
+			rl.SetShaderValueV(v.skyboxMtl.Shader, v.skyboxCamPosLoc, camPos, rl.ShaderUniformVec3, 1)
+		}
+		if v.skyboxTexLoc >= 0 {
+			rl.SetShaderValueTexture(v.skyboxMtl.Shader, v.skyboxTexLoc, v.skyboxTex)
+		}
+	}
+	rl.DrawMesh(v.skyboxMesh, v.skyboxMtl, transform)
+	rl.EnableBackfaceCulling()
+	rl.EnableDepthMask()
+}
@@ -0,0 +1,45 @@
+package render
+
+import (
+	"game-engine/internal/mapgen"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// syncTerrain generates the terrain mesh again when the scene's terrain object asks for another heightmap
+// than the installed one: after cmd heightmap, undo or redo of it, and loading a scene. The mesh is kept
+// when the terrain object is deleted, so redo brings it back without generating it again. A terrain
+// without a heightmap (saved before they were recorded) keeps whatever mesh is installed.
+func (v *View) syncTerrain() {
+	obj, ok := v.scene.Terrain()
+	if !ok || obj.Heightmap == nil || (v.terrain != nil && *v.terrain == *obj.Heightmap) {
+		return
+	}
+	hm := *obj.Heightmap
+	v.terrain = &hm
+	v.installTerrain(mapgen.TerrainOptions(hm))
+}
+
+// installTerrain builds the heightmap mesh for opts and installs it in the primitives registry. A single
+// deformed plane instead of thousands of cubes is much faster to render.
+func (v *View) installTerrain(opts mapgen.HeightMapOptions) {
+	heights := mapgen.HeightField(opts)
+
+	// Build a grayscale heightmap image from the height field, then let raylib turn it into a
+	// heightmapped mesh. This avoids manual vertex pointer math.
+	img := rl.GenImageColor(opts.Width, opts.Depth, rl.Black)
+	for z := 0; z < opts.Depth; z++ {
+		for x := 0; x < opts.Width; x++ {
+			g := uint8(heights[z*opts.Width+x] * 255)
+			rl.ImageDrawPixel(img, int32(x), int32(z), rl.NewColor(g, g, g, 255))
+		}
+	}
+	size := opts.Size()
+	mesh := rl.GenMeshHeightmap(*img, rl.NewVector3(size[0], size[1], size[2]))
+	rl.UnloadImage(img)
+	if mesh.VertexCount == 0 {
+		return
+	}
+	v.primitives.SetTerrainMesh(mesh)
+	v.terrainEnabled = true
+}
@@ -0,0 +1,71 @@
+package scene
+
+import "math"
+
+// Camera is the scene's perspective viewpoint, kept as plain data so visibility queries work without a
+// window. The renderer copies it into a raylib Camera3D each frame and writes back free-camera movement.
+type Camera struct {
+	Position [3]float32
+	Target   [3]float32
+	Up       [3]float32
+	Fovy     float32 // vertical field of view in degrees
+}
+
+// Default viewport used for visibility queries until the renderer reports the real screen size.
+const (
+	defaultViewportWidth  = 1280
+	defaultViewportHeight = 720
+)
+
+// Ray is a half-line from Position along Direction (normalized).
+type Ray struct {
+	Position  [3]float32
+	Direction [3]float32
+}
+
+// LookRay returns the ray from the camera position through its target.
+func (c Camera) LookRay() Ray {
+	return Ray{Position: c.Position, Direction: normalize(sub(c.Target, c.Position))}
+}
+
+// WorldToScreen projects p to pixel coordinates in a width×height viewport (origin top-left, Y down),
+// matching raylib's GetWorldToScreen for a perspective camera. ok is false for points at or behind the camera.
+func (c Camera) WorldToScreen(p [3]float32, width, height int) (screen [2]float32, ok bool) {
+	forward := normalize(sub(c.Target, c.Position))
+	right := normalize(cross(forward, c.Up))
+	up := cross(right, forward)
+	d := sub(p, c.Position)
+	depth := dot(d, forward)
+	if depth <= 1e-6 {
+		return [2]float32{}, false
+	}
+	tanHalf := float32(math.Tan(float64(c.Fovy) * math.Pi / 360))
+	aspect := float32(width) / float32(height)
+	ndcX := dot(d, right) / (depth * tanHalf * aspect)
+	ndcY := dot(d, up) / (depth * tanHalf)
+	return [2]float32{(ndcX + 1) / 2 * float32(width), (1 - ndcY) / 2 * float32(height)}, true
+}
+
+func sub(a, b [3]float32) [3]float32 {
+	return [3]float32{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func dot(a, b [3]float32) float32 {
+	return a[0]*b[0] + a[1]*b[1] + a[2]*b[2]
+}
+
+func cross(a, b [3]float32) [3]float32 {
+	return [3]float32{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+
+func length(a [3]float32) float32 {
+	return float32(math.Sqrt(float64(dot(a, a))))
+}
+
+func normalize(a [3]float32) [3]float32 {
+	l := length(a)
+	if l == 0 {
+		return a
+	}
+	return [3]float32{a[0] / l, a[1] / l, a[2] / l}
+}
@@ -0,0 +1,481 @@
+package scene
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"game-engine/internal/physics"
+)
+
+// GroupType is the object type of a group node: an empty transform that holds child objects so they move,
+// duplicate, delete and are named as one unit. Groups are not drawn and have no collider of their own.
+const GroupType = "group"
+
+// Hierarchy: objects stay in a flat list (sceneData.Objects, draw order) and parents[i] is the index of
+// object i's parent, or -1 for a root. A child's Position, Rotation and Scale are local to its parent:
+//
+//	world position = parent world position + parent world rotation * (parent world scale * local position)
+//	world rotation = parent world rotation * local rotation
+//	world scale    = parent world scale * local scale
+//
+// Scale is applied per axis in each object's own frame, so a non-uniformly scaled parent with rotated
+// children does not shear them (unlike a full matrix hierarchy). The scene file nests children under
+// their parent (ObjectInstance.Children); loadScene flattens the tree and SaveScene nests it again.
+
+// Transform is an object's placement: position, size (zero components count as 1) and rotation.
+type Transform struct {
+	Position [3]float32
+	Scale    [3]float32
+	Rotation physics.Quat
+}
+
+// compose returns local (expressed in t's frame) in the frame t is expressed in.
+func (t Transform) compose(local Transform) Transform {
+	off := t.Rotation.Rotate([3]float32{t.Scale[0] * local.Position[0], t.Scale[1] * local.Position[1], t.Scale[2] * local.Position[2]})
+	out := Transform{Rotation: t.Rotation.Mul(local.Rotation)}
+	for k := 0; k < 3; k++ {
+		out.Position[k] = t.Position[k] + off[k]
+		out.Scale[k] = t.Scale[k] * local.Scale[k]
+	}
+	return out
+}
+
+// relative is the inverse of compose: world expressed in t's frame.
+func (t Transform) relative(world Transform) Transform {
+	inv := t.Rotation.Inverse()
+	d := inv.Rotate([3]float32{world.Position[0] - t.Position[0], world.Position[1] - t.Position[1], world.Position[2] - t.Position[2]})
+	out := Transform{Rotation: inv.Mul(world.Rotation)}
+	for k := 0; k < 3; k++ {
+		out.Position[k] = d[k] / t.Scale[k]
+		out.Scale[k] = world.Scale[k] / t.Scale[k]
+	}
+	return out
+}
+
+// Box returns the oriented box the transform covers (unit primitive scaled, rotated and moved).
+func (t Transform) Box() physics.OBB {
+	return physics.OrientedBoxAt(t.Position, t.Scale, t.Rotation)
+}
+
+// flattenInto appends obj and its nested Children (depth-first) with obj under parent. Children are
+// cleared on the flat copies.
+func (s *Scene) flattenInto(obj ObjectInstance, parent int) int {
+	children := obj.Children
+	obj.Children = nil
+	if obj.Type == ModelType && obj.Scale == ([3]float32{}) {
+		// No size given: use the model's own (a missing file keeps the default 1x1x1 box).
+		obj.Scale, _ = ModelSize(obj.Model)
+	}
+	s.assignID(&obj)
+	idx := len(s.sceneData.Objects)
+	s.byID[obj.ID] = idx
+	s.sceneData.Objects = append(s.sceneData.Objects, obj)
+	s.parents = append(s.parents, parent)
+	for _, c := range children {
+		s.flattenInto(c, idx)
+	}
+	return idx
+}
+
+// insertObject inserts obj (without its children) as a root at index at (clamped to the end), shifting the
+// objects from there on, and returns its index. Undo uses it to put deleted objects back where they were.
+func (s *Scene) insertObject(obj ObjectInstance, at int) int {
+	at = min(max(at, 0), len(s.sceneData.Objects))
+	obj.Children = nil
+	s.assignID(&obj)
+	for i, p := range s.parents {
+		if p >= at {
+			s.parents[i] = p + 1
+		}
+	}
+	s.sceneData.Objects = slices.Insert(s.sceneData.Objects, at, obj)
+	s.parents = slices.Insert(s.parents, at, -1)
+	s.reindex()
+	return at
+}
+
+// childLists returns the direct children of every object, in index order.
+func (s *Scene) childLists() [][]int {
+	out := make([][]int, len(s.sceneData.Objects))
+	for i, p := range s.parents {
+		if p >= 0 {
+			out[p] = append(out[p], i)
+		}
+	}
+	return out
+}
+
+// nest returns object i with its subtree in Children (local transforms).
+func (s *Scene) nest(i int, children [][]int) ObjectInstance {
+	obj := s.sceneData.Objects[i]
+	obj.Children = nil
+	for _, c := range children[i] {
+		obj.Children = append(obj.Children, s.nest(c, children))
+	}
+	return obj
+}
+
+// nestedObjects returns the scene as a tree (roots in index order), as written to the scene file.
+func (s *Scene) nestedObjects() []ObjectInstance {
+	children := s.childLists()
+	var out []ObjectInstance
+	for i, p := range s.parents {
+		if p < 0 {
+			out = append(out, s.nest(i, children))
+		}
+	}
+	return out
+}
+
+// Parent returns the index of the object's parent, or -1 for a root (or an out-of-range index).
+func (s *Scene) Parent(index int) int {
+	if index < 0 || index >= len(s.parents) {
+		return -1
+	}
+	return s.parents[index]
+}
+
+// Root returns the index of the topmost ancestor of the object (itself for a root).
+func (s *Scene) Root(index int) int {
+	for s.Parent(index) >= 0 {
+		index = s.parents[index]
+	}
+	return index
+}
+
+// Children returns the indices of the object's direct children.
+func (s *Scene) Children(index int) []int {
+	var out []int
+	for i, p := range s.parents {
+		if p == index {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Subtree returns the object's index followed by the indices of all its descendants.
+func (s *Scene) Subtree(index int) []int {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return nil
+	}
+	children := s.childLists()
+	out := []int{index}
+	for k := 0; k < len(out); k++ {
+		out = append(out, children[out[k]]...)
+	}
+	return out
+}
+
+// Tree returns the object at index with its descendants nested in Children (local transforms), e.g. to
+// copy or save a group. Returns false if index is out of range.
+func (s *Scene) Tree(index int) (ObjectInstance, bool) {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return ObjectInstance{}, false
+	}
+	return s.nest(index, s.childLists()), true
+}
+
+// AddTree adds obj and its nested Children as a new subtree under the object with ID parent (0, or an
+// object that no longer exists, = root) and returns the ID of obj. Positions and scales in the tree are
+// local to their parent.
+func (s *Scene) AddTree(obj ObjectInstance, parent ObjectID) ObjectID {
+	defer s.edit("add " + obj.Type)()
+	if obj.Type == "plane" {
+		obj.Scale = applyPlaneDefaultScale(obj.Type, obj.Scale)
+	}
+	return s.IDAt(s.flattenInto(obj, s.IndexOf(parent)))
+}
+
+// localTransform returns the object's own transform relative to its parent. With motion, bob and spin
+// are applied at the current scene clock.
+func (s *Scene) localTransform(i int, motion bool) Transform {
+	obj := s.sceneData.Objects[i]
+	t := Transform{Position: obj.Position, Scale: scaleForPhysics(obj.Scale), Rotation: physics.QuatFromEuler(obj.Rotation)}
+	if motion {
+		t.Position = s.MotionPosition(obj)
+		t.Rotation = s.motionRotation(obj).Mul(t.Rotation)
+	}
+	return t
+}
+
+// worldTransform returns the object's world transform. With motion, bob and spin of the object and its
+// ancestors are applied (draw transform).
+func (s *Scene) worldTransform(i int, motion bool) Transform {
+	t := s.localTransform(i, motion)
+	if p := s.parents[i]; p >= 0 {
+		return s.worldTransform(p, motion).compose(t)
+	}
+	return t
+}
+
+// WorldTransform returns the object's world position, scale and rotation. Zero scale components count as 1.
+func (s *Scene) WorldTransform(index int) (Transform, bool) {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return Transform{}, false
+	}
+	return s.worldTransform(index, false), true
+}
+
+// DrawTransform is WorldTransform with motion (bob, spin) applied at the current scene clock. Used by the renderer.
+func (s *Scene) DrawTransform(index int) Transform {
+	return s.worldTransform(index, true)
+}
+
+// toLocal converts a world transform to the local space of parent (-1 = world).
+func (s *Scene) toLocal(parent int, t Transform) Transform {
+	if parent < 0 {
+		return t
+	}
+	return s.worldTransform(parent, false).relative(t)
+}
+
+// setLocal stores t as the object's position, scale and rotation (Euler degrees).
+func (s *Scene) setLocal(i int, t Transform) {
+	obj := &s.sceneData.Objects[i]
+	obj.Position = t.Position
+	obj.Scale = t.Scale
+	obj.Rotation = t.Rotation.Euler()
+}
+
+// SetWorldPosition moves the object with the given ID (and its subtree) so its world position is pos.
+func (s *Scene) SetWorldPosition(id ObjectID, pos [3]float32) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
+	}
+	defer s.edit("move")()
+	s.setWorldPosition(index, pos)
+	return nil
+}
+
+// setWorldPosition is SetWorldPosition for the object at index, without opening a history step.
+func (s *Scene) setWorldPosition(index int, pos [3]float32) {
+	t := s.worldTransform(index, false)
+	t.Position = pos
+	s.sceneData.Objects[index].Position = s.toLocal(s.parents[index], t).Position
+}
+
+// SetWorldRotation turns the object with the given ID (and its subtree) so its world rotation is rot.
+func (s *Scene) SetWorldRotation(id ObjectID, rot physics.Quat) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
+	}
+	defer s.edit("rotate")()
+	s.setWorldRotation(index, rot)
+	return nil
+}
+
+// setWorldRotation is SetWorldRotation for the object at index, without opening a history step.
+func (s *Scene) setWorldRotation(index int, rot physics.Quat) {
+	t := s.worldTransform(index, false)
+	t.Rotation = rot.Normalize()
+	s.sceneData.Objects[index].Rotation = s.toLocal(s.parents[index], t).Rotation.Euler()
+}
+
+// setParent moves child under parent (-1 = root), keeping its world transform.
+func (s *Scene) setParent(child, parent int) {
+	s.setLocal(child, s.toLocal(parent, s.worldTransform(child, false)))
+	s.parents[child] = parent
+}
+
+// isAncestor reports whether a is b or an ancestor of b.
+func (s *Scene) isAncestor(a, b int) bool {
+	for ; b >= 0; b = s.parents[b] {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// objectBox returns the world oriented box of the object's own shape, or false for a group (no shape).
+func (s *Scene) objectBox(i int, motion bool) (physics.OBB, bool) {
+	if s.sceneData.Objects[i].Type == GroupType {
+		return physics.OBB{}, false
+	}
+	return s.worldTransform(i, motion).Box(), true
+}
+
+// objectBounds returns the world AABB of the object's own shape, or false for a group (no shape).
+func (s *Scene) objectBounds(i int, motion bool) (physics.AABB, bool) {
+	b, ok := s.objectBox(i, motion)
+	return b.AABB(), ok
+}
+
+// ObjectBox returns the oriented box around the object's own shape as drawn (with motion), e.g. for a
+// selection outline. False for groups and out-of-range indices.
+func (s *Scene) ObjectBox(index int) (physics.OBB, bool) {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return physics.OBB{}, false
+	}
+	return s.objectBox(index, true)
+}
+
+// subtreeBounds returns the union of the world AABBs of the object and its descendants. A group with
+// no drawn descendants gets a unit box at its position.
+func (s *Scene) subtreeBounds(i int, motion bool) physics.AABB {
+	var box physics.AABB
+	found := false
+	for _, j := range s.Subtree(i) {
+		b, ok := s.objectBounds(j, motion)
+		if !ok {
+			continue
+		}
+		if !found {
+			box, found = b, true
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			box.Min[k] = min(box.Min[k], b.Min[k])
+			box.Max[k] = max(box.Max[k], b.Max[k])
+		}
+	}
+	if !found {
+		return physics.BoxAt(s.worldTransform(i, motion).Position, [3]float32{1, 1, 1})
+	}
+	return box
+}
+
+// SubtreeBounds returns the world AABB around the object and all its descendants, as drawn (with motion).
+// Used for selection outlines and preview highlights of groups.
+func (s *Scene) SubtreeBounds(index int) (physics.AABB, bool) {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return physics.AABB{}, false
+	}
+	return s.subtreeBounds(index, true), true
+}
+
+// center returns the world center of the object's subtree bounds.
+func (s *Scene) center(i int, motion bool) [3]float32 {
+	b := s.subtreeBounds(i, motion)
+	return [3]float32{(b.Min[0] + b.Max[0]) / 2, (b.Min[1] + b.Max[1]) / 2, (b.Min[2] + b.Max[2]) / 2}
+}
+
+// removeObjects removes exactly the objects at the given indices (not their subtrees) and their physics
+// bodies. Surviving children of a removed object become roots at their current world transform. Invalid
+// and duplicate indices are ignored.
+func (s *Scene) removeObjects(indices []int) {
+	objs := s.sceneData.Objects
+	remove := make([]bool, len(objs))
+	for _, i := range indices {
+		if i >= 0 && i < len(objs) {
+			remove[i] = true
+		}
+	}
+	for i := range objs {
+		if p := s.parents[i]; !remove[i] && p >= 0 && remove[p] {
+			s.setParent(i, -1)
+		}
+	}
+	newIndex := make([]int, len(objs))
+	keptObjs := objs[:0]
+	keptParents := s.parents[:0]
+	for i := range objs {
+		if remove[i] {
+			newIndex[i] = -1
+			if b := s.bodies[objs[i].ID]; b != nil {
+				s.physicsWorld.RemoveBody(b)
+				delete(s.bodies, objs[i].ID)
+			}
+			continue
+		}
+		newIndex[i] = len(keptObjs)
+		keptObjs = append(keptObjs, objs[i])
+		keptParents = append(keptParents, s.parents[i])
+	}
+	for i, p := range keptParents {
+		if p >= 0 {
+			keptParents[i] = newIndex[p]
+		}
+	}
+	s.sceneData.Objects = keptObjs
+	s.parents = keptParents
+	s.reindex()
+}
+
+// topLevel returns the given indices without any that are descendants of another listed index, sorted
+// and deduplicated.
+func (s *Scene) topLevel(indices []int) []int {
+	listed := map[int]bool{}
+	for _, i := range indices {
+		listed[i] = true
+	}
+	var out []int
+	for i := range listed {
+		top := true
+		for p := s.parents[i]; p >= 0; p = s.parents[p] {
+			if listed[p] {
+				top = false
+				break
+			}
+		}
+		if top {
+			out = append(out, i)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// Group puts the objects with the given IDs (with their subtrees) under a new group node named name,
+// placed at the center of their bounds, and selects it. Objects keep their world positions. The group
+// falls and collides as one body if any member had physics on. Returns the group's ID.
+func (s *Scene) Group(ids []ObjectID, name string) (ObjectID, error) {
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("nothing to group")
+	}
+	indices, err := s.indicesOf(ids)
+	if err != nil {
+		return 0, err
+	}
+	defer s.edit("group")()
+	members := s.topLevel(indices)
+	var box physics.AABB
+	phys := false
+	for k, i := range members {
+		b := s.subtreeBounds(i, false)
+		if k == 0 {
+			box = b
+		}
+		for a := 0; a < 3; a++ {
+			box.Min[a] = min(box.Min[a], b.Min[a])
+			box.Max[a] = max(box.Max[a], b.Max[a])
+		}
+		phys = phys || physicsEnabled(s.sceneData.Objects[i])
+	}
+	center := [3]float32{(box.Min[0] + box.Max[0]) / 2, (box.Min[1] + box.Max[1]) / 2, (box.Min[2] + box.Max[2]) / 2}
+	g := s.flattenInto(ObjectInstance{Type: GroupType, Name: name, Position: center, Scale: [3]float32{1, 1, 1}, Physics: &phys}, -1)
+	for _, i := range members {
+		s.setParent(i, g)
+	}
+	s.selectIndex(g)
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+	return s.IDAt(g), nil
+}
+
+// Ungroup moves the direct children of the object with the given ID up to its parent, keeping their world
+// transforms. A group node is then removed; any other object stays as a plain object. Returns the
+// number of objects released.
+func (s *Scene) Ungroup(id ObjectID) (int, error) {
+	index, err := s.index(id)
+	if err != nil {
+		return 0, err
+	}
+	children := s.Children(index)
+	if len(children) == 0 {
+		return 0, fmt.Errorf("object has no children")
+	}
+	defer s.edit("ungroup")()
+	for _, c := range children {
+		s.setParent(c, s.parents[index])
+	}
+	if s.sceneData.Objects[index].Type == GroupType {
+		s.removeObjects([]int{index})
+	}
+	s.syncSceneToPhysics()
+	return len(children), nil
+}
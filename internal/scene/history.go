@@ -0,0 +1,331 @@
+package scene
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// DefaultHistoryDepth is how many undo steps the scene keeps unless SetHistoryDepth changes it.
+const DefaultHistoryDepth = 100
+
+// objectState is one object as the history stores it: its own fields (no Children), its parent's ID (0 =
+// root) and its index in the scene, so an object brought back by undo or redo takes its old place in the
+// draw order and the scene file.
+type objectState struct {
+	obj    ObjectInstance
+	parent ObjectID
+	index  int
+}
+
+// historyStep is one undoable step: the state before and after of every object it changed, keyed by ID
+// (nil = the object did not exist), and the gravity before and after. ids lists the changed objects in
+// scene order.
+type historyStep struct {
+	label   string
+	ids     []ObjectID
+	before  map[ObjectID]*objectState
+	after   map[ObjectID]*objectState
+	gravity [2][3]float32
+}
+
+// history is the undo/redo stack. A step is recorded by comparing a snapshot taken when the outermost
+// BeginChange opens with the scene when the matching EndChange closes, so every mutation in between
+// (adds, deletes, edits, reparenting, gravity) is covered without each one describing its own inverse.
+type history struct {
+	undo  []*historyStep // oldest first
+	redo  []*historyStep // next to redo last
+	depth int            // max undo steps; 0 = DefaultHistoryDepth
+
+	revision uint64 // bumped by every recorded, undone or redone step (see Revision, Modified)
+
+	open         int // BeginChange nesting
+	label        string
+	start        []objectState // snapshot at the outermost BeginChange, in scene order
+	startGravity [3]float32
+}
+
+// BeginChange opens a history step labelled label (e.g. the command line or LLM request). Changes until the
+// matching EndChange undo as one step; nested calls join the open step and their label is ignored. Scene
+// mutators open their own step, so callers only need this to group several of them (one command, one LLM
+// turn, one mouse drag).
+func (s *Scene) BeginChange(label string) {
+	h := &s.history
+	if h.open == 0 {
+		h.label = label
+		h.start = s.snapshot()
+		h.startGravity = s.physicsWorld.Gravity
+	}
+	h.open++
+}
+
+// EndChange closes the step opened by BeginChange. When the outermost step closes and the scene changed,
+// the step is pushed onto the undo stack and the redo stack is cleared.
+func (s *Scene) EndChange() {
+	h := &s.history
+	if h.open == 0 {
+		return
+	}
+	h.open--
+	if h.open == 0 {
+		s.commitChange()
+		h.start = nil
+	}
+}
+
+// edit opens a history step for one mutator and returns the function that closes it:
+//
+//	defer s.edit("color")()
+func (s *Scene) edit(label string) func() {
+	s.BeginChange(label)
+	return s.EndChange
+}
+
+// ChangeGroup joins the steps opened with BeginGroupChange into one undo step, as long as no other step is
+// recorded between them. It is for an LLM turn, whose actions run in batches between round-trips to the
+// model: each batch is its own Begin/End pair, so edits the user makes in the meantime (commands, drags)
+// stay their own steps, and the turn still undoes as one step when nothing came between its batches.
+type ChangeGroup struct {
+	Label string
+	last  *historyStep // the group's step on the undo stack, nil before its first change
+}
+
+// BeginGroupChange opens a history step for one batch of the group g (see ChangeGroup).
+func (s *Scene) BeginGroupChange(g *ChangeGroup) {
+	s.BeginChange(g.Label)
+}
+
+// EndGroupChange closes the step opened by BeginGroupChange. When the batch changed the scene and the last
+// undo step was the group's own, the batch is merged into that step.
+func (s *Scene) EndGroupChange(g *ChangeGroup) {
+	h := &s.history
+	var top *historyStep
+	if len(h.undo) > 0 {
+		top = h.undo[len(h.undo)-1]
+	}
+	s.EndChange()
+	if h.open > 0 || len(h.undo) == 0 || h.undo[len(h.undo)-1] == top {
+		return // nested in another open step, or nothing recorded
+	}
+	if n := len(h.undo); g.last != nil && top == g.last && n >= 2 && h.undo[n-2] == g.last {
+		mergeStep(g.last, h.undo[n-1])
+		h.undo = h.undo[:n-1]
+	}
+	g.last = h.undo[len(h.undo)-1]
+}
+
+// mergeStep folds next, recorded right after into, into into: objects both changed keep into's before
+// state and take next's after state.
+func mergeStep(into, next *historyStep) {
+	for _, id := range next.ids {
+		if _, ok := into.before[id]; ok {
+			into.after[id] = next.after[id]
+			continue
+		}
+		into.ids = append(into.ids, id)
+		into.before[id], into.after[id] = next.before[id], next.after[id]
+	}
+	into.gravity[1] = next.gravity[1]
+}
+
+// snapshot returns the state of every object, in scene order.
+func (s *Scene) snapshot() []objectState {
+	out := make([]objectState, len(s.sceneData.Objects))
+	for i, obj := range s.sceneData.Objects {
+		out[i] = objectState{obj: copyObject(obj), parent: s.IDAt(s.parents[i]), index: i}
+	}
+	return out
+}
+
+// copyObject returns obj without Children and with its own copies of Physics, Light and Heightmap, so later
+// edits through either pointer do not reach the other.
+func copyObject(obj ObjectInstance) ObjectInstance {
+	obj.Children = nil
+	if obj.Physics != nil {
+		p := *obj.Physics
+		obj.Physics = &p
+	}
+	if obj.Light != nil {
+		l := *obj.Light
+		obj.Light = &l
+	}
+	if obj.Heightmap != nil {
+		h := *obj.Heightmap
+		obj.Heightmap = &h
+	}
+	return obj
+}
+
+// commitChange records the difference between the open step's start snapshot and the scene now.
+func (s *Scene) commitChange() {
+	h := &s.history
+	step := &historyStep{
+		label:   h.label,
+		before:  make(map[ObjectID]*objectState),
+		after:   make(map[ObjectID]*objectState),
+		gravity: [2][3]float32{h.startGravity, s.physicsWorld.Gravity},
+	}
+	now := s.snapshot()
+	current := make(map[ObjectID]*objectState, len(now))
+	for i := range now {
+		current[now[i].obj.ID] = &now[i]
+	}
+	started := make(map[ObjectID]bool, len(h.start))
+	for i := range h.start {
+		b := &h.start[i]
+		id := b.obj.ID
+		started[id] = true
+		// Only the object itself and its parent count: an index shifted by adds and deletes elsewhere is
+		// not a change of this object.
+		if a := current[id]; a == nil || a.parent != b.parent || !reflect.DeepEqual(a.obj, b.obj) {
+			step.ids = append(step.ids, id)
+			step.before[id], step.after[id] = b, a
+		}
+	}
+	for i := range now {
+		if id := now[i].obj.ID; !started[id] {
+			step.ids = append(step.ids, id)
+			step.before[id], step.after[id] = nil, &now[i]
+		}
+	}
+	if len(step.ids) == 0 && step.gravity[0] == step.gravity[1] {
+		return
+	}
+	h.undo = append(h.undo, step)
+	h.redo = nil
+	h.revision++
+	s.trimHistory()
+}
+
+// trimHistory drops the oldest undo steps beyond the configured depth.
+func (s *Scene) trimHistory() {
+	h := &s.history
+	if d := s.HistoryDepth(); len(h.undo) > d {
+		h.undo = append([]*historyStep(nil), h.undo[len(h.undo)-d:]...)
+	}
+}
+
+// restore puts the objects of a step into the given states (from step.before or step.after): objects whose
+// state is nil are removed, the others are overwritten or re-inserted under their own ID at their recorded
+// index (lowest first, so each lands where it was), then parents are relinked by ID.
+func (s *Scene) restore(step *historyStep, states map[ObjectID]*objectState, gravity [3]float32) {
+	var gone []int
+	for _, id := range step.ids {
+		if states[id] == nil {
+			if i := s.IndexOf(id); i >= 0 {
+				gone = append(gone, i)
+			}
+		}
+	}
+	s.removeObjects(gone)
+	var back []*objectState
+	for _, id := range step.ids {
+		st := states[id]
+		if st == nil {
+			continue
+		}
+		if i := s.IndexOf(id); i >= 0 {
+			s.sceneData.Objects[i] = copyObject(st.obj)
+		} else {
+			back = append(back, st)
+		}
+	}
+	sort.SliceStable(back, func(a, b int) bool { return back[a].index < back[b].index })
+	for _, st := range back {
+		s.insertObject(copyObject(st.obj), st.index)
+	}
+	for _, id := range step.ids {
+		if st := states[id]; st != nil {
+			s.parents[s.IndexOf(id)] = s.IndexOf(st.parent)
+		}
+	}
+	s.physicsWorld.Gravity = gravity
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+}
+
+// Undo reverts the last n steps (n < 1 counts as 1) and returns how many were undone. Undo inside an open
+// step (e.g. an LLM turn that says "undo that") first closes what the step changed so far as its own step.
+func (s *Scene) Undo(n int) (int, error) {
+	return s.travel(n, true)
+}
+
+// Redo re-applies the last n undone steps (n < 1 counts as 1) and returns how many were redone. Any new
+// change clears the redo stack.
+func (s *Scene) Redo(n int) (int, error) {
+	return s.travel(n, false)
+}
+
+// travel moves n steps back (undo) or forward through the history.
+func (s *Scene) travel(n int, back bool) (int, error) {
+	h := &s.history
+	if h.open > 0 {
+		s.commitChange()
+	}
+	from, to := &h.undo, &h.redo
+	if !back {
+		from, to = to, from
+	}
+	if len(*from) == 0 {
+		if back {
+			return 0, fmt.Errorf("nothing to undo")
+		}
+		return 0, fmt.Errorf("nothing to redo")
+	}
+	n = min(max(n, 1), len(*from))
+	for i := 0; i < n; i++ {
+		step := (*from)[len(*from)-1]
+		*from = (*from)[:len(*from)-1]
+		if back {
+			s.restore(step, step.before, step.gravity[0])
+		} else {
+			s.restore(step, step.after, step.gravity[1])
+		}
+		*to = append(*to, step)
+		h.revision++
+	}
+	if h.open > 0 {
+		// The rest of the open step starts from here; undo itself is not recorded.
+		h.start = s.snapshot()
+		h.startGravity = s.physicsWorld.Gravity
+	}
+	return n, nil
+}
+
+// resetHistory drops every undo and redo step, e.g. when another scene is loaded. An open step continues
+// from the current state.
+func (s *Scene) resetHistory() {
+	h := &s.history
+	h.undo, h.redo = nil, nil
+	if h.open > 0 {
+		h.start = s.snapshot()
+		h.startGravity = s.physicsWorld.Gravity
+	}
+}
+
+// History returns the labels of the undo steps (next to undo first) and redo steps (next to redo first).
+func (s *Scene) History() (undo, redo []string) {
+	h := &s.history
+	for i := len(h.undo) - 1; i >= 0; i-- {
+		undo = append(undo, h.undo[i].label)
+	}
+	for i := len(h.redo) - 1; i >= 0; i-- {
+		redo = append(redo, h.redo[i].label)
+	}
+	return undo, redo
+}
+
+// HistoryDepth returns the maximum number of undo steps kept.
+func (s *Scene) HistoryDepth() int {
+	if s.history.depth <= 0 {
+		return DefaultHistoryDepth
+	}
+	return s.history.depth
+}
+
+// SetHistoryDepth sets the maximum number of undo steps kept (n <= 0 selects DefaultHistoryDepth) and drops
+// older steps beyond it.
+func (s *Scene) SetHistoryDepth(n int) {
+	s.history.depth = max(n, 0)
+	s.trimHistory()
+}
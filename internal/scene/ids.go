@@ -0,0 +1,99 @@
+package scene
+
+import "fmt"
+
+// ObjectID identifies a scene object for its whole life: it is saved in the scene file, stays the same while
+// other objects are added, deleted or regrouped, and is never reused within a session. 0 means no object.
+// Anything that refers to an object across frames (selection, undo, physics bodies, async downloads, agent
+// previews) holds its ID; indices are only valid until the next add or delete.
+type ObjectID uint64
+
+// assignID gives obj a fresh ID unless it already carries one that is not in use (loaded from the scene
+// file or restored by undo).
+func (s *Scene) assignID(obj *ObjectInstance) {
+	if _, taken := s.byID[obj.ID]; obj.ID != 0 && !taken {
+		if obj.ID >= s.nextID {
+			s.nextID = obj.ID + 1
+		}
+		return
+	}
+	if s.nextID == 0 {
+		s.nextID = 1
+	}
+	obj.ID = s.nextID
+	s.nextID++
+}
+
+// reindex rebuilds the ID→index map after objects were removed or reordered.
+func (s *Scene) reindex() {
+	s.byID = make(map[ObjectID]int, len(s.sceneData.Objects))
+	for i, obj := range s.sceneData.Objects {
+		s.byID[obj.ID] = i
+	}
+}
+
+// IndexOf returns the current index of the object with the given ID, or -1 if there is none.
+func (s *Scene) IndexOf(id ObjectID) int {
+	if i, ok := s.byID[id]; ok && id != 0 {
+		return i
+	}
+	return -1
+}
+
+// index returns the current index of the object with the given ID, or an error if it no longer exists. The
+// ID-based mutators use it to turn the caller's ID into an index for this one change.
+func (s *Scene) index(id ObjectID) (int, error) {
+	if i := s.IndexOf(id); i >= 0 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("object #%d no longer exists", id)
+}
+
+// indicesOf returns the current indices of the objects with the given IDs, or an error naming the first
+// one that no longer exists.
+func (s *Scene) indicesOf(ids []ObjectID) ([]int, error) {
+	out := make([]int, len(ids))
+	for k, id := range ids {
+		i, err := s.index(id)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = i
+	}
+	return out, nil
+}
+
+// IDAt returns the ID of the object at index, or 0 if index is out of range.
+func (s *Scene) IDAt(index int) ObjectID {
+	if index < 0 || index >= len(s.sceneData.Objects) {
+		return 0
+	}
+	return s.sceneData.Objects[index].ID
+}
+
+// IDs returns the IDs of the objects at the given indices (out-of-range indices are skipped).
+func (s *Scene) IDs(indices []int) []ObjectID {
+	out := make([]ObjectID, 0, len(indices))
+	for _, i := range indices {
+		if id := s.IDAt(i); id != 0 {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Indices returns the current indices of the objects with the given IDs (deleted objects are skipped).
+func (s *Scene) Indices(ids []ObjectID) []int {
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if i := s.IndexOf(id); i >= 0 {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// Object returns the object with the given ID and true, or (zero, false) if it no longer exists.
+func (s *Scene) Object(id ObjectID) (ObjectInstance, bool) {
+	return s.ObjectAt(s.IndexOf(id))
+}
@@ -0,0 +1,383 @@
+package scene
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"game-engine/internal/physics"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scene library: every scene is a YAML file <name>.yaml in the scenes directory (assets/scenes, found from
+// the repo root or cmd/game). The Scene remembers the name it was opened or saved under; SaveScene writes
+// back to it and SaveAs/Load switch to another. Autosave writes timestamped snapshots to backups/ in the
+// same directory and keeps only the newest few.
+
+// DefaultSceneName is the scene opened when no other is requested (assets/scenes/default.yaml).
+const DefaultSceneName = "default"
+
+// DefaultAutosaveKeep is how many autosave snapshots of a scene Autosave keeps when keep <= 0.
+const DefaultAutosaveKeep = 10
+
+// backupStampLayout is the timestamp in an autosave snapshot's name, to the millisecond.
+const backupStampLayout = "20060102-150405.000"
+
+// backupStamp matches what follows "<scene>-" in a snapshot name: the timestamp (older snapshots have no
+// milliseconds) and, for a second snapshot in the same millisecond, a counter.
+var backupStamp = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}(\.[0-9]{3})?(-[0-9]+)?$`)
+
+// backupDirName is the subdirectory of the scenes directory that holds autosave snapshots.
+const backupDirName = "backups"
+
+// sceneDirs are tried in order so the scenes directory is found whether run from repo root or cmd/game.
+var sceneDirs = []string{
+	"assets/scenes",
+	"../../assets/scenes",
+}
+
+// SceneInfo describes one scene file in the library (see ListScenes).
+type SceneInfo struct {
+	Name     string
+	Objects  int // objects in the file, children included
+	Modified time.Time
+}
+
+// SceneDir returns the scenes directory: the first existing path in sceneDirs, or the first entry if none
+// exists yet (it is created on save).
+func SceneDir() string {
+	return firstDir(sceneDirs)
+}
+
+// firstDir returns the first existing directory in dirs, or the first entry if none exists.
+func firstDir(dirs []string) string {
+	for _, d := range dirs {
+		if info, err := os.Stat(filepath.Clean(d)); err == nil && info.IsDir() {
+			return filepath.Clean(d)
+		}
+	}
+	return filepath.Clean(dirs[0])
+}
+
+// BackupDir returns the directory autosave snapshots are written to.
+func BackupDir() string {
+	return filepath.Join(SceneDir(), backupDirName)
+}
+
+// ValidSceneName returns an error unless name can be used as a scene file name: letters, digits, '-', '_'
+// and '.', not starting with '.'.
+func ValidSceneName(name string) error {
+	return validName("scene", name)
+}
+
+// ValidPrefabName is ValidSceneName for prefabs.
+func ValidPrefabName(name string) error {
+	return validName("prefab", name)
+}
+
+// ValidMaterialName is ValidSceneName for materials.
+func ValidMaterialName(name string) error {
+	return validName("material", name)
+}
+
+// validName checks a library file name; kind ("scene", "prefab", "material") names it in the error.
+func validName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name is required", kind)
+	}
+	if strings.HasPrefix(name, ".") {
+		return fmt.Errorf("%s name %q must not start with '.'", kind, name)
+	}
+	for _, r := range name {
+		ok := r == '-' || r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !ok {
+			return fmt.Errorf("%s name %q may only contain letters, digits, '-', '_' and '.'", kind, name)
+		}
+	}
+	return nil
+}
+
+// sceneFile returns the path of the named scene in dir.
+func sceneFile(dir, name string) (string, error) {
+	return libraryFile(dir, "scene", name)
+}
+
+// libraryFile returns the path of the named kind ("scene", "prefab", "material") of YAML file in dir.
+func libraryFile(dir, kind, name string) (string, error) {
+	name = strings.TrimSuffix(name, ".yaml")
+	if err := validName(kind, name); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".yaml"), nil
+}
+
+// ListScenes returns the scenes in the library, sorted by name.
+func ListScenes() ([]SceneInfo, error) {
+	return listSceneFiles(SceneDir())
+}
+
+// SceneExists reports whether the named scene has a file in the library.
+func SceneExists(name string) bool {
+	path, err := sceneFile(SceneDir(), name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// ListBackups returns the autosave snapshots, newest first. Load them with LoadBackup.
+func ListBackups() ([]SceneInfo, error) {
+	out, err := listSceneFiles(BackupDir())
+	sort.Slice(out, func(a, b int) bool { return out[a].Modified.After(out[b].Modified) })
+	return out, err
+}
+
+// listSceneFiles returns the *.yaml files in dir, sorted by name. A missing dir is an empty list.
+func listSceneFiles(dir string) ([]SceneInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []SceneInfo
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		si := SceneInfo{Name: strings.TrimSuffix(e.Name(), ".yaml"), Modified: info.ModTime()}
+		if sd, _, err := readSceneFile(filepath.Join(dir, e.Name())); err == nil {
+			si.Objects = countObjects(sd.Objects)
+		}
+		out = append(out, si)
+	}
+	return out, nil
+}
+
+// countObjects returns the number of objects in a nested object list.
+func countObjects(objs []ObjectInstance) int {
+	n := len(objs)
+	for _, o := range objs {
+		n += countObjects(o.Children)
+	}
+	return n
+}
+
+// readSceneFile reads, upgrades and validates a scene YAML file (see parseScene). Issues are problems in
+// single objects; the error means the file cannot be loaded.
+func readSceneFile(path string) (SceneData, []Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SceneData{}, nil, err
+	}
+	return parseScene(data, path)
+}
+
+// writeSceneFile writes the scene as YAML to path, with children nested under their parents, creating the
+// directory if needed.
+func (s *Scene) writeSceneFile(path string) error {
+	return writeObjects(path, s.nestedObjects())
+}
+
+// writeObjects writes a nested object list as a scene file of the current schema version, creating the
+// directory if needed.
+func writeObjects(path string, objs []ObjectInstance) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(&SceneData{Version: SchemaVersion, Objects: objs})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Open returns the named scene from the library ("" = DefaultSceneName). The returned scene is never nil:
+// if the file is missing or cannot be parsed it is empty and the error says why; a missing file still
+// leaves the scene named name, so the first save creates it. Problems in single objects do not stop the
+// load; LoadIssues lists them.
+func Open(name string) (*Scene, error) {
+	if name == "" {
+		name = DefaultSceneName
+	}
+	s := NewEmpty()
+	path, err := sceneFile(SceneDir(), name)
+	if err != nil {
+		return s, err
+	}
+	s.name = name
+	sd, issues, err := readSceneFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, fmt.Errorf("scene %q not found", name)
+		}
+		return s, err
+	}
+	s.replaceObjects(sd.Objects)
+	s.loadIssues = issues
+	return s, nil
+}
+
+// Name returns the name the scene was opened or last saved under, or "" for an unsaved new scene.
+func (s *Scene) Name() string {
+	return s.name
+}
+
+// Modified reports whether the scene changed (any undo step, undo or redo) since it was opened or saved.
+func (s *Scene) Modified() bool {
+	return s.history.revision != s.savedRevision
+}
+
+// Load replaces the scene with the named scene from the library. Selection and undo history are cleared.
+// The current scene is not saved; callers that care check Modified first (cmd load autosaves it). A file
+// that cannot be parsed leaves the scene unchanged; problems in single objects are in LoadIssues.
+func (s *Scene) Load(name string) error {
+	path, err := sceneFile(SceneDir(), name)
+	if err != nil {
+		return err
+	}
+	sd, issues, err := readSceneFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("scene %q not found (cmd scenes lists them)", name)
+	}
+	if err != nil {
+		return err
+	}
+	s.replaceObjects(sd.Objects)
+	s.loadIssues = issues
+	s.name = strings.TrimSuffix(name, ".yaml")
+	return nil
+}
+
+// LoadBackup replaces the scene with an autosave snapshot (a name from ListBackups). The scene becomes
+// unnamed, so saving it needs a name and never overwrites the snapshot or the scene it was taken from.
+func (s *Scene) LoadBackup(name string) error {
+	path, err := sceneFile(BackupDir(), name)
+	if err != nil {
+		return err
+	}
+	sd, issues, err := readSceneFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("backup %q not found (cmd scenes --backups lists them)", name)
+	}
+	if err != nil {
+		return err
+	}
+	s.replaceObjects(sd.Objects)
+	s.loadIssues = issues
+	s.name = ""
+	return nil
+}
+
+// replaceObjects swaps in a new object list (nested, as read from a scene file) and clears everything tied
+// to the old objects: physics bodies, selection and undo history. Linked prefab instances are brought up
+// to date with their prefabs; those whose prefab is missing keep the objects saved with them.
+func (s *Scene) replaceObjects(objs []ObjectInstance) {
+	s.clearObjects()
+	for _, obj := range objs {
+		s.flattenInto(obj, -1)
+	}
+	_, _ = s.RefreshPrefab("")
+	s.ensurePhysicsBodies()
+	s.resetHistory()
+	s.savedRevision = s.history.revision
+}
+
+// clearObjects removes every object, its physics body and the selection.
+func (s *Scene) clearObjects() {
+	s.sceneData.Objects = nil
+	s.parents = nil
+	s.byID = make(map[ObjectID]int)
+	for _, b := range s.bodies {
+		s.physicsWorld.RemoveBody(b)
+	}
+	s.bodies = make(map[ObjectID]*physics.Body)
+	s.selection = nil
+}
+
+// SaveScene writes the scene (including runtime-spawned objects) to its file in the library. Returns an
+// error for an unnamed scene (after NewScene or LoadBackup); use SaveAs.
+func (s *Scene) SaveScene() error {
+	if s.name == "" {
+		return fmt.Errorf("scene has no name yet (save it with a name, e.g. cmd save my-level)")
+	}
+	return s.SaveAs(s.name)
+}
+
+// SaveAs writes the scene to the named file in the library (replacing any scene of that name) and makes
+// it the scene's name, so later saves go there.
+func (s *Scene) SaveAs(name string) error {
+	path, err := sceneFile(SceneDir(), name)
+	if err != nil {
+		return err
+	}
+	if err := s.writeSceneFile(path); err != nil {
+		return err
+	}
+	s.name = strings.TrimSuffix(name, ".yaml")
+	s.savedRevision = s.history.revision
+	return nil
+}
+
+// Autosave writes a snapshot of the scene to the backup directory as <name>-<timestamp>.yaml ("untitled"
+// for an unnamed scene) and deletes this scene's oldest snapshots beyond keep (keep <= 0 =
+// DefaultAutosaveKeep); other scenes' snapshots are left alone. It does not touch the scene's own file.
+// Returns the snapshot's name.
+func (s *Scene) Autosave(keep int) (string, error) {
+	if keep <= 0 {
+		keep = DefaultAutosaveKeep
+	}
+	base := s.name
+	if base == "" {
+		base = "untitled"
+	}
+	stamp := base + "-" + time.Now().Format(backupStampLayout)
+	name := stamp
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(BackupDir(), name+".yaml")); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", stamp, n)
+	}
+	if err := s.writeSceneFile(filepath.Join(BackupDir(), name+".yaml")); err != nil {
+		return "", err
+	}
+	backups, err := ListBackups()
+	if err != nil {
+		return name, err
+	}
+	var own []SceneInfo
+	for _, b := range backups {
+		if isBackupOf(b.Name, base) {
+			own = append(own, b)
+		}
+	}
+	for _, b := range own[min(keep, len(own)):] {
+		_ = os.Remove(filepath.Join(BackupDir(), b.Name+".yaml"))
+	}
+	return name, nil
+}
+
+// isBackupOf reports whether the snapshot name was taken by Autosave of the scene base, and not of another
+// scene whose name merely starts with it (e.g. "castle-2" for "castle").
+func isBackupOf(name, base string) bool {
+	rest, ok := strings.CutPrefix(name, base+"-")
+	return ok && backupStamp.MatchString(rest)
+}
+
+// Revision returns a counter that changes whenever the scene changes (an undo step is recorded, undone or
+// redone), e.g. to decide whether an autosave is due.
+func (s *Scene) Revision() uint64 {
+	return s.history.revision
+}
@@ -0,0 +1,246 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Lights: the sun (a directional light set by a lighting profile, see SetLighting) and light objects, point
+// and spot lights placed in the scene (type "light" with ObjectInstance.Light). A light object's color is
+// its light color (zero = white); a spot light shines along its rotation applied to straight down (-Y), so an
+// unrotated spot lights the ground below it. Light objects are not solid: they have no physics body, and are
+// only drawn as markers in the editor. The renderer evaluates the lights nearest the camera (see Lights).
+
+// LightType is the object type of point and spot lights.
+const LightType = "light"
+
+// Light kinds.
+const (
+	PointLight = "point"
+	SpotLight  = "spot"
+)
+
+// LightKinds are the kinds a light object can be.
+var LightKinds = []string{PointLight, SpotLight}
+
+// Defaults for the light properties a light object does not set.
+const (
+	DefaultLightIntensity = 1
+	DefaultLightRange     = 10 // world units
+	DefaultSpotCone       = 45 // degrees, full angle
+)
+
+// lightMarkerSize is the box a new light object gets, so it can be clicked and moved in the editor.
+const lightMarkerSize = 0.3
+
+// Light is the light of a light object.
+type Light struct {
+	Kind      string   `yaml:"kind"`                // "point" or "spot"
+	Intensity *float32 `yaml:"intensity,omitempty"` // brightness at the light, fading to 0 at range; 0 = off, nil = DefaultLightIntensity
+	Range     float32  `yaml:"range,omitempty"`     // distance at which the light fades out; 0 = DefaultLightRange
+	Cone      float32  `yaml:"cone,omitempty"`      // spot only: full cone angle in degrees (1-179); 0 = DefaultSpotCone
+}
+
+// IntensityValue returns the intensity, DefaultLightIntensity if unset.
+func (l Light) IntensityValue() float32 {
+	if l.Intensity == nil {
+		return DefaultLightIntensity
+	}
+	return *l.Intensity
+}
+
+// RangeValue returns the range, DefaultLightRange if unset.
+func (l Light) RangeValue() float32 {
+	if l.Range == 0 {
+		return DefaultLightRange
+	}
+	return l.Range
+}
+
+// ConeValue returns the spot cone angle, DefaultSpotCone if unset.
+func (l Light) ConeValue() float32 {
+	if l.Cone == 0 {
+		return DefaultSpotCone
+	}
+	return l.Cone
+}
+
+// Check returns an error describing the first invalid value: an unknown kind, a negative intensity or
+// range, a cone outside 1-179 degrees, or numbers that are not finite.
+func (l Light) Check() error {
+	for _, f := range []float32{l.IntensityValue(), l.Range, l.Cone} {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return fmt.Errorf("light values must be finite numbers")
+		}
+	}
+	switch {
+	case l.Kind != PointLight && l.Kind != SpotLight:
+		return fmt.Errorf("unknown light kind %q (%s)", l.Kind, strings.Join(LightKinds, " or "))
+	case l.IntensityValue() < 0:
+		return fmt.Errorf("light intensity must not be negative")
+	case l.Range < 0:
+		return fmt.Errorf("light range must not be negative")
+	case l.Cone != 0 && (l.Cone < 1 || l.Cone > 179):
+		return fmt.Errorf("spot cone must be between 1 and 179 degrees")
+	}
+	return nil
+}
+
+// LightProperties are the light values SetSelectedLight accepts.
+var LightProperties = []string{"intensity", "range", "cone"}
+
+// LightInstance returns a light object of the given kind at pos, with the default intensity, range and
+// cone. Its physics is off (see syncSceneToPhysics: lights have no body at all).
+func LightInstance(kind string, pos [3]float32) (ObjectInstance, error) {
+	l := Light{Kind: kind}
+	if err := l.Check(); err != nil {
+		return ObjectInstance{}, err
+	}
+	static := false
+	return ObjectInstance{
+		Type:     LightType,
+		Name:     kind + " light",
+		Position: pos,
+		Scale:    [3]float32{lightMarkerSize, lightMarkerSize, lightMarkerSize},
+		Physics:  &static,
+		Light:    &l,
+	}, nil
+}
+
+// AddLight adds a light object (see LightInstance) as a new root, selects it and returns its ID.
+func (s *Scene) AddLight(kind string, pos [3]float32) (ObjectID, error) {
+	obj, err := LightInstance(kind, pos)
+	if err != nil {
+		return 0, err
+	}
+	defer s.edit("add light")()
+	i := s.flattenInto(obj, -1)
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+	s.selectIndex(i)
+	return s.IDAt(i), nil
+}
+
+// SetSelectedLight sets one light property ("intensity", "range" or "cone") on every selected light object
+// and returns how many were changed. Other selected objects are left alone.
+func (s *Scene) SetSelectedLight(key string, value float32) (int, error) {
+	var lights []int
+	for _, i := range s.Selection() {
+		if s.sceneData.Objects[i].Type == LightType && s.sceneData.Objects[i].Light != nil {
+			lights = append(lights, i)
+		}
+	}
+	if len(lights) == 0 {
+		return 0, fmt.Errorf("no light selected (cmd light add point|spot adds one)")
+	}
+	next := make([]Light, len(lights))
+	for n, i := range lights {
+		next[n] = *s.sceneData.Objects[i].Light
+		switch key {
+		case "intensity":
+			next[n].Intensity = &value
+		case "range":
+			next[n].Range = value
+		case "cone":
+			next[n].Cone = value
+		default:
+			return 0, fmt.Errorf("unknown light property %q (known: %s)", key, strings.Join(LightProperties, ", "))
+		}
+		if err := next[n].Check(); err != nil {
+			return 0, err
+		}
+	}
+	defer s.edit("light")()
+	for n, i := range lights {
+		// A new Light each, so the undo snapshot keeps the old values.
+		s.sceneData.Objects[i].Light = &next[n]
+	}
+	return len(lights), nil
+}
+
+// LightSource is a light object as the renderer needs it: world position and spot direction, and its
+// properties with the defaults filled in.
+type LightSource struct {
+	Index     int  // object index
+	Spot      bool // false = point light
+	Position  [3]float32
+	Direction [3]float32 // spot only: unit direction the cone points
+	Color     [3]float32 // RGB 0-1
+	Intensity float32
+	Range     float32
+	Cone      float32 // spot only: full cone angle in degrees
+}
+
+// Lights returns the scene's light objects at their draw transforms (including motion), nearest to the
+// given point first, so a renderer that can only evaluate a few keeps the ones that matter most.
+func (s *Scene) Lights(near [3]float32) []LightSource {
+	var out []LightSource
+	for i, obj := range s.sceneData.Objects {
+		if obj.Type != LightType || obj.Light == nil {
+			continue
+		}
+		t := s.DrawTransform(i)
+		color := obj.Color
+		if color == ([3]float32{}) {
+			color = [3]float32{1, 1, 1}
+		}
+		out = append(out, LightSource{
+			Index:     i,
+			Spot:      obj.Light.Kind == SpotLight,
+			Position:  t.Position,
+			Direction: t.Rotation.Rotate([3]float32{0, -1, 0}),
+			Color:     color,
+			Intensity: obj.Light.IntensityValue(),
+			Range:     obj.Light.RangeValue(),
+			Cone:      obj.Light.ConeValue(),
+		})
+	}
+	// Distance to the edge of the lit sphere: a wide light a little further away still reaches near.
+	reach := func(l LightSource) float64 {
+		var d2 float64
+		for k := range near {
+			d := float64(l.Position[k] - near[k])
+			d2 += d * d
+		}
+		return math.Sqrt(d2) - float64(l.Range)
+	}
+	sort.SliceStable(out, func(a, b int) bool { return reach(out[a]) < reach(out[b]) })
+	return out
+}
+
+// Sun is the directional light of a lighting profile.
+type Sun struct {
+	Dir       [3]float32 // direction to the sun (not normalized)
+	Color     [3]float32 // RGB 0-1
+	Intensity float32
+	Ambient   [3]float32 // light reaching every surface, so shade is not pure black
+}
+
+// LightingProfiles are the profiles SetLighting accepts; "noon" is the default.
+var LightingProfiles = []string{"noon", "sunset", "night"}
+
+// lightingProfiles: noon is high and white, sunset low and warm, night a faint blue moon with little ambient
+// light, so light objects carry the scene.
+var lightingProfiles = map[string]Sun{
+	"noon":   {Dir: [3]float32{0.5, 1, 0.5}, Color: [3]float32{1, 0.98, 0.95}, Intensity: 0.75, Ambient: [3]float32{0.2, 0.22, 0.26}},
+	"sunset": {Dir: [3]float32{0.8, 0.3, 0.2}, Color: [3]float32{1, 0.7, 0.45}, Intensity: 0.6, Ambient: [3]float32{0.18, 0.14, 0.14}},
+	"night":  {Dir: [3]float32{-0.3, 0.5, -0.5}, Color: [3]float32{0.55, 0.65, 0.9}, Intensity: 0.12, Ambient: [3]float32{0.04, 0.05, 0.08}},
+}
+
+// Sun returns the directional light of the current lighting profile.
+func (s *Scene) Sun() Sun {
+	if sun, ok := lightingProfiles[s.lighting]; ok {
+		return sun
+	}
+	return lightingProfiles["noon"]
+}
+
+// Lighting returns the current lighting profile.
+func (s *Scene) Lighting() string {
+	if _, ok := lightingProfiles[s.lighting]; ok {
+		return s.lighting
+	}
+	return "noon"
+}
@@ -0,0 +1,377 @@
+package scene
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Materials: named physically based surfaces, each a YAML file <name>.yaml in the materials directory
+// (assets/materials, found like the scenes directory). An object refers to one with ObjectInstance.Material;
+// the renderer then draws it with the PBR shader (internal/primitives). The object's own color and texture,
+// when set, replace the material's albedo color and albedo map, so one material can serve several tints.
+// Materials are read once and cached; SaveMaterial updates the file and the cache and bumps
+// MaterialRevision so the renderer reloads its textures.
+
+// DefaultRoughness is the roughness of a material that does not set one.
+const DefaultRoughness = 0.5
+
+// Material is a PBR material (metallic-roughness, as in glTF). Texture paths resolve like object textures
+// (as-is, or in assets/textures).
+type Material struct {
+	Albedo               [3]float32 `yaml:"albedo,omitempty"`                 // base color RGB 0-1; zero = white
+	AlbedoMap            string     `yaml:"albedo_map,omitempty"`             // base color texture, multiplied by albedo
+	NormalMap            string     `yaml:"normal_map,omitempty"`             // tangent-space normal map (OpenGL, +Y up)
+	Roughness            *float32   `yaml:"roughness,omitempty"`              // 0 = mirror-smooth, 1 = matte; nil = DefaultRoughness
+	Metallic             float32    `yaml:"metallic,omitempty"`               // 0 = dielectric (plastic, stone), 1 = metal
+	MetallicRoughnessMap string     `yaml:"metallic_roughness_map,omitempty"` // G = roughness, B = metallic, multiplied by the values
+	Emissive             [3]float32 `yaml:"emissive,omitempty"`               // emitted RGB light, may exceed 1; zero = none
+	EmissiveMap          string     `yaml:"emissive_map,omitempty"`           // multiplied by emissive
+	UVScale              [2]float32 `yaml:"uv_scale,omitempty"`               // texture repeats across the surface (u, v); zero = [1, 1]
+}
+
+// AlbedoColor returns the base color, white if unset.
+func (m Material) AlbedoColor() [3]float32 {
+	if m.Albedo == ([3]float32{}) {
+		return [3]float32{1, 1, 1}
+	}
+	return m.Albedo
+}
+
+// RoughnessValue returns the roughness, DefaultRoughness if unset.
+func (m Material) RoughnessValue() float32 {
+	if m.Roughness == nil {
+		return DefaultRoughness
+	}
+	return *m.Roughness
+}
+
+// UV returns the texture repeat, [1, 1] if unset.
+func (m Material) UV() [2]float32 {
+	uv := m.UVScale
+	for k := range uv {
+		if uv[k] == 0 {
+			uv[k] = 1
+		}
+	}
+	return uv
+}
+
+// Check returns an error describing the first invalid value: colors outside 0-1, roughness or metallic
+// outside 0-1, negative emission or UV scale, or numbers that are not finite.
+func (m Material) Check() error {
+	finite := func(fs ...float32) bool {
+		for _, f := range fs {
+			if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+				return false
+			}
+		}
+		return true
+	}
+	in01 := func(fs ...float32) bool {
+		for _, f := range fs {
+			if f < 0 || f > 1 {
+				return false
+			}
+		}
+		return finite(fs...)
+	}
+	switch {
+	case !in01(m.Albedo[:]...):
+		return fmt.Errorf("albedo components must be between 0 and 1")
+	case m.Roughness != nil && !in01(*m.Roughness):
+		return fmt.Errorf("roughness must be between 0 and 1")
+	case !in01(m.Metallic):
+		return fmt.Errorf("metallic must be between 0 and 1")
+	case !finite(m.Emissive[:]...) || m.Emissive[0] < 0 || m.Emissive[1] < 0 || m.Emissive[2] < 0:
+		return fmt.Errorf("emissive components must not be negative")
+	case !finite(m.UVScale[:]...) || m.UVScale[0] < 0 || m.UVScale[1] < 0:
+		return fmt.Errorf("uv_scale must not be negative")
+	}
+	return nil
+}
+
+// Set sets one property from its YAML key and text values (e.g. "roughness" "0.8", "albedo" "1" "0.5"
+// "0.2", "albedo_map" "bricks.png"; "none" clears a map). Used by cmd material and the agent.
+func (m *Material) Set(key string, values ...string) error {
+	floats := func(n int) ([]float32, error) {
+		if len(values) != n {
+			return nil, fmt.Errorf("%s takes %d number(s)", key, n)
+		}
+		out := make([]float32, n)
+		for i, v := range values {
+			if _, err := fmt.Sscan(v, &out[i]); err != nil {
+				return nil, fmt.Errorf("%s: %q is not a number", key, v)
+			}
+		}
+		return out, nil
+	}
+	texture := func(dst *string) error {
+		if len(values) != 1 {
+			return fmt.Errorf("%s takes one texture path (or none)", key)
+		}
+		*dst = values[0]
+		if *dst == "none" {
+			*dst = ""
+		}
+		return nil
+	}
+	next := *m
+	switch key {
+	case "albedo", "emissive":
+		f, err := floats(3)
+		if err != nil {
+			return err
+		}
+		if key == "albedo" {
+			next.Albedo = [3]float32{f[0], f[1], f[2]}
+		} else {
+			next.Emissive = [3]float32{f[0], f[1], f[2]}
+		}
+	case "roughness":
+		f, err := floats(1)
+		if err != nil {
+			return err
+		}
+		next.Roughness = &f[0]
+	case "metallic":
+		f, err := floats(1)
+		if err != nil {
+			return err
+		}
+		next.Metallic = f[0]
+	case "uv_scale":
+		f, err := floats(2)
+		if err != nil {
+			return err
+		}
+		next.UVScale = [2]float32{f[0], f[1]}
+	case "albedo_map":
+		if err := texture(&next.AlbedoMap); err != nil {
+			return err
+		}
+	case "normal_map":
+		if err := texture(&next.NormalMap); err != nil {
+			return err
+		}
+	case "metallic_roughness_map":
+		if err := texture(&next.MetallicRoughnessMap); err != nil {
+			return err
+		}
+	case "emissive_map":
+		if err := texture(&next.EmissiveMap); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown material property %q (known: %s)", key, strings.Join(MaterialProperties, ", "))
+	}
+	if err := next.Check(); err != nil {
+		return err
+	}
+	*m = next
+	return nil
+}
+
+// MaterialProperties are the keys Material.Set accepts, in file order.
+var MaterialProperties = []string{"albedo", "albedo_map", "normal_map", "roughness", "metallic", "metallic_roughness_map", "emissive", "emissive_map", "uv_scale"}
+
+// materialDirs are tried in order so the materials directory is found whether run from repo root or cmd/game.
+var materialDirs = []string{
+	"assets/materials",
+	"../../assets/materials",
+}
+
+// materialLib caches materials read from the library by name (see LoadMaterial).
+var materialLib = struct {
+	sync.Mutex
+	cache    map[string]materialEntry
+	revision uint64
+}{cache: map[string]materialEntry{}}
+
+type materialEntry struct {
+	m   Material
+	err error
+}
+
+// MaterialDir returns the materials directory: the first existing path in materialDirs, or the first entry
+// if none exists yet (it is created on save).
+func MaterialDir() string {
+	return firstDir(materialDirs)
+}
+
+// MaterialNames returns the names of the materials in the library, sorted (nil if there are none).
+func MaterialNames() []string {
+	entries, err := os.ReadDir(MaterialDir())
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".yaml") {
+			out = append(out, strings.TrimSuffix(e.Name(), ".yaml"))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LoadMaterial returns the named material from the library. Files are read once (errors included) until
+// SaveMaterial or ReloadMaterials.
+func LoadMaterial(name string) (Material, error) {
+	name = strings.TrimSuffix(name, ".yaml")
+	materialLib.Lock()
+	e, ok := materialLib.cache[name]
+	materialLib.Unlock()
+	if ok {
+		return e.m, e.err
+	}
+	m, err := readMaterial(name)
+	materialLib.Lock()
+	materialLib.cache[name] = materialEntry{m, err}
+	materialLib.Unlock()
+	return m, err
+}
+
+// readMaterial reads and checks a material file. Unknown keys are errors, with their line.
+func readMaterial(name string) (Material, error) {
+	path, err := libraryFile(MaterialDir(), "material", name)
+	if err != nil {
+		return Material{}, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Material{}, fmt.Errorf("material %q not found (cmd material list lists them)", name)
+	}
+	if err != nil {
+		return Material{}, err
+	}
+	var m Material
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return Material{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := m.Check(); err != nil {
+		return Material{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// SaveMaterial writes the material to the library as name (replacing any material of that name) and
+// bumps MaterialRevision.
+func SaveMaterial(name string, m Material) error {
+	name = strings.TrimSuffix(name, ".yaml")
+	if err := m.Check(); err != nil {
+		return err
+	}
+	path, err := libraryFile(MaterialDir(), "material", name)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(&m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	materialLib.Lock()
+	materialLib.cache[name] = materialEntry{m: m}
+	materialLib.revision++
+	materialLib.Unlock()
+	return nil
+}
+
+// ReloadMaterials drops the cached materials so they are read again (after editing files by hand) and
+// bumps MaterialRevision.
+func ReloadMaterials() {
+	materialLib.Lock()
+	materialLib.cache = map[string]materialEntry{}
+	materialLib.revision++
+	materialLib.Unlock()
+}
+
+// MaterialRevision returns a counter that changes whenever a material is saved or the cache is dropped.
+func MaterialRevision() uint64 {
+	materialLib.Lock()
+	defer materialLib.Unlock()
+	return materialLib.revision
+}
+
+// SetSelectedMaterial sets the material on every selected object and its descendants ("" = none, back to
+// color and texture). The material must exist in the library.
+func (s *Scene) SetSelectedMaterial(name string) error {
+	sel := s.Selection()
+	if len(sel) == 0 {
+		return fmt.Errorf("no object selected")
+	}
+	name = strings.TrimSuffix(name, ".yaml")
+	if name != "" {
+		if _, err := LoadMaterial(name); err != nil {
+			return err
+		}
+	}
+	defer s.edit("material")()
+	for _, idx := range sel {
+		for _, i := range s.Subtree(idx) {
+			s.sceneData.Objects[i].Material = name
+		}
+	}
+	return nil
+}
+
+// TerrainMaterialName is the name SetTerrainUVScale gives the terrain's own material; when it is taken,
+// "-2", "-3", ... are appended.
+const TerrainMaterialName = "terrain"
+
+// SetTerrainUVScale sets how often textures repeat across the terrain: the uv_scale of its material. Library
+// materials are shared by other objects and scenes, so the terrain's material (or an empty one if it has
+// none) is cloned to a new file named after TerrainMaterialName with the new uv_scale, and the terrain is
+// switched to it as one undo step; undo switches back to the unchanged original. Returns the new material's
+// name.
+func (s *Scene) SetTerrainUVScale(u, v float32) (string, error) {
+	idx := s.terrainObjectIndex()
+	if idx < 0 {
+		return "", fmt.Errorf("no terrain in the scene (cmd heightmap creates one)")
+	}
+	var m Material
+	if base := s.sceneData.Objects[idx].Material; base != "" {
+		var err error
+		if m, err = LoadMaterial(base); err != nil && MaterialExists(base) {
+			return "", err
+		}
+	}
+	m.UVScale = [2]float32{u, v}
+	name := TerrainMaterialName
+	for n := 2; MaterialExists(name); n++ {
+		name = fmt.Sprintf("%s-%d", TerrainMaterialName, n)
+	}
+	if err := SaveMaterial(name, m); err != nil {
+		return "", err
+	}
+	defer s.edit("terrain repeat")()
+	s.sceneData.Objects[idx].Material = name
+	return name, nil
+}
+
+// MaterialExists reports whether the named material has a file in the library.
+func MaterialExists(name string) bool {
+	path, err := libraryFile(MaterialDir(), "material", name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package scene
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"game-engine/internal/modelfile"
+)
+
+// Models: objects of type "model" draw a 3D model file (glTF 2.0 .gltf/.glb or Wavefront .obj, see
+// internal/modelfile) named by ObjectInstance.Model, a path relative to the models directory (assets/models,
+// found like the scenes directory) or to the working directory. As for primitives, Position is the center
+// of the object's box and Scale its size in world units: the renderer fits the model's bounds to that box,
+// so picking, physics and the selection outline use the same box as for a cube. A model object without a
+// scale gets the model's own size when it enters the scene. ImportModel copies a model file and the files it
+// refers to (buffers, textures, material libraries) into the models directory.
+
+// ModelType is the object type of 3D model objects.
+const ModelType = "model"
+
+// minModelSize is the smallest size a model object gets from its file per axis, so flat models (a single
+// quad) still have a box to pick and collide with.
+const minModelSize = 0.01
+
+// modelDirs are tried in order so the models directory is found whether run from repo root or cmd/game.
+var modelDirs = []string{
+	"assets/models",
+	"../../assets/models",
+}
+
+// modelSizes caches model file sizes by resolved path (see ModelSize).
+var modelSizes = struct {
+	sync.Mutex
+	m map[string][3]float32
+}{m: map[string][3]float32{}}
+
+// ModelDir returns the models directory: the first existing path in modelDirs, or the first entry if none
+// exists yet (it is created on import).
+func ModelDir() string {
+	return firstDir(modelDirs)
+}
+
+// ResolveModelPath returns the file a model path refers to: the path itself if it exists, else the path in
+// the models directory. ok is false if neither exists.
+func ResolveModelPath(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	for _, p := range []string{path, filepath.Join(ModelDir(), path)} {
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// ModelNames returns the model files in the models directory, sorted (nil if there are none).
+func ModelNames() []string {
+	entries, err := os.ReadDir(ModelDir())
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, e := range entries {
+		if !e.IsDir() && modelfile.Supported(e.Name()) {
+			out = append(out, e.Name())
+		}
+	}
+	return out
+}
+
+// ModelSize returns the size of a model's geometry in model units (see ResolveModelPath for the path),
+// at least minModelSize per axis. Results are cached.
+func ModelSize(path string) ([3]float32, error) {
+	file, ok := ResolveModelPath(path)
+	if !ok {
+		return [3]float32{}, fmt.Errorf("model file %q not found (in %s or the working directory)", path, ModelDir())
+	}
+	modelSizes.Lock()
+	size, cached := modelSizes.m[file]
+	modelSizes.Unlock()
+	if cached {
+		return size, nil
+	}
+	lo, hi, err := modelfile.Bounds(file)
+	if err != nil {
+		return [3]float32{}, err
+	}
+	for k := range size {
+		size[k] = max(hi[k]-lo[k], minModelSize)
+	}
+	modelSizes.Lock()
+	modelSizes.m[file] = size
+	modelSizes.Unlock()
+	return size, nil
+}
+
+// ModelInstance returns a model object for the given model path standing on base (the center of its
+// bottom face). A zero scale means the model's own size. Physics is off unless requested, since most models
+// are scenery; the object still blocks dynamic objects.
+func ModelInstance(path string, base, scale [3]float32, physics bool) (ObjectInstance, error) {
+	size, err := ModelSize(path)
+	if err != nil {
+		return ObjectInstance{}, err
+	}
+	if scale == ([3]float32{}) {
+		scale = size
+	}
+	pos := [3]float32{base[0], base[1] + scaleForPhysics(scale)[1]/2, base[2]}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ObjectInstance{Type: ModelType, Model: path, Name: name, Position: pos, Scale: scale, Physics: &physics}, nil
+}
+
+// AddModel adds a model object (see ModelInstance) as a new root, selects it and returns its ID.
+func (s *Scene) AddModel(path string, base, scale [3]float32, physics bool) (ObjectID, error) {
+	obj, err := ModelInstance(path, base, scale, physics)
+	if err != nil {
+		return 0, err
+	}
+	defer s.edit("add model")()
+	i := s.flattenInto(obj, -1)
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+	s.selectIndex(i)
+	return s.IDAt(i), nil
+}
+
+// ImportModel copies a model file and the files it refers to (modelfile.Dependencies) into the models
+// directory and returns the path to store in ObjectInstance.Model (relative to the models directory). A file
+// already in the models directory is not copied. Everything is checked before anything is copied: a
+// dependency outside the model's directory, or a file of the same name with other content already in the
+// models directory (another model's, which would be overwritten), refuses the import. Files that are already
+// there with the same content are left as they are, so importing a model again is harmless.
+func ImportModel(src string) (string, error) {
+	if !modelfile.Supported(src) {
+		return "", fmt.Errorf("%s: unsupported model format (use %s)", src, strings.Join(modelfile.Extensions, ", "))
+	}
+	if _, err := os.Stat(src); err != nil {
+		return "", err
+	}
+	dir := ModelDir()
+	if rel, ok := inDir(dir, src); ok {
+		return filepath.ToSlash(rel), nil
+	}
+	deps, err := modelfile.Dependencies(src)
+	if err != nil {
+		return "", err
+	}
+	for _, dep := range deps {
+		if !filepath.IsLocal(dep) {
+			return "", fmt.Errorf("%s refers to %s outside its directory; copy the model by hand", src, dep)
+		}
+	}
+	name := filepath.Base(src)
+	srcDir := filepath.Dir(src)
+	var copies []string
+	for _, f := range append([]string{name}, deps...) {
+		same, err := sameContent(filepath.Join(srcDir, f), filepath.Join(dir, f))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", filepath.ToSlash(f), err)
+		}
+		if !same {
+			copies = append(copies, f)
+		}
+	}
+	for _, f := range copies {
+		if err := copyFile(filepath.Join(srcDir, f), filepath.Join(dir, f)); err != nil {
+			return "", fmt.Errorf("copying %s: %w", f, err)
+		}
+	}
+	modelSizes.Lock()
+	delete(modelSizes.m, filepath.Join(dir, name))
+	modelSizes.Unlock()
+	return name, nil
+}
+
+// sameContent reports whether dst already holds the content of src. A missing dst is not the same; a dst
+// with other content is an error, since copying src would overwrite it.
+func sameContent(src, dst string) (bool, error) {
+	want, err := os.ReadFile(src)
+	if err != nil {
+		return false, err
+	}
+	have, err := os.ReadFile(dst)
+	switch {
+	case os.IsNotExist(err):
+		return false, nil
+	case err != nil:
+		return false, err
+	case !bytes.Equal(have, want):
+		return false, fmt.Errorf("a different file of that name is already in the models directory; rename or remove one of them to import")
+	}
+	return true, nil
+}
+
+// inDir returns path relative to dir if it lies inside dir.
+func inDir(dir, path string) (string, bool) {
+	absDir, err1 := filepath.Abs(dir)
+	absPath, err2 := filepath.Abs(path)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return rel, true
+}
+
+// copyFile copies src to dst, creating dst's directory if needed.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
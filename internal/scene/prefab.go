@@ -0,0 +1,284 @@
+package scene
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"game-engine/internal/physics"
+)
+
+// Prefabs: reusable object templates, each a scene file <name>.yaml in the prefabs directory (assets/prefabs,
+// found like the scenes directory; same schema and validation as scenes). A prefab's objects are placed
+// relative to its origin. PlacePrefab adds an instance: a group named after the prefab, at the given
+// position and rotation, holding a copy of the prefab's objects. A linked instance remembers its prefab
+// (ObjectInstance.Prefab): when the prefab is saved again, and whenever a scene is loaded, the children of
+// linked instances are replaced with the prefab's current objects, keeping the instance's own transform,
+// name and ID. UnlinkSelected (or ungroup) detaches an instance; it then keeps its objects.
+
+// prefabDirs are tried in order so the prefabs directory is found whether run from repo root or cmd/game.
+var prefabDirs = []string{
+	"assets/prefabs",
+	"../../assets/prefabs",
+}
+
+// Prefab is a prefab loaded from the library.
+type Prefab struct {
+	Name    string
+	Objects []ObjectInstance // nested, relative to the prefab's origin
+	Issues  []Issue          // problems found in the file (see schema.go)
+}
+
+// PrefabDir returns the prefabs directory: the first existing path in prefabDirs, or the first entry if
+// none exists yet (it is created on save).
+func PrefabDir() string {
+	return firstDir(prefabDirs)
+}
+
+// ListPrefabs returns the prefabs in the library, sorted by name.
+func ListPrefabs() ([]SceneInfo, error) {
+	return listSceneFiles(PrefabDir())
+}
+
+// PrefabNames returns the names of the prefabs in the library, sorted (nil if there are none).
+func PrefabNames() []string {
+	list, _ := ListPrefabs()
+	var out []string
+	for _, info := range list {
+		out = append(out, info.Name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// PrefabExists reports whether the named prefab has a file in the library.
+func PrefabExists(name string) bool {
+	path, err := libraryFile(PrefabDir(), "prefab", name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// LoadPrefab reads the named prefab from the library.
+func LoadPrefab(name string) (Prefab, error) {
+	path, err := libraryFile(PrefabDir(), "prefab", name)
+	if err != nil {
+		return Prefab{}, err
+	}
+	sd, issues, err := readSceneFile(path)
+	if os.IsNotExist(err) {
+		return Prefab{}, fmt.Errorf("prefab %q not found (cmd prefab list lists them)", name)
+	}
+	if err != nil {
+		return Prefab{}, err
+	}
+	if len(sd.Objects) == 0 {
+		return Prefab{}, fmt.Errorf("prefab %q has no objects", name)
+	}
+	return Prefab{Name: strings.TrimSuffix(name, ".yaml"), Objects: sd.Objects, Issues: issues}, nil
+}
+
+// Instance returns a new instance of the prefab: a group at pos with the given rotation (Euler degrees)
+// holding a copy of the prefab's objects. The group has physics if any of the prefab's top-level objects
+// does. With linked, the group is linked to the prefab (see RefreshPrefab).
+func (p Prefab) Instance(pos, rotation [3]float32, linked bool) ObjectInstance {
+	phys := false
+	for _, obj := range p.Objects {
+		phys = phys || physicsEnabled(obj)
+	}
+	g := ObjectInstance{Type: GroupType, Name: p.Name, Position: pos, Scale: [3]float32{1, 1, 1}, Rotation: rotation, Physics: &phys, Children: prefabCopy(p.Objects)}
+	if linked {
+		g.Prefab = p.Name
+	}
+	return g
+}
+
+// prefabCopy returns a deep copy of objs without IDs (each instance gets fresh ones) and without prefab
+// links, so nested instances are plain copies and a prefab can never contain itself.
+func prefabCopy(objs []ObjectInstance) []ObjectInstance {
+	out := make([]ObjectInstance, len(objs))
+	for i, obj := range objs {
+		obj = copyObject(obj)
+		obj.ID = 0
+		obj.Prefab = ""
+		obj.Children = prefabCopy(objs[i].Children)
+		out[i] = obj
+	}
+	return out
+}
+
+// PlacePrefab adds an instance of the prefab (see Prefab.Instance) as a new root and returns its ID.
+func (s *Scene) PlacePrefab(p Prefab, pos, rotation [3]float32, linked bool) ObjectID {
+	defer s.edit("prefab " + p.Name)()
+	id := s.AddTree(p.Instance(pos, rotation, linked), 0)
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+	return id
+}
+
+// SavePrefab saves the selected objects (with their subtrees) as the named prefab, replacing any prefab of
+// that name, and updates the scene's linked instances of it. A single selected group is saved as its
+// contents, in the group's frame, so an edited instance can be saved back without nesting it deeper;
+// otherwise the origin is the center of the selection's bounds at floor level. Names are kept, IDs and
+// prefab links are not. Returns the number of objects saved and of instances updated.
+func (s *Scene) SavePrefab(name string) (objects, updated int, err error) {
+	roots := s.selectedRoots()
+	if len(roots) == 0 {
+		return 0, 0, fmt.Errorf("no object selected")
+	}
+	path, err := libraryFile(PrefabDir(), "prefab", name)
+	if err != nil {
+		return 0, 0, err
+	}
+	var objs []ObjectInstance
+	if len(roots) == 1 && s.sceneData.Objects[roots[0]].Type == GroupType {
+		tree, _ := s.Tree(roots[0])
+		objs = tree.Children
+	} else {
+		box, _ := s.SelectionBounds()
+		origin := [3]float32{(box.Min[0] + box.Max[0]) / 2, box.Min[1], (box.Min[2] + box.Max[2]) / 2}
+		for _, i := range roots {
+			tree, _ := s.Tree(i)
+			t := s.worldTransform(i, false)
+			tree.Position = [3]float32{t.Position[0] - origin[0], t.Position[1] - origin[1], t.Position[2] - origin[2]}
+			tree.Rotation = t.Rotation.Euler()
+			tree.Scale = t.Scale
+			objs = append(objs, tree)
+		}
+	}
+	if len(objs) == 0 {
+		return 0, 0, fmt.Errorf("the selected group is empty")
+	}
+	objs = prefabCopy(objs)
+	if err := writeObjects(path, objs); err != nil {
+		return 0, 0, err
+	}
+	updated, err = s.RefreshPrefab(strings.TrimSuffix(name, ".yaml"))
+	return countObjects(objs), updated, err
+}
+
+// RefreshPrefab replaces the children of every instance linked to the named prefab ("" = every linked
+// instance) with the prefab's current objects, as one undo step. When the prefab's tree has the same shape
+// as the instance's children, the new objects take over the old IDs position by position, so refreshing
+// an unchanged prefab (as every load does) changes no IDs and records no undo step. Instances whose prefab cannot be loaded
+// are left as they are; the first such error is returned. Returns the number of instances updated.
+func (s *Scene) RefreshPrefab(name string) (int, error) {
+	var ids []ObjectID
+	for _, obj := range s.sceneData.Objects {
+		if obj.Prefab != "" && (name == "" || obj.Prefab == name) {
+			ids = append(ids, obj.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	defer s.edit("refresh prefab")()
+	prefabs := map[string]*Prefab{}
+	var firstErr error
+	n := 0
+	for _, id := range ids {
+		i := s.IndexOf(id)
+		if i < 0 {
+			continue
+		}
+		pname := s.sceneData.Objects[i].Prefab
+		p, loaded := prefabs[pname]
+		if !loaded {
+			if pf, err := LoadPrefab(pname); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+			} else {
+				p = &pf
+			}
+			prefabs[pname] = p
+		}
+		if p == nil {
+			continue
+		}
+		var old []int
+		var current []ObjectInstance
+		for _, c := range s.Children(i) {
+			old = append(old, s.Subtree(c)...)
+			tree, _ := s.Tree(c)
+			current = append(current, tree)
+		}
+		fresh := prefabCopy(p.Objects)
+		if sameShape(fresh, current) {
+			keepIDs(fresh, current)
+		}
+		s.removeObjects(old)
+		i = s.IndexOf(id)
+		for _, c := range fresh {
+			s.flattenInto(c, i)
+		}
+		n++
+	}
+	s.ensurePhysicsBodies()
+	s.syncSceneToPhysics()
+	return n, firstErr
+}
+
+// sameShape reports whether the trees a and b have the same number of children at every level.
+func sameShape(a, b []ObjectInstance) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameShape(a[i].Children, b[i].Children) {
+			return false
+		}
+	}
+	return true
+}
+
+// keepIDs gives the objects of to the IDs of the objects at the same place in from (see sameShape).
+func keepIDs(to, from []ObjectInstance) {
+	for i := range to {
+		to[i].ID = from[i].ID
+		keepIDs(to[i].Children, from[i].Children)
+	}
+}
+
+// UnlinkSelected detaches the linked prefab instances among the selected objects and their subtrees, so
+// they keep their objects when the prefab changes. Returns the number of instances unlinked.
+func (s *Scene) UnlinkSelected() (int, error) {
+	roots := s.selectedRoots()
+	if len(roots) == 0 {
+		return 0, fmt.Errorf("no object selected")
+	}
+	defer s.edit("unlink")()
+	n := 0
+	for _, r := range roots {
+		for _, i := range s.Subtree(r) {
+			if s.sceneData.Objects[i].Prefab != "" {
+				s.sceneData.Objects[i].Prefab = ""
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
+// WorldObjects returns the drawable objects of a tree (e.g. a prefab instance) as a flat list with world
+// transforms, for previews. Groups are left out.
+func WorldObjects(tree ObjectInstance) []ObjectInstance {
+	var out []ObjectInstance
+	var walk func(obj ObjectInstance, parent Transform)
+	walk = func(obj ObjectInstance, parent Transform) {
+		t := parent.compose(Transform{Position: obj.Position, Scale: scaleForPhysics(obj.Scale), Rotation: physics.QuatFromEuler(obj.Rotation)})
+		if obj.Type != GroupType {
+			flat := copyObject(obj)
+			flat.Position, flat.Scale, flat.Rotation = t.Position, t.Scale, t.Rotation.Euler()
+			out = append(out, flat)
+		}
+		for _, c := range obj.Children {
+			walk(c, t)
+		}
+	}
+	walk(tree, Transform{Scale: [3]float32{1, 1, 1}, Rotation: physics.QuatFromEuler([3]float32{})})
+	return out
+}
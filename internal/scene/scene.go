@@ -6,95 +6,75 @@ import (
 	"math"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"game-engine/internal/physics"
-	"game-engine/internal/primitives"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
-	"gopkg.in/yaml.v3"
-)
-
-const (
-	gridExtent     = 50
-	gridMinorStep  = 1
-	gridMajorStep  = 10
-	gridMinorAlpha = 50
-	gridMajorAlpha = 120
-	axisLineAlpha  = 220
-	skyboxScale    = 1000
-	// Y-drag: world units per pixel (screen-space mouse delta → vertical movement).
-	yDragSensitivity = float32(0.015)
-	// Gizmo arrows: visual-only length (no picking).
-	gizmoArrowLength = float32(1.5)
 )
 
-// skyboxPaths are tried in order so the skybox is found whether run from repo root or cmd/game.
-// Skybox assets live under assets/skybox/ to keep them separate from other future assets.
-var skyboxPaths = []string{
-	"assets/skybox/skybox.png",
-	"assets/skybox/skybox.jpg",
-	"../../assets/skybox/skybox.png",
-	"../../assets/skybox/skybox.jpg",
-}
-
-// scenePaths are tried in order so the scene YAML is found whether run from repo root or cmd/game.
-var scenePaths = []string{
-	"assets/scenes/default.yaml",
-	"../../assets/scenes/default.yaml",
-}
-
-// textureBasePaths are tried as prefixes when loading an object's texture path (e.g. run from cmd/game vs repo root).
-var textureBasePaths = []string{
-	"",
-	"assets/textures/",
-	"../../assets/textures/",
-}
-
-// SceneData is the YAML format for a scene: list of object instances.
+// SceneData is the YAML format for a scene: schema version (see schema.go) and list of object instances.
 type SceneData struct {
+	Version int              `yaml:"version,omitempty"`
 	Objects []ObjectInstance `yaml:"objects"`
 }
 
 // ObjectInstance describes one object in the scene: type (e.g. cube), position, optional scale.
+// ID: stable identifier (see ObjectID); assigned when the object enters the scene if missing or taken.
 // Physics: nil or true = falls and collides; false = static (no gravity, still blocks others). Omit in YAML = physics on.
 // Texture: optional path to an image file (e.g. assets/textures/downloaded/foo.png); loaded and applied as albedo when set.
 // Color: optional RGB tint (0-1). When set, object is drawn with this tint; omit = default material color.
 // Name: optional label for reference (e.g. "Tower"); used by delete name <name> and inspector.
-// Motion: optional "spin" (rotate Y each frame) or "bob" (oscillate Y); omit = static.
+// Rotation: optional Euler angles in degrees, applied about world X, then Y, then Z (see physics.QuatFromEuler);
+// resolved to a quaternion for drawing, picking and hierarchy transforms.
+// Motion: optional "spin" (rotate about Y, spinDegreesPerSecond) or "bob" (oscillate Y); omit = static.
+// Model: for type "model", the model file to draw (see model.go).
+// Material: optional name of a PBR material in the material library (see material.go); Color and Texture, when
+// set, replace its albedo color and map.
+// Light: for type "light", the point or spot light (see light.go); Color is the light's color.
+// Children: objects attached to this one, with Position, Rotation and Scale local to it (see hierarchy.go). Only used
+// in the scene file and in trees (Tree, AddTree); the Scene keeps objects flat, so Objects, ObjectAt etc.
+// return them with Children nil.
 type ObjectInstance struct {
-	Type     string     `yaml:"type"`
-	Position [3]float32 `yaml:"position"`
-	Scale    [3]float32 `yaml:"scale,omitempty"`
-	Physics  *bool      `yaml:"physics,omitempty"`
-	Texture  string     `yaml:"texture,omitempty"`
-	Color    [3]float32 `yaml:"color,omitempty"`    // RGB 0-1; zero = use default
-	Name     string     `yaml:"name,omitempty"`
-	Motion   string     `yaml:"motion,omitempty"` // "spin" | "bob" | ""
+	ID        ObjectID         `yaml:"id,omitempty"`
+	Type      string           `yaml:"type"`
+	Position  [3]float32       `yaml:"position"`
+	Scale     [3]float32       `yaml:"scale,omitempty"`
+	Rotation  [3]float32       `yaml:"rotation,omitempty"`
+	Physics   *bool            `yaml:"physics,omitempty"`
+	Texture   string           `yaml:"texture,omitempty"`
+	Color     [3]float32       `yaml:"color,omitempty"` // RGB 0-1; zero = use default
+	Name      string           `yaml:"name,omitempty"`
+	Motion    string           `yaml:"motion,omitempty"`    // "spin" | "bob" | ""
+	Prefab    string           `yaml:"prefab,omitempty"`    // prefab this group is a linked instance of (see prefab.go); "" = none
+	Model     string           `yaml:"model,omitempty"`     // model file for type "model", relative to assets/models
+	Material  string           `yaml:"material,omitempty"`  // PBR material in assets/materials (primitives and terrain; models keep their own); "" = none
+	Light     *Light           `yaml:"light,omitempty"`     // for type "light": kind, intensity, range, cone (see light.go)
+	Heightmap *Heightmap       `yaml:"heightmap,omitempty"` // for type "terrain": how its mesh is generated (see terrain.go)
+	Children  []ObjectInstance `yaml:"children,omitempty"`
 }
 
 // VisibleObject describes one scene object currently in the camera's view.
 // Used by camera object-awareness: ObjectsInView and ViewAwareness.
 type VisibleObject struct {
-	Index         int             // index in scene objects
-	Object        ObjectInstance
-	Distance      float32         // distance from camera position
-	ScreenPos     rl.Vector2      // 2D position on screen (object center)
-	DrawPosition  [3]float32      // world position used for drawing (e.g. with motion)
+	ID           ObjectID // stable object ID
+	Index        int      // index in scene objects (valid until the next add or delete)
+	Object       ObjectInstance
+	Distance     float32    // distance from camera position
+	ScreenPos    [2]float32 // 2D position on screen (object center), X right and Y down
+	DrawPosition [3]float32 // world position used for drawing (e.g. with motion)
 }
 
 // ViewAwareness holds state for camera object-awareness and optional logging.
 // When attached to a scene and updated each frame, it can detect when objects
 // enter or leave the camera view and call OnEnterView/OnLeaveView.
 type ViewAwareness struct {
-	// lastVisible is the set of object indices that were in view last frame.
-	lastVisible map[int]struct{}
-	// OnEnterView is called when an object index enters the camera view (optional).
-	OnEnterView func(index int, obj ObjectInstance, distance float32)
-	// OnLeaveView is called when an object index leaves the camera view (optional).
-	OnLeaveView func(index int, obj ObjectInstance)
+	// lastVisible holds the objects that were in view last frame, by ID (as they were then, so a deleted
+	// object can still be reported as leaving).
+	lastVisible map[ObjectID]ObjectInstance
+	// OnEnterView is called when an object enters the camera view (optional).
+	OnEnterView func(id ObjectID, obj ObjectInstance, distance float32)
+	// OnLeaveView is called when an object leaves the camera view or is deleted (optional).
+	OnLeaveView func(id ObjectID, obj ObjectInstance)
 	// OnUpdate is called every frame with the current list of visible objects (optional).
 	// Use for logging or documenting "what the camera sees" at a given time.
 	OnUpdate func(visible []VisibleObject)
@@ -104,138 +84,125 @@ type ViewAwareness struct {
 // Optionally set OnUpdate for per-frame "what the camera sees" (can be noisy).
 func NewViewAwarenessWithLogging() *ViewAwareness {
 	return &ViewAwareness{
-		OnEnterView: func(index int, obj ObjectInstance, distance float32) {
+		OnEnterView: func(id ObjectID, obj ObjectInstance, distance float32) {
 			name := obj.Name
 			if name == "" {
-				name = fmt.Sprintf("#%d", index)
+				name = fmt.Sprintf("#%d", id)
 			}
 			log.Printf("[camera] enter view: %s (%s) at %.2f", name, obj.Type, distance)
 		},
-		OnLeaveView: func(index int, obj ObjectInstance) {
+		OnLeaveView: func(id ObjectID, obj ObjectInstance) {
 			name := obj.Name
 			if name == "" {
-				name = fmt.Sprintf("#%d", index)
+				name = fmt.Sprintf("#%d", id)
 			}
 			log.Printf("[camera] leave view: %s (%s)", name, obj.Type)
 		},
 	}
 }
 
-// Scene holds a 3D camera and draws the 3D world. Update runs camera logic (e.g. free camera);
-// Draw renders between BeginMode3D and EndMode3D. Based on raylib examples/core/core_3d_camera_free.
+// Scene is the scene model: objects loaded from YAML, selection, undo, the physics world and the camera
+// used for "in view" queries. It has no raylib dependency, so scene generation, agent handlers and physics
+// run headless (tests, servers); internal/render draws it and runs the mouse editor on top.
 type Scene struct {
-	Camera      rl.Camera3D
-	cursorDone  bool
-	GridVisible bool
+	Camera Camera
 	// Scene objects loaded from YAML; drawn each frame. Not hardcoded.
-	sceneData   SceneData
-	scenePath   string // path we loaded from; Save writes here (or first scenePaths if never loaded)
-	primitives  *primitives.Registry
-	// Editor: when terminal is open (cursor visible), user can select and move primitives. -1 = no selection.
-	selectedIndex int
-	dragging      bool
-	// Drag mode from selection box face: 0=none, 1=top/bottom (XZ), 2=side (Y). For Y we use mouse delta.
-	dragMode        int
-	dragStartObjY   float32
-	lastMouseY      int32   // screen Y when Y drag started (total delta from this)
-	dragOffsetX     float32 // XZ: offset from object center to click point so drag keeps that point under cursor
-	dragOffsetZ     float32
-	// Skybox: optional texture drawn first in 3D mode. Cubemap or equirectangular panorama.
-	skyboxTex       rl.Texture2D
-	skyboxMesh      rl.Mesh
-	skyboxMtl       rl.Material
-	skyboxLoaded    bool
-	skyboxPending   bool   // true = path known, GPU load deferred until first Draw (after window/GL exists)
-	skyboxPath      string // set when pending; used to load texture on first frame
-	skyboxEquirect  bool   // true = panorama (2D texture + shader), false = cubemap
-	skyboxShader    rl.Shader
-	skyboxCamPosLoc int32
-	skyboxTexLoc    int32
-	// 3D physics: AABB bodies in 1:1 with scene objects. Stepped only when terminal is closed (game mode).
+	sceneData SceneData
+	// name: scene file in the library (see library.go); "" = new unsaved scene. savedRevision: history
+	// revision when it was last opened or saved (see Modified).
+	name          string
+	savedRevision uint64
+	// loadIssues: problems found in the scene file when it was opened or loaded (see schema.go).
+	loadIssues []Issue
+	// parents[i]: index of object i's parent, -1 = root. Same length as sceneData.Objects. See hierarchy.go.
+	parents []int
+	// byID: object ID → index in sceneData.Objects; rebuilt when objects are removed. nextID: next fresh ID.
+	byID   map[ObjectID]int
+	nextID ObjectID
+	// selection: objects selected in the editor or by commands, in the order they were selected; the last one
+	// is the primary (gizmo, inspector). See selection.go.
+	selection []ObjectID
+	// viewportW/H: screen size in pixels for ObjectsInView; the renderer updates it each frame.
+	viewportW, viewportH int
+	// clock: simulated seconds, advanced by Step and AdvanceClock. Drives motion (bob, spin).
+	clock float64
+	// 3D physics: one AABB body per scene object, paired by ID. Stepped by Step.
 	physicsWorld *physics.World
-	// textureCache: path -> GPU texture for object albedo. Loaded lazily in Draw when object has Texture set.
-	textureCache map[string]rl.Texture2D
-	// lightDir: direction to sun for primitive shading. Set by SetLighting(profile).
-	lightDir [3]float32
-	// lastUndo: one level of undo (add or delete).
-	lastUndo *undoRecord
-	// viewAwareness: optional camera object-awareness; when set, updated each frame and can log enter/exit.
+	bodies       map[ObjectID]*physics.Body
+	// lighting: lighting profile for the sun (see light.go); "" = noon. Set by SetLighting(profile).
+	lighting string
+	// history: undo/redo stack of scene changes (see history.go).
+	history history
+	// viewAwareness: optional camera object-awareness; when set, updated each Step and can log enter/exit.
 	viewAwareness *ViewAwareness
-	// terrainEnabled: when true, draw optimized heightmapped terrain mesh (single deformed plane).
-	terrainEnabled bool
 }
 
-// getLightDir returns the current light direction (normalized). Used by Draw.
-func (s *Scene) getLightDir() [3]float32 {
-	if s.lightDir[0] == 0 && s.lightDir[1] == 0 && s.lightDir[2] == 0 {
-		return [3]float32{0.5, 1, 0.5}
+// LightDir returns the current direction to the sun. Used by the renderer.
+func (s *Scene) LightDir() [3]float32 {
+	return s.Sun().Dir
+}
+
+// spinDegreesPerSecond is how fast an object with motion "spin" turns about the vertical (Y) axis.
+const spinDegreesPerSecond = 45
+
+// motionRotation returns the extra rotation motion adds at the current scene clock (identity unless "spin").
+func (s *Scene) motionRotation(obj ObjectInstance) physics.Quat {
+	if obj.Motion != "spin" {
+		return physics.QuatIdentity
 	}
-	return s.lightDir
+	deg := math.Mod(s.clock*spinDegreesPerSecond, 360)
+	return physics.QuatAxisAngle([3]float32{0, 1, 0}, float32(deg))
 }
 
-// motionPosition returns the draw position for obj, applying motion (e.g. bob) when set.
-func (s *Scene) motionPosition(obj ObjectInstance, index int) [3]float32 {
+// MotionPosition returns the draw position for obj at the current scene clock, applying motion (e.g. bob) when set.
+func (s *Scene) MotionPosition(obj ObjectInstance) [3]float32 {
 	pos := obj.Position
 	if obj.Motion == "bob" {
-		t := float32(rl.GetTime())
+		t := float32(s.clock)
 		pos[1] += 0.2 * float32(math.Sin(float64(t*2)))
 	}
 	return pos
 }
 
-// New returns a scene with a perspective camera looking at the origin.
-// Camera: position (10,10,10), target (0,0,0), up (0,1,0), fovy 45°. Grid is visible by default.
-// Tries to load skybox from assets/skybox/ (see skyboxPaths); see assets/README.md.
+// New returns the default scene from the library (see Open and DefaultSceneName), with a perspective camera
+// looking at the origin. A missing or invalid file gives an empty scene.
+// Camera: position (11,10.5,9.5), target (0,0,0), up (0,1,0), fovy 45°.
 func New() *Scene {
+	s, _ := Open(DefaultSceneName)
+	return s
+}
+
+// NewEmpty returns a scene like New but with no objects, without reading the scene file from disk.
+// Used by headless tools and tests.
+func NewEmpty() *Scene {
 	s := &Scene{}
 	// Slightly off from center so the initial view isn't perfectly symmetric.
-	s.Camera.Position = rl.NewVector3(11, 10.5, 9.5)
-	s.Camera.Target = rl.NewVector3(0, 0, 0)
-	s.Camera.Up = rl.NewVector3(0, 1, 0)
-	s.Camera.Fovy = 45
-	s.Camera.Projection = rl.CameraPerspective
-	s.GridVisible = true
-	s.primitives = primitives.NewRegistry()
-	s.selectedIndex = -1 // no selection until user selects in terminal mode
+	s.Camera = Camera{
+		Position: [3]float32{11, 10.5, 9.5},
+		Target:   [3]float32{0, 0, 0},
+		Up:       [3]float32{0, 1, 0},
+		Fovy:     45,
+	}
+	s.viewportW, s.viewportH = defaultViewportWidth, defaultViewportHeight
+	s.byID = make(map[ObjectID]int)
 	s.physicsWorld = physics.NewWorld()
-	s.textureCache = make(map[string]rl.Texture2D)
-	s.lightDir = [3]float32{0.5, 1, 0.5}
-	s.loadScene()
-	s.ensurePhysicsBodies()
-	s.loadSkybox()
+	s.bodies = make(map[ObjectID]*physics.Body)
 	return s
 }
 
-// loadScene reads the scene from the first existing path in scenePaths and unmarshals into sceneData.
-// If no file is found or YAML is invalid, sceneData.Objects stays nil (no objects drawn).
-func (s *Scene) loadScene() {
-	var path string
-	for _, p := range scenePaths {
-		cleaned := filepath.Clean(p)
-		if _, err := os.Stat(cleaned); err == nil {
-			path = cleaned
-			break
-		}
-	}
-	if path == "" {
-		return
-	}
-	s.scenePath = path
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return
+// SetViewport sets the screen size in pixels used by ObjectsInView. The renderer calls it each frame;
+// headless scenes keep the default 1280x720.
+func (s *Scene) SetViewport(width, height int) {
+	if width > 0 && height > 0 {
+		s.viewportW, s.viewportH = width, height
 	}
-	var sd SceneData
-	if err := yaml.Unmarshal(data, &sd); err != nil {
-		return
-	}
-	s.sceneData = sd
 }
 
-// AddObject appends an object to the scene. It is drawn on the next frame.
-// Use for runtime spawning (e.g. from the spawn command).
-func (s *Scene) AddObject(obj ObjectInstance) {
-	s.sceneData.Objects = append(s.sceneData.Objects, obj)
+// AddObject appends an object to the scene as a root (with its Children, if any) and returns its ID. It is
+// drawn on the next frame. Use for runtime spawning (e.g. from the spawn command).
+func (s *Scene) AddObject(obj ObjectInstance) ObjectID {
+	defer s.edit("add " + obj.Type)()
+	return s.IDAt(s.flattenInto(obj, -1))
 }
 
 // planeDefaultScaleY is the default Y scale (height) for plane primitives so they render and collide as a thin slab.
@@ -243,20 +210,21 @@ const planeDefaultScaleY = 0.1
 
 // AddPrimitive adds a primitive with the given position and scale. Default scale is [1,1,1].
 // Plane uses Y scale 0.1 by default when scale[1] is 1. Position is the center of the primitive. Physics defaults to on.
-func (s *Scene) AddPrimitive(typ string, position, scale [3]float32) {
+// Returns the new object's ID.
+func (s *Scene) AddPrimitive(typ string, position, scale [3]float32) ObjectID {
 	scale = applyPlaneDefaultScale(typ, scale)
-	s.AddObject(ObjectInstance{Type: typ, Position: position, Scale: scale})
+	return s.AddObject(ObjectInstance{Type: typ, Position: position, Scale: scale})
 }
 
-// AddPrimitiveWithPhysics adds a primitive with the given position, scale, and physics flag.
+// AddPrimitiveWithPhysics adds a primitive with the given position, scale, and physics flag and returns its ID.
 // color is optional (nil = default material); name and motion can be set via SetSelected* after add.
-func (s *Scene) AddPrimitiveWithPhysics(typ string, position, scale [3]float32, physics bool, color *[3]float32) {
+func (s *Scene) AddPrimitiveWithPhysics(typ string, position, scale [3]float32, physics bool, color *[3]float32) ObjectID {
 	scale = applyPlaneDefaultScale(typ, scale)
 	obj := ObjectInstance{Type: typ, Position: position, Scale: scale, Physics: &physics}
 	if color != nil {
 		obj.Color = *color
 	}
-	s.AddObject(obj)
+	return s.AddObject(obj)
 }
 
 // applyPlaneDefaultScale returns scale with Y set to planeDefaultScaleY when typ is "plane" and scale[1] is 1.
@@ -267,97 +235,153 @@ func applyPlaneDefaultScale(typ string, scale [3]float32) [3]float32 {
 	return scale
 }
 
-// SelectedIndex returns the index of the selected object, or -1 if none.
+// SelectedIndex returns the current index of the primary selected object (the last one selected), or -1
+// if none.
 func (s *Scene) SelectedIndex() int {
-	return s.selectedIndex
+	return s.IndexOf(s.SelectedID())
 }
 
-// SelectedObject returns the currently selected object and true, or (zero, false) if none.
-func (s *Scene) SelectedObject() (ObjectInstance, bool) {
-	if s.selectedIndex < 0 || s.selectedIndex >= len(s.sceneData.Objects) {
-		return ObjectInstance{}, false
+// SelectedID returns the ID of the primary selected object, or 0 if none.
+func (s *Scene) SelectedID() ObjectID {
+	for i := len(s.selection) - 1; i >= 0; i-- {
+		if s.IndexOf(s.selection[i]) >= 0 {
+			return s.selection[i]
+		}
+	}
+	return 0
+}
+
+// SelectID selects only the object with the given ID (0 or an unknown ID clears the selection).
+func (s *Scene) SelectID(id ObjectID) {
+	s.selection = nil
+	if s.IndexOf(id) >= 0 {
+		s.selection = []ObjectID{id}
 	}
-	return s.sceneData.Objects[s.selectedIndex], true
 }
 
-// SetPhysicsForIndex sets whether the object at index has physics (falling/collision) enabled.
-// Returns an error if index is out of range. Persist with SaveScene.
-func (s *Scene) SetPhysicsForIndex(index int, enabled bool) error {
+// ObjectCount returns the number of objects in the scene.
+func (s *Scene) ObjectCount() int {
+	return len(s.sceneData.Objects)
+}
+
+// Objects returns a copy of the scene's objects in draw order.
+func (s *Scene) Objects() []ObjectInstance {
+	return append([]ObjectInstance(nil), s.sceneData.Objects...)
+}
+
+// ObjectAt returns the object at index and true, or (zero, false) if index is out of range.
+func (s *Scene) ObjectAt(index int) (ObjectInstance, bool) {
 	if index < 0 || index >= len(s.sceneData.Objects) {
-		return fmt.Errorf("object index %d out of range (0..%d)", index, len(s.sceneData.Objects)-1)
+		return ObjectInstance{}, false
+	}
+	return s.sceneData.Objects[index], true
+}
+
+// SelectedObject returns the primary selected object and true, or (zero, false) if none.
+func (s *Scene) SelectedObject() (ObjectInstance, bool) {
+	return s.Object(s.SelectedID())
+}
+
+// SetObjectPhysics sets whether the object with the given ID has physics (falling/collision) enabled.
+// Returns an error if the object no longer exists. Persist with SaveScene.
+func (s *Scene) SetObjectPhysics(id ObjectID, enabled bool) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
 	}
+	defer s.edit("physics")()
 	s.sceneData.Objects[index].Physics = &enabled
 	return nil
 }
 
-// SetSelectedPhysics sets physics on or off for the currently selected object.
+// SetSelectedPhysics sets physics on or off for every selected object.
 // Returns an error if no object is selected.
 func (s *Scene) SetSelectedPhysics(enabled bool) error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+	ids := s.SelectionIDs()
+	if len(ids) == 0 {
 		return fmt.Errorf("no object selected (click an object with terminal open)")
 	}
-	return s.SetPhysicsForIndex(idx, enabled)
-}
-
-// DeleteObjectAtIndex removes the object at index i and the corresponding physics body.
-// Adjusts selectedIndex if needed (clears or decrements). Returns error if index out of range.
-func (s *Scene) DeleteObjectAtIndex(i int) error {
-	objs := s.sceneData.Objects
-	if i < 0 || i >= len(objs) {
-		return fmt.Errorf("object index %d out of range (0..%d)", i, len(objs)-1)
-	}
-	if objs[i].Type == "terrain" {
-		s.clearTerrain()
-	}
-	s.sceneData.Objects = append(objs[:i], objs[i+1:]...)
-	bodies := s.physicsWorld.Bodies
-	if i < len(bodies) {
-		s.physicsWorld.Bodies = append(bodies[:i], bodies[i+1:]...)
-	}
-	if s.selectedIndex == i {
-		s.selectedIndex = -1
-	} else if s.selectedIndex > i {
-		s.selectedIndex--
+	defer s.edit("physics")()
+	for _, id := range ids {
+		if err := s.SetObjectPhysics(id, enabled); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// DeleteSelected removes the currently selected object. Returns error if none selected.
+// DeleteObject removes the object with the given ID with its subtree (children, grandchildren...) and the
+// corresponding physics bodies. Returns an error if the object no longer exists.
+func (s *Scene) DeleteObject(id ObjectID) error {
+	return s.DeleteObjects([]ObjectID{id})
+}
+
+// DeleteSelected removes every selected object (with its subtree). Returns error if none selected.
 func (s *Scene) DeleteSelected() error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+	sel := s.Selection()
+	if len(sel) == 0 {
 		return fmt.Errorf("no object selected (click an object with terminal open)")
 	}
-	s.RecordDelete([]ObjectInstance{s.sceneData.Objects[idx]})
-	return s.DeleteObjectAtIndex(idx)
+	return s.deleteIndices(sel)
 }
 
 // DeleteAtCameraLook casts a ray from the camera position through the camera target and removes
 // the first object hit. Returns error if no object is hit.
 func (s *Scene) DeleteAtCameraLook() error {
-	objs := s.sceneData.Objects
-	if len(objs) == 0 {
+	if len(s.sceneData.Objects) == 0 {
 		return fmt.Errorf("no objects in scene")
 	}
-	dir := rl.Vector3Subtract(s.Camera.Target, s.Camera.Position)
-	dir = rl.Vector3Normalize(dir)
-	ray := rl.Ray{Position: s.Camera.Position, Direction: dir}
+	idx := s.LookTarget()
+	if idx < 0 {
+		return fmt.Errorf("no object in view (camera not looking at any object)")
+	}
+	return s.deleteIndices([]int{idx})
+}
+
+// LookTarget returns the index of the first object hit by a ray from the camera position through the
+// camera target, or -1 if none. A hit on part of a group returns the group (its topmost ancestor).
+func (s *Scene) LookTarget() int {
+	idx, _ := s.Pick(s.Camera.LookRay())
+	if idx < 0 {
+		return -1
+	}
+	return s.Root(idx)
+}
+
+// Pick returns the index of the closest object whose world (oriented) box the ray hits, and where it was
+// hit, or -1 if none. Group nodes have no shape; the hit is on one of their descendants (see Root).
+func (s *Scene) Pick(ray Ray) (int, physics.RayHit) {
 	bestIdx := -1
-	bestDist := float32(1e30)
-	for i := range objs {
-		box := objectAABB(objs[i])
-		hit := rl.GetRayCollisionBox(ray, box)
-		if hit.Hit && hit.Distance > 0 && hit.Distance < bestDist {
-			bestDist = hit.Distance
+	var best physics.RayHit
+	for i := range s.sceneData.Objects {
+		box, ok := s.objectBox(i, false)
+		if !ok {
+			continue
+		}
+		hit, ok := box.IntersectRay(ray.Position, ray.Direction)
+		if ok && (bestIdx < 0 || hit.Distance < best.Distance) {
 			bestIdx = i
+			best = hit
 		}
 	}
-	if bestIdx < 0 {
-		return fmt.Errorf("no object in view (camera not looking at any object)")
+	return bestIdx, best
+}
+
+// selectIndex selects only the object at index; -1 (or any out-of-range index) clears the selection.
+func (s *Scene) selectIndex(index int) {
+	s.SelectID(s.IDAt(index))
+}
+
+// SetObjectPosition moves the object with the given ID (e.g. editor drag). Physics picks it up on the next
+// Step. Returns an error if the object no longer exists.
+func (s *Scene) SetObjectPosition(id ObjectID, pos [3]float32) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
 	}
-	s.RecordDelete([]ObjectInstance{s.sceneData.Objects[bestIdx]})
-	return s.DeleteObjectAtIndex(bestIdx)
+	defer s.edit("move")()
+	s.sceneData.Objects[index].Position = pos
+	return nil
 }
 
 // DeleteRandom removes a random object from the scene. Returns error if scene is empty.
@@ -367,8 +391,7 @@ func (s *Scene) DeleteRandom() error {
 		return fmt.Errorf("no objects in scene")
 	}
 	i := rand.Intn(len(objs))
-	s.RecordDelete([]ObjectInstance{objs[i]})
-	return s.DeleteObjectAtIndex(i)
+	return s.deleteIndices([]int{i})
 }
 
 // DeleteVisibleByDescription deletes the closest object in the camera view that matches the given type
@@ -377,6 +400,16 @@ func (s *Scene) DeleteRandom() error {
 // tolerance 0.35); objects with no color set (zero) do not match a color filter.
 // Returns error if no matching object is in view.
 func (s *Scene) DeleteVisibleByDescription(typ string, colorOptional *[3]float32) error {
+	idx, err := s.FindVisibleByDescription(typ, colorOptional)
+	if err != nil {
+		return err
+	}
+	return s.deleteIndices([]int{idx})
+}
+
+// FindVisibleByDescription returns the index of the visible object that DeleteVisibleByDescription would
+// remove, without changing the scene.
+func (s *Scene) FindVisibleByDescription(typ string, colorOptional *[3]float32) (int, error) {
 	visible := s.ObjectsInView()
 	for _, v := range visible {
 		if v.Object.Type != typ {
@@ -395,19 +428,17 @@ func (s *Scene) DeleteVisibleByDescription(typ string, colorOptional *[3]float32
 				continue
 			}
 		}
-		s.RecordDelete([]ObjectInstance{s.sceneData.Objects[v.Index]})
-		return s.DeleteObjectAtIndex(v.Index)
+		return v.Index, nil
 	}
 	if colorOptional != nil {
-		return fmt.Errorf("no %s with that color in view (look at the object and try again)", typ)
+		return -1, fmt.Errorf("no %s with that color in view (look at the object and try again)", typ)
 	}
-	return fmt.Errorf("no %s in view (look at the object and try again)", typ)
+	return -1, fmt.Errorf("no %s in view (look at the object and try again)", typ)
 }
 
 // visibleMatchFilters returns visible objects that match type (or any if typ empty), optional color, and optional name substring.
 func visibleMatchFilters(visible []VisibleObject, typ string, colorOptional *[3]float32, nameSubstring string) []VisibleObject {
-	primTypes := map[string]bool{"cube": true, "sphere": true, "cylinder": true, "plane": true, "terrain": true}
-	if typ != "" && !primTypes[typ] {
+	if typ != "" && !objectTypes[typ] {
 		return nil
 	}
 	nameLower := strings.ToLower(nameSubstring)
@@ -452,25 +483,25 @@ func visiblePickByPosition(visible []VisibleObject, position string) (VisibleObj
 	switch pos {
 	case "left":
 		for _, v := range visible[1:] {
-			if v.ScreenPos.X < best.ScreenPos.X {
+			if v.ScreenPos[0] < best.ScreenPos[0] {
 				best = v
 			}
 		}
 	case "right":
 		for _, v := range visible[1:] {
-			if v.ScreenPos.X > best.ScreenPos.X {
+			if v.ScreenPos[0] > best.ScreenPos[0] {
 				best = v
 			}
 		}
 	case "top":
 		for _, v := range visible[1:] {
-			if v.ScreenPos.Y < best.ScreenPos.Y {
+			if v.ScreenPos[1] < best.ScreenPos[1] {
 				best = v
 			}
 		}
 	case "bottom":
 		for _, v := range visible[1:] {
-			if v.ScreenPos.Y > best.ScreenPos.Y {
+			if v.ScreenPos[1] > best.ScreenPos[1] {
 				best = v
 			}
 		}
@@ -494,61 +525,108 @@ func visiblePickByPosition(visible []VisibleObject, position string) (VisibleObj
 
 // DeleteVisibleByPosition deletes the one visible object at the given position (left, right, top, bottom, closest, farthest).
 func (s *Scene) DeleteVisibleByPosition(position string) error {
-	visible := s.ObjectsInView()
-	best, ok := visiblePickByPosition(visible, position)
+	idx, err := s.FindVisibleByPosition(position)
+	if err != nil {
+		return err
+	}
+	return s.deleteIndices([]int{idx})
+}
+
+// FindVisibleByPosition returns the index of the visible object that DeleteVisibleByPosition would remove.
+func (s *Scene) FindVisibleByPosition(position string) (int, error) {
+	best, ok := visiblePickByPosition(s.ObjectsInView(), position)
 	if !ok {
-		return fmt.Errorf("no objects in view")
+		return -1, fmt.Errorf("no objects in view")
 	}
-	s.RecordDelete([]ObjectInstance{s.sceneData.Objects[best.Index]})
-	return s.DeleteObjectAtIndex(best.Index)
+	return best.Index, nil
 }
 
 // DeleteVisibleByDescriptionAndPosition deletes the visible object matching type/color/name and at the given position.
 // typ can be "" for any type. nameSubstring "" = any name. position: left, right, top, bottom, closest, farthest, or "" for closest.
 func (s *Scene) DeleteVisibleByDescriptionAndPosition(typ string, colorOptional *[3]float32, nameSubstring string, position string) error {
+	idx, err := s.FindVisible(typ, colorOptional, nameSubstring, position)
+	if err != nil {
+		return err
+	}
+	return s.deleteIndices([]int{idx})
+}
+
+// FindVisible returns the index of the visible object matching type/color/name at the given position, without
+// changing the scene. Same matching as DeleteVisibleByDescriptionAndPosition.
+func (s *Scene) FindVisible(typ string, colorOptional *[3]float32, nameSubstring string, position string) (int, error) {
 	visible := s.ObjectsInView()
 	filtered := visibleMatchFilters(visible, typ, colorOptional, nameSubstring)
 	best, ok := visiblePickByPosition(filtered, position)
 	if !ok {
 		if typ != "" && nameSubstring != "" {
-			return fmt.Errorf("no %q matching %q in view", typ, nameSubstring)
+			return -1, fmt.Errorf("no %q matching %q in view", typ, nameSubstring)
 		}
 		if typ != "" {
-			return fmt.Errorf("no %s in view", typ)
+			return -1, fmt.Errorf("no %s in view", typ)
 		}
-		return fmt.Errorf("no matching object in view")
+		return -1, fmt.Errorf("no matching object in view")
 	}
-	s.RecordDelete([]ObjectInstance{s.sceneData.Objects[best.Index]})
-	return s.DeleteObjectAtIndex(best.Index)
+	return best.Index, nil
 }
 
 // DeleteAllVisibleByDescription deletes all visible objects matching type (or any if ""), optional color, and optional name substring.
 // Returns the number deleted. typ "" means any type; nameSubstring "" means any name.
 func (s *Scene) DeleteAllVisibleByDescription(typ string, colorOptional *[3]float32, nameSubstring string) (int, error) {
+	indices, err := s.FindAllVisible(typ, colorOptional, nameSubstring)
+	if err != nil {
+		return 0, err
+	}
+	return len(indices), s.deleteIndices(indices)
+}
+
+// FindAllVisible returns the indices of all visible objects matching type/color/name, without changing the scene.
+// Same matching as DeleteAllVisibleByDescription.
+func (s *Scene) FindAllVisible(typ string, colorOptional *[3]float32, nameSubstring string) ([]int, error) {
 	visible := s.ObjectsInView()
 	filtered := visibleMatchFilters(visible, typ, colorOptional, nameSubstring)
 	if len(filtered) == 0 {
 		if nameSubstring != "" {
-			return 0, fmt.Errorf("no objects matching %q in view", nameSubstring)
+			return nil, fmt.Errorf("no objects matching %q in view", nameSubstring)
 		}
-		return 0, fmt.Errorf("no matching objects in view")
+		return nil, fmt.Errorf("no matching objects in view")
 	}
-	// Delete in reverse index order so indices remain valid
 	indices := make([]int, len(filtered))
 	for i, v := range filtered {
 		indices[i] = v.Index
 	}
-	sort.Sort(sort.Reverse(sort.IntSlice(indices)))
+	return indices, nil
+}
+
+// DeleteObjects removes the objects with the given IDs with their subtrees (any order, duplicates ignored)
+// as one undo step. Returns an error, and deletes nothing, if one of them no longer exists.
+func (s *Scene) DeleteObjects(ids []ObjectID) error {
+	indices, err := s.indicesOf(ids)
+	if err != nil {
+		return err
+	}
+	return s.deleteIndices(indices)
+}
+
+// deleteIndices is DeleteObjects for the objects at the given indices.
+func (s *Scene) deleteIndices(indices []int) error {
 	for _, idx := range indices {
-		s.RecordDelete([]ObjectInstance{s.sceneData.Objects[idx]})
-		_ = s.DeleteObjectAtIndex(idx)
+		if idx < 0 || idx >= len(s.sceneData.Objects) {
+			return fmt.Errorf("object index %d out of range (0..%d)", idx, len(s.sceneData.Objects)-1)
+		}
 	}
-	return len(indices), nil
+	defer s.edit("delete")()
+	roots := s.topLevel(indices)
+	var all []int
+	for _, idx := range roots {
+		all = append(all, s.Subtree(idx)...)
+	}
+	s.removeObjects(all)
+	return nil
 }
 
-// ClearSelection clears any selected object in the scene.
+// ClearSelection clears the selection.
 func (s *Scene) ClearSelection() {
-	s.selectedIndex = -1
+	s.selection = nil
 }
 
 // SelectVisibleByPosition selects the one visible object at the given position (left, right, top, bottom, closest, farthest).
@@ -558,7 +636,7 @@ func (s *Scene) SelectVisibleByPosition(position string) error {
 	if !ok {
 		return fmt.Errorf("no objects in view")
 	}
-	s.selectedIndex = best.Index
+	s.SelectID(best.ID)
 	return nil
 }
 
@@ -580,7 +658,7 @@ func (s *Scene) SelectVisibleByDescriptionAndPosition(typ string, colorOptional
 		}
 		return fmt.Errorf("no matching object in view")
 	}
-	s.selectedIndex = best.Index
+	s.SelectID(best.ID)
 	return nil
 }
 
@@ -592,8 +670,7 @@ func (s *Scene) FocusOnVisibleByPosition(position string) error {
 	if !ok {
 		return fmt.Errorf("no objects in view")
 	}
-	obj := s.sceneData.Objects[best.Index]
-	s.Camera.Target = rl.NewVector3(obj.Position[0], obj.Position[1], obj.Position[2])
+	s.Camera.Target = s.center(best.Index, false)
 	return nil
 }
 
@@ -615,13 +692,13 @@ func (s *Scene) FocusOnVisibleByDescriptionAndPosition(typ string, colorOptional
 		}
 		return fmt.Errorf("no matching object in view")
 	}
-	obj := s.sceneData.Objects[best.Index]
-	s.Camera.Target = rl.NewVector3(obj.Position[0], obj.Position[1], obj.Position[2])
+	s.Camera.Target = s.center(best.Index, false)
 	return nil
 }
 
 // GetViewContextSummary returns a short text summary of what the camera currently sees, for the LLM.
-// Format: "Visible (left to right): 1. cube 'Tower' (left), 2. plane (center), 3. sphere (right)."
+// Format: "Visible (left to right): 1. "Tower" (cube) #3 (left), 2. plane #1 (center), 3. sphere #7 (right)."
+// The #id lets the LLM refer to unnamed objects (e.g. cmd group).
 func (s *Scene) GetViewContextSummary() string {
 	visible := s.ObjectsInView()
 	if len(visible) == 0 {
@@ -630,16 +707,16 @@ func (s *Scene) GetViewContextSummary() string {
 	// Sort by screen X for left-to-right order
 	byX := make([]VisibleObject, len(visible))
 	copy(byX, visible)
-	sort.Slice(byX, func(i, j int) bool { return byX[i].ScreenPos.X < byX[j].ScreenPos.X })
-	minX, maxX := byX[0].ScreenPos.X, byX[len(byX)-1].ScreenPos.X
+	sort.Slice(byX, func(i, j int) bool { return byX[i].ScreenPos[0] < byX[j].ScreenPos[0] })
+	minX, maxX := byX[0].ScreenPos[0], byX[len(byX)-1].ScreenPos[0]
 	midX := (minX + maxX) / 2
 	var parts []string
 	for i, v := range byX {
 		posLabel := "center"
 		if maxX > minX {
-			if v.ScreenPos.X < midX-20 {
+			if v.ScreenPos[0] < midX-20 {
 				posLabel = "left"
-			} else if v.ScreenPos.X > midX+20 {
+			} else if v.ScreenPos[0] > midX+20 {
 				posLabel = "right"
 			}
 		}
@@ -649,143 +726,161 @@ func (s *Scene) GetViewContextSummary() string {
 		} else {
 			name = fmt.Sprintf("%q (%s)", name, v.Object.Type)
 		}
-		parts = append(parts, fmt.Sprintf("%d. %s (%s)", i+1, name, posLabel))
+		parts = append(parts, fmt.Sprintf("%d. %s #%d (%s)", i+1, name, v.ID, posLabel))
 	}
 	return "Visible (left to right): " + strings.Join(parts, ", ") + "."
 }
 
-// EnsureTexture loads and caches a texture from path. Path is tried as-is and with textureBasePaths.
-// Returns the texture and true if loaded or already cached; (zero, false) if path is empty or load failed.
-// Safe to call from Draw (loads on first use when GL context exists).
-func (s *Scene) EnsureTexture(path string) (rl.Texture2D, bool) {
-	if path == "" {
-		return rl.Texture2D{}, false
-	}
-	if tex, ok := s.textureCache[path]; ok && rl.IsTextureValid(tex) {
-		return tex, true
-	}
-	var fullPath string
-	for _, base := range textureBasePaths {
-		candidate := filepath.Join(base, path)
-		if base == "" {
-			candidate = path
-		}
-		candidate = filepath.Clean(candidate)
-		if _, err := os.Stat(candidate); err == nil {
-			fullPath = candidate
-			break
-		}
+// SetSelectedTexture sets the texture path on every selected object and its descendants. Path is stored
+// as-is (e.g. assets/textures/downloaded/foo.png). Returns an error if no object is selected.
+func (s *Scene) SetSelectedTexture(path string) error {
+	ids := s.SelectionIDs()
+	if len(ids) == 0 {
+		return fmt.Errorf("no object selected (click an object with terminal open)")
 	}
-	if fullPath == "" {
-		// path as-is (absolute or cwd-relative)
-		if _, err := os.Stat(path); err == nil {
-			fullPath = filepath.Clean(path)
+	defer s.edit("texture")()
+	for _, id := range ids {
+		if err := s.SetObjectTexture(id, path); err != nil {
+			return err
 		}
 	}
-	if fullPath == "" {
-		return rl.Texture2D{}, false
+	return nil
+}
+
+// SetObjectTexture sets the texture path on the object with the given ID and its descendants. Used when a
+// background download completes: addressing by ID means the texture lands on the object that was selected
+// when the download started, even if objects were added or deleted meanwhile.
+func (s *Scene) SetObjectTexture(id ObjectID, path string) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
 	}
-	tex := rl.LoadTexture(fullPath)
-	if !rl.IsTextureValid(tex) {
-		return rl.Texture2D{}, false
+	defer s.edit("texture")()
+	for _, i := range s.Subtree(index) {
+		s.sceneData.Objects[i].Texture = path
 	}
-	s.textureCache[path] = tex
-	return tex, true
+	return nil
 }
 
-// SetSelectedTexture sets the texture path on the currently selected object. Path is stored as-is (e.g. assets/textures/downloaded/foo.png).
-// Returns an error if no object is selected.
-func (s *Scene) SetSelectedTexture(path string) error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
-		return fmt.Errorf("no object selected (click an object with terminal open)")
+// SetSelectedColor sets the RGB color (0-1) on every selected object and its descendants.
+func (s *Scene) SetSelectedColor(c [3]float32) error {
+	sel := s.Selection()
+	if len(sel) == 0 {
+		return fmt.Errorf("no object selected")
+	}
+	defer s.edit("color")()
+	for _, idx := range sel {
+		for _, i := range s.Subtree(idx) {
+			s.sceneData.Objects[i].Color = c
+		}
 	}
-	s.sceneData.Objects[idx].Texture = path
 	return nil
 }
 
-// SetObjectTexture sets the texture path on the object at the given index. Used when a background download completes.
-func (s *Scene) SetObjectTexture(index int, path string) error {
-	if index < 0 || index >= len(s.sceneData.Objects) {
-		return fmt.Errorf("object index out of range")
+// SetSelectedName sets the name on every selected object.
+func (s *Scene) SetSelectedName(name string) error {
+	sel := s.Selection()
+	if len(sel) == 0 {
+		return fmt.Errorf("no object selected")
+	}
+	defer s.edit("name")()
+	for _, idx := range sel {
+		s.sceneData.Objects[idx].Name = name
 	}
-	s.sceneData.Objects[index].Texture = path
 	return nil
 }
 
-// SetSelectedColor sets the RGB color (0-1) on the currently selected object.
-func (s *Scene) SetSelectedColor(c [3]float32) error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+// SetSelectedMotion sets motion on every selected object ("", "spin", "bob").
+func (s *Scene) SetSelectedMotion(motion string) error {
+	sel := s.Selection()
+	if len(sel) == 0 {
 		return fmt.Errorf("no object selected")
 	}
-	s.sceneData.Objects[idx].Color = c
+	defer s.edit("motion")()
+	for _, idx := range sel {
+		s.sceneData.Objects[idx].Motion = motion
+	}
 	return nil
 }
 
-// SetSelectedName sets the name on the currently selected object.
-func (s *Scene) SetSelectedName(name string) error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+// SetSelectedRotation sets the rotation of every selected object (Euler degrees, relative to its parent);
+// descendants turn with them.
+func (s *Scene) SetSelectedRotation(euler [3]float32) error {
+	ids := s.SelectionIDs()
+	if len(ids) == 0 {
 		return fmt.Errorf("no object selected")
 	}
-	s.sceneData.Objects[idx].Name = name
+	defer s.edit("rotate")()
+	for _, id := range ids {
+		if err := s.SetObjectRotation(id, euler); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// SetSelectedMotion sets motion on the selected object ("", "spin", "bob").
-func (s *Scene) SetSelectedMotion(motion string) error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
-		return fmt.Errorf("no object selected")
+// SetObjectRotation sets the rotation (Euler degrees, relative to its parent) of the object with the given
+// ID. Angles are stored normalized (e.g. 370 becomes 10). Returns an error if the object no longer exists.
+func (s *Scene) SetObjectRotation(id ObjectID, euler [3]float32) error {
+	index, err := s.index(id)
+	if err != nil {
+		return err
 	}
-	s.sceneData.Objects[idx].Motion = motion
+	defer s.edit("rotate")()
+	s.sceneData.Objects[index].Rotation = physics.QuatFromEuler(euler).Euler()
+	s.syncSceneToPhysics()
 	return nil
 }
 
-// SetLighting sets the directional light from a profile: "noon" (default), "sunset", "night".
-func (s *Scene) SetLighting(profile string) {
-	switch profile {
-	case "sunset":
-		s.lightDir = [3]float32{0.8, 0.3, 0.2} // warm, low
-	case "night":
-		s.lightDir = [3]float32{-0.3, 0.5, -0.5} // dim, blue-ish
-	default:
-		s.lightDir = [3]float32{0.5, 1, 0.5} // noon
+// RotateSelected turns every selected object (each about its own origin) by deg degrees about the world
+// axis (e.g. {0,1,0} for yaw).
+func (s *Scene) RotateSelected(axis [3]float32, deg float32) error {
+	if len(s.Selection()) == 0 {
+		return fmt.Errorf("no object selected")
 	}
+	return s.RotateSelectionBy(physics.QuatAxisAngle(axis, deg))
 }
 
-// SetTerrainTextureRepeat controls how many times the terrain texture repeats across
-// the heightmap in U (X) and V (Z). For example, (4,4) tiles the texture 4x4; (1,1)
-// is the default (stretched once across the terrain).
-func (s *Scene) SetTerrainTextureRepeat(u, v float32) {
-	s.primitives.SetTerrainUVScale(u, v)
+// SetLighting sets the sun (direction, color, intensity and ambient light) from a profile: "noon"
+// (default), "sunset", "night" (see LightingProfiles).
+func (s *Scene) SetLighting(profile string) error {
+	if _, ok := lightingProfiles[profile]; !ok {
+		return fmt.Errorf("unknown lighting %q (%s)", profile, strings.Join(LightingProfiles, ", "))
+	}
+	s.lighting = profile
+	return nil
 }
 
-// EnableTerrain installs a custom terrain mesh in the primitives registry and enables
-// drawing of a single deformed plane as optimized heightmap terrain. size is (width, heightScale, depth) in world units;
-// the terrain object is given this scale and centered position so it can be selected by clicking anywhere on the mesh.
-func (s *Scene) EnableTerrain(mesh rl.Mesh, size [3]float32) {
-	s.primitives.SetTerrainMesh(mesh)
-	s.terrainEnabled = true
-	for _, obj := range s.sceneData.Objects {
-		if obj.Type == "terrain" {
-			// Update existing terrain object's scale/position to match new size so selection works
-			idx := s.terrainObjectIndex()
-			if idx >= 0 {
-				s.sceneData.Objects[idx].Scale = size
-				s.sceneData.Objects[idx].Position = [3]float32{0, size[1] / 2, 0}
-			}
-			return
-		}
+// SetTerrain adds a static terrain object generated from hm, sized to it (width, heightScale, depth in
+// world units) and centered so it can be selected by clicking anywhere on the heightmap, or regenerates
+// the existing one. The renderer draws it with the mesh it generates from hm (see render.View.syncTerrain).
+func (s *Scene) SetTerrain(hm Heightmap) error {
+	if err := hm.Check(); err != nil {
+		return err
+	}
+	size := hm.Size()
+	defer s.edit("heightmap")()
+	if idx := s.terrainObjectIndex(); idx >= 0 {
+		// Update existing terrain object's scale/position to match new size so selection works
+		s.sceneData.Objects[idx].Scale = size
+		s.sceneData.Objects[idx].Position = [3]float32{0, size[1] / 2, 0}
+		s.sceneData.Objects[idx].Heightmap = &hm
+		return nil
 	}
 	static := false
-	s.sceneData.Objects = append(s.sceneData.Objects, ObjectInstance{
-		Type:     "terrain",
-		Position: [3]float32{0, size[1] / 2, 0},
-		Scale:    size,
-		Physics:  &static,
+	s.AddObject(ObjectInstance{
+		Type:      "terrain",
+		Position:  [3]float32{0, size[1] / 2, 0},
+		Scale:     size,
+		Physics:   &static,
+		Heightmap: &hm,
 	})
+	return nil
+}
+
+// HasTerrain reports whether the scene has a terrain object.
+func (s *Scene) HasTerrain() bool {
+	return s.terrainObjectIndex() >= 0
 }
 
 // terrainObjectIndex returns the index of the first object with Type "terrain", or -1.
@@ -798,19 +893,11 @@ func (s *Scene) terrainObjectIndex() int {
 	return -1
 }
 
-// clearTerrain disables terrain drawing and frees the terrain mesh. Call when the terrain object is deleted.
-func (s *Scene) clearTerrain() {
-	if !s.terrainEnabled {
-		return
-	}
-	s.terrainEnabled = false
-	s.primitives.ClearTerrain()
-}
-
-// DuplicateSelected clones the selected object n times with a small position offset. Returns count duplicated.
+// DuplicateSelected clones every selected object (with its subtree) n times with a small position offset,
+// as siblings under the same parent. Returns the number of copies made of each.
 func (s *Scene) DuplicateSelected(n int, offset [3]float32) (int, error) {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+	roots := s.topLevel(s.Selection())
+	if len(roots) == 0 {
 		return 0, fmt.Errorf("no object selected")
 	}
 	if n <= 0 {
@@ -819,80 +906,64 @@ func (s *Scene) DuplicateSelected(n int, offset [3]float32) (int, error) {
 	if n > 20 {
 		n = 20
 	}
-	obj := s.sceneData.Objects[idx]
-	for i := 0; i < n; i++ {
-		clone := obj
-		clone.Position[0] += offset[0] * float32(i+1)
-		clone.Position[1] += offset[1] * float32(i+1)
-		clone.Position[2] += offset[2] * float32(i+1)
-		clone.Name = "" // avoid duplicate names
-		s.sceneData.Objects = append(s.sceneData.Objects, clone)
+	defer s.edit("duplicate")()
+	for _, id := range s.IDs(roots) {
+		idx := s.IndexOf(id)
+		tree, _ := s.Tree(idx)
+		parent := s.parents[idx]
+		for i := 0; i < n; i++ {
+			t := s.worldTransform(idx, false)
+			t.Position[0] += offset[0] * float32(i+1)
+			t.Position[1] += offset[1] * float32(i+1)
+			t.Position[2] += offset[2] * float32(i+1)
+			clone := clearIdentity(tree) // avoid duplicate names; clones get fresh IDs
+			clone.Position = s.toLocal(parent, t).Position
+			s.flattenInto(clone, parent)
+		}
 	}
+	s.ensurePhysicsBodies()
 	s.syncSceneToPhysics()
 	return n, nil
 }
 
-// undoRecord holds one level of undo (either added indices or deleted objects).
-type undoRecord struct {
-	addCount    int              // last N objects added at end of list
-	deletedObjs []ObjectInstance // objects that were deleted
-}
-
-// RecordAdd records that count objects were just added at the end (for undo).
-func (s *Scene) RecordAdd(count int) {
-	if count <= 0 {
-		return
-	}
-	s.lastUndo = &undoRecord{addCount: count}
-}
-
-// RecordDelete records the given objects as deleted (for undo). Call before actually removing them.
-func (s *Scene) RecordDelete(objs []ObjectInstance) {
-	if len(objs) == 0 {
-		return
-	}
-	s.lastUndo = &undoRecord{deletedObjs: objs}
-}
-
-// Undo reverts the last add or delete. Returns nil on success.
-func (s *Scene) Undo() error {
-	if s.lastUndo == nil {
-		return fmt.Errorf("nothing to undo")
-	}
-	if s.lastUndo.addCount > 0 {
-		n := len(s.sceneData.Objects) - s.lastUndo.addCount
-		if n < 0 {
-			n = 0
-		}
-		s.sceneData.Objects = s.sceneData.Objects[:n]
-		s.syncSceneToPhysics()
-		if s.selectedIndex >= len(s.sceneData.Objects) {
-			s.selectedIndex = len(s.sceneData.Objects) - 1
-		}
-	} else {
-		s.sceneData.Objects = append(s.sceneData.Objects, s.lastUndo.deletedObjs...)
-		s.syncSceneToPhysics()
+// clearIdentity returns a copy of tree with every Name and ID cleared.
+func clearIdentity(tree ObjectInstance) ObjectInstance {
+	tree.ID = 0
+	tree.Name = ""
+	children := make([]ObjectInstance, len(tree.Children))
+	for i, c := range tree.Children {
+		children[i] = clearIdentity(c)
 	}
-	s.lastUndo = nil
-	return nil
+	tree.Children = children
+	return tree
 }
 
 // SetGravity sets the physics world gravity vector (e.g. [0, -9.8, 0] for down).
 func (s *Scene) SetGravity(g [3]float32) {
+	defer s.edit("gravity")()
 	s.physicsWorld.SetGravity(g)
 }
 
-// FocusOnSelected sets the camera target to the selected object's position.
+// FocusOnSelected sets the camera target to the center of the selected object (and its subtree).
 func (s *Scene) FocusOnSelected() error {
-	idx := s.SelectedIndex()
-	if idx < 0 {
+	box, ok := s.SelectionBounds()
+	if !ok {
 		return fmt.Errorf("no object selected")
 	}
-	obj := s.sceneData.Objects[idx]
-	s.Camera.Target = rl.NewVector3(obj.Position[0], obj.Position[1], obj.Position[2])
+	s.Camera.Target = [3]float32{(box.Min[0] + box.Max[0]) / 2, (box.Min[1] + box.Max[1]) / 2, (box.Min[2] + box.Max[2]) / 2}
 	return nil
 }
 
+// FindByName returns the index of the first object whose name matches, or -1.
+func (s *Scene) FindByName(name string) int {
+	for i := range s.sceneData.Objects {
+		if s.sceneData.Objects[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
 // DeleteByName removes the first object whose name matches. Returns true if one was removed.
 func (s *Scene) DeleteByName(name string) (bool, error) {
 	if name == "" {
@@ -900,178 +971,41 @@ func (s *Scene) DeleteByName(name string) (bool, error) {
 	}
 	for i := range s.sceneData.Objects {
 		if s.sceneData.Objects[i].Name == name {
-			s.RecordDelete([]ObjectInstance{s.sceneData.Objects[i]})
-			return true, s.DeleteObjectAtIndex(i)
+			return true, s.deleteIndices([]int{i})
 		}
 	}
 	return false, fmt.Errorf("no object named %q", name)
 }
 
-// SaveScene writes the current scene (including runtime-spawned objects) to the scene YAML file.
-// Uses the path we loaded from, or the first path in scenePaths if none was loaded.
-// Returns an error if the file cannot be written.
-func (s *Scene) SaveScene() error {
-	path := s.scenePath
-	if path == "" {
-		path = filepath.Clean(scenePaths[0])
-	}
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
-	data, err := yaml.Marshal(&s.sceneData)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(path, data, 0644)
-}
-
-// NewScene clears all objects from the scene and saves immediately, marking a fresh start.
-// The scene file is overwritten with an empty objects list. Physics bodies are cleared.
-func (s *Scene) NewScene() error {
-	s.sceneData.Objects = nil
-	s.physicsWorld.Bodies = nil
-	return s.SaveScene()
-}
-
-// equirectAspectMin/Max: width/height ratio for equirectangular panorama (typically 2:1).
-const equirectAspectMin = 1.8
-const equirectAspectMax = 2.2
-
-// loadSkybox finds the skybox file from skyboxPaths. GPU loading and equirect detection are deferred to
-// ensureSkyboxLoaded (called from Draw) so they run after the window/OpenGL context exists.
-func (s *Scene) loadSkybox() {
-	for _, p := range skyboxPaths {
-		cleaned := filepath.Clean(p)
-		if _, err := os.Stat(cleaned); err == nil {
-			s.skyboxPath = cleaned
-			s.skyboxPending = true
-			return
+// NewScene clears all objects (one undo step) and starts a new scene named name, or an unnamed one for "".
+// Nothing is written to disk: the previous scene's file stays as it was last saved, and the new scene is
+// created on the first save. A name already used in the library is refused, so that save cannot overwrite it.
+func (s *Scene) NewScene(name string) error {
+	if name != "" {
+		path, err := sceneFile(SceneDir(), name)
+		if err != nil {
+			return err
 		}
-	}
-}
-
-// ensureSkyboxLoaded runs the first time we Draw with a pending skybox; it loads GPU resources
-// (texture, mesh, material, shader) so that LoadTexture/LoadTextureCubemap run after the window/GL context exists.
-// Only clears pending/path on success so a failed load (e.g. GL not ready on first frame) will retry next frame.
-// Detects equirect vs cubemap from image aspect ratio when loading from a dynamically set path.
-func (s *Scene) ensureSkyboxLoaded() {
-	if !s.skyboxPending || s.skyboxPath == "" {
-		return
-	}
-	path := s.skyboxPath
-	img := rl.LoadImage(path)
-	if img == nil || img.Width <= 0 || img.Height <= 0 {
-		return
-	}
-	aspect := float32(img.Width) / float32(img.Height)
-	s.skyboxEquirect = aspect >= equirectAspectMin && aspect <= equirectAspectMax
-
-	if !s.skyboxEquirect {
-		s.skyboxTex = rl.LoadTextureCubemap(img, rl.CubemapLayoutAutoDetect)
-		rl.UnloadImage(img)
-		if !rl.IsTextureValid(s.skyboxTex) {
-			return
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("scene %q already exists (use cmd load %s)", name, name)
 		}
-		s.skyboxMesh = rl.GenMeshCube(1, 1, 1)
-		s.skyboxMtl = rl.LoadMaterialDefault()
-		rl.SetMaterialTexture(&s.skyboxMtl, rl.MapCubemap, s.skyboxTex)
-		s.skyboxPending = false
-		s.skyboxPath = ""
-		s.skyboxLoaded = true
-		return
-	}
-
-	rl.UnloadImage(img)
-	s.skyboxTex = rl.LoadTexture(path)
-	if !rl.IsTextureValid(s.skyboxTex) {
-		return
 	}
-	shader := loadEquirectSkyboxShader()
-	if !rl.IsShaderValid(shader) {
-		rl.UnloadTexture(s.skyboxTex)
-		return
-	}
-	s.skyboxMesh = rl.GenMeshCube(1, 1, 1)
-	s.skyboxMtl = rl.LoadMaterialDefault()
-	s.skyboxMtl.Shader = shader
-	s.skyboxCamPosLoc = rl.GetShaderLocation(shader, "cameraPosition")
-	s.skyboxTexLoc = rl.GetShaderLocation(shader, "skybox")
-	s.skyboxShader = shader
-	s.skyboxPending = false
-	s.skyboxPath = ""
-	s.skyboxLoaded = true
-}
-
-// UnloadSkybox releases GPU resources for the current skybox. Call before setting a new skybox path.
-// UnloadMaterial unloads the material's attached shader, so we must not call UnloadShader separately (double-free).
-func (s *Scene) UnloadSkybox() {
-	if !s.skyboxLoaded {
-		return
-	}
-	rl.UnloadTexture(s.skyboxTex)
-	rl.UnloadMesh(&s.skyboxMesh)
-	rl.UnloadMaterial(s.skyboxMtl)
-	s.skyboxLoaded = false
-}
-
-// SetSkyboxPath sets the skybox to the given image path (e.g. from a downloaded file). Loads in the next Draw.
-// Supports equirectangular panoramas (2:1 aspect) and cubemaps. Call UnloadSkybox is not required; SetSkyboxPath unloads the current skybox first.
-func (s *Scene) SetSkyboxPath(path string) {
-	s.UnloadSkybox()
-	s.skyboxPath = path
-	s.skyboxPending = true
-}
-
-// Equirectangular skybox shader: samples a 2D panorama by view direction.
-const (
-	equirectVS = `#version 330
-in vec3 vertexPosition;
-uniform mat4 matProjection;
-uniform mat4 matView;
-uniform mat4 matModel;
-out vec3 fragWorldPos;
-void main() {
-  vec4 worldPos = matModel * vec4(vertexPosition, 1.0);
-  fragWorldPos = worldPos.xyz;
-  gl_Position = matProjection * matView * worldPos;
-}
-`
-	equirectFS = `#version 330
-in vec3 fragWorldPos;
-out vec4 finalColor;
-uniform sampler2D skybox;
-uniform vec3 cameraPosition;
-void main() {
-  vec3 dir = normalize(fragWorldPos - cameraPosition);
-  float lon = atan(dir.z, dir.x);
-  float lat = asin(clamp(dir.y, -1.0, 1.0));
-  float u = lon / 6.28318530718 + 0.5;
-  float v = 0.5 - lat / 3.14159265359;
-  finalColor = texture(skybox, vec2(u, v));
-}
-`
-)
-
-func loadEquirectSkyboxShader() rl.Shader {
-	return rl.LoadShaderFromMemory(equirectVS, equirectFS)
-}
-
-// SetGridVisible sets whether the editor grid is drawn.
-func (s *Scene) SetGridVisible(visible bool) {
-	s.GridVisible = visible
+	defer s.edit("new scene")()
+	s.clearObjects()
+	s.name = strings.TrimSuffix(name, ".yaml")
+	return nil
 }
 
-// ensurePhysicsBodies keeps physics world bodies in 1:1 with scene objects. Adds bodies for new objects.
+// ensurePhysicsBodies gives every scene object a physics body (paired by ID; removeObjects drops them).
 // Static = physics disabled (no fall); dynamic = physics enabled (falls, collides). Scale 0 is treated as 1.
 func (s *Scene) ensurePhysicsBodies() {
-	objs := s.sceneData.Objects
-	for len(s.physicsWorld.Bodies) < len(objs) {
-		i := len(s.physicsWorld.Bodies)
-		obj := objs[i]
-		scale := scaleForPhysicsBody(obj)
-		static := !physicsEnabled(obj)
-		s.physicsWorld.AddBody(physics.NewBody(obj.Position, scale, 1, static))
+	for _, obj := range s.sceneData.Objects {
+		if _, ok := s.bodies[obj.ID]; ok {
+			continue
+		}
+		b := physics.NewBody(obj.Position, scaleForPhysicsBody(obj), 1, !physicsEnabled(obj))
+		s.bodies[obj.ID] = b
+		s.physicsWorld.AddBody(b)
 	}
 }
 
@@ -1118,102 +1052,127 @@ func scaleForPhysicsBody(obj ObjectInstance) [3]float32 {
 }
 
 // syncSceneToPhysics copies each scene object's position, scale, and physics flag into the corresponding physics body.
+// A root with children gets one body around its whole subtree, so a group falls and collides as a unit; the
+// children's own bodies are disabled and simply follow their root.
 func (s *Scene) syncSceneToPhysics() {
-	bodies := s.physicsWorld.Bodies
 	objs := s.sceneData.Objects
-	for i := 0; i < len(bodies) && i < len(objs); i++ {
-		bodies[i].Position = objs[i].Position
-		bodies[i].Scale = scaleForPhysicsBody(objs[i])
-		bodies[i].Static = !physicsEnabled(objs[i])
+	children := s.childLists()
+	for i := range objs {
+		b := s.bodies[objs[i].ID]
+		if b == nil {
+			continue // added since the last ensurePhysicsBodies
+		}
+		b.Disabled = false
+		switch {
+		case s.parents[i] >= 0:
+			b.Disabled = true
+			b.Position = s.worldTransform(i, false).Position
+		case len(children[i]) > 0:
+			box := s.subtreeBounds(i, false)
+			for k := 0; k < 3; k++ {
+				b.Position[k] = (box.Min[k] + box.Max[k]) / 2
+				b.Scale[k] = box.Max[k] - box.Min[k]
+			}
+			b.Static = !physicsEnabled(objs[i])
+		case objs[i].Type == LightType:
+			b.Disabled = true // lights are not solid
+			b.Position = objs[i].Position
+		case objs[i].Type == GroupType:
+			b.Disabled = true // empty group: nothing to collide
+			b.Position = objs[i].Position
+		default:
+			b.Position = objs[i].Position
+			b.Scale = scaleForPhysicsBody(objs[i])
+			if objs[i].Rotation != ([3]float32{}) {
+				// Bodies are axis-aligned: a rotated object collides with the box around it.
+				box := physics.OrientedBoxAt(b.Position, b.Scale, physics.QuatFromEuler(objs[i].Rotation)).AABB()
+				b.Scale = [3]float32{box.Max[0] - box.Min[0], box.Max[1] - box.Min[1], box.Max[2] - box.Min[2]}
+			}
+			b.Static = !physicsEnabled(objs[i])
+		}
 	}
 }
 
-// syncPhysicsToScene copies dynamic body positions back to scene objects.
+// syncPhysicsToScene copies dynamic body positions back to scene objects. A subtree body moved by physics
+// moves its root by the same amount.
 func (s *Scene) syncPhysicsToScene() {
-	bodies := s.physicsWorld.Bodies
 	objs := s.sceneData.Objects
-	for i := 0; i < len(bodies) && i < len(objs); i++ {
-		if !bodies[i].Static {
-			objs[i].Position = bodies[i].Position
+	children := s.childLists()
+	for i := range objs {
+		b := s.bodies[objs[i].ID]
+		if b == nil || b.Static || b.Disabled {
+			continue
+		}
+		if len(children[i]) == 0 {
+			objs[i].Position = b.Position
+			continue
+		}
+		c := s.center(i, false)
+		for k := 0; k < 3; k++ {
+			objs[i].Position[k] += b.Position[k] - c[k]
 		}
 	}
 }
 
-// Update runs once per frame. Uses raylib UpdateCamera with CameraFree so the user can
-// move the camera with mouse (zoom, pan) and keyboard. Cursor is disabled so the mouse
-// is captured for camera control. When terminal is closed (game mode), runs 3D physics:
-// sync scene→bodies, Step(dt), sync bodies→scene.
-func (s *Scene) Update() {
-	if !s.cursorDone {
-		rl.DisableCursor()
-		s.cursorDone = true
-	}
-	rl.UpdateCamera(&s.Camera, rl.CameraFree)
+// Step advances the scene by dt seconds: the clock (motion), then 3D physics (sync scene→bodies,
+// step, sync bodies→scene), then view awareness. The renderer calls it each frame in game mode;
+// headless callers call it directly.
+func (s *Scene) Step(dt float32) {
+	s.AdvanceClock(dt)
 	s.ensurePhysicsBodies()
 	s.syncSceneToPhysics()
-	s.physicsWorld.Step(rl.GetFrameTime())
+	s.physicsWorld.Step(dt)
 	s.syncPhysicsToScene()
 	s.UpdateViewAwareness()
 }
 
-// objectAABB returns the world-space AABB for a scene object (primitives are centered at position).
-func objectAABB(obj ObjectInstance) rl.BoundingBox {
-	return objectAABBAt(obj, obj.Position)
+// AdvanceClock advances the scene clock without stepping physics (editor mode, where objects stay put
+// but motion keeps animating).
+func (s *Scene) AdvanceClock(dt float32) {
+	s.clock += float64(dt)
 }
 
-// objectAABBAt returns the AABB for obj using the given center position (e.g. with motion applied).
-func objectAABBAt(obj ObjectInstance, pos [3]float32) rl.BoundingBox {
-	sx, sy, sz := obj.Scale[0], obj.Scale[1], obj.Scale[2]
-	if sx == 0 {
-		sx = 1
-	}
-	if sy == 0 {
-		sy = 1
-	}
-	if sz == 0 {
-		sz = 1
-	}
-	half := [3]float32{sx * 0.5, sy * 0.5, sz * 0.5}
-	return rl.NewBoundingBox(
-		rl.NewVector3(pos[0]-half[0], pos[1]-half[1], pos[2]-half[2]),
-		rl.NewVector3(pos[0]+half[0], pos[1]+half[1], pos[2]+half[2]),
-	)
+// Bounds returns the world-space AABB for obj centered at pos (primitives are centered at their position;
+// pass obj.Position, or MotionPosition for where it is drawn), around its rotated shape. Zero scale
+// components count as 1.
+func Bounds(obj ObjectInstance, pos [3]float32) physics.AABB {
+	return physics.OrientedBoxAt(pos, obj.Scale, physics.QuatFromEuler(obj.Rotation)).AABB()
 }
 
 // ObjectsInView returns all scene objects currently visible to the camera:
-// in front of the camera and with their center projected inside the screen bounds.
-// Results are sorted by distance (closest first). Uses current camera and screen size.
+// in front of the camera and with their center projected inside the screen bounds. Positions are world
+// positions; a group counts as visible when the center of its contents is. Results are sorted by distance (closest first). Uses current camera and screen size.
 func (s *Scene) ObjectsInView() []VisibleObject {
 	objs := s.sceneData.Objects
 	if len(objs) == 0 {
 		return nil
 	}
 	camPos := s.Camera.Position
-	forward := rl.Vector3Subtract(s.Camera.Target, camPos)
-	forward = rl.Vector3Normalize(forward)
-	w := float32(rl.GetScreenWidth())
-	h := float32(rl.GetScreenHeight())
+	forward := normalize(sub(s.Camera.Target, camPos))
+	w, h := float32(s.viewportW), float32(s.viewportH)
 	const inFrontEpsilon = 0.01
 
 	var out []VisibleObject
 	for i := range objs {
 		obj := objs[i]
-		drawPos := s.motionPosition(obj, i)
-		center := rl.NewVector3(drawPos[0], drawPos[1], drawPos[2])
-		toCenter := rl.Vector3Subtract(center, camPos)
-		dist := rl.Vector3Length(toCenter)
+		drawPos := s.worldTransform(i, true).Position
+		if obj.Type == GroupType {
+			drawPos = s.center(i, true)
+		}
+		toCenter := sub(drawPos, camPos)
+		dist := length(toCenter)
 		if dist < 1e-6 {
 			continue
 		}
-		dirToCenter := rl.Vector3Scale(toCenter, 1/dist)
-		if rl.Vector3DotProduct(dirToCenter, forward) < inFrontEpsilon {
+		if dot(toCenter, forward)/dist < inFrontEpsilon {
 			continue // behind or to the side (outside view cone)
 		}
-		screen := rl.GetWorldToScreen(center, s.Camera)
-		if screen.X < 0 || screen.X > w || screen.Y < 0 || screen.Y > h {
+		screen, ok := s.Camera.WorldToScreen(drawPos, s.viewportW, s.viewportH)
+		if !ok || screen[0] < 0 || screen[0] > w || screen[1] < 0 || screen[1] > h {
 			continue
 		}
 		out = append(out, VisibleObject{
+			ID:           obj.ID,
 			Index:        i,
 			Object:       obj,
 			Distance:     dist,
@@ -1225,50 +1184,39 @@ func (s *Scene) ObjectsInView() []VisibleObject {
 	return out
 }
 
-// EnableViewAwareness attaches a ViewAwareness to the scene. It will be updated each frame in Update.
+// EnableViewAwareness attaches a ViewAwareness to the scene. It will be updated on each Step.
 // Pass nil to disable. The caller can set OnEnterView, OnLeaveView, OnUpdate on the provided awareness.
 func (s *Scene) EnableViewAwareness(a *ViewAwareness) {
 	s.viewAwareness = a
 }
 
 // UpdateViewAwareness updates view-awareness state and invokes enter/leave/update callbacks.
-// Called automatically from Update when viewAwareness is set.
+// Called automatically from Step when viewAwareness is set.
 func (s *Scene) UpdateViewAwareness() {
 	if s.viewAwareness == nil {
 		return
 	}
 	visible := s.ObjectsInView()
-	cur := make(map[int]struct{})
+	cur := make(map[ObjectID]ObjectInstance, len(visible))
 	for _, v := range visible {
-		cur[v.Index] = struct{}{}
+		cur[v.ID] = v.Object
 	}
 	last := s.viewAwareness.lastVisible
-	// Enter: in cur but not in last
-	for idx := range cur {
-		if last == nil {
-			break
-		}
-		if _, was := last[idx]; was {
-			continue
-		}
-		for _, v := range visible {
-			if v.Index == idx {
-				if s.viewAwareness.OnEnterView != nil {
-					s.viewAwareness.OnEnterView(idx, v.Object, v.Distance)
+	if last != nil {
+		// Enter: in cur but not in last
+		if s.viewAwareness.OnEnterView != nil {
+			for _, v := range visible {
+				if _, was := last[v.ID]; !was {
+					s.viewAwareness.OnEnterView(v.ID, v.Object, v.Distance)
 				}
-				break
 			}
 		}
-	}
-	// Leave: in last but not in cur
-	if last != nil {
-		for idx := range last {
-			if _, now := cur[idx]; now {
-				continue
-			}
-			objs := s.sceneData.Objects
-			if idx >= 0 && idx < len(objs) && s.viewAwareness.OnLeaveView != nil {
-				s.viewAwareness.OnLeaveView(idx, objs[idx])
+		// Leave: in last but not in cur (including deleted objects)
+		if s.viewAwareness.OnLeaveView != nil {
+			for id, obj := range last {
+				if _, now := cur[id]; !now {
+					s.viewAwareness.OnLeaveView(id, obj)
+				}
 			}
 		}
 	}
@@ -1277,299 +1225,3 @@ func (s *Scene) UpdateViewAwareness() {
 		s.viewAwareness.OnUpdate(visible)
 	}
 }
-
-// rayPlaneY returns the intersection of ray with the horizontal plane Y = planeY.
-// Returns (hit point, true) if hit in front of the ray, otherwise (zero, false).
-func rayPlaneY(ray rl.Ray, planeY float32) (rl.Vector3, bool) {
-	dy := ray.Direction.Y
-	if dy > -1e-6 && dy < 1e-6 {
-		return rl.Vector3{}, false
-	}
-	t := (planeY - ray.Position.Y) / dy
-	if t < 0 {
-		return rl.Vector3{}, false
-	}
-	hit := rl.Vector3{
-		X: ray.Position.X + t*ray.Direction.X,
-		Y: ray.Position.Y + t*ray.Direction.Y,
-		Z: ray.Position.Z + t*ray.Direction.Z,
-	}
-	return hit, true
-}
-
-// rayPlane returns the intersection of ray with a plane (point + normal). Returns (hit, true) if t >= 0.
-func rayPlane(ray rl.Ray, planePoint rl.Vector3, planeNormal rl.Vector3) (rl.Vector3, bool) {
-	dn := ray.Direction.X*planeNormal.X + ray.Direction.Y*planeNormal.Y + ray.Direction.Z*planeNormal.Z
-	if dn > -1e-6 && dn < 1e-6 {
-		return rl.Vector3{}, false
-	}
-	diffX := planePoint.X - ray.Position.X
-	diffY := planePoint.Y - ray.Position.Y
-	diffZ := planePoint.Z - ray.Position.Z
-	t := (diffX*planeNormal.X + diffY*planeNormal.Y + diffZ*planeNormal.Z) / dn
-	if t < 0 {
-		return rl.Vector3{}, false
-	}
-	return rl.Vector3{
-		X: ray.Position.X + t*ray.Direction.X,
-		Y: ray.Position.Y + t*ray.Direction.Y,
-		Z: ray.Position.Z + t*ray.Direction.Z,
-	}, true
-}
-
-// UpdateEditor runs when the terminal is open (cursor visible). It handles selection and
-// movement of scene primitives. terminalBarHeight is the height in pixels of the bar at
-// the bottom; mouse events in that area are ignored so the terminal can receive input.
-// Drag mode is chosen by which face of the selection box was hit: top/bottom → XZ (forward/sides),
-// side faces → Y (up/down). Only scene objects are selectable and movable; skybox and grid are not.
-func (s *Scene) UpdateEditor(cursorVisible bool, terminalBarHeight int) {
-	if !cursorVisible {
-		s.dragging = false
-		s.dragMode = 0
-		return
-	}
-	objs := s.sceneData.Objects
-	if len(objs) == 0 {
-		return
-	}
-	screenH := int32(rl.GetScreenHeight())
-	mouseY := rl.GetMouseY()
-	if mouseY >= screenH-int32(terminalBarHeight) {
-		s.dragging = false
-		s.dragMode = 0
-		return
-	}
-	mousePos := rl.GetMousePosition()
-	ray := rl.GetMouseRay(mousePos, s.Camera)
-
-	if rl.IsMouseButtonReleased(rl.MouseButtonLeft) {
-		s.dragging = false
-		s.dragMode = 0
-		return
-	}
-
-	// Y drag: move object up/down from screen-space mouse delta (total pixels since drag start)
-	if s.dragMode == 2 && s.selectedIndex >= 0 && s.selectedIndex < len(objs) {
-		obj := &objs[s.selectedIndex]
-		deltaPixels := mouseY - s.lastMouseY
-		obj.Position[1] = s.dragStartObjY - float32(deltaPixels)*yDragSensitivity
-		return
-	}
-
-	if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
-		// Box pick: find closest hit and use hit normal to choose drag mode
-		bestIdx := -1
-		bestDist := float32(1e30)
-		var bestHit rl.RayCollision
-		for i := range objs {
-			box := objectAABB(objs[i])
-			hit := rl.GetRayCollisionBox(ray, box)
-			if hit.Hit && hit.Distance > 0 && hit.Distance < bestDist {
-				bestDist = hit.Distance
-				bestIdx = i
-				bestHit = hit
-			}
-		}
-		s.selectedIndex = bestIdx
-		s.dragging = bestIdx >= 0
-		if bestIdx >= 0 {
-			// Top or bottom face only when normal is clearly vertical (Y ≈ ±1). All 4 side faces (Y ≈ 0) → Y drag.
-			n := bestHit.Normal
-			if n.Y > 0.99 || n.Y < -0.99 {
-				s.dragMode = 1 // top or bottom: drag on horizontal plane (XZ)
-				// Store offset from object center to click point so the clicked point stays under cursor
-				s.dragOffsetX = bestHit.Point.X - objs[bestIdx].Position[0]
-				s.dragOffsetZ = bestHit.Point.Z - objs[bestIdx].Position[2]
-			} else {
-				s.dragMode = 2 // any of the 4 side faces: drag up/down (Y)
-				s.dragStartObjY = objs[bestIdx].Position[1]
-				s.lastMouseY = mouseY // store so total delta = mouseY - lastMouseY each frame
-			}
-		} else {
-			s.dragMode = 0
-		}
-		return
-	}
-
-	// XZ drag (top/bottom face): drag on horizontal plane at object Y, keeping click offset under cursor
-	if s.dragMode == 1 && s.dragging && s.selectedIndex >= 0 && s.selectedIndex < len(objs) {
-		obj := &objs[s.selectedIndex]
-		hit, ok := rayPlaneY(ray, obj.Position[1])
-		if ok {
-			obj.Position[0] = hit.X - s.dragOffsetX
-			obj.Position[2] = hit.Z - s.dragOffsetZ
-		}
-	}
-}
-
-// drawGizmoArrows draws red (X), green (Y), blue (Z) arrows at pos. Visual only; no picking.
-func drawGizmoArrows(pos [3]float32) {
-	length := gizmoArrowLength
-	headSize := length * 0.2
-	red := rl.NewColor(220, 80, 80, 255)
-	green := rl.NewColor(80, 220, 80, 255)
-	blue := rl.NewColor(80, 80, 220, 255)
-	base := rl.NewVector3(pos[0], pos[1], pos[2])
-	// X
-	endX := rl.NewVector3(pos[0]+length, pos[1], pos[2])
-	rl.DrawLine3D(base, endX, red)
-	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1], pos[2]+headSize), red)
-	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1], pos[2]-headSize), red)
-	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1]+headSize, pos[2]), red)
-	rl.DrawLine3D(endX, rl.NewVector3(pos[0]+length-headSize, pos[1]-headSize, pos[2]), red)
-	// Y
-	endY := rl.NewVector3(pos[0], pos[1]+length, pos[2])
-	rl.DrawLine3D(base, endY, green)
-	rl.DrawLine3D(endY, rl.NewVector3(pos[0], pos[1]+length-headSize, pos[2]+headSize), green)
-	rl.DrawLine3D(endY, rl.NewVector3(pos[0], pos[1]+length-headSize, pos[2]-headSize), green)
-	rl.DrawLine3D(endY, rl.NewVector3(pos[0]+headSize, pos[1]+length-headSize, pos[2]), green)
-	rl.DrawLine3D(endY, rl.NewVector3(pos[0]-headSize, pos[1]+length-headSize, pos[2]), green)
-	// Z
-	endZ := rl.NewVector3(pos[0], pos[1], pos[2]+length)
-	rl.DrawLine3D(base, endZ, blue)
-	rl.DrawLine3D(endZ, rl.NewVector3(pos[0]+headSize, pos[1], pos[2]+length-headSize), blue)
-	rl.DrawLine3D(endZ, rl.NewVector3(pos[0]-headSize, pos[1], pos[2]+length-headSize), blue)
-	rl.DrawLine3D(endZ, rl.NewVector3(pos[0], pos[1]+headSize, pos[2]+length-headSize), blue)
-	rl.DrawLine3D(endZ, rl.NewVector3(pos[0], pos[1]-headSize, pos[2]+length-headSize), blue)
-}
-
-// Draw renders the 3D scene. Call after ClearBackground and before 2D overlay (e.g. terminal).
-// Draws skybox first (if loaded), then a Unity-style grid on the XZ plane (Y=0) when GridVisible is true.
-// selectionVisible should be true only when terminal is open (editor mode); the selection outline is drawn only then.
-func (s *Scene) Draw(selectionVisible bool) {
-	s.ensureSkyboxLoaded()
-	rl.BeginMode3D(s.Camera)
-	if s.skyboxLoaded {
-		drawSkybox(s)
-	}
-	viewPos := [3]float32{s.Camera.Position.X, s.Camera.Position.Y, s.Camera.Position.Z}
-	lightDir := s.getLightDir()
-	s.primitives.SetView(viewPos, lightDir)
-	// Optimized terrain: single deformed plane mesh rendered before objects, using the terrain object's texture and color.
-	if s.terrainEnabled {
-		var terrainTint *[4]float32
-		var terrainTex string
-		for _, obj := range s.sceneData.Objects {
-			if obj.Type == "terrain" {
-				if obj.Color[0] != 0 || obj.Color[1] != 0 || obj.Color[2] != 0 {
-					t := [4]float32{obj.Color[0], obj.Color[1], obj.Color[2], 1}
-					terrainTint = &t
-				}
-				terrainTex = obj.Texture
-				break
-			}
-		}
-		pos := [3]float32{0, 0, 0}
-		scale := [3]float32{1, 1, 1}
-		if terrainTex != "" {
-			if tex, ok := s.EnsureTexture(terrainTex); ok {
-				s.primitives.DrawWithTexture("terrain", pos, scale, tex, terrainTint)
-			} else {
-				s.primitives.Draw("terrain", pos, scale, terrainTint)
-			}
-		} else {
-			s.primitives.Draw("terrain", pos, scale, terrainTint)
-		}
-	}
-	for i, obj := range s.sceneData.Objects {
-		if obj.Type == "terrain" {
-			// Terrain mesh already drawn above; only draw selection outline if selected.
-			if selectionVisible && s.selectedIndex == i {
-				drawPos := s.motionPosition(obj, i)
-				box := objectAABBAt(obj, drawPos)
-				rl.DrawBoundingBox(box, rl.Yellow)
-				drawGizmoArrows(drawPos)
-			}
-			continue
-		}
-		drawPos := s.motionPosition(obj, i)
-		var tint *[4]float32
-		if obj.Color[0] != 0 || obj.Color[1] != 0 || obj.Color[2] != 0 {
-			t := [4]float32{obj.Color[0], obj.Color[1], obj.Color[2], 1}
-			tint = &t
-		}
-		if obj.Texture != "" {
-			if tex, ok := s.EnsureTexture(obj.Texture); ok {
-				s.primitives.DrawWithTexture(obj.Type, drawPos, obj.Scale, tex, tint)
-			} else {
-				s.primitives.Draw(obj.Type, drawPos, obj.Scale, tint)
-			}
-		} else {
-			s.primitives.Draw(obj.Type, drawPos, obj.Scale, tint)
-		}
-		// Outline only in terminal mode and when this object is selected
-		if selectionVisible && s.selectedIndex == i {
-			box := objectAABBAt(obj, drawPos)
-			rl.DrawBoundingBox(box, rl.Yellow)
-			drawGizmoArrows(drawPos)
-		}
-	}
-	if s.GridVisible {
-		drawEditorGrid()
-	}
-	rl.EndMode3D()
-}
-
-// drawSkybox draws the skybox as a large cube centered on the camera (cubemap or equirect).
-func drawSkybox(s *Scene) {
-	rl.DisableDepthMask()
-	rl.DisableBackfaceCulling()
-	pos := s.Camera.Position
-	scale := rl.MatrixScale(skyboxScale, skyboxScale, skyboxScale)
-	trans := rl.MatrixTranslate(pos.X, pos.Y, pos.Z)
-	transform := rl.MatrixMultiply(scale, trans)
-	if s.skyboxEquirect {
-		if s.skyboxCamPosLoc >= 0 {
-			camPos := []float32{pos.X, pos.Y, pos.Z}
-			rl.SetShaderValueV(s.skyboxMtl.Shader, s.skyboxCamPosLoc, camPos, rl.ShaderUniformVec3, 1)
-		}
-		if s.skyboxTexLoc >= 0 {
-			rl.SetShaderValueTexture(s.skyboxMtl.Shader, s.skyboxTexLoc, s.skyboxTex)
-		}
-	}
-	rl.DrawMesh(s.skyboxMesh, s.skyboxMtl, transform)
-	rl.EnableBackfaceCulling()
-	rl.EnableDepthMask()
-}
-
-// drawEditorGrid draws an infinite-style grid on the XZ plane with major/minor lines and axis lines.
-// Reuses start/end vectors to avoid per-frame allocations in the hot loop.
-func drawEditorGrid() {
-	minor := rl.NewColor(128, 128, 128, gridMinorAlpha)
-	major := rl.NewColor(160, 160, 160, gridMajorAlpha)
-	axisX := rl.NewColor(220, 80, 80, axisLineAlpha)
-	axisY := rl.NewColor(80, 220, 80, axisLineAlpha)
-	axisZ := rl.NewColor(80, 80, 220, axisLineAlpha)
-
-	var start, end rl.Vector3
-	// Grid lines on XZ plane (Y=0): lines along X (varying Z) and along Z (varying X)
-	for x := -gridExtent; x <= gridExtent; x += gridMinorStep {
-		c := major
-		if x%gridMajorStep != 0 {
-			c = minor
-		}
-		start.X, start.Y, start.Z = float32(x), 0, float32(-gridExtent)
-		end.X, end.Y, end.Z = float32(x), 0, float32(gridExtent)
-		rl.DrawLine3D(start, end, c)
-	}
-	for z := -gridExtent; z <= gridExtent; z += gridMinorStep {
-		c := major
-		if z%gridMajorStep != 0 {
-			c = minor
-		}
-		start.X, start.Y, start.Z = float32(-gridExtent), 0, float32(z)
-		end.X, end.Y, end.Z = float32(gridExtent), 0, float32(z)
-		rl.DrawLine3D(start, end, c)
-	}
-
-	// Axis lines through origin (X=red, Y=green, Z=blue)
-	start.X, start.Y, start.Z = float32(-gridExtent), 0, 0
-	end.X, end.Y, end.Z = float32(gridExtent), 0, 0
-	rl.DrawLine3D(start, end, axisX)
-	start.X, start.Y, start.Z = 0, float32(-gridExtent), 0
-	end.X, end.Y, end.Z = 0, float32(gridExtent), 0
-	rl.DrawLine3D(start, end, axisY)
-	start.X, start.Y, start.Z = 0, 0, float32(-gridExtent)
-	end.X, end.Y, end.Z = 0, 0, float32(gridExtent)
-	rl.DrawLine3D(start, end, axisZ)
-}
@@ -0,0 +1,1006 @@
+package scene
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestObjectsInViewHeadless(t *testing.T) {
+	s := NewEmpty()
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	s.AddPrimitive("sphere", [3]float32{30, 30, 30}, [3]float32{1, 1, 1}) // behind the camera
+	visible := s.ObjectsInView()
+	if len(visible) != 1 || visible[0].Index != 0 {
+		t.Fatalf("visible = %+v, want only the cube", visible)
+	}
+	// The camera looks at the origin, so the cube projects to the middle of the default viewport.
+	if p := visible[0].ScreenPos; p[0] < 639 || p[0] > 641 || p[1] < 359 || p[1] > 361 {
+		t.Errorf("screen pos = %v, want center of 1280x720", p)
+	}
+	if idx := s.LookTarget(); idx != 0 {
+		t.Errorf("LookTarget = %d, want 0", idx)
+	}
+}
+
+func TestStepSettlesOnStaticPlane(t *testing.T) {
+	s := NewEmpty()
+	s.AddPrimitiveWithPhysics("plane", [3]float32{0, 0, 0}, [3]float32{10, 1, 10}, false, nil)
+	s.AddPrimitive("cube", [3]float32{0, 3, 0}, [3]float32{1, 1, 1})
+	for i := 0; i < 120; i++ {
+		s.Step(1.0 / 60)
+	}
+	cube, _ := s.ObjectAt(1)
+	// Plane top is at 0.05 (thin slab), so the cube rests with its center half a unit above it.
+	if y := cube.Position[1]; y < 0.5 || y > 0.6 {
+		t.Errorf("cube y = %.3f after 2s, want resting on the plane (~0.55)", y)
+	}
+	if plane, _ := s.ObjectAt(0); plane.Position != [3]float32{0, 0, 0} {
+		t.Errorf("static plane moved to %v", plane.Position)
+	}
+}
+
+func TestGroupHierarchy(t *testing.T) {
+	s := NewEmpty()
+	cube := s.AddPrimitive("cube", [3]float32{2, 0, 0}, [3]float32{1, 1, 1})
+	sphere := s.AddPrimitive("sphere", [3]float32{4, 0, 0}, [3]float32{1, 1, 1})
+	gid, err := s.Group([]ObjectID{cube, sphere}, "Pair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := s.IndexOf(gid)
+	if s.SelectedID() != gid || len(s.Subtree(g)) != 3 {
+		t.Fatalf("selected %d, subtree %v; want group %d with both members", s.SelectedID(), s.Subtree(g), gid)
+	}
+	// Members keep their world positions; moving the group moves them.
+	if w, _ := s.WorldTransform(1); w.Position != [3]float32{4, 0, 0} {
+		t.Errorf("sphere world pos after group = %v, want [4 0 0]", w.Position)
+	}
+	if err := s.SetWorldPosition(gid, [3]float32{3, 5, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := s.WorldTransform(1); w.Position != [3]float32{4, 5, 0} {
+		t.Errorf("sphere world pos after move = %v, want [4 5 0]", w.Position)
+	}
+
+	// The scene file nests members under the group.
+	nested := s.nestedObjects()
+	if len(nested) != 1 || nested[0].Type != GroupType || len(nested[0].Children) != 2 {
+		t.Fatalf("nested = %+v, want one group with two children", nested)
+	}
+
+	// Deleting the group removes its subtree; undo restores the hierarchy.
+	if err := s.DeleteSelected(); err != nil {
+		t.Fatal(err)
+	}
+	if s.ObjectCount() != 0 {
+		t.Fatalf("object count after delete = %d, want 0", s.ObjectCount())
+	}
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if s.ObjectCount() != 3 || s.Parent(0) != g || s.Parent(1) != g {
+		t.Fatalf("after undo: count %d, parents %v; want group %d with children 0, 1", s.ObjectCount(), s.parents, g)
+	}
+
+	if n, err := s.Ungroup(gid); err != nil || n != 2 {
+		t.Fatalf("Ungroup = %d, %v; want 2, nil", n, err)
+	}
+	if s.ObjectCount() != 2 || s.Parent(0) != -1 {
+		t.Fatalf("after ungroup: count %d, parents %v; want two roots", s.ObjectCount(), s.parents)
+	}
+	if w, _ := s.WorldTransform(1); w.Position != [3]float32{4, 5, 0} {
+		t.Errorf("sphere world pos after ungroup = %v, want [4 5 0]", w.Position)
+	}
+}
+
+func TestGroupFallsAsOneBody(t *testing.T) {
+	s := NewEmpty()
+	s.AddPrimitiveWithPhysics("plane", [3]float32{0, 0, 0}, [3]float32{20, 1, 20}, false, nil)
+	bottom := s.AddPrimitive("cube", [3]float32{0, 3, 0}, [3]float32{1, 1, 1})
+	top := s.AddPrimitive("cube", [3]float32{0, 4, 0}, [3]float32{1, 1, 1}) // stacked on the first: would push it if they collided
+	if _, err := s.Group([]ObjectID{bottom, top}, "Stack"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 180; i++ {
+		s.Step(1.0 / 60)
+	}
+	low, _ := s.WorldTransform(1)
+	high, _ := s.WorldTransform(2)
+	if y := low.Position[1]; y < 0.5 || y > 0.6 {
+		t.Errorf("lower cube y = %.3f, want resting on the plane (~0.55)", y)
+	}
+	if d := high.Position[1] - low.Position[1]; d < 0.99 || d > 1.01 {
+		t.Errorf("cubes %.3f apart, want the group to stay rigid (1)", d)
+	}
+}
+
+func TestRotationHierarchyAndPick(t *testing.T) {
+	s := NewEmpty()
+	a := s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	b := s.AddPrimitive("cube", [3]float32{2, 0, 0}, [3]float32{1, 1, 1})
+	g, _ := s.Group([]ObjectID{a, b}, "Pair") // group center is (1,0,0)
+	s.SelectID(g)
+	if err := s.SetSelectedRotation([3]float32{0, 90, 0}); err != nil {
+		t.Fatal(err)
+	}
+	// Turning the group 90° about Y swings the second cube from +X of the center to -Z.
+	w, _ := s.WorldTransform(1)
+	if p := w.Position; !near(p[0], 1) || !near(p[1], 0) || !near(p[2], -1) {
+		t.Errorf("cube world pos = %v, want [1 0 -1]", p)
+	}
+	if e := w.Rotation.Euler(); !near(e[1], 90) {
+		t.Errorf("cube world rotation = %v, want yaw 90", e)
+	}
+
+	// A long thin plank turned 45°: a ray through its unrotated corner misses, one along its new axis hits.
+	s = NewEmpty()
+	plank := s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{4, 0.2, 0.2})
+	if err := s.SetObjectRotation(plank, [3]float32{0, 45, 0}); err != nil {
+		t.Fatal(err)
+	}
+	down := [3]float32{0, -1, 0}
+	if idx, _ := s.Pick(Ray{Position: [3]float32{1.8, 5, 0}, Direction: down}); idx != -1 {
+		t.Errorf("pick at unrotated end hit %d, want miss", idx)
+	}
+	if idx, _ := s.Pick(Ray{Position: [3]float32{1.2, 5, -1.2}, Direction: down}); idx != 0 {
+		t.Errorf("pick along rotated plank = %d, want 0", idx)
+	}
+}
+
+func near(a, b float32) bool {
+	d := a - b
+	return d > -1e-3 && d < 1e-3
+}
+
+func TestStableIDs(t *testing.T) {
+	s := NewEmpty()
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	s.AddPrimitive("sphere", [3]float32{2, 0, 0}, [3]float32{1, 1, 1})
+	s.AddPrimitive("cylinder", [3]float32{4, 0, 0}, [3]float32{1, 1, 1})
+	cube, sphere, cyl := s.IDAt(0), s.IDAt(1), s.IDAt(2)
+	if cube == 0 || cube == sphere || sphere == cyl {
+		t.Fatalf("ids = %d, %d, %d, want distinct and nonzero", cube, sphere, cyl)
+	}
+	s.SelectID(cyl)
+	// An async texture download started for the cylinder must not land on another object after a delete.
+	if err := s.DeleteObjects([]ObjectID{cube}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetObjectTexture(cyl, "wood.png"); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.Object(cyl); obj.Type != "cylinder" || obj.Texture != "wood.png" {
+		t.Errorf("textured %+v, want the cylinder", obj)
+	}
+	if obj, _ := s.SelectedObject(); obj.ID != cyl {
+		t.Errorf("selection moved to %+v after delete", obj)
+	}
+	if err := s.SetObjectTexture(cube, "x.png"); err == nil {
+		t.Error("SetObjectTexture on a deleted object succeeded")
+	}
+
+	// Undoing the texture and the delete restores the object under its old ID; new objects never reuse it.
+	if _, err := s.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+	if obj, ok := s.Object(cube); !ok || obj.Type != "cube" {
+		t.Errorf("after undo, #%d = %+v, want the cube", cube, obj)
+	}
+	s.AddPrimitive("cube", [3]float32{6, 0, 0}, [3]float32{1, 1, 1})
+	added := s.IDAt(s.ObjectCount() - 1)
+	if added == cube || added == sphere || added == cyl {
+		t.Errorf("new object reused ID %d", added)
+	}
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Object(added); ok || s.ObjectCount() != 3 || len(s.physicsWorld.Bodies) != 3 {
+		t.Errorf("after undo: %d objects, %d bodies, want 3 and 3", s.ObjectCount(), len(s.physicsWorld.Bodies))
+	}
+
+	// IDs survive a save/load round trip.
+	data, err := yaml.Marshal(&SceneData{Objects: s.nestedObjects()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sd SceneData
+	if err := yaml.Unmarshal(data, &sd); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewEmpty()
+	for _, obj := range sd.Objects {
+		loaded.AddObject(obj)
+	}
+	for _, id := range []ObjectID{cube, cyl} {
+		a, _ := s.Object(id)
+		b, ok := loaded.Object(id)
+		if !ok || a.Type != b.Type || a.Position != b.Position {
+			t.Errorf("#%d after reload = %+v, want %+v", id, b, a)
+		}
+	}
+}
+
+func TestHistoryUndoRedo(t *testing.T) {
+	s := NewEmpty()
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	s.AddPrimitive("sphere", [3]float32{2, 0, 0}, [3]float32{1, 1, 1})
+	cube, sphere := s.IDAt(0), s.IDAt(1)
+
+	// One LLM turn: several actions, one step.
+	s.BeginChange("make it red and add a cylinder")
+	s.SelectID(cube)
+	if err := s.SetSelectedColor([3]float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	s.AddPrimitive("cylinder", [3]float32{4, 0, 0}, [3]float32{1, 1, 1})
+	s.EndChange()
+	cyl := s.IDAt(2)
+
+	s.SetGravity([3]float32{0, -1, 0})
+	// An intervening delete of another object must not throw off the undo of the earlier steps.
+	if err := s.DeleteObjects([]ObjectID{sphere}); err != nil {
+		t.Fatal(err)
+	}
+	undo, _ := s.History()
+	if len(undo) != 5 || undo[0] != "delete" || undo[2] != "make it red and add a cylinder" {
+		t.Fatalf("history = %q", undo)
+	}
+
+	if n, err := s.Undo(3); err != nil || n != 3 {
+		t.Fatalf("Undo(3) = %d, %v", n, err)
+	}
+	obj, _ := s.Object(cube)
+	if _, ok := s.Object(cyl); ok || obj.Color != ([3]float32{}) || s.ObjectCount() != 2 {
+		t.Errorf("after undo: cube %+v, %d objects; want uncolored cube and sphere", obj, s.ObjectCount())
+	}
+	if g := s.physicsWorld.Gravity; g != ([3]float32{0, -9.8, 0}) {
+		t.Errorf("gravity after undo = %v", g)
+	}
+	if _, ok := s.Object(sphere); !ok {
+		t.Error("sphere not restored under its ID")
+	}
+
+	if n, err := s.Redo(1); err != nil || n != 1 {
+		t.Fatalf("Redo = %d, %v", n, err)
+	}
+	if obj, _ := s.Object(cube); obj.Color != ([3]float32{1, 0, 0}) {
+		t.Errorf("after redo: cube color %v, want red", obj.Color)
+	}
+	if _, ok := s.Object(cyl); !ok || len(s.physicsWorld.Bodies) != 3 {
+		t.Errorf("after redo: cylinder missing or %d bodies", len(s.physicsWorld.Bodies))
+	}
+
+	// A new change clears the redo stack; depth limits the undo stack.
+	if err := s.SetSelectedName("Box"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Redo(1); err == nil {
+		t.Error("redo after a new change succeeded")
+	}
+	s.SetHistoryDepth(2)
+	if undo, _ := s.History(); len(undo) != 2 || undo[0] != "name" {
+		t.Errorf("history after depth 2 = %q", undo)
+	}
+
+	// Undo inside an open step (an LLM turn saying "undo that") reverts the previous step, and is not
+	// recorded itself.
+	s.BeginChange("undo that and add a cube")
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	s.AddPrimitive("cube", [3]float32{0, 3, 0}, [3]float32{1, 1, 1})
+	s.EndChange()
+	undo, redo := s.History()
+	if obj, _ := s.Object(cube); obj.Name != "" || len(undo) != 2 || undo[0] != "undo that and add a cube" || len(redo) != 0 {
+		t.Errorf("after turn: name %q, history %q / %q", obj.Name, undo, redo)
+	}
+}
+
+func TestHistoryKeepsObjectOrder(t *testing.T) {
+	s := NewEmpty()
+	for i, typ := range []string{"cube", "sphere", "cylinder", "cone", "plane"} {
+		s.AddPrimitive(typ, [3]float32{float32(2 * i), 0, 0}, [3]float32{1, 1, 1})
+	}
+	if _, err := s.Group(s.IDs([]int{1, 2}), "pair"); err != nil {
+		t.Fatal(err)
+	}
+	ids := func() []ObjectID {
+		out := make([]ObjectID, s.ObjectCount())
+		for i := range out {
+			out[i] = s.IDAt(i)
+		}
+		return out
+	}
+	file := func() string {
+		data, err := yaml.Marshal(&SceneData{Objects: s.nestedObjects()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	wantIDs, wantFile := ids(), file()
+
+	// Deleting objects from the front and the middle (a group with its children) and undoing puts every
+	// object back at its old index, so undo then save writes the same file.
+	if err := s.DeleteObjects(s.IDs([]int{0, s.FindByName("pair")})); err != nil {
+		t.Fatal(err)
+	}
+	if s.ObjectCount() != 2 {
+		t.Fatalf("%d objects after delete, want 2", s.ObjectCount())
+	}
+	for range 2 {
+		if _, err := s.Undo(1); err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(); !slices.Equal(got, wantIDs) || file() != wantFile {
+			t.Errorf("after undo: ids %v, want %v; file\n%s\nwant\n%s", got, wantIDs, file(), wantFile)
+		}
+		if _, err := s.Redo(1); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHistoryChangeGroup(t *testing.T) {
+	s := NewEmpty()
+	g := &ChangeGroup{Label: "add a cube and a sphere"}
+
+	// Two batches of one LLM turn undo as one step.
+	s.BeginGroupChange(g)
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	s.EndGroupChange(g)
+	s.BeginGroupChange(g)
+	s.AddPrimitive("sphere", [3]float32{2, 0, 0}, [3]float32{1, 1, 1})
+	s.EndGroupChange(g)
+	if undo, _ := s.History(); len(undo) != 1 || undo[0] != g.Label {
+		t.Fatalf("history = %q, want one step for the turn", undo)
+	}
+
+	// A user edit between batches stays its own step; the next batch starts a new one.
+	s.SelectID(s.IDAt(0))
+	if err := s.SetSelectedName("Box"); err != nil {
+		t.Fatal(err)
+	}
+	s.BeginGroupChange(g)
+	s.AddPrimitive("cylinder", [3]float32{4, 0, 0}, [3]float32{1, 1, 1})
+	s.EndGroupChange(g)
+	if undo, _ := s.History(); len(undo) != 3 || undo[1] != "name" {
+		t.Fatalf("history = %q, want turn, name, turn", undo)
+	}
+	if _, err := s.Undo(2); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.Object(s.IDAt(0)); s.ObjectCount() != 2 || obj.Name != "" {
+		t.Errorf("after undoing the user edit and the last batch: %d objects, name %q", s.ObjectCount(), obj.Name)
+	}
+	if _, err := s.Undo(1); err != nil || s.ObjectCount() != 0 {
+		t.Errorf("after undoing the turn: %d objects, %v", s.ObjectCount(), err)
+	}
+}
+
+func TestMultiSelection(t *testing.T) {
+	s := NewEmpty()
+	for i, name := range []string{"Building1", "Building2", "Tree", "Shed"} {
+		typ, pos := "cube", [3]float32{float32(2 * i), 0, 0}
+		if name == "Tree" {
+			typ = "sphere"
+		}
+		if name == "Shed" {
+			pos = [3]float32{30, 30, 30} // behind the camera
+		}
+		s.SelectID(s.AddPrimitive(typ, pos, [3]float32{1, 1, 1}))
+		if err := s.SetSelectedName(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := s.SelectMatching("", nil, "building*", false); err != nil || n != 2 {
+		t.Fatalf("SelectMatching(building*) = %d, %v", n, err)
+	}
+	if err := s.SetSelectedColor([3]float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, true, false, false} {
+		if obj, _ := s.ObjectAt(i); (obj.Color == [3]float32{1, 0, 0}) != want {
+			t.Errorf("object %d color %v, red = %v", i, obj.Color, want)
+		}
+	}
+
+	// Adding every cube brings in the shed (not in view); the selection moves as one undo step.
+	if n, err := s.SelectMatching("cube", nil, "", true); err != nil || n != 3 || len(s.Selection()) != 3 {
+		t.Fatalf("SelectMatching(cube, add) = %d, %v; selection %v", n, err, s.Selection())
+	}
+	if err := s.MoveSelection([3]float32{0, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float32{1, 1, 0, 31} {
+		if obj, _ := s.ObjectAt(i); obj.Position[1] != want {
+			t.Errorf("object %d y = %v, want %v", i, obj.Position[1], want)
+		}
+	}
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.ObjectAt(0); obj.Position[1] != 0 {
+		t.Errorf("after undo: y = %v, want 0", obj.Position[1])
+	}
+
+	s.ToggleSelection(s.IDAt(0))
+	if s.IsSelected(0) || len(s.Selection()) != 2 {
+		t.Errorf("after toggle: selection %v, want without 0", s.Selection())
+	}
+
+	// The whole viewport holds the three objects in front of the camera; the middle only the first cube.
+	if n := s.SelectInRect([2]float32{0, 0}, [2]float32{1280, 720}, false); n != 3 || s.IsSelected(3) {
+		t.Errorf("SelectInRect(viewport) = %d, selection %v", n, s.Selection())
+	}
+	if n := s.SelectInRect([2]float32{650, 370}, [2]float32{630, 350}, false); n != 1 || s.SelectedIndex() != 0 {
+		t.Errorf("SelectInRect(center) = %d, selection %v", n, s.Selection())
+	}
+}
+
+func TestSceneLibrary(t *testing.T) {
+	defer func(dirs []string) { sceneDirs = dirs }(sceneDirs)
+	sceneDirs = []string{t.TempDir()}
+
+	s, err := Open("")
+	if err == nil || s == nil || s.Name() != DefaultSceneName {
+		t.Fatalf("Open(missing default) = %v, %v; want empty scene named default and an error", s, err)
+	}
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	if !s.Modified() {
+		t.Error("not modified after an add")
+	}
+	if err := s.SaveAs("castle"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Modified() || s.Name() != "castle" {
+		t.Errorf("after SaveAs: modified %v, name %q", s.Modified(), s.Name())
+	}
+	if err := s.SaveAs("../escape"); err == nil {
+		t.Error("SaveAs accepted a path")
+	}
+
+	// NewScene clears only the scene in memory; the saved file is untouched.
+	if err := s.NewScene("castle"); err == nil {
+		t.Error("NewScene reused an existing scene name")
+	}
+	if err := s.NewScene(""); err != nil || s.ObjectCount() != 0 || s.Name() != "" {
+		t.Fatalf("NewScene = %v; %d objects, name %q", err, s.ObjectCount(), s.Name())
+	}
+	if err := s.SaveScene(); err == nil {
+		t.Error("SaveScene saved an unnamed scene")
+	}
+	list, err := ListScenes()
+	if err != nil || len(list) != 1 || list[0].Name != "castle" || list[0].Objects != 1 {
+		t.Fatalf("ListScenes = %+v, %v", list, err)
+	}
+
+	s.AddPrimitive("sphere", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	backup, err := s.Autosave(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("castle"); err != nil {
+		t.Fatal(err)
+	}
+	if undo, _ := s.History(); s.ObjectCount() != 1 || s.Name() != "castle" || len(undo) != 0 || s.Modified() {
+		t.Errorf("after Load: %d objects, name %q, %d undo steps, modified %v", s.ObjectCount(), s.Name(), len(undo), s.Modified())
+	}
+	if err := s.LoadBackup(backup); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.ObjectAt(0); s.ObjectCount() != 1 || obj.Type != "sphere" || s.Name() != "" {
+		t.Errorf("after LoadBackup: %d objects (%s), name %q", s.ObjectCount(), obj.Type, s.Name())
+	}
+
+	// Pruning only counts the scene's own snapshots: autosaving "castle" (twice within a millisecond or
+	// so, which must not overwrite) leaves the untitled and "castle-2" snapshots alone.
+	other := NewEmpty()
+	if err := other.SaveAs("castle-2"); err != nil {
+		t.Fatal(err)
+	}
+	otherBackup, err := other.Autosave(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("castle"); err != nil {
+		t.Fatal(err)
+	}
+	first, err := s.Autosave(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Autosave(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("two autosaves both named %q", first)
+	}
+	var names []string
+	backups, err := ListBackups()
+	for _, b := range backups {
+		names = append(names, b.Name)
+	}
+	slices.Sort(names)
+	want := []string{backup, first, second, otherBackup}
+	slices.Sort(want)
+	if err != nil || !slices.Equal(names, want) {
+		t.Errorf("backups = %v, %v; want %v", names, err, want)
+	}
+	if _, err := s.Autosave(2); err != nil {
+		t.Fatal(err)
+	}
+	if backups, _ := ListBackups(); len(backups) != 4 {
+		t.Errorf("%d backups after a third castle autosave with keep 2; want 4", len(backups))
+	}
+}
+
+func TestTerrainHeightmap(t *testing.T) {
+	defer func(dirs []string) { sceneDirs = dirs }(sceneDirs)
+	sceneDirs = []string{t.TempDir()}
+
+	s := NewEmpty()
+	if err := s.SetTerrain(Heightmap{Width: 1, Depth: 8, TileSize: 1, HeightScale: 2}); err == nil {
+		t.Error("SetTerrain accepted a 1x8 grid")
+	}
+	first := Heightmap{Width: 16, Depth: 8, TileSize: 2, HeightScale: 3, Seed: 42}
+	second := Heightmap{Width: 32, Depth: 32, TileSize: 1, HeightScale: 5, Seed: 7}
+	for _, hm := range []Heightmap{first, second} {
+		if err := s.SetTerrain(hm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if obj, _ := s.Terrain(); s.ObjectCount() != 1 || obj.Heightmap == nil || *obj.Heightmap != second || obj.Scale != second.Size() {
+		t.Fatalf("terrain after two heightmaps: %d objects, %+v", s.ObjectCount(), obj)
+	}
+
+	// Undo brings back the heightmap (so the renderer regenerates the old mesh), not only the size.
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.Terrain(); obj.Heightmap == nil || *obj.Heightmap != first || obj.Scale != [3]float32{32, 3, 16} {
+		t.Errorf("terrain after undo: %+v; want the first heightmap", obj)
+	}
+
+	// The heightmap is saved with the scene.
+	if err := s.SaveAs("hills"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("hills"); err != nil {
+		t.Fatal(err)
+	}
+	if obj, ok := s.Terrain(); !ok || obj.Heightmap == nil || *obj.Heightmap != first {
+		t.Errorf("terrain after load: %+v", obj)
+	}
+	if issues := s.Validate(); len(issues) != 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestSceneSchema(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// A version 1 file (no version field) loads and upgrades cleanly.
+	sd, issues, err := readSceneFile(write("v1.yaml", "objects:\n  - type: cube\n    position: [0, 1, 0]\n    scale: [1, 1, 1]\n"))
+	if err != nil || len(issues) != 0 || len(sd.Objects) != 1 || sd.Version != SchemaVersion {
+		t.Fatalf("v1 file: %+v, %v, %v", sd, issues, err)
+	}
+
+	bad := write("bad.yaml", `version: 2
+objects:
+  - type: cub
+    position: [0, 0, 0]
+  - type: sphere
+    position: [0, .nan, 0]
+    scale: [1, -2, 1]
+    texture: missing.png
+`)
+	issues, err = ValidateFile(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line, col int
+		path      string
+	}{
+		{3, 11, "objects[0].type"},
+		{6, 19, "objects[1].position[1]"},
+		{7, 16, "objects[1].scale[1]"},
+		{8, 14, "objects[1].texture"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %v; want %d", issues, len(want))
+	}
+	for i, w := range want {
+		if is := issues[i]; is.Line != w.line || is.Column != w.col || is.Path != w.path || is.File != bad {
+			t.Errorf("issue %d = %+v; want %s at %d:%d", i, is, w.path, w.line, w.col)
+		}
+	}
+
+	if _, err := ValidateFile(write("future.yaml", "version: 99\nobjects: []\n")); err == nil {
+		t.Error("a newer version loaded")
+	}
+	syntax := write("syntax.yaml", "objects:\n  - type: cube\n\tposition: [0, 0, 0]\n")
+	if _, err := ValidateFile(syntax); err == nil || !strings.HasPrefix(err.Error(), syntax+":2:1: found a tab") {
+		t.Errorf("syntax error = %v; want it with file and line", err)
+	}
+
+	// Saved files carry the current version and validate cleanly.
+	s := NewEmpty()
+	s.AddPrimitive("cube", [3]float32{0, 0, 0}, [3]float32{1, 1, 1})
+	path := filepath.Join(dir, "saved.yaml")
+	if err := s.writeSceneFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "version: 2") {
+		t.Errorf("saved file has no version:\n%s", data)
+	}
+	if issues := s.Validate(); len(issues) != 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+}
+
+func TestPrefabs(t *testing.T) {
+	defer func(scenes, prefabs []string) { sceneDirs, prefabDirs = scenes, prefabs }(sceneDirs, prefabDirs)
+	sceneDirs, prefabDirs = []string{t.TempDir()}, []string{t.TempDir()}
+
+	s := NewEmpty()
+	box := s.AddPrimitive("cube", [3]float32{4, 0.5, 0}, [3]float32{1, 1, 1})
+	ball := s.AddPrimitive("sphere", [3]float32{6, 1.5, 0}, [3]float32{1, 1, 1})
+	s.SetSelection([]ObjectID{box, ball})
+	if n, _, err := s.SavePrefab("hut"); err != nil || n != 2 {
+		t.Fatalf("SavePrefab = %d, %v", n, err)
+	}
+	if names := PrefabNames(); len(names) != 1 || names[0] != "hut" {
+		t.Fatalf("PrefabNames() = %v", names)
+	}
+
+	// Objects are saved relative to the floor center of the selection.
+	p, err := LoadPrefab("hut")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkedID := s.PlacePrefab(p, [3]float32{10, 0, 0}, [3]float32{}, true)
+	s.PlacePrefab(p, [3]float32{20, 0, 0}, [3]float32{}, false)
+	cube := s.Children(s.IndexOf(linkedID))[0]
+	if wt, _ := s.WorldTransform(cube); wt.Position != [3]float32{9, 0.5, 0} {
+		t.Errorf("instance cube at %v; want [9 0.5 0]", wt.Position)
+	}
+
+	// Saving the prefab again updates linked instances only.
+	s.SelectID(ball)
+	if _, updated, err := s.SavePrefab("hut"); err != nil || updated != 1 {
+		t.Fatalf("SavePrefab again = %d updated, %v", updated, err)
+	}
+	if n := len(s.Children(s.IndexOf(linkedID))); n != 1 {
+		t.Errorf("linked instance has %d children after the update; want 1", n)
+	}
+	for i := range s.sceneData.Objects {
+		if s.Parent(i) < 0 && s.sceneData.Objects[i].Type == GroupType && s.sceneData.Objects[i].Prefab == "" {
+			if n := len(s.Children(i)); n != 2 {
+				t.Errorf("unlinked instance has %d children; want 2", n)
+			}
+		}
+	}
+
+	// A scene saved with a linked instance picks up prefab changes when loaded.
+	if err := s.SaveAs("village"); err != nil {
+		t.Fatal(err)
+	}
+	s.SetSelection([]ObjectID{box, ball})
+	if _, _, err := s.SavePrefab("hut"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("village"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Children(s.IndexOf(linkedID))); n != 2 {
+		t.Errorf("linked instance has %d children after load; want 2", n)
+	}
+	if issues := s.Validate(); len(issues) != 0 {
+		t.Errorf("Validate() = %v", issues)
+	}
+
+	// Refreshing an unchanged prefab keeps the children's IDs and records no step.
+	children := s.IDs(s.Children(s.IndexOf(linkedID)))
+	if err := s.SaveScene(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("village"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RefreshPrefab("hut"); err != nil {
+		t.Fatal(err)
+	}
+	if after := s.IDs(s.Children(s.IndexOf(linkedID))); !slices.Equal(after, children) {
+		t.Errorf("children after reload and refresh = %v; want %v", after, children)
+	}
+	if undo, _ := s.History(); len(undo) != 0 {
+		t.Errorf("refreshing an unchanged prefab recorded %q", undo)
+	}
+
+	s.SelectID(linkedID)
+	if n, err := s.UnlinkSelected(); err != nil || n != 1 {
+		t.Errorf("UnlinkSelected = %d, %v", n, err)
+	}
+}
+
+func TestModelObjects(t *testing.T) {
+	defer func(scenes, models []string) { sceneDirs, modelDirs = scenes, models }(sceneDirs, modelDirs)
+	sceneDirs, modelDirs = []string{t.TempDir()}, []string{t.TempDir()}
+
+	// A 2 x 4 x 1 box with a material library and a texture in a subdirectory.
+	src := t.TempDir()
+	os.WriteFile(filepath.Join(src, "crate.obj"), []byte("mtllib crate.mtl\nv -1 0 0\nv 1 4 1\nv 1 0 0\nf 1 2 3\n"), 0644)
+	os.WriteFile(filepath.Join(src, "crate.mtl"), []byte("newmtl wood\nmap_Kd tex/wood.png\n"), 0644)
+	os.MkdirAll(filepath.Join(src, "tex"), 0755)
+	os.WriteFile(filepath.Join(src, "tex", "wood.png"), []byte("png"), 0644)
+
+	path, err := ImportModel(filepath.Join(src, "crate.obj"))
+	if err != nil || path != "crate.obj" {
+		t.Fatalf("ImportModel = %q, %v", path, err)
+	}
+	if _, err := os.Stat(filepath.Join(ModelDir(), "tex", "wood.png")); err != nil {
+		t.Errorf("texture not copied: %v", err)
+	}
+	if names := ModelNames(); len(names) != 1 || names[0] != "crate.obj" {
+		t.Errorf("ModelNames() = %v", names)
+	}
+
+	// Importing the same files again is fine; another model's file of the same name is never overwritten.
+	if path, err := ImportModel(filepath.Join(src, "crate.obj")); err != nil || path != "crate.obj" {
+		t.Errorf("ImportModel again = %q, %v", path, err)
+	}
+	other := t.TempDir()
+	os.WriteFile(filepath.Join(other, "barrel.obj"), []byte("mtllib crate.mtl\nv 0 0 0\nv 1 1 1\nv 1 0 0\nf 1 2 3\n"), 0644)
+	os.WriteFile(filepath.Join(other, "crate.mtl"), []byte("newmtl oak\n"), 0644)
+	if _, err := ImportModel(filepath.Join(other, "barrel.obj")); err == nil || !strings.Contains(err.Error(), "crate.mtl") {
+		t.Errorf("ImportModel over another model's material library = %v; want an error naming crate.mtl", err)
+	}
+	if _, err := os.Stat(filepath.Join(ModelDir(), "barrel.obj")); err == nil {
+		t.Error("refused import copied the model file")
+	}
+
+	// The object gets the model's size and stands on the given point.
+	s := NewEmpty()
+	id, err := s.AddModel(path, [3]float32{5, 0, 0}, [3]float32{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, _ := s.Object(id)
+	if obj.Scale != [3]float32{2, 4, 1} || obj.Position != [3]float32{5, 2, 0} {
+		t.Errorf("model at %v size %v; want [5 2 0] size [2 4 1]", obj.Position, obj.Scale)
+	}
+	if hit, _ := s.Pick(Ray{Position: [3]float32{5, 3.5, -10}, Direction: [3]float32{0, 0, 1}}); hit != s.IndexOf(id) {
+		t.Errorf("Pick through the top of the model = %d; want %d", hit, s.IndexOf(id))
+	}
+
+	// A model object without a scale gets the model's size on load; a missing file is an issue.
+	if err := writeObjects(filepath.Join(SceneDir(), "yard.yaml"), []ObjectInstance{
+		{Type: ModelType, Model: "crate.obj", Position: [3]float32{0, 2, 0}},
+		{Type: ModelType, Model: "gone.glb"},
+		{Type: ModelType},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("yard"); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.ObjectAt(0); obj.Scale != [3]float32{2, 4, 1} {
+		t.Errorf("loaded model size %v; want [2 4 1]", obj.Scale)
+	}
+	issues := s.LoadIssues()
+	if len(issues) != 2 || issues[0].Path != "objects[1].model" || issues[1].Path != "objects[2].type" {
+		t.Errorf("LoadIssues() = %v; want a missing file and a missing model field", issues)
+	}
+}
+
+func TestMaterials(t *testing.T) {
+	defer func(scenes, materials []string) {
+		sceneDirs, materialDirs = scenes, materials
+		ReloadMaterials()
+	}(sceneDirs, materialDirs)
+	sceneDirs, materialDirs = []string{t.TempDir()}, []string{t.TempDir()}
+	ReloadMaterials()
+
+	// Set validates each property; defaults fill in what a file leaves out.
+	var gold Material
+	for _, kv := range [][]string{{"albedo", "1", "0.8", "0.3"}, {"metallic", "1"}, {"roughness", "0.2"}, {"albedo_map", "gold.png"}, {"albedo_map", "none"}} {
+		if err := gold.Set(kv[0], kv[1:]...); err != nil {
+			t.Fatalf("Set(%v): %v", kv, err)
+		}
+	}
+	for _, kv := range [][]string{{"roughness", "2"}, {"albedo", "1"}, {"shininess", "1"}, {"uv_scale", "-1", "1"}} {
+		if err := gold.Set(kv[0], kv[1:]...); err == nil {
+			t.Errorf("Set(%v) accepted", kv)
+		}
+	}
+	if gold.AlbedoMap != "" || gold.RoughnessValue() != 0.2 || gold.UV() != [2]float32{1, 1} {
+		t.Errorf("gold = %+v", gold)
+	}
+	rev := MaterialRevision()
+	if err := SaveMaterial("gold", gold); err != nil {
+		t.Fatal(err)
+	}
+	if MaterialRevision() == rev {
+		t.Error("SaveMaterial did not bump the revision")
+	}
+	if err := SaveMaterial("../gold", gold); err == nil || !strings.Contains(err.Error(), "material name") {
+		t.Errorf("SaveMaterial(../gold) = %v; want a material name error", err)
+	}
+	ReloadMaterials()
+	if m, err := LoadMaterial("gold"); err != nil || m.Metallic != 1 || m.AlbedoColor() != gold.Albedo {
+		t.Errorf("LoadMaterial = %+v, %v", m, err)
+	}
+	os.WriteFile(filepath.Join(MaterialDir(), "bad.yaml"), []byte("shininess: 3\n"), 0644)
+	if _, err := LoadMaterial("bad"); err == nil {
+		t.Error("LoadMaterial accepted an unknown key")
+	}
+	if names := MaterialNames(); len(names) != 2 || names[0] != "bad" || names[1] != "gold" {
+		t.Errorf("MaterialNames() = %v", names)
+	}
+
+	// Materials apply to the selection and its descendants; an unknown one is refused.
+	s := NewEmpty()
+	s.SelectID(s.AddObject(ObjectInstance{Type: "cube", Children: []ObjectInstance{{Type: "sphere"}}}))
+	if err := s.SetSelectedMaterial("silver"); err == nil {
+		t.Error("SetSelectedMaterial accepted a missing material")
+	}
+	if err := s.SetSelectedMaterial("gold"); err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range s.Objects() {
+		if obj.Material != "gold" {
+			t.Errorf("%s material = %q; want gold", obj.Type, obj.Material)
+		}
+	}
+
+	// The terrain's texture repeat is its material's UV scale; a terrain without one gets "terrain".
+	if _, err := s.SetTerrainUVScale(4, 4); err == nil {
+		t.Error("SetTerrainUVScale without a terrain succeeded")
+	}
+	if err := s.SetTerrain(Heightmap{Width: 20, Depth: 20, TileSize: 1, HeightScale: 2, Seed: 7}); err != nil {
+		t.Fatal(err)
+	}
+	name, err := s.SetTerrainUVScale(4, 2)
+	if err != nil || name != TerrainMaterialName {
+		t.Fatalf("SetTerrainUVScale = %q, %v", name, err)
+	}
+	if m, _ := LoadMaterial(name); m.UV() != [2]float32{4, 2} {
+		t.Errorf("terrain material uv = %v; want [4 2]", m.UV())
+	}
+	// Changing it again clones the material instead of rewriting the one that may be shared, and undo
+	// switches back to it.
+	if name, err := s.SetTerrainUVScale(8, 8); err != nil || name != TerrainMaterialName+"-2" {
+		t.Fatalf("SetTerrainUVScale again = %q, %v", name, err)
+	}
+	if m, _ := LoadMaterial(TerrainMaterialName); m.UV() != [2]float32{4, 2} {
+		t.Errorf("original terrain material uv = %v after the second change; want [4 2]", m.UV())
+	}
+	if _, err := s.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.Terrain(); obj.Material != TerrainMaterialName {
+		t.Errorf("terrain material after undo = %q; want %q", obj.Material, TerrainMaterialName)
+	}
+
+	// A missing material is a load issue, not an error.
+	if err := writeObjects(filepath.Join(SceneDir(), "shiny.yaml"), []ObjectInstance{
+		{Type: "cube", Material: "gold"},
+		{Type: "cube", Material: "chrome"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Load("shiny"); err != nil {
+		t.Fatal(err)
+	}
+	if issues := s.LoadIssues(); len(issues) != 1 || issues[0].Path != "objects[1].material" {
+		t.Errorf("LoadIssues() = %v; want the missing chrome material", issues)
+	}
+}
+
+func TestLights(t *testing.T) {
+	s := NewEmpty()
+	if _, err := s.AddLight("torch", [3]float32{0, 3, 0}); err == nil {
+		t.Error("AddLight accepted an unknown kind")
+	}
+	lamp, err := s.AddLight(PointLight, [3]float32{0, 3, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	spot, _ := s.AddLight(SpotLight, [3]float32{20, 4, 0})
+
+	// Lights are not solid: a cube dropped on the lamp falls past it.
+	dropped := s.AddPrimitive("cube", [3]float32{0, 4, 0}, [3]float32{1, 1, 1})
+	for range 60 {
+		s.Step(1.0 / 60)
+	}
+	if cube, _ := s.Object(dropped); cube.Position[1] > 2 {
+		t.Errorf("cube stopped at y=%v above the light", cube.Position[1])
+	}
+
+	// Lights come nearest first, with defaults filled in; an unrotated spot points down.
+	lights := s.Lights([3]float32{18, 0, 0})
+	if len(lights) != 2 || lights[0].Index != s.IndexOf(spot) || lights[1].Index != s.IndexOf(lamp) {
+		t.Fatalf("Lights() = %+v; want the spot first", lights)
+	}
+	if l := lights[0]; !l.Spot || l.Cone != DefaultSpotCone || l.Range != DefaultLightRange || l.Color != [3]float32{1, 1, 1} {
+		t.Errorf("spot = %+v", l)
+	}
+	if d := lights[0].Direction; math.Abs(float64(d[1]+1)) > 1e-5 {
+		t.Errorf("spot direction = %v; want down", d)
+	}
+
+	// Properties apply to the selected lights only, validated, and undo restores them.
+	s.SelectID(spot)
+	s.AddToSelection([]ObjectID{dropped})
+	if n, err := s.SetSelectedLight("cone", 30); err != nil || n != 1 {
+		t.Fatalf("SetSelectedLight = %d, %v; want the one spot", n, err)
+	}
+	if _, err := s.SetSelectedLight("intensity", 0); err != nil {
+		t.Fatal(err)
+	}
+	if obj, _ := s.Object(spot); obj.Light.IntensityValue() != 0 {
+		t.Errorf("intensity = %v; want 0 (off), not the default", obj.Light.IntensityValue())
+	}
+	if _, err := s.SetSelectedLight("cone", 200); err == nil {
+		t.Error("SetSelectedLight accepted a 200 degree cone")
+	}
+	if obj, _ := s.Object(spot); obj.Light.ConeValue() != 30 {
+		t.Errorf("cone = %v; want 30", obj.Light.ConeValue())
+	}
+	s.Undo(2)
+	if obj, _ := s.Object(spot); obj.Light.ConeValue() != DefaultSpotCone {
+		t.Errorf("cone after undo = %v; want %v", obj.Light.ConeValue(), DefaultSpotCone)
+	}
+
+	// Lights round-trip through the scene file; bad light values are issues.
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lamps.yaml")
+	if err := s.writeSceneFile(path); err != nil {
+		t.Fatal(err)
+	}
+	sd, issues, err := readSceneFile(path)
+	if err != nil || len(issues) != 0 || sd.Objects[0].Light == nil || sd.Objects[0].Light.Kind != PointLight {
+		t.Fatalf("read back: %+v, %v, %v", sd.Objects, issues, err)
+	}
+	bad := filepath.Join(dir, "bad.yaml")
+	os.WriteFile(bad, []byte("objects:\n  - type: light\n    position: [0, 3, 0]\n  - type: light\n    position: [0, 3, 0]\n    light: {kind: spot, cone: 0.5, glow: 1}\n"), 0644)
+	issues, err = ValidateFile(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, len(issues))
+	for i, is := range issues {
+		paths[i] = is.Path
+	}
+	if want := []string{"objects[0].type", "objects[1].light.glow", "objects[1].light"}; !slices.Equal(paths, want) {
+		t.Errorf("issues = %v; want %v", issues, want)
+	}
+
+	// Lighting profiles set the sun; night is dim so local lights stand out.
+	if err := s.SetLighting("dusk"); err == nil {
+		t.Error("SetLighting accepted an unknown profile")
+	}
+	noon := s.Sun()
+	s.SetLighting("night")
+	if night := s.Sun(); night.Intensity >= noon.Intensity || night.Ambient[0] >= noon.Ambient[0] || s.LightDir() != night.Dir {
+		t.Errorf("night sun = %+v; noon %+v", night, noon)
+	}
+}
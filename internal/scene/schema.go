@@ -0,0 +1,458 @@
+package scene
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"game-engine/internal/modelfile"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scene file schema. Files carry "version:"; files without one are version 1. Loading parses the YAML into
+// a node tree, upgrades it to SchemaVersion with the migrations below, checks every object (validate) and
+// only then decodes it into SceneData, so problems are reported with their line and column instead of
+// leaving an empty or half-loaded scene without a word. Problems in single objects are Issues: the scene
+// still loads and the caller logs them. A file that is not valid YAML or is newer than this engine does
+// not load at all.
+//
+// Adding a field that older files lack needs no migration as long as its zero value keeps the old
+// meaning. Renaming or restructuring one does: bump SchemaVersion and add migrations[old version] that
+// rewrites the node tree of an old file into the new shape.
+
+// SchemaVersion is the scene file version this engine writes.
+//
+//	1: objects with type, position, scale, physics, texture, color, name, motion (no version field)
+//	2: adds id, rotation and children (later optional fields, no version bump: prefab, model, material, light,
+//	   heightmap)
+const SchemaVersion = 2
+
+// migrations[v] upgrades a version v document (the file's root mapping) to version v+1 in place.
+var migrations = map[int]func(root *yaml.Node) error{
+	// id, rotation and children are optional: version 1 objects load as they are and get IDs on load.
+	1: func(root *yaml.Node) error { return nil },
+}
+
+// objectTypes are the object types the engine can draw (or, for groups, hold children).
+var objectTypes = map[string]bool{"cube": true, "sphere": true, "cylinder": true, "plane": true, "terrain": true, GroupType: true, ModelType: true, LightType: true}
+
+// objectFields are the keys an object may have in the scene file (ObjectInstance's yaml tags).
+var objectFields = map[string]bool{
+	"id": true, "type": true, "position": true, "scale": true, "rotation": true, "physics": true,
+	"texture": true, "color": true, "name": true, "motion": true, "children": true, "prefab": true,
+	"model": true, "material": true, "light": true, "heightmap": true,
+}
+
+// lightFields are the keys of an object's light (Light's yaml tags).
+var lightFields = map[string]bool{"kind": true, "intensity": true, "range": true, "cone": true}
+
+// heightmapFields are the keys of a terrain's heightmap (Heightmap's yaml tags).
+var heightmapFields = map[string]bool{
+	"width": true, "depth": true, "tile": true, "height": true, "seed": true, "octaves": true,
+	"frequency": true, "lacunarity": true, "gain": true,
+}
+
+// Issue is one problem found in a scene file: where it is (line and column in the file, and the object
+// path such as objects[2].children[0].scale) and what is wrong.
+type Issue struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+}
+
+// String formats the issue as file:line:col: path: message.
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.File != "" {
+		b.WriteString(i.File + ":")
+	}
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "%d:%d:", i.Line, i.Column)
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ValidateFile checks a scene file without loading it: syntax, version, and every object (known type,
+// finite numbers, non-negative scale, color in 0-1, known motion, unique IDs, texture files that exist).
+// A file that cannot be read or parsed at all is returned as the error.
+func ValidateFile(path string) ([]Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, issues, err := parseScene(data, path)
+	return issues, err
+}
+
+// Validate checks the scene as it would be saved (same checks as ValidateFile; paths refer to the nested
+// objects, lines to the file SaveScene would write).
+func (s *Scene) Validate() []Issue {
+	data, err := yaml.Marshal(&SceneData{Version: SchemaVersion, Objects: s.nestedObjects()})
+	if err != nil {
+		return []Issue{{Message: err.Error()}}
+	}
+	_, issues, err := parseScene(data, "")
+	if err != nil {
+		issues = append(issues, Issue{Message: err.Error()})
+	}
+	return issues
+}
+
+// LoadIssues returns the problems found in the scene file when it was last opened or loaded (nil if none).
+func (s *Scene) LoadIssues() []Issue {
+	return s.loadIssues
+}
+
+// yamlLine matches the position yaml.v3 puts in its error messages.
+var yamlLine = regexp.MustCompile(`line (\d+): ?`)
+
+// parseScene parses, migrates and validates a scene file. file is used in issues and to find texture
+// files relative to it. The error is set only when nothing can be loaded.
+func parseScene(data []byte, file string) (SceneData, []Issue, error) {
+	var sd SceneData
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			msg = strings.Replace(msg, m[0], "", 1)
+			return sd, nil, errors.New(Issue{File: file, Line: line, Column: 1, Message: msg}.String())
+		}
+		return sd, nil, fmt.Errorf("%s: %s", file, msg)
+	}
+	if len(doc.Content) == 0 {
+		return sd, nil, nil // empty file: empty scene
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return sd, nil, errors.New(Issue{File: file, Line: root.Line, Column: root.Column, Message: "scene file must be a mapping with an objects list"}.String())
+	}
+
+	version := 1
+	if _, v := mappingValue(root, "version"); v != nil {
+		if err := v.Decode(&version); err != nil || version < 1 {
+			return sd, nil, errors.New(Issue{File: file, Line: v.Line, Column: v.Column, Path: "version", Message: "version must be a positive integer"}.String())
+		}
+	}
+	if version > SchemaVersion {
+		return sd, nil, fmt.Errorf("%s: scene file version %d is newer than this engine supports (%d)", file, version, SchemaVersion)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := migrations[version](root); err != nil {
+			return sd, nil, fmt.Errorf("%s: upgrading from version %d: %w", file, version, err)
+		}
+	}
+
+	v := &validator{file: file, ids: map[ObjectID]string{}}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, val := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "version":
+		case "objects":
+			v.objects(val, "objects")
+		default:
+			v.add(key, key.Value, "unknown field")
+		}
+	}
+	if err := root.Decode(&sd); err != nil {
+		// Fields of the wrong type are decoded as zero; report those the checks above did not.
+		te, ok := err.(*yaml.TypeError)
+		if !ok {
+			return sd, v.issues, fmt.Errorf("%s: %w", file, err)
+		}
+		seen := map[int]bool{}
+		for _, is := range v.issues {
+			seen[is.Line] = true
+		}
+		for _, msg := range te.Errors {
+			line := 0
+			if m := yamlLine.FindStringSubmatch(msg); m != nil {
+				line, _ = strconv.Atoi(m[1])
+				msg = strings.Replace(msg, m[0], "", 1)
+			}
+			if line == 0 || !seen[line] {
+				v.issues = append(v.issues, Issue{File: file, Line: line, Column: 1, Message: msg})
+			}
+		}
+	}
+	sd.Version = SchemaVersion
+	return sd, v.issues, nil
+}
+
+// mappingValue returns the key and value nodes for key in a mapping node, or nils.
+func mappingValue(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// validator collects the issues of one scene file.
+type validator struct {
+	file   string
+	ids    map[ObjectID]string // ID → path of the first object that uses it
+	issues []Issue
+}
+
+func (v *validator) add(n *yaml.Node, path, format string, args ...any) {
+	v.issues = append(v.issues, Issue{File: v.file, Line: n.Line, Column: n.Column, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// objects checks a list of objects (the root list or a children list).
+func (v *validator) objects(n *yaml.Node, path string) {
+	if n.Kind != yaml.SequenceNode {
+		if n.Tag != "!!null" {
+			v.add(n, path, "must be a list of objects")
+		}
+		return
+	}
+	for i, obj := range n.Content {
+		v.object(obj, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+// object checks one object mapping and its children.
+func (v *validator) object(n *yaml.Node, path string) {
+	if n.Kind != yaml.MappingNode {
+		v.add(n, path, "object must be a mapping (type, position, ...)")
+		return
+	}
+	if _, t := mappingValue(n, "type"); t == nil {
+		v.add(n, path, "missing type")
+	} else if _, m := mappingValue(n, "model"); t.Value == ModelType && m == nil {
+		v.add(t, path+".type", "model object needs a model file (model: name.glb)")
+	} else if _, l := mappingValue(n, "light"); t.Value == LightType && l == nil {
+		v.add(t, path+".type", "light object needs a light (light: {kind: point})")
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, val := n.Content[i], n.Content[i+1]
+		p := path + "." + key.Value
+		if !objectFields[key.Value] {
+			v.add(key, p, "unknown field (known: %s)", strings.Join(sortedKeys(objectFields), ", "))
+			continue
+		}
+		switch key.Value {
+		case "id":
+			var id ObjectID
+			if err := val.Decode(&id); err != nil {
+				v.add(val, p, "id must be a positive integer")
+			} else if first, dup := v.ids[id]; dup && id != 0 {
+				v.add(val, p, "duplicate id %d (also %s); a fresh id is assigned on load", id, first)
+			} else {
+				v.ids[id] = path
+			}
+		case "type":
+			if !objectTypes[val.Value] {
+				v.add(val, p, "unknown type %q (known: %s)", val.Value, strings.Join(sortedKeys(objectTypes), ", "))
+			}
+		case "position", "rotation":
+			v.vector(val, p, func(f float64) string { return "" })
+		case "scale":
+			v.vector(val, p, func(f float64) string {
+				if f < 0 {
+					return "must not be negative"
+				}
+				return ""
+			})
+		case "color":
+			v.vector(val, p, func(f float64) string {
+				if f < 0 || f > 1 {
+					return "must be between 0 and 1"
+				}
+				return ""
+			})
+		case "physics":
+			var b bool
+			if val.Decode(&b) != nil {
+				v.add(val, p, "must be true or false")
+			}
+		case "motion":
+			if val.Value != "" && val.Value != "spin" && val.Value != "bob" {
+				v.add(val, p, "unknown motion %q (spin, bob or none)", val.Value)
+			}
+		case "texture":
+			if val.Value != "" && !v.textureExists(val.Value) {
+				v.add(val, p, "texture file %q not found", val.Value)
+			}
+		case "prefab":
+			if err := ValidPrefabName(val.Value); err != nil {
+				v.add(val, p, "%v", err)
+			} else if !v.prefabExists(val.Value) {
+				v.add(val, p, "prefab %q not found; the instance keeps the objects saved with it", val.Value)
+			}
+		case "model":
+			if !modelfile.Supported(val.Value) {
+				v.add(val, p, "unsupported model file %q (use %s)", val.Value, strings.Join(modelfile.Extensions, ", "))
+			} else if !v.modelExists(val.Value) {
+				v.add(val, p, "model file %q not found; the object is drawn as a placeholder box", val.Value)
+			}
+		case "material":
+			if err := ValidMaterialName(val.Value); err != nil {
+				v.add(val, p, "%v", err)
+			} else if !v.materialExists(val.Value) {
+				v.add(val, p, "material %q not found; the object is drawn with its color and texture", val.Value)
+			} else if _, err := LoadMaterial(val.Value); err != nil && MaterialExists(val.Value) {
+				v.add(val, p, "material %q: %v", val.Value, err)
+			}
+		case "light":
+			v.light(val, p)
+		case "heightmap":
+			v.heightmap(val, p)
+		case "children":
+			v.objects(val, p)
+		}
+	}
+}
+
+// light checks an object's light mapping: known keys and valid values (see Light.Check).
+func (v *validator) light(n *yaml.Node, path string) {
+	if n.Kind != yaml.MappingNode {
+		v.add(n, path, "light must be a mapping (kind, intensity, range, cone)")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if key := n.Content[i]; !lightFields[key.Value] {
+			v.add(key, path+"."+key.Value, "unknown field (known: %s)", strings.Join(sortedKeys(lightFields), ", "))
+		}
+	}
+	var l Light
+	if err := n.Decode(&l); err != nil {
+		v.add(n, path, "%v", err)
+	} else if err := l.Check(); err != nil {
+		v.add(n, path, "%v", err)
+	}
+}
+
+// heightmap checks a terrain's heightmap mapping: known keys and valid values (see Heightmap.Check).
+func (v *validator) heightmap(n *yaml.Node, path string) {
+	if n.Kind != yaml.MappingNode {
+		v.add(n, path, "heightmap must be a mapping (width, depth, tile, height, seed, ...)")
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if key := n.Content[i]; !heightmapFields[key.Value] {
+			v.add(key, path+"."+key.Value, "unknown field (known: %s)", strings.Join(sortedKeys(heightmapFields), ", "))
+		}
+	}
+	var h Heightmap
+	if err := n.Decode(&h); err != nil {
+		v.add(n, path, "%v", err)
+	} else if err := h.Check(); err != nil {
+		v.add(n, path, "%v", err)
+	}
+}
+
+// vector checks a [x, y, z] list of finite numbers; check returns a message for a bad component or "".
+func (v *validator) vector(n *yaml.Node, path string, check func(f float64) string) {
+	if n.Kind != yaml.SequenceNode || len(n.Content) != 3 {
+		v.add(n, path, "must be a list of 3 numbers")
+		return
+	}
+	for k, c := range n.Content {
+		var f float64
+		switch {
+		case c.Decode(&f) != nil:
+			v.add(c, fmt.Sprintf("%s[%d]", path, k), "%q is not a number", c.Value)
+		case math.IsNaN(f) || math.IsInf(f, 0):
+			v.add(c, fmt.Sprintf("%s[%d]", path, k), "%v is not a finite number", f)
+		default:
+			if msg := check(f); msg != "" {
+				v.add(c, fmt.Sprintf("%s[%d]", path, k), "%v %s", f, msg)
+			}
+		}
+	}
+}
+
+// textureBases are tried as prefixes for texture paths, as the renderer does (run from the repo root or
+// cmd/game).
+var textureBases = []string{"", "assets/textures/", "../../assets/textures/"}
+
+// textureExists reports whether a texture path resolves the way the renderer resolves it, or relative to
+// a directory above the scene file (so CI can validate from anywhere).
+func (v *validator) textureExists(path string) bool {
+	for _, base := range textureBases {
+		if _, err := os.Stat(filepath.Join(base, path)); err == nil {
+			return true
+		}
+	}
+	if v.file == "" {
+		return false
+	}
+	dir := filepath.Dir(v.file)
+	for i := 0; i < 4; i++ {
+		if _, err := os.Stat(filepath.Join(dir, path)); err == nil {
+			return true
+		}
+		dir = filepath.Join(dir, "..")
+	}
+	return false
+}
+
+// modelExists reports whether a model path resolves the way the renderer resolves it (ResolveModelPath),
+// or in the models directory next to the scene file's directory (assets/scenes -> assets/models).
+func (v *validator) modelExists(path string) bool {
+	if _, ok := ResolveModelPath(path); ok {
+		return true
+	}
+	if v.file == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(v.file), "..", "models", path))
+	return err == nil
+}
+
+// materialExists reports whether the named material is in the material library, or in the materials
+// directory next to the scene file's directory (assets/scenes -> assets/materials).
+func (v *validator) materialExists(name string) bool {
+	if MaterialExists(name) {
+		return true
+	}
+	if v.file == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(v.file), "..", "materials", name+".yaml"))
+	return err == nil
+}
+
+// prefabExists reports whether the named prefab is in the prefab library, or in the prefabs directory next
+// to the scene file's directory (assets/scenes -> assets/prefabs).
+func (v *validator) prefabExists(name string) bool {
+	paths := []string{filepath.Join(PrefabDir(), name+".yaml")}
+	if v.file != "" {
+		paths = append(paths, filepath.Join(filepath.Dir(v.file), "..", "prefabs", name+".yaml"))
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of m in order, for messages.
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,207 @@
+package scene
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"game-engine/internal/physics"
+)
+
+// Selection: the editor and commands work on a set of objects, kept by ID in the order they were selected.
+// The last one is the primary: the gizmo sits on it and single-object views (inspector fields, focus of
+// rotate drags) read it. Property setters (SetSelectedColor, SetSelectedPhysics, ...) apply to every
+// selected object as one undo step; moves and rotations apply to the top-level selected objects only, so a
+// child selected together with its parent is not moved twice.
+
+// Selection returns the current indices of the selected objects, primary last. Deleted objects are skipped.
+func (s *Scene) Selection() []int {
+	return s.Indices(s.selection)
+}
+
+// SelectionIDs returns the IDs of the selected objects that still exist, primary last.
+func (s *Scene) SelectionIDs() []ObjectID {
+	return s.IDs(s.Selection())
+}
+
+// SetSelection selects exactly the objects with the given IDs (the last one becomes the primary).
+// IDs of objects that no longer exist and duplicates are ignored; an empty list clears the selection.
+func (s *Scene) SetSelection(ids []ObjectID) {
+	s.selection = nil
+	seen := map[ObjectID]bool{}
+	for _, id := range ids {
+		if !seen[id] && s.IndexOf(id) >= 0 {
+			seen[id] = true
+			s.selection = append(s.selection, id)
+		}
+	}
+}
+
+// AddToSelection adds the objects with the given IDs to the selection; the last one added becomes the
+// primary.
+func (s *Scene) AddToSelection(ids []ObjectID) {
+	s.SetSelection(append(s.SelectionIDs(), ids...))
+	for _, id := range ids {
+		s.moveToEnd(id)
+	}
+}
+
+// ToggleSelection adds the object with the given ID to the selection (as the primary) or removes it if it
+// is already selected (shift-click).
+func (s *Scene) ToggleSelection(id ObjectID) {
+	if s.IndexOf(id) < 0 {
+		return
+	}
+	if s.IsSelected(s.IndexOf(id)) {
+		s.SetSelection(without(s.selection, id))
+		return
+	}
+	s.AddToSelection([]ObjectID{id})
+}
+
+// IsSelected reports whether the object at index is in the selection.
+func (s *Scene) IsSelected(index int) bool {
+	id := s.IDAt(index)
+	for _, sel := range s.selection {
+		if id != 0 && sel == id {
+			return true
+		}
+	}
+	return false
+}
+
+// moveToEnd makes id the primary if it is selected.
+func (s *Scene) moveToEnd(id ObjectID) {
+	rest := without(s.selection, id)
+	if len(rest) < len(s.selection) {
+		s.selection = append(rest, id)
+	}
+}
+
+// without returns ids minus id, as a new slice.
+func without(ids []ObjectID, id ObjectID) []ObjectID {
+	out := make([]ObjectID, 0, len(ids))
+	for _, x := range ids {
+		if x != id {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+// MatchesName reports whether name matches pattern, case-insensitively: a glob (path.Match syntax, e.g.
+// "building*") when pattern contains *, ? or [, else a substring. An empty pattern matches every name.
+func MatchesName(name, pattern string) bool {
+	name, pattern = strings.ToLower(name), strings.ToLower(pattern)
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.Contains(name, pattern)
+	}
+	ok, err := path.Match(pattern, name)
+	return ok && err == nil
+}
+
+// SelectMatching selects every object in the scene (in view or not) matching type, color and name pattern
+// (see MatchesName); typ "" = any type, color nil = any color. With add, the matches are added to the
+// current selection instead of replacing it. Returns the number of matches.
+func (s *Scene) SelectMatching(typ string, colorOptional *[3]float32, namePattern string, add bool) (int, error) {
+	all := make([]VisibleObject, len(s.sceneData.Objects))
+	for i, obj := range s.sceneData.Objects {
+		all[i] = VisibleObject{ID: obj.ID, Index: i, Object: obj}
+	}
+	var matches []int
+	for _, v := range visibleMatchFilters(all, typ, colorOptional, "") {
+		if MatchesName(v.Object.Name, namePattern) {
+			matches = append(matches, v.Index)
+		}
+	}
+	if len(matches) == 0 {
+		if namePattern != "" {
+			return 0, fmt.Errorf("no objects matching %q", namePattern)
+		}
+		return 0, fmt.Errorf("no matching objects")
+	}
+	if add {
+		s.AddToSelection(s.IDs(matches))
+	} else {
+		s.SetSelection(s.IDs(matches))
+	}
+	return len(matches), nil
+}
+
+// SelectInRect selects the top-level objects whose on-screen center lies in the rectangle with corners a
+// and b (pixels, any order), as for a marquee drag. With add, they are added to the current selection.
+// Returns the number of objects in the rectangle.
+func (s *Scene) SelectInRect(a, b [2]float32, add bool) int {
+	lo := [2]float32{min(a[0], b[0]), min(a[1], b[1])}
+	hi := [2]float32{max(a[0], b[0]), max(a[1], b[1])}
+	var hits []int
+	for _, v := range s.ObjectsInView() {
+		if s.parents[v.Index] >= 0 {
+			continue
+		}
+		p := v.ScreenPos
+		if p[0] >= lo[0] && p[0] <= hi[0] && p[1] >= lo[1] && p[1] <= hi[1] {
+			hits = append(hits, v.Index)
+		}
+	}
+	if add {
+		s.AddToSelection(s.IDs(hits))
+	} else {
+		s.SetSelection(s.IDs(hits))
+	}
+	return len(hits)
+}
+
+// selectedRoots returns the indices of the selected objects that have no selected ancestor.
+func (s *Scene) selectedRoots() []int {
+	return s.topLevel(s.Selection())
+}
+
+// SelectionBounds returns the world AABB around every selected object and its descendants (without
+// motion), or false if nothing is selected.
+func (s *Scene) SelectionBounds() (physics.AABB, bool) {
+	roots := s.selectedRoots()
+	if len(roots) == 0 {
+		return physics.AABB{}, false
+	}
+	box := s.subtreeBounds(roots[0], false)
+	for _, i := range roots[1:] {
+		b := s.subtreeBounds(i, false)
+		for k := 0; k < 3; k++ {
+			box.Min[k] = min(box.Min[k], b.Min[k])
+			box.Max[k] = max(box.Max[k], b.Max[k])
+		}
+	}
+	return box, true
+}
+
+// MoveSelection moves every top-level selected object (with its subtree) by delta in world space, as one
+// undo step.
+func (s *Scene) MoveSelection(delta [3]float32) error {
+	roots := s.selectedRoots()
+	if len(roots) == 0 {
+		return fmt.Errorf("no object selected")
+	}
+	defer s.edit("move")()
+	for _, i := range roots {
+		p := s.worldTransform(i, false).Position
+		s.setWorldPosition(i, [3]float32{p[0] + delta[0], p[1] + delta[1], p[2] + delta[2]})
+	}
+	s.syncSceneToPhysics()
+	return nil
+}
+
+// RotateSelectionBy turns every top-level selected object by the world rotation q about its own origin,
+// as one undo step.
+func (s *Scene) RotateSelectionBy(q physics.Quat) error {
+	roots := s.selectedRoots()
+	if len(roots) == 0 {
+		return fmt.Errorf("no object selected")
+	}
+	defer s.edit("rotate")()
+	for _, i := range roots {
+		s.setWorldRotation(i, q.Mul(s.worldTransform(i, false).Rotation))
+	}
+	s.syncSceneToPhysics()
+	return nil
+}
@@ -0,0 +1,56 @@
+package scene
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxHeightmapTiles is the largest width or depth, in tiles, of a heightmap terrain.
+const MaxHeightmapTiles = 1024
+
+// Heightmap is how a terrain object's mesh is generated (see mapgen): a Width x Depth grid of tiles of
+// TileSize on X/Z, up to HeightScale high, shaped by fractal noise from Seed. It is saved with the terrain
+// object, so the renderer can generate the same mesh again after undo, redo and loading the scene.
+// Octaves, Frequency, Lacunarity and Gain control the noise; 0 selects mapgen's default. Seed 0 picks a new
+// random terrain each time the mesh is generated.
+type Heightmap struct {
+	Width       int     `yaml:"width"`
+	Depth       int     `yaml:"depth"`
+	TileSize    float32 `yaml:"tile"`
+	HeightScale float32 `yaml:"height"`
+	Seed        int64   `yaml:"seed,omitempty"`
+	Octaves     int     `yaml:"octaves,omitempty"`
+	Frequency   float32 `yaml:"frequency,omitempty"`
+	Lacunarity  float32 `yaml:"lacunarity,omitempty"`
+	Gain        float32 `yaml:"gain,omitempty"`
+}
+
+// Size returns the world size of the terrain: width, height scale and depth.
+func (h Heightmap) Size() [3]float32 {
+	return [3]float32{float32(h.Width) * h.TileSize, h.HeightScale, float32(h.Depth) * h.TileSize}
+}
+
+// Check returns an error describing the first invalid value: a grid smaller than 2x2 or larger than
+// MaxHeightmapTiles, a tile size or height that is not positive, negative noise values, or numbers that
+// are not finite.
+func (h Heightmap) Check() error {
+	for _, f := range []float32{h.TileSize, h.HeightScale, h.Frequency, h.Lacunarity, h.Gain} {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return fmt.Errorf("heightmap values must be finite numbers")
+		}
+	}
+	switch {
+	case h.Width < 2 || h.Depth < 2 || h.Width > MaxHeightmapTiles || h.Depth > MaxHeightmapTiles:
+		return fmt.Errorf("heightmap width and depth must be between 2 and %d tiles", MaxHeightmapTiles)
+	case h.TileSize <= 0 || h.HeightScale <= 0:
+		return fmt.Errorf("heightmap tile size and height must be positive")
+	case h.Octaves < 0 || h.Frequency < 0 || h.Lacunarity < 0 || h.Gain < 0:
+		return fmt.Errorf("heightmap noise values must not be negative")
+	}
+	return nil
+}
+
+// Terrain returns the scene's terrain object and true, or false if it has none.
+func (s *Scene) Terrain() (ObjectInstance, bool) {
+	return s.ObjectAt(s.terrainObjectIndex())
+}
@@ -22,15 +22,16 @@ const (
 
 var (
 	// Reused every frame when drawing the terminal bar to avoid per-frame color allocations.
-	termBarColor   = rl.NewColor(40, 40, 40, 255)
-	termLineColor  = rl.NewColor(80, 80, 80, 255)
+	termBarColor    = rl.NewColor(40, 40, 40, 255)
+	termLineColor   = rl.NewColor(80, 80, 80, 255)
 	termChatBgColor = rl.NewColor(24, 24, 24, 240)
 )
 
 // Terminal is the chat/terminal input bar at the bottom of the screen. It is shown/hidden with ESC.
 // When open, it handles typing and drawing; when closed, nothing is drawn and the player can move (WASD).
 // Lines starting with "cmd " are parsed as subcommand + flags and executed via the command registry.
-// Other lines are treated as natural language; if OnNaturalLanguage is set, it is called in a goroutine.
+// Other lines are treated as natural language; if OnNaturalLanguage is set, it is called on the main thread
+// in submission order and must not block (e.g. it queues the request). Ctrl+C calls OnCancel.
 // GetViewContext, if set, is called on the main thread when the user submits natural language; its result
 // is passed as the second argument so the LLM can reason about what the camera sees (e.g. "delete the one on the right").
 type Terminal struct {
@@ -38,9 +39,10 @@ type Terminal struct {
 	reg               *commands.Registry
 	inputBuf          string
 	open              bool
-	font              rl.Font // optional; when set, Draw uses DrawTextEx instead of default font
-	GetViewContext    func() string       // optional; called on main thread when user submits NL
-	OnNaturalLanguage func(line string, viewContext string) // called in a goroutine when user submits a non-cmd line
+	font              rl.Font                               // optional; when set, Draw uses DrawTextEx instead of default font
+	GetViewContext    func() string                         // optional; called on main thread when user submits NL
+	OnNaturalLanguage func(line string, viewContext string) // called on the main thread when user submits a non-cmd line; must not block
+	OnCancel          func()                                // optional; called on Ctrl+C (e.g. cancel the running LLM request)
 }
 
 // New returns a new Terminal that logs lines and runs "cmd ..." through reg. It starts closed (hidden); press ESC to open.
@@ -71,6 +73,10 @@ func (t *Terminal) Update() {
 	if !t.open {
 		return
 	}
+	ctrl := rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)
+	if ctrl && rl.IsKeyPressed(rl.KeyC) && t.OnCancel != nil {
+		t.OnCancel()
+	}
 	// Paste: Ctrl+V (Windows/Linux) or Cmd+V (macOS)
 	if rl.IsKeyPressed(rl.KeyV) && (rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl) || rl.IsKeyDown(rl.KeyLeftSuper) || rl.IsKeyDown(rl.KeyRightSuper)) {
 		if pasted := rl.GetClipboardText(); pasted != "" {
@@ -103,8 +109,7 @@ func (t *Terminal) Update() {
 			if t.GetViewContext != nil {
 				viewCtx = t.GetViewContext()
 			}
-			viewCtxCopy := viewCtx
-			go t.OnNaturalLanguage(line, viewCtxCopy)
+			t.OnNaturalLanguage(line, viewCtx)
 		} else {
 			t.log.Log(line)
 		}
@@ -135,6 +140,9 @@ func (t *Terminal) Draw() {
 		rl.DrawRectangle(0, int32(chatY), int32(screenW), int32(chatHeight), termChatBgColor)
 	}
 	lines := t.log.Lines()
+	if status := t.log.Status(); status != "" {
+		lines = append(lines, status)
+	}
 	start := 0
 	if len(lines) > maxLinesOnScreen {
 		start = len(lines) - maxLinesOnScreen
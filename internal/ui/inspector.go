@@ -2,7 +2,8 @@ package ui
 
 import "fmt"
 
-// Inspector is a right-side panel that shows name, position, scale, and physics of a selected object.
+// Inspector is a right-side panel that shows name, position, scale, and physics of the selection. With
+// several objects selected, fields that differ between them read "Mixed".
 // It owns its nodes and updates their text when AppendNodes is called with visible true.
 // Shown only when visible is true (e.g. terminal open and an object selected).
 type Inspector struct {
@@ -29,13 +30,20 @@ func NewInspector() *Inspector {
 }
 
 // Selection holds the data shown in the inspector (name/type, position, scale, physics, texture).
-// Pass this from the scene or game layer; ui does not depend on scene.
+// Pass this from the scene or game layer; ui does not depend on scene. For several objects, Count is their
+// number, Position their center, and the *Mixed flags mark fields whose values differ between them.
 type Selection struct {
+	Count    int // number of selected objects; 0 or 1 = a single object
 	Name     string
 	Position [3]float32
 	Scale    [3]float32
 	Physics  bool   // true = falling/collision on; false = static (use cmd physics on/off to toggle)
 	Texture  string // path to texture if set (e.g. assets/textures/downloaded/foo.png)
+
+	NameMixed    bool
+	ScaleMixed   bool
+	PhysicsMixed bool
+	TextureMixed bool
 }
 
 // AppendNodes appends inspector nodes to dst when visible is true, after updating labels from sel.
@@ -45,17 +53,33 @@ func (in *Inspector) AppendNodes(dst []*Node, visible bool, sel Selection) []*No
 		return dst
 	}
 	in.title.Text = "Inspector"
-	in.name.Text = "Name: " + sel.Name
 	in.position.Text = fmt.Sprintf("Position: %.2f, %.2f, %.2f", sel.Position[0], sel.Position[1], sel.Position[2])
+	if sel.Count > 1 {
+		in.title.Text = fmt.Sprintf("Inspector (%d selected)", sel.Count)
+		in.position.Text = fmt.Sprintf("Center: %.2f, %.2f, %.2f", sel.Position[0], sel.Position[1], sel.Position[2])
+	}
+	in.name.Text = "Name: " + sel.Name
+	if sel.NameMixed {
+		in.name.Text = "Name: Mixed"
+	}
 	in.scale.Text = fmt.Sprintf("Scale: %.2f, %.2f, %.2f", sel.Scale[0], sel.Scale[1], sel.Scale[2])
-	if sel.Physics {
+	if sel.ScaleMixed {
+		in.scale.Text = "Scale: Mixed"
+	}
+	switch {
+	case sel.PhysicsMixed:
+		in.physics.Text = "Physics: Mixed"
+	case sel.Physics:
 		in.physics.Text = "Physics: On"
-	} else {
+	default:
 		in.physics.Text = "Physics: Off"
 	}
-	if sel.Texture != "" {
+	switch {
+	case sel.TextureMixed:
+		in.texture.Text = "Texture: Mixed"
+	case sel.Texture != "":
 		in.texture.Text = "Texture: " + sel.Texture
-	} else {
+	default:
 		in.texture.Text = "Texture: —"
 	}
 	return append(dst, in.panel, in.title, in.name, in.position, in.scale, in.physics, in.texture)